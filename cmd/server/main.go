@@ -39,8 +39,13 @@ func main() {
 	server.SetStorage()
 	server.LoadMetricsFromFile()
 	server.SaveMetricsAtIntervals(ctx)
+	server.PruneSamplesAtIntervals(ctx)
+	server.EvaluateAlertsAtIntervals(ctx)
 	server.SaveMetricsOnExit(ctx)
-	server.RunServer(ctx)
+	server.ReloadKeysOnSignal(ctx)
+	server.RunServer(ctx, cancel)
+	server.RunStatsD(ctx)
+	server.RunGraphite()
 
 	<-ctx.Done()
 	server.Shutdown()
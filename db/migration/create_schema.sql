@@ -1,9 +1,47 @@
 create table IF NOT EXISTS public.metrics_counter (
-  name character varying primary key not null,
+  name character varying not null,
+  labels jsonb not null default '{}',
   value bigint not null
 );
 
 create table IF NOT EXISTS public.metrics_gauge (
-  name character varying primary key not null,
+  name character varying not null,
+  labels jsonb not null default '{}',
   value double precision not null
 );
+
+create table IF NOT EXISTS public.metrics_histogram (
+  name character varying not null,
+  labels jsonb not null default '{}',
+  bounds double precision[] not null,
+  counts bigint[] not null,
+  count bigint not null,
+  sum double precision not null
+);
+
+-- Series are identified by name and labels
+alter table public.metrics_counter add column IF NOT EXISTS labels jsonb not null default '{}';
+alter table public.metrics_counter drop constraint IF EXISTS metrics_counter_pkey;
+create unique index IF NOT EXISTS metrics_counter_name_labels_idx on public.metrics_counter (name, labels);
+
+alter table public.metrics_gauge add column IF NOT EXISTS labels jsonb not null default '{}';
+alter table public.metrics_gauge drop constraint IF EXISTS metrics_gauge_pkey;
+create unique index IF NOT EXISTS metrics_gauge_name_labels_idx on public.metrics_gauge (name, labels);
+
+create unique index IF NOT EXISTS metrics_histogram_name_labels_idx on public.metrics_histogram (name, labels);
+
+create table IF NOT EXISTS public.metrics_gauge_sample (
+  name character varying not null,
+  labels jsonb not null default '{}',
+  ts timestamp with time zone not null,
+  value double precision not null
+);
+create index IF NOT EXISTS metrics_gauge_sample_series_idx on public.metrics_gauge_sample (name, labels, ts);
+
+create table IF NOT EXISTS public.metrics_counter_sample (
+  name character varying not null,
+  labels jsonb not null default '{}',
+  ts timestamp with time zone not null,
+  value bigint not null
+);
+create index IF NOT EXISTS metrics_counter_sample_series_idx on public.metrics_counter_sample (name, labels, ts);
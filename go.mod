@@ -7,6 +7,7 @@ require (
 	github.com/caarlos0/env/v10 v10.0.0
 	github.com/go-chi/chi/v5 v5.0.11
 	github.com/go-resty/resty/v2 v2.10.0
+	github.com/golang/snappy v0.0.4
 	github.com/grpc-ecosystem/go-grpc-middleware/v2 v2.1.0
 	github.com/jackc/pgerrcode v0.0.0-20220416144525-469b46aa5efa
 	github.com/jackc/pgx/v5 v5.5.2
@@ -15,6 +16,7 @@ require (
 	github.com/stretchr/testify v1.8.4
 	github.com/timakin/bodyclose v0.0.0-20240125160201-f835fa56326a
 	go.uber.org/zap v1.26.0
+	golang.org/x/time v0.3.0
 	golang.org/x/tools v0.17.0
 	google.golang.org/grpc v1.63.2
 	google.golang.org/protobuf v1.34.1
@@ -1,12 +1,16 @@
 package agent
 
 import (
+	"crypto/tls"
+
 	"github.com/fishus/go-advanced-metrics/internal/cryptokey"
+	"github.com/fishus/go-advanced-metrics/internal/tlsconfig"
 )
 
 var (
 	Config    config
 	PublicKey []byte
+	TLSConfig *tls.Config
 )
 
 func Initialize() error {
@@ -25,5 +29,13 @@ func Initialize() error {
 		PublicKey = pubKey
 	}
 
+	if Config.UseTLS() {
+		tlsConf, err := tlsconfig.Client(Config.tlsCAPath, Config.tlsCertPath, Config.tlsKeyPath)
+		if err != nil {
+			return err
+		}
+		TLSConfig = tlsConf
+	}
+
 	return nil
 }
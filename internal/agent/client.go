@@ -9,4 +9,5 @@ import (
 type IAgentClient interface {
 	Init() error
 	RetryUpdateBatch(context.Context, []metrics.Metrics) error
+	Close() error
 }
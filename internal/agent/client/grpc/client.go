@@ -3,11 +3,13 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/realip"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
@@ -15,16 +17,27 @@ import (
 
 	ac "github.com/fishus/go-advanced-metrics/internal/agent/client"
 	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
+	ic "github.com/fishus/go-advanced-metrics/internal/grpc/interceptors"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
 	pb "github.com/fishus/go-advanced-metrics/proto"
 )
 
+// requestTimeout limits the time of a batch update.
+const requestTimeout = 1 * time.Second
+
+// Client sends the batches of metrics over a long-lived StreamUpdates stream.
+// It falls back to the unary Updates calls when the server does not support the stream.
 type Client struct {
 	config Config
 	conn   *grpc.ClientConn
 	client pb.MetricsClient
 	ip     string
+
+	mu        sync.Mutex
+	stream    *batchStream
+	unaryOnly bool // сервер не поддерживает StreamUpdates
 }
 
 func NewClient(conf Config) *Client {
@@ -38,8 +51,43 @@ func (c *Client) Conn() *grpc.ClientConn {
 	return c.conn
 }
 
+// Close closes the update stream and the connection to the server.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.stream != nil {
+		c.stream.close()
+		c.stream = nil
+	}
+	c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
 func (c *Client) Init() error {
-	conn, err := grpc.Dial(c.config.ServerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds := insecure.NewCredentials()
+	if c.config.TLSConfig != nil {
+		creds = credentials.NewTLS(c.config.TLSConfig)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(
+			ic.TokenUnaryClientInterceptor(c.config.Token),
+			ic.SignUnaryClientInterceptor(secure.Key{ID: c.config.KeyID, Secret: []byte(c.config.SecretKey)}),
+		),
+		grpc.WithChainStreamInterceptor(
+			ic.TokenStreamClientInterceptor(c.config.Token),
+			ic.SignStreamClientInterceptor(secure.Key{ID: c.config.KeyID, Secret: []byte(c.config.SecretKey)}),
+		),
+	}
+	if len(c.config.PublicKey) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.ForceCodec(sg.EncryptCodec{PublicKey: c.config.PublicKey})))
+	}
+
+	conn, err := grpc.Dial(c.config.ServerAddr, opts...)
 	if err != nil {
 		logger.Log.Error(err.Error(), logger.String("address", c.config.ServerAddr), logger.String("event", "start agent worker"))
 		return fmt.Errorf("can't connect to grpc server: %w", err)
@@ -106,7 +154,7 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 	default:
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
 
 	var mb []*pb.Metric
@@ -119,6 +167,24 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 		mb = append(mb, &m)
 	}
 
+	c.mu.Lock()
+	unaryOnly := c.unaryOnly
+	c.mu.Unlock()
+
+	if !unaryOnly {
+		err := c.streamUpdateBatch(ctx, mb)
+		if status.Code(err) != codes.Unimplemented {
+			return err
+		}
+
+		logger.Log.Warn("The server does not support stream updates, falling back to unary calls",
+			logger.String("event", "send request"),
+			logger.String("addr", c.config.ServerAddr))
+		c.mu.Lock()
+		c.unaryOnly = true
+		c.mu.Unlock()
+	}
+
 	req := &pb.UpdatesRequest{
 		Metrics: mb,
 	}
@@ -145,3 +211,23 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 
 	return nil
 }
+
+// streamUpdateBatch sends the batch over the update stream and waits for its acknowledgement.
+func (c *Client) streamUpdateBatch(ctx context.Context, mb []*pb.Metric) error {
+	stream, err := c.getStream()
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return err
+	}
+
+	logger.Log.Debug(`Send batch to the update stream`,
+		logger.String("event", "send request"),
+		logger.String("addr", c.config.ServerAddr),
+		logger.Int("metrics", len(mb)))
+
+	err = stream.send(ctx, mb)
+	if err != nil && status.Code(err) != codes.Unimplemented {
+		logger.Log.Error(err.Error())
+	}
+	return err
+}
@@ -0,0 +1,252 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/cryptokey"
+	gs "github.com/fishus/go-advanced-metrics/internal/grpc/server"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+type ClientSuite struct {
+	suite.Suite
+	addr string
+}
+
+func (s *ClientSuite) SetupTest() {
+	controller.Storage = store.NewMemStorage()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	s.addr = l.Addr().String()
+	s.Require().NoError(l.Close())
+}
+
+type testServer interface {
+	Run(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+func (s *ClientSuite) runServer(cfg gs.Config) testServer {
+	cfg.ServerAddr = s.addr
+	cfg.Storage = controller.Storage
+	srv := gs.NewServer(cfg)
+	go func() { _ = srv.Run(context.Background()) }()
+
+	s.Require().Eventually(func() bool {
+		conn, err := net.Dial("tcp", s.addr)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	return srv
+}
+
+func (s *ClientSuite) stopServer(srv testServer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.Require().NoError(srv.Shutdown(ctx))
+}
+
+func (s *ClientSuite) TestStreamUpdates() {
+	ctx := context.Background()
+	srv := s.runServer(gs.Config{})
+
+	client := NewClient(Config{ServerAddr: s.addr})
+	s.Require().NoError(client.Init())
+	defer client.Close()
+
+	batch := []metrics.Metrics{
+		metrics.NewCounterMetric("requests").SetDelta(2),
+		metrics.NewGaugeMetric("temp").SetValue(1.5),
+	}
+	s.Require().NoError(client.UpdateBatch(ctx, batch))
+	s.Require().NoError(client.UpdateBatch(ctx, batch))
+	s.NotNil(client.stream)
+
+	counter, _ := controller.Storage.CounterValue("requests")
+	s.Equal(int64(4), counter)
+
+	err := client.UpdateBatch(ctx, []metrics.Metrics{metrics.NewCounterMetric("requests")})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+
+	// После перезапуска сервера поток открывается заново.
+	s.stopServer(srv)
+	srv = s.runServer(gs.Config{})
+	defer s.stopServer(srv)
+
+	s.Require().NoError(client.RetryUpdateBatch(ctx, batch))
+	counter, _ = controller.Storage.CounterValue("requests")
+	s.Equal(int64(6), counter)
+}
+
+func (s *ClientSuite) TestSecure() {
+	ctx := context.Background()
+
+	privateKey, err := cryptokey.ReadKeyFile("../../../cryptokey/test-private.pem")
+	s.Require().NoError(err)
+	publicKey, err := cryptokey.ReadKeyFile("../../../cryptokey/test-public.pem")
+	s.Require().NoError(err)
+
+	srv := s.runServer(gs.Config{Keys: secure.NewKeyring([]byte("secret")), PrivateKey: privateKey})
+	defer s.stopServer(srv)
+
+	batch := []metrics.Metrics{metrics.NewCounterMetric("requests").SetDelta(2)}
+
+	testCases := []struct {
+		name    string
+		config  Config
+		unary   bool
+		code    codes.Code
+		counter int64
+	}{
+		{
+			name:    "Stream",
+			config:  Config{SecretKey: "secret", PublicKey: publicKey},
+			code:    codes.OK,
+			counter: 2,
+		},
+		{
+			name:    "Unary",
+			config:  Config{SecretKey: "secret", PublicKey: publicKey},
+			unary:   true,
+			code:    codes.OK,
+			counter: 4,
+		},
+		{
+			name:    "Wrong secret key",
+			config:  Config{SecretKey: "wrong", PublicKey: publicKey},
+			code:    codes.InvalidArgument,
+			counter: 4,
+		},
+		{
+			name:    "Not encrypted",
+			config:  Config{SecretKey: "secret"},
+			code:    codes.Internal,
+			counter: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.config.ServerAddr = s.addr
+			client := NewClient(tc.config)
+			s.Require().NoError(client.Init())
+			defer client.Close()
+			client.unaryOnly = tc.unary
+
+			err := client.UpdateBatch(ctx, batch)
+			s.Equal(tc.code, status.Code(err))
+
+			counter, _ := controller.Storage.CounterValue("requests")
+			s.Equal(tc.counter, counter)
+		})
+	}
+
+	// Запросы чтения не шифруются
+	conn, err := grpc.NewClient(s.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+	defer conn.Close()
+	resp, err := pb.NewMetricsClient(conn).Value(ctx, &pb.ValueRequest{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER})
+	s.Require().NoError(err)
+	s.Equal(int64(4), resp.Metric.GetDelta())
+}
+
+func (s *ClientSuite) TestKeyRotation() {
+	ctx := context.Background()
+
+	path := filepath.Join(s.T().TempDir(), "keyring.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{"signing_key": "new", "keys": [
+		{"id": "old", "secret": "old secret"},
+		{"id": "new", "secret": "new secret"}
+	]}`), 0600))
+	keys, err := secure.LoadKeyring(path, []byte("legacy secret"))
+	s.Require().NoError(err)
+
+	srv := s.runServer(gs.Config{Keys: keys})
+	defer s.stopServer(srv)
+
+	batch := []metrics.Metrics{metrics.NewCounterMetric("requests").SetDelta(1)}
+
+	testCases := []struct {
+		name   string
+		config Config
+		code   codes.Code
+	}{
+		{
+			name:   "Old key",
+			config: Config{SecretKey: "old secret", KeyID: "old"},
+			code:   codes.OK,
+		},
+		{
+			name:   "New key",
+			config: Config{SecretKey: "new secret", KeyID: "new"},
+			code:   codes.OK,
+		},
+		{
+			name:   "Key without ID",
+			config: Config{SecretKey: "old secret"},
+			code:   codes.OK,
+		},
+		{
+			name:   "Legacy key",
+			config: Config{SecretKey: "legacy secret"},
+			code:   codes.OK,
+		},
+		{
+			name:   "Unknown key ID",
+			config: Config{SecretKey: "old secret", KeyID: "unknown"},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Wrong secret of the key",
+			config: Config{SecretKey: "old secret", KeyID: "new"},
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, unary := range []bool{false, true} {
+			s.Run(tc.name, func() {
+				tc.config.ServerAddr = s.addr
+				client := NewClient(tc.config)
+				s.Require().NoError(client.Init())
+				defer client.Close()
+				client.unaryOnly = unary
+
+				err := client.UpdateBatch(ctx, batch)
+				s.Equal(tc.code, status.Code(err), "unary: %v", unary)
+			})
+		}
+	}
+
+	// Старый ключ удаляется из связки без перезапуска сервера
+	s.Require().NoError(os.WriteFile(path, []byte(`{"signing_key": "new", "keys": [{"id": "new", "secret": "new secret"}]}`), 0600))
+	s.Require().NoError(keys.Reload())
+
+	client := NewClient(Config{ServerAddr: s.addr, SecretKey: "old secret", KeyID: "old"})
+	s.Require().NoError(client.Init())
+	defer client.Close()
+	s.Equal(codes.InvalidArgument, status.Code(client.UpdateBatch(ctx, batch)))
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
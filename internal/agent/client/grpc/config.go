@@ -1,7 +1,12 @@
 package grpc
 
+import "crypto/tls"
+
 type Config struct {
 	ServerAddr string
 	SecretKey  string
+	KeyID      string // ID ключа подписи, пустое значение не передаётся
+	Token      string // API-токен агента
 	PublicKey  []byte
+	TLSConfig  *tls.Config // Настройки TLS, nil - соединение без шифрования
 }
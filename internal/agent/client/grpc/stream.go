@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/realip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+// errStreamClosed is returned for the pending batches when the server closes the stream without an error.
+var errStreamClosed = status.Error(codes.Unavailable, "update stream closed by the server")
+
+// batchStream is a long-lived StreamUpdates stream shared by the workers of the agent.
+// The acknowledgements of the server are passed to the senders by the batch id.
+type batchStream struct {
+	stream pb.Metrics_StreamUpdatesClient
+	cancel context.CancelFunc
+
+	sendMu sync.Mutex // Send нельзя вызывать одновременно из нескольких горутин
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *pb.StreamUpdatesResponse // ожидающие подтверждения пакеты
+
+	done chan struct{} // закрывается при обрыве потока
+	err  error         // ошибка, с которой оборвался поток
+}
+
+// getStream returns the open update stream, a broken stream is re-established.
+func (c *Client) getStream() (*batchStream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stream != nil {
+		select {
+		case <-c.stream.done:
+			logger.Log.Warn(c.stream.err.Error(), logger.String("event", "update stream broken"))
+			c.stream.close()
+			c.stream = nil
+		default:
+			return c.stream, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	md := metadata.New(map[string]string{realip.XRealIp: c.ip})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	stream, err := c.client.StreamUpdates(ctx, grpc.UseCompressor(gzip.Name))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c.stream = &batchStream{
+		stream:  stream,
+		cancel:  cancel,
+		pending: make(map[uint64]chan *pb.StreamUpdatesResponse),
+		done:    make(chan struct{}),
+	}
+	go c.stream.receive()
+
+	logger.Log.Debug("Update stream opened", logger.String("event", "open stream"), logger.String("addr", c.config.ServerAddr))
+	return c.stream, nil
+}
+
+// receive passes the acknowledgements to the senders until the stream is broken.
+func (s *batchStream) receive() {
+	for {
+		ack, err := s.stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errStreamClosed
+			}
+			s.err = err
+			close(s.done)
+			return
+		}
+
+		s.mu.Lock()
+		ch, ok := s.pending[ack.BatchId]
+		delete(s.pending, ack.BatchId)
+		s.mu.Unlock()
+
+		if ok {
+			ch <- ack
+		}
+	}
+}
+
+// send sends the batch and waits for its acknowledgement.
+// The rejected batch is returned as the status error with the code of the acknowledgement.
+func (s *batchStream) send(ctx context.Context, mb []*pb.Metric) error {
+	ack := make(chan *pb.StreamUpdatesResponse, 1)
+
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.pending[id] = ack
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	s.sendMu.Lock()
+	err := s.stream.Send(&pb.StreamUpdatesRequest{BatchId: id, Metrics: mb})
+	s.sendMu.Unlock()
+	// При io.EOF поток оборван, причина будет получена в receive.
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	select {
+	case resp := <-ack:
+		if code := codes.Code(resp.Code); code != codes.OK {
+			return status.Error(code, resp.Error)
+		}
+		return nil
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// close closes the sending side of the stream and cancels it.
+func (s *batchStream) close() {
+	s.sendMu.Lock()
+	_ = s.stream.CloseSend()
+	s.sendMu.Unlock()
+	s.cancel()
+}
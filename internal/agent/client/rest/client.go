@@ -9,6 +9,9 @@ import (
 	"errors"
 	"io"
 	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -24,6 +27,7 @@ type Client struct {
 	config Config
 	client *resty.Client
 	gz     *gzip.Writer
+	chunks atomic.Bool // Сервер не поддерживает конверт, тело шифруется блоками
 }
 
 func NewClient(conf Config) *Client {
@@ -34,7 +38,11 @@ func NewClient(conf Config) *Client {
 }
 
 func (c *Client) Init() error {
-	c.client = resty.New().SetBaseURL("http://" + c.config.ServerAddr)
+	if c.config.TLSConfig != nil {
+		c.client = resty.New().SetBaseURL("https://" + c.config.ServerAddr).SetTLSClientConfig(c.config.TLSConfig)
+	} else {
+		c.client = resty.New().SetBaseURL("http://" + c.config.ServerAddr)
+	}
 	logger.Log.Info("Running rest worker", logger.String("address", c.config.ServerAddr), logger.String("event", "start agent worker"))
 
 	ip, err := ac.GetIP()
@@ -45,6 +53,10 @@ func (c *Client) Init() error {
 		c.client.SetHeader("X-Real-IP", ip.String())
 	}
 
+	if c.config.Token != "" {
+		c.client.SetAuthToken(c.config.Token)
+	}
+
 	gz, err := gzip.NewWriterLevel(nil, gzip.BestCompression)
 	if err != nil {
 		logger.Log.Warn(err.Error())
@@ -54,6 +66,11 @@ func (c *Client) Init() error {
 	return nil
 }
 
+// Close releases the resources of the client.
+func (c *Client) Close() error {
+	return nil
+}
+
 func (c *Client) RetryUpdateBatch(ctx context.Context, batch []metrics.Metrics) (err error) {
 	var neterr *net.OpError
 
@@ -103,14 +120,24 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 		return err
 	}
 
-	var hashString string
+	// Метка времени и nonce подписываются вместе с телом и защищают запрос от повтора
+	var hashString, timestamp, nonce string
 	if c.config.SecretKey != "" {
-		hash := secure.Hash(jsonBody, []byte(c.config.SecretKey))
+		timestamp, nonce, err = secure.NewNonce()
+		if err != nil {
+			return err
+		}
+		hash := secure.RequestHash(jsonBody, []byte(c.config.SecretKey), timestamp, nonce)
 		hashString = hex.EncodeToString(hash[:])
 	}
 
+	var scheme string
 	if len(c.config.PublicKey) > 0 {
-		jsonBody, err = cryptokey.Encrypt(jsonBody, c.config.PublicKey)
+		scheme = cryptokey.SchemeEnvelope
+		if c.chunks.Load() {
+			scheme = cryptokey.SchemeChunks
+		}
+		jsonBody, err = cryptokey.EncryptScheme(jsonBody, c.config.PublicKey, scheme)
 		if err != nil {
 			return err
 		}
@@ -147,7 +174,16 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 		SetBody(buf)
 
 	if hashString != "" {
-		req.SetHeader("HashSHA256", hashString)
+		req.SetHeader("HashSHA256", hashString).
+			SetHeader(secure.TimestampHeader, timestamp).
+			SetHeader(secure.NonceHeader, nonce)
+		if c.config.KeyID != "" {
+			req.SetHeader(secure.KeyIDHeader, c.config.KeyID)
+		}
+	}
+
+	if scheme != "" {
+		req.SetHeader(cryptokey.SchemeHeader, scheme)
 	}
 
 	url := "updates/"
@@ -156,7 +192,7 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 	if err != nil {
 		logger.Log.Error(err.Error(),
 			logger.String("event", "send request"),
-			logger.String("url", "http://"+c.config.ServerAddr+"/"+url),
+			logger.String("url", c.client.BaseURL+"/"+url),
 			logger.Any("body", json.RawMessage(jsonBody)))
 		return err
 	}
@@ -164,6 +200,16 @@ func (c *Client) UpdateBatch(ctx context.Context, batch []metrics.Metrics) error
 	rawBody := resp.RawBody()
 	defer rawBody.Close()
 
+	// Сервер старой версии не сообщает о поддержке конверта и не может его расшифровать
+	if scheme == cryptokey.SchemeEnvelope && resp.StatusCode() == http.StatusBadRequest &&
+		!strings.Contains(resp.Header().Get(cryptokey.AcceptSchemeHeader), cryptokey.SchemeEnvelope) {
+		logger.Log.Warn("Server does not support the envelope encryption, falling back to the chunks",
+			logger.String("event", "negotiate encryption"),
+			logger.String("addr", c.config.ServerAddr))
+		c.chunks.Store(true)
+		return c.UpdateBatch(ctx, batch)
+	}
+
 	gzBody, err := gzip.NewReader(rawBody)
 	if err != nil && err != io.EOF {
 		return err
@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/cryptokey"
+	"github.com/fishus/go-advanced-metrics/internal/handlers/middleware"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+type ClientSuite struct {
+	suite.Suite
+	publicKey  []byte
+	privateKey []byte
+
+	mu      sync.Mutex
+	schemes []string
+}
+
+func (s *ClientSuite) SetupSuite() {
+	publicKey, err := cryptokey.ReadKeyFile("../../../cryptokey/test-public.pem")
+	s.Require().NoError(err)
+	s.publicKey = publicKey
+
+	privateKey, err := cryptokey.ReadKeyFile("../../../cryptokey/test-private.pem")
+	s.Require().NoError(err)
+	s.privateKey = privateKey
+}
+
+func (s *ClientSuite) SetupTest() {
+	s.schemes = nil
+}
+
+// updates decodes the batch.
+func (s *ClientSuite) updates(w http.ResponseWriter, r *http.Request) {
+	var batch []metrics.Metrics
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	_, _ = io.WriteString(gz, "{}")
+}
+
+// newServer runs the server that records the encryption scheme of every request.
+func (s *ClientSuite) newServer(decrypt func(http.Handler) http.Handler) *httptest.Server {
+	handler := decrypt(http.HandlerFunc(s.updates))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.schemes = append(s.schemes, r.Header.Get(cryptokey.SchemeHeader))
+		s.mu.Unlock()
+
+		gz, err := gzip.NewReader(r.Body)
+		s.Require().NoError(err)
+		r.Body = gz
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+// legacyDecrypt is the middleware of the servers that only support the chunks.
+func (s *ClientSuite) legacyDecrypt(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		s.Require().NoError(err)
+
+		body, err = cryptokey.DecryptChunks(nil, s.rsaKey(), body)
+		if err != nil {
+			http.Error(w, "Failed to decrypt request data", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *ClientSuite) rsaKey() *rsa.PrivateKey {
+	key, err := x509.ParsePKCS1PrivateKey(s.privateKey)
+	s.Require().NoError(err)
+	return key
+}
+
+func (s *ClientSuite) TestEncryptionNegotiation() {
+	batch := []metrics.Metrics{metrics.NewCounterMetric("PollCount").SetDelta(1)}
+
+	testCases := []struct {
+		name    string
+		decrypt func(http.Handler) http.Handler
+		want    []string
+	}{
+		{
+			name:    "Server supports the envelope",
+			decrypt: middleware.Decrypt(s.privateKey),
+			want:    []string{cryptokey.SchemeEnvelope, cryptokey.SchemeEnvelope},
+		},
+		{
+			name:    "Legacy server",
+			decrypt: s.legacyDecrypt,
+			want:    []string{cryptokey.SchemeEnvelope, cryptokey.SchemeChunks, cryptokey.SchemeChunks},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+			ts := s.newServer(tc.decrypt)
+			defer ts.Close()
+
+			client := NewClient(Config{
+				ServerAddr: strings.TrimPrefix(ts.URL, "http://"),
+				PublicKey:  s.publicKey,
+			})
+			s.Require().NoError(client.Init())
+
+			s.Require().NoError(client.UpdateBatch(context.Background(), batch))
+			s.Require().NoError(client.UpdateBatch(context.Background(), batch))
+			s.Equal(tc.want, s.schemes)
+		})
+	}
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
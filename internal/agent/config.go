@@ -1,17 +1,28 @@
 package agent
 
-import "time"
+import (
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
 
 type config struct {
 	serverAddr     string        // serverAddr store address and port to send requests to a server
 	secretKey      string        // Ключ для подписи данных
+	keyID          string        // ID ключа подписи, передаётся серверу для выбора ключа из связки
+	apiToken       string        // API-токен агента
 	publicKeyPath  string        // Путь до файла с публичным ключом
+	tlsCAPath      string        // Путь до файла с сертификатами CA для проверки сервера
+	tlsCertPath    string        // Путь до файла с сертификатом агента для mTLS
+	tlsKeyPath     string        // Путь до файла с ключом сертификата агента
 	configFile     string        // Путь к файлу конфигурации
 	logLevel       string        //
 	pollInterval   time.Duration // Обновлять метрики с заданной частотой (в секундах)
 	reportInterval time.Duration // Отправлять метрики на сервер с заданной частотой (в секундах)
 	rateLimit      uint          // Количество одновременно исходящих запросов
+	useTLS         bool          // Использовать TLS при подключении к серверу
 	clientType     ClientType
+	labels         metrics.Labels // Метки, добавляемые ко всем отправляемым метрикам
 }
 
 type ClientType string
@@ -78,6 +89,24 @@ func (c config) SetSecretKey(key string) config {
 	return c
 }
 
+func (c config) KeyID() string {
+	return c.keyID
+}
+
+func (c config) SetKeyID(id string) config {
+	c.keyID = id
+	return c
+}
+
+func (c config) APIToken() string {
+	return c.apiToken
+}
+
+func (c config) SetAPIToken(token string) config {
+	c.apiToken = token
+	return c
+}
+
 func (c config) PublicKeyPath() string {
 	return c.publicKeyPath
 }
@@ -87,6 +116,42 @@ func (c config) SetPublicKeyPath(path string) config {
 	return c
 }
 
+func (c config) UseTLS() bool {
+	return c.useTLS || c.tlsCAPath != "" || c.tlsCertPath != ""
+}
+
+func (c config) SetUseTLS(use bool) config {
+	c.useTLS = use
+	return c
+}
+
+func (c config) TLSCAPath() string {
+	return c.tlsCAPath
+}
+
+func (c config) SetTLSCAPath(path string) config {
+	c.tlsCAPath = path
+	return c
+}
+
+func (c config) TLSCertPath() string {
+	return c.tlsCertPath
+}
+
+func (c config) SetTLSCertPath(path string) config {
+	c.tlsCertPath = path
+	return c
+}
+
+func (c config) TLSKeyPath() string {
+	return c.tlsKeyPath
+}
+
+func (c config) SetTLSKeyPath(path string) config {
+	c.tlsKeyPath = path
+	return c
+}
+
 func (c config) RateLimit() uint {
 	if c.rateLimit < 1 {
 		return 1
@@ -111,3 +176,12 @@ func (c config) SetClientType(t ClientType) config {
 func (c config) LogLevel() string {
 	return c.logLevel
 }
+
+func (c config) Labels() metrics.Labels {
+	return c.labels
+}
+
+func (c config) SetLabels(labels metrics.Labels) config {
+	c.labels = labels.Clone()
+	return c
+}
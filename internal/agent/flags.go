@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/caarlos0/env/v10"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
 )
 
 func loadConfig() (conf config, err error) {
@@ -46,10 +48,34 @@ func parseConfigFile(config config) (config, error) {
 		config.serverAddr = cf.serverAddr
 	}
 
+	if config.keyID == defaults.keyID && cf.keyID != defaults.keyID {
+		config.keyID = cf.keyID
+	}
+
+	if config.apiToken == defaults.apiToken && cf.apiToken != defaults.apiToken {
+		config.apiToken = cf.apiToken
+	}
+
 	if config.publicKeyPath == defaults.publicKeyPath && cf.publicKeyPath != defaults.publicKeyPath {
 		config.publicKeyPath = cf.publicKeyPath
 	}
 
+	if config.useTLS == defaults.useTLS && cf.useTLS != defaults.useTLS {
+		config.useTLS = cf.useTLS
+	}
+
+	if config.tlsCAPath == defaults.tlsCAPath && cf.tlsCAPath != defaults.tlsCAPath {
+		config.tlsCAPath = cf.tlsCAPath
+	}
+
+	if config.tlsCertPath == defaults.tlsCertPath && cf.tlsCertPath != defaults.tlsCertPath {
+		config.tlsCertPath = cf.tlsCertPath
+	}
+
+	if config.tlsKeyPath == defaults.tlsKeyPath && cf.tlsKeyPath != defaults.tlsKeyPath {
+		config.tlsKeyPath = cf.tlsKeyPath
+	}
+
 	if config.pollInterval == defaults.pollInterval && cf.pollInterval != defaults.pollInterval {
 		config.pollInterval = cf.pollInterval
 	}
@@ -62,6 +88,10 @@ func parseConfigFile(config config) (config, error) {
 		config.rateLimit = cf.rateLimit
 	}
 
+	if len(config.labels) == 0 && len(cf.labels) != 0 {
+		config.labels = cf.labels
+	}
+
 	return config, nil
 }
 
@@ -72,11 +102,18 @@ func loadConfigFile(path string, config config) (config, error) {
 	}
 
 	type Conf struct {
-		Address        string `json:"address,omitempty"`
-		PollInterval   string `json:"poll_interval,omitempty"`
-		ReportInterval string `json:"report_interval,omitempty"`
-		RateLimit      uint   `json:"rate_limit,omitempty"`
-		CryptoKey      string `json:"crypto_key,omitempty"`
+		Address        string            `json:"address,omitempty"`
+		PollInterval   string            `json:"poll_interval,omitempty"`
+		ReportInterval string            `json:"report_interval,omitempty"`
+		RateLimit      uint              `json:"rate_limit,omitempty"`
+		CryptoKey      string            `json:"crypto_key,omitempty"`
+		KeyID          string            `json:"key_id,omitempty"`
+		Token          string            `json:"token,omitempty"`
+		TLS            bool              `json:"tls,omitempty"`
+		TLSCA          string            `json:"tls_ca,omitempty"`
+		TLSCert        string            `json:"tls_cert,omitempty"`
+		TLSKey         string            `json:"tls_key,omitempty"`
+		Labels         map[string]string `json:"labels,omitempty"`
 	}
 	var conf Conf
 	if err = json.Unmarshal(data, &conf); err != nil {
@@ -111,6 +148,38 @@ func loadConfigFile(path string, config config) (config, error) {
 		config = config.SetPublicKeyPath(conf.CryptoKey)
 	}
 
+	if conf.KeyID != "" {
+		config = config.SetKeyID(conf.KeyID)
+	}
+
+	if conf.Token != "" {
+		config = config.SetAPIToken(conf.Token)
+	}
+
+	if conf.TLS {
+		config = config.SetUseTLS(conf.TLS)
+	}
+
+	if conf.TLSCA != "" {
+		config = config.SetTLSCAPath(conf.TLSCA)
+	}
+
+	if conf.TLSCert != "" {
+		config = config.SetTLSCertPath(conf.TLSCert)
+	}
+
+	if conf.TLSKey != "" {
+		config = config.SetTLSKeyPath(conf.TLSKey)
+	}
+
+	if len(conf.Labels) != 0 {
+		labels := metrics.Labels(conf.Labels)
+		if err := labels.Validate(); err != nil {
+			return config, fmt.Errorf("failed to parse labels when processing config file: %w", err)
+		}
+		config = config.SetLabels(labels)
+	}
+
 	return config, nil
 }
 
@@ -129,15 +198,40 @@ func parseFlags(config config) config {
 	// Флаг -k=<КЛЮЧ> Ключ для подписи данных
 	secretKey := flag.String("k", config.secretKey, "Secret key for signing data")
 
+	// Флаг -key-id=<ID> ID ключа подписи
+	keyID := flag.String("key-id", config.keyID, "ID of the secret key for signing data")
+
+	// Флаг -token=<ТОКЕН> API-токен агента
+	apiToken := flag.String("token", config.apiToken, "API token of the agent")
+
 	// Флаг -l=<ЗНАЧЕНИЕ> Количество одновременно исходящих запросов
 	rateLimit := flag.Uint("l", config.rateLimit, "Количество одновременно исходящих запросов")
 
 	// Флаг -crypto-key путь до файла с публичным ключом
 	publicKeyPath := flag.String("crypto-key", config.publicKeyPath, "Path to the public key file")
 
+	// Флаг -tls подключаться к серверу по TLS
+	useTLS := flag.Bool("tls", config.useTLS, "Connect to the server over TLS")
+
+	// Флаг -tls-ca путь до файла с сертификатами CA для проверки сервера
+	tlsCAPath := flag.String("tls-ca", config.tlsCAPath, "Path to the CA bundle to verify the server certificate")
+
+	// Флаг -tls-cert путь до файла с сертификатом агента для mTLS
+	tlsCertPath := flag.String("tls-cert", config.tlsCertPath, "Path to the client TLS certificate file")
+
+	// Флаг -tls-key путь до файла с ключом сертификата агента
+	tlsKeyPath := flag.String("tls-key", config.tlsKeyPath, "Path to the client TLS key file")
+
 	// Флаг -g запускать gRPC сервер
 	useGRPC := flag.Bool("g", false, "run gRPC server instead of REST")
 
+	// Флаг -labels метки, добавляемые ко всем метрикам (например, host=web1,env=prod)
+	labels := config.labels
+	flag.Func("labels", "Labels added to all metrics, e.g. host=web1,env=prod", func(s string) (err error) {
+		labels, err = metrics.ParseLabels(s)
+		return err
+	})
+
 	// Флаг -config путь к файлу конфигурации
 	const configUsage = "Path to the config file"
 	flag.StringVar(&config.configFile, "config", "", configUsage)
@@ -154,19 +248,33 @@ func parseFlags(config config) config {
 		SetPollIntervalInSeconds(*pollInterval).
 		SetReportIntervalInSeconds(*reportInterval).
 		SetSecretKey(*secretKey).
+		SetKeyID(*keyID).
+		SetAPIToken(*apiToken).
 		SetPublicKeyPath(*publicKeyPath).
-		SetRateLimit(*rateLimit)
+		SetUseTLS(*useTLS).
+		SetTLSCAPath(*tlsCAPath).
+		SetTLSCertPath(*tlsCertPath).
+		SetTLSKeyPath(*tlsKeyPath).
+		SetRateLimit(*rateLimit).
+		SetLabels(labels)
 }
 
 func parseEnvs(config config) (config, error) {
 	var cfg struct {
 		ServerAddr     string `env:"ADDRESS"`
 		SecretKey      string `env:"KEY"`
+		KeyID          string `env:"KEY_ID"`
+		APIToken       string `env:"API_TOKEN"`
 		PublicKeyPath  string `env:"CRYPTO_KEY"`
+		TLSCAPath      string `env:"TLS_CA"`
+		TLSCertPath    string `env:"TLS_CERT"`
+		TLSKeyPath     string `env:"TLS_KEY"`
+		UseTLS         bool   `env:"TLS"`
 		ConfigFile     string `env:"CONFIG"`
 		PollInterval   uint   `env:"POLL_INTERVAL"`
 		ReportInterval uint   `env:"REPORT_INTERVAL"`
 		RateLimit      uint   `env:"RATE_LIMIT"`
+		Labels         string `env:"LABELS"`
 	}
 	err := env.Parse(&cfg)
 	if err != nil {
@@ -189,6 +297,14 @@ func parseEnvs(config config) (config, error) {
 		config = config.SetSecretKey(cfg.SecretKey)
 	}
 
+	if _, exists := os.LookupEnv("KEY_ID"); exists {
+		config = config.SetKeyID(cfg.KeyID)
+	}
+
+	if _, exists := os.LookupEnv("API_TOKEN"); exists {
+		config = config.SetAPIToken(cfg.APIToken)
+	}
+
 	if _, exists := os.LookupEnv("RATE_LIMIT"); exists {
 		config = config.SetRateLimit(cfg.RateLimit)
 	}
@@ -197,6 +313,30 @@ func parseEnvs(config config) (config, error) {
 		config = config.SetPublicKeyPath(cfg.PublicKeyPath)
 	}
 
+	if _, exists := os.LookupEnv("TLS"); exists {
+		config = config.SetUseTLS(cfg.UseTLS)
+	}
+
+	if _, exists := os.LookupEnv("TLS_CA"); exists {
+		config = config.SetTLSCAPath(cfg.TLSCAPath)
+	}
+
+	if _, exists := os.LookupEnv("TLS_CERT"); exists {
+		config = config.SetTLSCertPath(cfg.TLSCertPath)
+	}
+
+	if _, exists := os.LookupEnv("TLS_KEY"); exists {
+		config = config.SetTLSKeyPath(cfg.TLSKeyPath)
+	}
+
+	if _, exists := os.LookupEnv("LABELS"); exists {
+		labels, err := metrics.ParseLabels(cfg.Labels)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse environment variables: %w", err)
+		}
+		config = config.SetLabels(labels)
+	}
+
 	if _, exists := os.LookupEnv("CONFIG"); exists {
 		config.configFile = cfg.ConfigFile
 	}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
 )
 
 type FlagsTestSuite struct {
@@ -29,8 +31,15 @@ func (suite *FlagsTestSuite) SetupSuite() {
 		"POLL_INTERVAL",
 		"REPORT_INTERVAL",
 		"KEY",
+		"KEY_ID",
+		"API_TOKEN",
 		"CRYPTO_KEY",
+		"TLS",
+		"TLS_CA",
+		"TLS_CERT",
+		"TLS_KEY",
 		"RATE_LIMIT",
+		"LABELS",
 	} {
 		suite.osEnviron[e] = os.Getenv(e)
 	}
@@ -77,8 +86,14 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 				"pollInterval":   2 * time.Second,
 				"reportInterval": 10 * time.Second,
 				"secretKey":      "",
+				"keyID":          "",
+				"apiToken":       "",
 				"publicKeyPath":  "",
 				"rateLimit":      uint(3),
+				"useTLS":         false,
+				"tlsCAPath":      "",
+				"tlsCertPath":    "",
+				"tlsKeyPath":     "",
 			},
 		},
 		{
@@ -101,6 +116,16 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 			args: []string{"-k=secret"},
 			want: map[string]interface{}{"secretKey": "secret"},
 		},
+		{
+			name: "Positive case: Set flag -key-id",
+			args: []string{"-key-id=2024-02"},
+			want: map[string]interface{}{"keyID": "2024-02"},
+		},
+		{
+			name: "Positive case: Set flag -token",
+			args: []string{"-token=t0ken"},
+			want: map[string]interface{}{"apiToken": "t0ken"},
+		},
 		{
 			name: "Positive case: Set flag -l",
 			args: []string{"-l=15"},
@@ -111,6 +136,26 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 			args: []string{"-crypto-key=/tmp/key.pub"},
 			want: map[string]interface{}{"publicKeyPath": "/tmp/key.pub"},
 		},
+		{
+			name: "Positive case: Set flag -tls",
+			args: []string{"-tls"},
+			want: map[string]interface{}{"useTLS": true},
+		},
+		{
+			name: "Positive case: Set flag -tls-ca",
+			args: []string{"-tls-ca=/tmp/ca.crt"},
+			want: map[string]interface{}{"tlsCAPath": "/tmp/ca.crt"},
+		},
+		{
+			name: "Positive case: Set flag -tls-cert",
+			args: []string{"-tls-cert=/tmp/agent.crt"},
+			want: map[string]interface{}{"tlsCertPath": "/tmp/agent.crt"},
+		},
+		{
+			name: "Positive case: Set flag -tls-key",
+			args: []string{"-tls-key=/tmp/agent.key"},
+			want: map[string]interface{}{"tlsKeyPath": "/tmp/agent.key"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -132,6 +177,32 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 	}
 }
 
+func (suite *FlagsTestSuite) TestParseLabels() {
+	suite.Run("Positive case: Set flag -labels", func() {
+		os.Args = append(os.Args, "-labels=host=web1,env=prod")
+
+		config := parseFlags(newConfig())
+		suite.Assert().Equal(metrics.Labels{"host": "web1", "env": "prod"}, config.Labels())
+	})
+
+	suite.Run("Positive case: Set flag -labels and env LABELS", func() {
+		os.Args = append(os.Args, "-labels=host=web1")
+		suite.T().Setenv("LABELS", "host=web2")
+
+		config := parseFlags(newConfig())
+		config, err := parseEnvs(config)
+		suite.Require().NoError(err)
+		suite.Assert().Equal(metrics.Labels{"host": "web2"}, config.Labels())
+	})
+
+	suite.Run("Negative case: Invalid env LABELS", func() {
+		suite.T().Setenv("LABELS", "host")
+
+		_, err := parseEnvs(newConfig())
+		suite.Assert().Error(err)
+	})
+}
+
 func (suite *FlagsTestSuite) TestParseEnvs() {
 	testCases := []struct {
 		name string
@@ -158,6 +229,16 @@ func (suite *FlagsTestSuite) TestParseEnvs() {
 			envs: []string{"KEY=secret"},
 			want: map[string]interface{}{"secretKey": "secret"},
 		},
+		{
+			name: "Positive case: Set env KEY_ID",
+			envs: []string{"KEY_ID=2024-02"},
+			want: map[string]interface{}{"keyID": "2024-02"},
+		},
+		{
+			name: "Positive case: Set env API_TOKEN",
+			envs: []string{"API_TOKEN=t0ken"},
+			want: map[string]interface{}{"apiToken": "t0ken"},
+		},
 		{
 			name: "Positive case: Set env RATE_LIMIT",
 			envs: []string{"RATE_LIMIT=15"},
@@ -168,6 +249,26 @@ func (suite *FlagsTestSuite) TestParseEnvs() {
 			envs: []string{"CRYPTO_KEY=/tmp/key.pub"},
 			want: map[string]interface{}{"publicKeyPath": "/tmp/key.pub"},
 		},
+		{
+			name: "Positive case: Set env TLS",
+			envs: []string{"TLS=true"},
+			want: map[string]interface{}{"useTLS": true},
+		},
+		{
+			name: "Positive case: Set env TLS_CA",
+			envs: []string{"TLS_CA=/tmp/ca.crt"},
+			want: map[string]interface{}{"tlsCAPath": "/tmp/ca.crt"},
+		},
+		{
+			name: "Positive case: Set env TLS_CERT",
+			envs: []string{"TLS_CERT=/tmp/agent.crt"},
+			want: map[string]interface{}{"tlsCertPath": "/tmp/agent.crt"},
+		},
+		{
+			name: "Positive case: Set env TLS_KEY",
+			envs: []string{"TLS_KEY=/tmp/agent.key"},
+			want: map[string]interface{}{"tlsKeyPath": "/tmp/agent.key"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -218,6 +319,8 @@ func (suite *FlagsTestSuite) TestLoadConfig() {
 				"pollInterval":   2 * time.Second,
 				"reportInterval": 10 * time.Second,
 				"secretKey":      "",
+				"keyID":          "",
+				"apiToken":       "",
 				"publicKeyPath":  "",
 				"rateLimit":      uint(3),
 			},
@@ -330,6 +433,30 @@ func (suite *FlagsTestSuite) TestLoadConfig() {
 			envs: []string{"CRYPTO_KEY=/tmp/key.pub"},
 			want: map[string]interface{}{"publicKeyPath": "/tmp/key.pub"},
 		},
+		{
+			name: "Positive case: Set flag -key-id and env KEY_ID",
+			args: []string{"-key-id=2024-01"},
+			envs: []string{"KEY_ID=2024-02"},
+			want: map[string]interface{}{"keyID": "2024-02"},
+		},
+		{
+			name: "Positive case: Set flag -token and env API_TOKEN",
+			args: []string{"-token=token1"},
+			envs: []string{"API_TOKEN=token2"},
+			want: map[string]interface{}{"apiToken": "token2"},
+		},
+		{
+			name: "Positive case: Set flag -tls-ca and env TLS_CA",
+			args: []string{"-tls-ca=/tmp/ca1.crt"},
+			envs: []string{"TLS_CA=/tmp/ca2.crt"},
+			want: map[string]interface{}{"tlsCAPath": "/tmp/ca2.crt"},
+		},
+		{
+			name: "Positive case: Set env TLS_CERT and TLS_KEY only",
+			args: nil,
+			envs: []string{"TLS_CERT=/tmp/agent.crt", "TLS_KEY=/tmp/agent.key"},
+			want: map[string]interface{}{"tlsCertPath": "/tmp/agent.crt", "tlsKeyPath": "/tmp/agent.key"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -113,11 +113,38 @@ func postMetricsAtIntervals(ctx context.Context, dataCh <-chan *storage.MemStora
 	workerCh := make(chan *storage.MemStorage, Config.RateLimit())
 	defer close(workerCh)
 
-	wgAgent.Add(int(Config.RateLimit()))
+	// Воркеры gRPC-клиента отправляют пакеты через один общий поток.
+	var shared IAgentClient
+	if Config.ClientType() == ClientTypeGRPC {
+		shared = newAgentClient()
+	}
 
+	var wgWorkers sync.WaitGroup
+	clients := make([]IAgentClient, 0, Config.RateLimit())
 	for w := 1; w <= int(Config.RateLimit()); w++ {
-		go workerPostMetrics(ctx, workerCh)
+		client := shared
+		if client == nil {
+			client = newAgentClient()
+			clients = append(clients, client)
+		}
+		wgWorkers.Add(1)
+		go workerPostMetrics(ctx, client, workerCh, &wgWorkers)
 	}
+	if shared != nil {
+		clients = append(clients, shared)
+	}
+
+	// Клиенты закрываются после того, как воркеры отправят оставшиеся данные.
+	wgAgent.Add(1)
+	go func() {
+		defer wgAgent.Done()
+		wgWorkers.Wait()
+		for _, client := range clients {
+			if err := client.Close(); err != nil {
+				logger.Log.Error(err.Error(), logger.String("event", "close agent client"))
+			}
+		}
+	}()
 
 	ticker := time.NewTicker(Config.ReportInterval())
 	for {
@@ -138,9 +165,20 @@ func postMetricsAtIntervals(ctx context.Context, dataCh <-chan *storage.MemStora
 }
 
 // workerPostMetrics posts collected metrics
-func workerPostMetrics(ctx context.Context, dataCh <-chan *storage.MemStorage) {
-	defer wgAgent.Done()
+func workerPostMetrics(ctx context.Context, client IAgentClient, dataCh <-chan *storage.MemStorage, wg *sync.WaitGroup) {
+	defer wg.Done()
 
+	for data := range dataCh {
+		batch := packMetricsIntoBatch(data)
+		err := client.RetryUpdateBatch(ctx, batch)
+		if err != nil {
+			logger.Log.Error(err.Error())
+		}
+	}
+}
+
+// newAgentClient returns the initialized client of the configured type.
+func newAgentClient() IAgentClient {
 	var client IAgentClient
 
 	switch Config.ClientType() {
@@ -148,7 +186,10 @@ func workerPostMetrics(ctx context.Context, dataCh <-chan *storage.MemStorage) {
 		client = rest.NewClient(rest.Config{
 			ServerAddr: Config.ServerAddr(),
 			SecretKey:  Config.SecretKey(),
+			KeyID:      Config.KeyID(),
+			Token:      Config.APIToken(),
 			PublicKey:  PublicKey,
+			TLSConfig:  TLSConfig,
 		})
 		err := client.Init()
 		if err != nil {
@@ -158,38 +199,32 @@ func workerPostMetrics(ctx context.Context, dataCh <-chan *storage.MemStorage) {
 		client = cg.NewClient(cg.Config{
 			ServerAddr: Config.ServerAddr(),
 			SecretKey:  Config.SecretKey(),
+			KeyID:      Config.KeyID(),
+			Token:      Config.APIToken(),
 			PublicKey:  PublicKey,
+			TLSConfig:  TLSConfig,
 		})
 		err := client.Init()
 		if err != nil {
 			logger.Log.Panic(err.Error())
 		}
-		conn := client.(*cg.Client).Conn()
-		if conn != nil {
-			defer conn.Close()
-		}
 	default:
 		logger.Log.Panic("unspecified client type")
 	}
 
-	for data := range dataCh {
-		batch := packMetricsIntoBatch(data)
-		err := client.RetryUpdateBatch(ctx, batch)
-		if err != nil {
-			logger.Log.Error(err.Error())
-		}
-	}
+	return client
 }
 
 func packMetricsIntoBatch(data *storage.MemStorage) []metrics.Metrics {
 	batch := make([]metrics.Metrics, 0)
+	labels := Config.Labels()
 
 	for _, counter := range data.Counters() {
-		batch = append(batch, metrics.NewCounterMetric(counter.Name()).SetDelta(counter.Value()))
+		batch = append(batch, metrics.NewCounterMetric(counter.Name()).SetDelta(counter.Value()).WithLabels(labels))
 	}
 
 	for _, gauge := range data.Gauges() {
-		batch = append(batch, metrics.NewGaugeMetric(gauge.Name()).SetValue(gauge.Value()))
+		batch = append(batch, metrics.NewGaugeMetric(gauge.Name()).SetValue(gauge.Value()).WithLabels(labels))
 	}
 
 	return batch
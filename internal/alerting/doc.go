@@ -0,0 +1,10 @@
+// Package alerting implements threshold alerting rules evaluated over the stored metrics.
+//
+// A rule is declared by an expression like
+//
+//	gauge FreeMemory{host=web1} < 1e9 for 5m
+//
+// Every series matching the metric name and labels of the rule gets its own alert.
+// The alert becomes pending as soon as the condition is met, firing when the condition
+// holds for the duration of the rule and resolved when the condition is no longer met.
+package alerting
@@ -0,0 +1,187 @@
+package alerting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+// State is the state of an alert.
+type State string
+
+// Available alert states.
+const (
+	StatePending  State = "pending"  // Условие выполняется, но меньше заданного в правиле времени
+	StateFiring   State = "firing"   // Условие выполняется дольше заданного в правиле времени
+	StateResolved State = "resolved" // Условие сработавшего алерта больше не выполняется
+)
+
+// Alert is the state of a rule for a single series.
+type Alert struct {
+	Labels     metrics.Labels `json:"labels,omitempty"`
+	ActiveAt   time.Time      `json:"active_at"`             // Когда условие начало выполняться
+	FiredAt    *time.Time     `json:"fired_at,omitempty"`    // Когда алерт сработал
+	ResolvedAt *time.Time     `json:"resolved_at,omitempty"` // Когда условие перестало выполняться
+	Rule       string         `json:"rule"`
+	Expr       string         `json:"expr"`
+	Metric     string         `json:"metric"`
+	State      State          `json:"state"`
+	Value      float64        `json:"value"`
+}
+
+// Key returns the key that identifies the alert: the rule name followed by the series key.
+func (a Alert) Key() string {
+	return a.Rule + "/" + metrics.SeriesKey(a.Metric, a.Labels)
+}
+
+// Storager is an interface for reading the current values of the series.
+type Storager interface {
+	GaugesContext(ctx context.Context, filters ...store.StorageFilter) map[string]metrics.Gauge
+	CountersContext(ctx context.Context, filters ...store.StorageFilter) map[string]metrics.Counter
+}
+
+// Engine evaluates the alerting rules and tracks the states of the alerts.
+type Engine struct {
+	storage Storager
+	alerts  map[string]*Alert // Активные и разрешённые, но ещё не забытые алерты по ключу Alert.Key
+	rules   []Rule
+	mu      sync.RWMutex
+}
+
+func NewEngine(storage Storager, rules []Rule) *Engine {
+	return &Engine{
+		storage: storage,
+		rules:   rules,
+		alerts:  make(map[string]*Alert),
+	}
+}
+
+// Rules returns the alerting rules.
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Eval checks the conditions of all rules at the time now and updates the alerts.
+// Returns the alerts which state has changed. The alert of the rule without the duration
+// goes straight to the firing state.
+func (e *Engine) Eval(ctx context.Context, now time.Time) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	changed := make([]Alert, 0)
+	for _, rule := range e.rules {
+		seen := make(map[string]struct{})
+
+		for _, s := range e.series(ctx, rule) {
+			if !rule.Match(s.value) {
+				continue
+			}
+
+			alert := &Alert{
+				Labels:   s.labels.Clone(),
+				ActiveAt: now,
+				Rule:     rule.Name,
+				Expr:     rule.String(),
+				Metric:   rule.Metric,
+				State:    StatePending,
+			}
+			key := alert.Key()
+			seen[key] = struct{}{}
+
+			// Разрешённый алерт заменяется новым, если условие снова выполняется
+			active, ok := e.alerts[key]
+			if ok && active.State != StateResolved {
+				alert = active
+			} else {
+				e.alerts[key] = alert
+			}
+			alert.Value = s.value
+
+			switch {
+			case alert.State == StatePending && now.Sub(alert.ActiveAt) >= rule.For:
+				firedAt := now
+				alert.State = StateFiring
+				alert.FiredAt = &firedAt
+				changed = append(changed, *alert)
+			case alert != active:
+				changed = append(changed, *alert)
+			}
+		}
+
+		// Алерты серий, которые больше не удовлетворяют условию
+		for key, alert := range e.alerts {
+			if _, ok := seen[key]; ok || alert.Rule != rule.Name {
+				continue
+			}
+
+			switch alert.State {
+			case StatePending:
+				delete(e.alerts, key)
+			case StateFiring:
+				// Разрешённый алерт показывается, пока о нём не отправлено уведомление
+				resolvedAt := now
+				alert.State = StateResolved
+				alert.ResolvedAt = &resolvedAt
+				changed = append(changed, *alert)
+			}
+		}
+	}
+	return changed
+}
+
+// Forget removes the resolved alerts, e.g. once their notification has been sent.
+// The alerts that have fired again since then are kept.
+func (e *Engine) Forget(alerts []Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, alert := range alerts {
+		key := alert.Key()
+		if active, ok := e.alerts[key]; ok && active.State == StateResolved {
+			delete(e.alerts, key)
+		}
+	}
+}
+
+// Alerts returns the pending, firing and resolved alerts sorted by rule and series.
+// The resolved alerts are returned until they are removed by Forget.
+func (e *Engine) Alerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Key() < alerts[j].Key()
+	})
+	return alerts
+}
+
+type seriesValue struct {
+	labels metrics.Labels
+	value  float64
+}
+
+// series returns the current values of the series matching the rule.
+func (e *Engine) series(ctx context.Context, rule Rule) []seriesValue {
+	filters := []store.StorageFilter{store.FilterName(rule.Metric), store.FilterLabels(rule.Labels)}
+
+	values := make([]seriesValue, 0)
+	switch rule.MType {
+	case metrics.TypeGauge:
+		for _, g := range e.storage.GaugesContext(ctx, filters...) {
+			values = append(values, seriesValue{labels: g.Labels(), value: g.Value()})
+		}
+	case metrics.TypeCounter:
+		for _, c := range e.storage.CountersContext(ctx, filters...) {
+			values = append(values, seriesValue{labels: c.Labels(), value: float64(c.Value())})
+		}
+	}
+	return values
+}
@@ -0,0 +1,129 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func TestEngine_Eval(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+
+	rule, err := ParseRule("LowFreeMemory", "gauge FreeMemory < 100 for 1m")
+	require.NoError(t, err)
+	e := NewEngine(storage, []Rule{rule})
+
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Нет данных - нет алертов
+	assert.Empty(t, e.Eval(ctx, t0))
+
+	require.NoError(t, storage.SetGaugeContext(ctx, "FreeMemory", metrics.Labels{"host": "web1"}, 50))
+	require.NoError(t, storage.SetGaugeContext(ctx, "FreeMemory", metrics.Labels{"host": "web2"}, 500))
+
+	changed := e.Eval(ctx, t0)
+	require.Len(t, changed, 1)
+	assert.Equal(t, StatePending, changed[0].State)
+	assert.Equal(t, metrics.Labels{"host": "web1"}, changed[0].Labels)
+
+	// Условие выполняется меньше минуты
+	assert.Empty(t, e.Eval(ctx, t0.Add(30*time.Second)))
+
+	changed = e.Eval(ctx, t0.Add(time.Minute))
+	require.Len(t, changed, 1)
+	assert.Equal(t, StateFiring, changed[0].State)
+	assert.Equal(t, t0, changed[0].ActiveAt)
+	require.NotNil(t, changed[0].FiredAt)
+	assert.Equal(t, t0.Add(time.Minute), *changed[0].FiredAt)
+
+	alerts := e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	assert.Equal(t, float64(50), alerts[0].Value)
+
+	require.NoError(t, storage.SetGaugeContext(ctx, "FreeMemory", metrics.Labels{"host": "web1"}, 200))
+	changed = e.Eval(ctx, t0.Add(2*time.Minute))
+	require.Len(t, changed, 1)
+	assert.Equal(t, StateResolved, changed[0].State)
+	require.NotNil(t, changed[0].ResolvedAt)
+
+	// Разрешённый алерт показывается, пока о нём не отправлено уведомление
+	alerts = e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateResolved, alerts[0].State)
+	assert.Empty(t, e.Eval(ctx, t0.Add(3*time.Minute)))
+
+	e.Forget(changed)
+	assert.Empty(t, e.Alerts())
+}
+
+func TestEngine_EvalFiringAgain(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+
+	rule, err := ParseRule("HighLoad", "gauge Load > 1")
+	require.NoError(t, err)
+	e := NewEngine(storage, []Rule{rule})
+
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.SetGauge("Load", 2))
+	require.Len(t, e.Eval(ctx, t0), 1)
+
+	require.NoError(t, storage.SetGauge("Load", 0))
+	resolved := e.Eval(ctx, t0.Add(time.Minute))
+	require.Len(t, resolved, 1)
+
+	// Условие снова выполняется до отправки уведомления о разрешении
+	require.NoError(t, storage.SetGauge("Load", 3))
+	changed := e.Eval(ctx, t0.Add(2*time.Minute))
+	require.Len(t, changed, 1)
+	assert.Equal(t, StateFiring, changed[0].State)
+	assert.Equal(t, t0.Add(2*time.Minute), changed[0].ActiveAt)
+
+	// Уведомление о прошлом разрешении не удаляет снова сработавший алерт
+	e.Forget(resolved)
+	alerts := e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+}
+
+func TestEngine_EvalPendingDropped(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+
+	rule, err := ParseRule("TooManyPolls", "counter PollCount > 10 for 1m")
+	require.NoError(t, err)
+	e := NewEngine(storage, []Rule{rule})
+
+	t0 := time.Now()
+	require.NoError(t, storage.AddCounter("PollCount", 20))
+	require.Len(t, e.Eval(ctx, t0), 1)
+	assert.Len(t, e.Alerts(), 1)
+
+	// Условие перестало выполняться до срабатывания - алерт удаляется без уведомления
+	require.NoError(t, storage.ResetCounters())
+	assert.Empty(t, e.Eval(ctx, t0.Add(time.Second)))
+	assert.Empty(t, e.Alerts())
+}
+
+func TestEngine_EvalWithoutDuration(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+
+	rule, err := ParseRule("HighLoad", "gauge Load > 1")
+	require.NoError(t, err)
+	e := NewEngine(storage, []Rule{rule})
+
+	// Алерт правила без длительности срабатывает сразу, минуя ожидание
+	require.NoError(t, storage.SetGauge("Load", 2))
+	changed := e.Eval(ctx, time.Now())
+	require.Len(t, changed, 1)
+	assert.Equal(t, StateFiring, changed[0].State)
+}
@@ -0,0 +1,236 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+)
+
+type group struct {
+	alerts   map[string]alerting.Alert // Последнее состояние алертов группы
+	sent     map[string]alerting.State // Состояние алертов в последнем уведомлении
+	lastSent time.Time
+}
+
+// queueSize is the number of the notifications waiting for the delivery by Run.
+const queueSize = 100
+
+// Dispatcher groups the changed alerts by rule and passes the notifications to the notifiers.
+type Dispatcher struct {
+	groups         map[string]*group
+	notifiers      []Notifier
+	groupInterval  time.Duration // Минимальный интервал между уведомлениями одной группы
+	repeatInterval time.Duration // Через сколько повторить уведомление о сработавших алертах, 0 - не повторять
+	queue          chan Notification
+	onResolved     func([]alerting.Alert)
+	mu             sync.Mutex
+}
+
+func NewDispatcher(notifiers []Notifier, groupInterval, repeatInterval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		groups:         make(map[string]*group),
+		notifiers:      notifiers,
+		groupInterval:  groupInterval,
+		repeatInterval: repeatInterval,
+		queue:          make(chan Notification, queueSize),
+	}
+}
+
+// OnResolved sets the function that is called with the resolved alerts the dispatcher is done with:
+// their notification has been delivered or dropped, or they resolved before any notification about them was sent.
+// It must be set before the alerts are dispatched.
+func (d *Dispatcher) OnResolved(fn func([]alerting.Alert)) {
+	d.onResolved = fn
+}
+
+// Dispatch takes into account the alerts which state has changed and sends the notifications
+// of the groups that are due at the time now. It is supposed to be called on every evaluation
+// of the rules, even without changes, so that the notifications are repeated.
+func (d *Dispatcher) Dispatch(ctx context.Context, changed []alerting.Alert, now time.Time) error {
+	notifications, dropped := d.due(changed, now)
+	d.resolved(dropped)
+
+	var errs []error
+	for _, n := range notifications {
+		errs = append(errs, d.notify(ctx, n))
+		d.resolved(n.Alerts)
+	}
+	return errors.Join(errs...)
+}
+
+// Enqueue is like Dispatch, but the notifications are delivered in the background by Run,
+// so that a slow notifier does not hold up the evaluation of the rules.
+// The notifications that do not fit into the full queue are dropped.
+func (d *Dispatcher) Enqueue(changed []alerting.Alert, now time.Time) error {
+	notifications, dropped := d.due(changed, now)
+	d.resolved(dropped)
+
+	var errs []error
+	for _, n := range notifications {
+		select {
+		case d.queue <- n:
+		default:
+			errs = append(errs, fmt.Errorf("notification of group %s dropped: delivery queue is full", n.Group))
+			d.resolved(n.Alerts)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run delivers the notifications queued by Enqueue one by one until the context is done.
+// The delivery errors are passed to onError.
+func (d *Dispatcher) Run(ctx context.Context, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-d.queue:
+			if err := d.notify(ctx, n); err != nil && onError != nil {
+				onError(err)
+			}
+			d.resolved(n.Alerts)
+		}
+	}
+}
+
+// due takes into account the changed alerts and returns the notifications of the groups
+// that are due at the time now. The returned notifications are considered sent.
+// The resolved alerts that are dropped without a notification are returned as well.
+func (d *Dispatcher) due(changed []alerting.Alert, now time.Time) ([]Notification, []alerting.Alert) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, alert := range changed {
+		if alert.State == alerting.StatePending {
+			continue
+		}
+
+		g, ok := d.groups[alert.Rule]
+		if !ok {
+			g = &group{
+				alerts: make(map[string]alerting.Alert),
+				sent:   make(map[string]alerting.State),
+			}
+			d.groups[alert.Rule] = g
+		}
+		g.alerts[alert.Key()] = alert
+	}
+
+	names := make([]string, 0, len(d.groups))
+	for name := range d.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var notifications []Notification
+	var dropped []alerting.Alert
+	for _, name := range names {
+		g := d.groups[name]
+
+		dropped = append(dropped, g.prune()...)
+		n, ok := d.notification(name, g, now)
+		if ok {
+			notifications = append(notifications, n)
+			g.lastSent = now
+			g.commit()
+		}
+
+		if len(g.alerts) == 0 {
+			delete(d.groups, name)
+		}
+	}
+	return notifications, dropped
+}
+
+// notification returns the notification of the group if it is due at the time now.
+func (d *Dispatcher) notification(name string, g *group, now time.Time) (Notification, bool) {
+	var changed, firing bool
+	for key, alert := range g.alerts {
+		if alert.State != g.sent[key] {
+			changed = true
+		}
+		if alert.State == alerting.StateFiring {
+			firing = true
+		}
+	}
+
+	elapsed := now.Sub(g.lastSent)
+	switch {
+	case changed && elapsed >= d.groupInterval:
+	case firing && d.repeatInterval > 0 && elapsed >= d.repeatInterval:
+	default:
+		return Notification{}, false
+	}
+
+	n := Notification{
+		Time:   now,
+		Group:  name,
+		Status: StatusResolved,
+		Alerts: make([]alerting.Alert, 0, len(g.alerts)),
+	}
+	if firing {
+		n.Status = StatusFiring
+	}
+	for _, alert := range g.alerts {
+		n.Alerts = append(n.Alerts, alert)
+	}
+	sort.Slice(n.Alerts, func(i, j int) bool {
+		return n.Alerts[i].Key() < n.Alerts[j].Key()
+	})
+	return n, true
+}
+
+// prune removes and returns the alerts that resolved before any notification about them was sent.
+func (g *group) prune() []alerting.Alert {
+	var pruned []alerting.Alert
+	for key, alert := range g.alerts {
+		if alert.State == alerting.StateResolved && g.sent[key] != alerting.StateFiring {
+			delete(g.alerts, key)
+			delete(g.sent, key)
+			pruned = append(pruned, alert)
+		}
+	}
+	return pruned
+}
+
+// commit remembers the states of the sent alerts and forgets the resolved ones.
+func (g *group) commit() {
+	for key, alert := range g.alerts {
+		if alert.State == alerting.StateResolved {
+			delete(g.alerts, key)
+			delete(g.sent, key)
+			continue
+		}
+		g.sent[key] = alert.State
+	}
+}
+
+func (d *Dispatcher) notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range d.notifiers {
+		errs = append(errs, notifier.Notify(ctx, n))
+	}
+	return errors.Join(errs...)
+}
+
+// resolved passes the resolved ones of the alerts to the function set by OnResolved.
+func (d *Dispatcher) resolved(alerts []alerting.Alert) {
+	if d.onResolved == nil {
+		return
+	}
+
+	resolved := make([]alerting.Alert, 0)
+	for _, alert := range alerts {
+		if alert.State == alerting.StateResolved {
+			resolved = append(resolved, alert)
+		}
+	}
+	if len(resolved) > 0 {
+		d.onResolved(resolved)
+	}
+}
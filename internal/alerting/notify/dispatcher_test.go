@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+type recorder struct {
+	notifications []Notification
+}
+
+func (r *recorder) Notify(ctx context.Context, n Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func newAlert(rule, host string, state alerting.State) alerting.Alert {
+	return alerting.Alert{Rule: rule, Metric: "FreeMemory", Labels: metrics.Labels{"host": host}, State: state}
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	ctx := context.Background()
+	rec := &recorder{}
+	d := NewDispatcher([]Notifier{rec}, time.Minute, time.Hour)
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Ожидающие алерты не отправляются
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StatePending)}, t0))
+	assert.Empty(t, rec.notifications)
+
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateFiring)}, t0))
+	require.Len(t, rec.notifications, 1)
+	assert.Equal(t, "LowFreeMemory", rec.notifications[0].Group)
+	assert.Equal(t, StatusFiring, rec.notifications[0].Status)
+	assert.Len(t, rec.notifications[0].Alerts, 1)
+
+	// Второй алерт группы ждёт окончания интервала группы
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web2", alerting.StateFiring)}, t0.Add(10*time.Second)))
+	assert.Len(t, rec.notifications, 1)
+
+	require.NoError(t, d.Dispatch(ctx, nil, t0.Add(time.Minute)))
+	require.Len(t, rec.notifications, 2)
+	assert.Len(t, rec.notifications[1].Alerts, 2)
+
+	// Без изменений уведомление повторяется через repeat interval
+	require.NoError(t, d.Dispatch(ctx, nil, t0.Add(30*time.Minute)))
+	assert.Len(t, rec.notifications, 2)
+	require.NoError(t, d.Dispatch(ctx, nil, t0.Add(time.Minute+time.Hour)))
+	assert.Len(t, rec.notifications, 3)
+
+	t1 := t0.Add(2 * time.Hour)
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{
+		newAlert("LowFreeMemory", "web1", alerting.StateResolved),
+		newAlert("LowFreeMemory", "web2", alerting.StateResolved),
+	}, t1))
+	require.Len(t, rec.notifications, 4)
+	assert.Equal(t, StatusResolved, rec.notifications[3].Status)
+
+	// Разрешённые алерты больше не отправляются
+	require.NoError(t, d.Dispatch(ctx, nil, t1.Add(2*time.Hour)))
+	assert.Len(t, rec.notifications, 4)
+}
+
+func TestDispatcher_Flapping(t *testing.T) {
+	ctx := context.Background()
+	rec := &recorder{}
+	d := NewDispatcher([]Notifier{rec}, time.Minute, 0)
+	t0 := time.Now()
+
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateFiring)}, t0))
+	require.Len(t, rec.notifications, 1)
+
+	// Алерт разрешается и снова срабатывает в течение интервала группы
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateResolved)}, t0.Add(15*time.Second)))
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateFiring)}, t0.Add(30*time.Second)))
+	require.NoError(t, d.Dispatch(ctx, nil, t0.Add(2*time.Minute)))
+	assert.Len(t, rec.notifications, 1)
+
+	// Алерт, разрешённый до первого уведомления, не отправляется
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("HighLoad", "web1", alerting.StateResolved)}, t0.Add(3*time.Minute)))
+	assert.Len(t, rec.notifications, 1)
+}
+
+func TestDispatcher_OnResolved(t *testing.T) {
+	ctx := context.Background()
+	rec := &recorder{}
+	d := NewDispatcher([]Notifier{rec}, time.Minute, 0)
+
+	var resolved []alerting.Alert
+	d.OnResolved(func(alerts []alerting.Alert) {
+		resolved = append(resolved, alerts...)
+	})
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateFiring)}, t0))
+	assert.Empty(t, resolved)
+
+	// Уведомление о разрешении ждёт окончания интервала группы
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateResolved)}, t0.Add(10*time.Second)))
+	assert.Empty(t, resolved)
+
+	require.NoError(t, d.Dispatch(ctx, nil, t0.Add(time.Minute)))
+	require.Len(t, rec.notifications, 2)
+	require.Len(t, resolved, 1)
+	assert.Equal(t, "LowFreeMemory", resolved[0].Rule)
+
+	// Алерт разрешился раньше, чем о нём было отправлено уведомление
+	t1 := t0.Add(time.Hour)
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("HighCPU", "web1", alerting.StateFiring)}, t1))
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("HighCPU", "web2", alerting.StateFiring)}, t1.Add(time.Second)))
+	require.NoError(t, d.Dispatch(ctx, []alerting.Alert{newAlert("HighCPU", "web2", alerting.StateResolved)}, t1.Add(2*time.Second)))
+	require.Len(t, resolved, 2)
+	assert.Equal(t, "HighCPU", resolved[1].Rule)
+	assert.Len(t, rec.notifications, 3)
+}
+
+// blocker is the notifier that does not return until it is released.
+type blocker struct {
+	release  chan struct{}
+	notified chan Notification
+}
+
+func (b *blocker) Notify(ctx context.Context, n Notification) error {
+	<-b.release
+	b.notified <- n
+	return nil
+}
+
+func TestDispatcher_Enqueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := &blocker{release: make(chan struct{}), notified: make(chan Notification, 2)}
+	d := NewDispatcher([]Notifier{b}, 0, 0)
+	go d.Run(ctx, nil)
+
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Зависший получатель не задерживает вычисление правил
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, d.Enqueue([]alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateFiring)}, t0))
+		assert.NoError(t, d.Enqueue([]alerting.Alert{newAlert("HighCPU", "web1", alerting.StateFiring)}, t0))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Enqueue is blocked by the notifier")
+	}
+
+	close(b.release)
+	assert.Equal(t, "LowFreeMemory", (<-b.notified).Group)
+	assert.Equal(t, "HighCPU", (<-b.notified).Group)
+}
+
+func TestDispatcher_EnqueueFullQueue(t *testing.T) {
+	d := NewDispatcher([]Notifier{&recorder{}}, 0, 0)
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Без Run очередь не разбирается
+	for i := 0; i < queueSize; i++ {
+		require.NoError(t, d.Enqueue([]alerting.Alert{newAlert(fmt.Sprintf("Rule%d", i), "web1", alerting.StateFiring)}, t0))
+	}
+	assert.Error(t, d.Enqueue([]alerting.Alert{newAlert("Dropped", "web1", alerting.StateFiring)}, t0))
+}
@@ -0,0 +1,7 @@
+// Package notify sends notifications about the alerts that have changed their state.
+//
+// Alerts are grouped by rule. A group is notified when some of its alerts start firing
+// or get resolved, but not more often than the group interval, so that a flapping
+// series does not produce a notification on every evaluation. Firing alerts are
+// repeated after the repeat interval.
+package notify
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// File writes the notifications as JSON lines.
+type File struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewFile returns the notifier writing to w, e.g. os.Stdout.
+func NewFile(w io.Writer) *File {
+	return &File{w: w}
+}
+
+// OpenFile returns the notifier appending to the file. The path "-" means stdout.
+func OpenFile(path string) (*File, error) {
+	if path == "-" {
+		return NewFile(os.Stdout), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return nil, err
+	}
+	return NewFile(file), nil
+}
+
+// Notify writes the notification.
+func (f *File) Notify(ctx context.Context, n Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return json.NewEncoder(f.w).Encode(n)
+}
+
+// Close closes the underlying file. Stdout is left open.
+func (f *File) Close() error {
+	if c, ok := f.w.(io.Closer); ok && f.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
+
+var (
+	_ Notifier = (*File)(nil)
+	_ Notifier = (*Webhook)(nil)
+)
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Notify(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := NewFile(buf)
+
+	require.NoError(t, f.Notify(context.Background(), Notification{Group: "a", Status: StatusFiring}))
+	require.NoError(t, f.Notify(context.Background(), Notification{Group: "b", Status: StatusResolved}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var n Notification
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &n))
+	assert.Equal(t, "b", n.Group)
+	assert.Equal(t, StatusResolved, n.Status)
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.log")
+
+	f, err := OpenFile(path)
+	require.NoError(t, err)
+	require.NoError(t, f.Notify(context.Background(), Notification{Group: "a"}))
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"group":"a"`)
+
+	f, err = OpenFile("-")
+	require.NoError(t, err)
+	assert.Equal(t, os.Stdout, f.w)
+	assert.NoError(t, f.Close())
+}
@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+)
+
+// Notification statuses.
+const (
+	StatusFiring   = "firing"   // В группе есть сработавшие алерты
+	StatusResolved = "resolved" // Все алерты группы разрешены
+)
+
+// Notification is the payload sent about a group of alerts.
+type Notification struct {
+	Time   time.Time        `json:"time"`
+	Group  string           `json:"group"`  // Имя правила
+	Status string           `json:"status"` // firing или resolved
+	Alerts []alerting.Alert `json:"alerts"` // Сработавшие и разрешённые алерты группы
+}
+
+// Notifier is an interface for delivering the notifications.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+)
+
+// Webhook posts the notifications in JSON format to the URL.
+// When the keyring is set the body is signed with its signing key in the HashSHA256 header,
+// the key ID is passed in the header the same way as in the signed responses of the server.
+type Webhook struct {
+	client     *http.Client
+	url        string
+	keys       *secure.Keyring
+	retryDelay []time.Duration
+}
+
+func NewWebhook(url string, keys *secure.Keyring) *Webhook {
+	return &Webhook{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    url,
+		keys:   keys,
+		retryDelay: []time.Duration{
+			1 * time.Second,
+			3 * time.Second,
+			5 * time.Second,
+		},
+	}
+}
+
+// SetRetryDelay sets the delays between the attempts to deliver a notification.
+// The number of attempts is one more than the number of delays.
+func (wh *Webhook) SetRetryDelay(delay []time.Duration) {
+	wh.retryDelay = delay
+}
+
+// Notify posts the notification. Network errors and server errors are retried.
+func (wh *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	// Ключ берётся при каждом уведомлении, чтобы учитывать перезагрузку ключей
+	var hashString string
+	key := wh.keys.Signing()
+	if len(key.Secret) > 0 {
+		hashString = hex.EncodeToString(secure.Hash(body, key.Secret))
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := wh.post(ctx, body, hashString, key.ID)
+		if err == nil || !retry || attempt >= len(wh.retryDelay) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wh.retryDelay[attempt]):
+		}
+	}
+}
+
+// post sends the request once. Reports whether the failed request should be retried.
+func (wh *Webhook) post(ctx context.Context, body []byte, hashString, keyID string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if hashString != "" {
+		req.Header.Set("HashSHA256", hashString)
+		if keyID != "" {
+			req.Header.Set(secure.KeyIDHeader, keyID)
+		}
+	}
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		err = fmt.Errorf("webhook %s responded with status %s", wh.url, resp.Status)
+		return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests, err
+	}
+	return false, nil
+}
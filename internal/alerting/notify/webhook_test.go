@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	key := []byte("secret")
+	received := make(chan Notification, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, hex.EncodeToString(secure.Hash(body, key)), r.Header.Get("HashSHA256"))
+		assert.Empty(t, r.Header.Get(secure.KeyIDHeader))
+
+		var n Notification
+		assert.NoError(t, json.Unmarshal(body, &n))
+		received <- n
+	}))
+	defer ts.Close()
+
+	wh := NewWebhook(ts.URL, secure.NewKeyring(key))
+	err := wh.Notify(context.Background(), Notification{
+		Group:  "LowFreeMemory",
+		Status: StatusFiring,
+		Alerts: []alerting.Alert{newAlert("LowFreeMemory", "web1", alerting.StateFiring)},
+	})
+	require.NoError(t, err)
+
+	n := <-received
+	assert.Equal(t, "LowFreeMemory", n.Group)
+	assert.Len(t, n.Alerts, 1)
+}
+
+func TestWebhook_NotifySigningKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `{"signing_key": "2024-02", "keys": [
+		{"id": "2024-01", "secret": "old secret"},
+		{"id": "2024-02", "secret": "new secret"}
+	]}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	keys, err := secure.LoadKeyring(path, []byte("legacy secret"))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		// Подпись ключом для подписи, а не ключом без ID
+		assert.Equal(t, hex.EncodeToString(secure.Hash(body, []byte("new secret"))), r.Header.Get("HashSHA256"))
+		assert.Equal(t, "2024-02", r.Header.Get(secure.KeyIDHeader))
+	}))
+	defer ts.Close()
+
+	wh := NewWebhook(ts.URL, keys)
+	require.NoError(t, wh.Notify(context.Background(), Notification{Group: "a"}))
+}
+
+func TestWebhook_NotifyRetry(t *testing.T) {
+	var requests atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("HashSHA256"))
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	wh := NewWebhook(ts.URL, nil)
+	wh.SetRetryDelay([]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond})
+	require.NoError(t, wh.Notify(context.Background(), Notification{Group: "a"}))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestWebhook_NotifyNoRetry(t *testing.T) {
+	testCases := []struct {
+		name         string
+		status       int
+		wantRequests int32
+	}{
+		{
+			name:         "Client error is not retried",
+			status:       http.StatusBadRequest,
+			wantRequests: 1,
+		},
+		{
+			name:         "All attempts failed",
+			status:       http.StatusBadGateway,
+			wantRequests: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tc.status)
+			}))
+			defer ts.Close()
+
+			wh := NewWebhook(ts.URL, nil)
+			wh.SetRetryDelay([]time.Duration{time.Millisecond})
+			assert.Error(t, wh.Notify(context.Background(), Notification{Group: "a"}))
+			assert.Equal(t, tc.wantRequests, requests.Load())
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// Comparison operators of the rule condition.
+const (
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+var ErrInvalidRule = errors.New("invalid alerting rule")
+
+// Rule is a threshold condition on the series of a metric.
+type Rule struct {
+	Labels    metrics.Labels // Метки, которые должны быть у серии
+	Name      string         // Имя правила
+	MType     string         // Тип метрики: gauge или counter
+	Metric    string         // Имя метрики
+	Op        string         // Оператор сравнения
+	Threshold float64        // Пороговое значение
+	For       time.Duration  // Сколько условие должно выполняться, чтобы алерт сработал
+}
+
+// ParseRule parses the rule expression: <type> <metric>[{labels}] <op> <threshold> [for <duration>],
+// e.g. "gauge FreeMemory{host=web1} < 1e9 for 5m".
+func ParseRule(name string, expr string) (Rule, error) {
+	rule := Rule{Name: name}
+	if name == "" {
+		return rule, fmt.Errorf("%w: name cannot be empty", ErrInvalidRule)
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 4 && len(fields) != 6 {
+		return rule, fmt.Errorf("%w %q: expected '<type> <metric> <op> <threshold> [for <duration>]'", ErrInvalidRule, expr)
+	}
+
+	rule.MType = fields[0]
+	if rule.MType != metrics.TypeGauge && rule.MType != metrics.TypeCounter {
+		return rule, fmt.Errorf("%w %q: unsupported metric type %q", ErrInvalidRule, expr, rule.MType)
+	}
+
+	metric, labels, hasLabels := strings.Cut(fields[1], "{")
+	if hasLabels {
+		if !strings.HasSuffix(labels, "}") {
+			return rule, fmt.Errorf("%w %q: unclosed label set", ErrInvalidRule, expr)
+		}
+		l, err := metrics.ParseLabels(strings.TrimSuffix(labels, "}"))
+		if err != nil {
+			return rule, fmt.Errorf("%w %q: %w", ErrInvalidRule, expr, err)
+		}
+		rule.Labels = l
+	}
+	if metric == "" {
+		return rule, fmt.Errorf("%w %q: metric name cannot be empty", ErrInvalidRule, expr)
+	}
+	rule.Metric = metric
+
+	switch op := fields[2]; op {
+	case OpLess, OpLessEqual, OpGreater, OpGreaterEqual, OpEqual, OpNotEqual:
+		rule.Op = op
+	default:
+		return rule, fmt.Errorf("%w %q: unknown operator %q", ErrInvalidRule, expr, op)
+	}
+
+	threshold, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil {
+		return rule, fmt.Errorf("%w %q: %w", ErrInvalidRule, expr, err)
+	}
+	rule.Threshold = threshold
+
+	if len(fields) == 6 {
+		if fields[4] != "for" {
+			return rule, fmt.Errorf("%w %q: unexpected %q", ErrInvalidRule, expr, fields[4])
+		}
+		d, err := time.ParseDuration(fields[5])
+		if err != nil || d < 0 {
+			return rule, fmt.Errorf("%w %q: incorrect duration %q", ErrInvalidRule, expr, fields[5])
+		}
+		rule.For = d
+	}
+
+	return rule, nil
+}
+
+// Match reports whether the value meets the condition of the rule.
+func (r Rule) Match(value float64) bool {
+	switch r.Op {
+	case OpLess:
+		return value < r.Threshold
+	case OpLessEqual:
+		return value <= r.Threshold
+	case OpGreater:
+		return value > r.Threshold
+	case OpGreaterEqual:
+		return value >= r.Threshold
+	case OpEqual:
+		return value == r.Threshold
+	case OpNotEqual:
+		return value != r.Threshold
+	}
+	return false
+}
+
+// String returns the expression of the rule.
+func (r Rule) String() string {
+	expr := fmt.Sprintf("%s %s %s %s", r.MType, metrics.SeriesKey(r.Metric, r.Labels), r.Op, strconv.FormatFloat(r.Threshold, 'g', -1, 64))
+	if r.For > 0 {
+		expr += " for " + r.For.String()
+	}
+	return expr
+}
+
+// LoadRules reads the rules from a JSON file:
+//
+//	{"rules": [{"name": "LowFreeMemory", "expr": "gauge FreeMemory < 1e9 for 5m"}]}
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read alerting rules file: %w", err)
+	}
+
+	var file struct {
+		Rules []struct {
+			Name string `json:"name"`
+			Expr string `json:"expr"`
+		} `json:"rules"`
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse json data from alerting rules file: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(file.Rules))
+	names := make(map[string]struct{}, len(file.Rules))
+	for _, r := range file.Rules {
+		rule, err := ParseRule(r.Name, r.Expr)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := names[rule.Name]; exists {
+			return nil, fmt.Errorf("%w: duplicate rule name %q", ErrInvalidRule, rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,101 @@
+package alerting
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestParseRule(t *testing.T) {
+	testCases := []struct {
+		name    string
+		expr    string
+		want    Rule
+		wantErr bool
+	}{
+		{
+			name: "Positive case: gauge with duration",
+			expr: "gauge FreeMemory < 1e9 for 5m",
+			want: Rule{Name: "r", MType: metrics.TypeGauge, Metric: "FreeMemory", Op: OpLess, Threshold: 1e9, For: 5 * time.Minute},
+		},
+		{
+			name: "Positive case: counter with labels",
+			expr: "counter PollCount{host=web1,env=prod} >= 100",
+			want: Rule{Name: "r", MType: metrics.TypeCounter, Metric: "PollCount", Labels: metrics.Labels{"host": "web1", "env": "prod"}, Op: OpGreaterEqual, Threshold: 100},
+		},
+		{
+			name:    "Negative case: histogram",
+			expr:    "histogram latency > 1",
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: unknown operator",
+			expr:    "gauge FreeMemory =< 1",
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: incorrect threshold",
+			expr:    "gauge FreeMemory < low",
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: incorrect duration",
+			expr:    "gauge FreeMemory < 1 for ever",
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: unclosed labels",
+			expr:    "gauge FreeMemory{host=web1 < 1",
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: incomplete expression",
+			expr:    "gauge FreeMemory <",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule("r", tc.expr)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, rule)
+		})
+	}
+}
+
+func TestRule_String(t *testing.T) {
+	rule, err := ParseRule("r", "gauge FreeMemory{host=web1} < 1e9 for 5m")
+	require.NoError(t, err)
+	assert.Equal(t, `gauge FreeMemory{host="web1"} < 1e+09 for 5m0s`, rule.String())
+}
+
+func TestLoadRules(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "rules*.json")
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"rules": [{"name": "LowFreeMemory", "expr": "gauge FreeMemory < 1e9 for 5m"}, {"name": "TooManyPolls", "expr": "counter PollCount > 100"}]}`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	rules, err := LoadRules(file.Name())
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "LowFreeMemory", rules[0].Name)
+	assert.Equal(t, "TooManyPolls", rules[1].Name)
+
+	require.NoError(t, os.WriteFile(file.Name(), []byte(`{"rules": [{"name": "a", "expr": "gauge A > 1"}, {"name": "a", "expr": "gauge B > 1"}]}`), 0644))
+	_, err = LoadRules(file.Name())
+	assert.ErrorIs(t, err, ErrInvalidRule)
+
+	_, err = LoadRules(file.Name() + ".missing")
+	assert.Error(t, err)
+}
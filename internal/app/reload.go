@@ -0,0 +1,26 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// RegReload calls reload on every SIGHUP until the context is done.
+func RegReload(ctx context.Context, reload func()) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sig:
+				reload()
+			}
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+// Package audit records the write and admin operations of the clients: who changed what and with what result.
+//
+// Every operation is written to the log as an event with the address and the identity of the client,
+// the request ID, the number of the metrics and the outcome. A long-lived operation, e.g. the stream
+// of the metrics batches, records an event for every batch.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+// Protocols of the operations.
+const (
+	ProtocolREST   = "rest"
+	ProtocolGRPC   = "grpc"
+	ProtocolSignal = "signal"
+)
+
+// Outcome is the result of the operation.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Event is the record of the operation.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Protocol  string    `json:"protocol"`             // rest, grpc или signal
+	Action    string    `json:"action"`               // Метод и путь запроса REST или полное имя метода gRPC
+	ClientIP  string    `json:"client_ip,omitempty"`  // Адрес клиента с учётом доверенных прокси
+	Identity  string    `json:"identity,omitempty"`   // Имя API-токена или сертификата клиента
+	RequestID string    `json:"request_id,omitempty"` // ID запроса, тот же, что в логе запросов
+	Metrics   int       `json:"metrics"`              // Количество метрик в пакете
+	Outcome   Outcome   `json:"outcome"`
+	Code      int       `json:"code"`            // HTTP-статус для REST, код статуса gRPC для gRPC
+	Error     string    `json:"error,omitempty"` // Текст ошибки неуспешной операции
+}
+
+// operation is the event of the operation in progress kept in the context.
+type operation struct {
+	log   *Log
+	event Event
+}
+
+type contextKey struct{}
+
+// NewContext returns the context of the operation that is recorded to the log by Record.
+// Nothing is recorded when the log is nil.
+func NewContext(ctx context.Context, log *Log, e Event) context.Context {
+	if log == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, &operation{log: log, event: e})
+}
+
+// SetMetrics sets the number of the metrics of the operation.
+func SetMetrics(ctx context.Context, n int) {
+	if op, ok := ctx.Value(contextKey{}).(*operation); ok {
+		op.event.Metrics = n
+	}
+}
+
+// SetIdentity sets the identity of the client authenticated after the operation has been started.
+func SetIdentity(ctx context.Context, name string) {
+	if op, ok := ctx.Value(contextKey{}).(*operation); ok && name != "" {
+		op.event.Identity = name
+	}
+}
+
+// Record writes the event of the operation with its status code to the log.
+// The operation has failed if err is not nil.
+func Record(ctx context.Context, code int, err error) {
+	op, ok := ctx.Value(contextKey{}).(*operation)
+	if !ok {
+		return
+	}
+
+	e := op.event
+	e.Code = code
+	e.Outcome = OutcomeSuccess
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+	}
+	op.log.Record(e)
+}
+
+// NewRequestID returns a random ID for the requests that came without one.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Identity returns the name of the API token of the client, the name of its certificate if the token is not used.
+func Identity(ctx context.Context) string {
+	if token, ok := auth.FromContext(ctx); ok {
+		return token.Name
+	}
+	if id, ok := identity.FromContext(ctx); ok {
+		return id.Name
+	}
+	return ""
+}
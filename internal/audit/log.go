@@ -0,0 +1,248 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+)
+
+// maxLineSize is the maximal size of the event line read by Query.
+const maxLineSize = 1 << 20
+
+// Log writes the events to the file as JSON lines.
+// When the file grows over the maximal size, it is renamed to path.1, the older files are shifted
+// to path.2, path.3 and so on, and the oldest one over the number of the backups is removed.
+//
+// The nil Log records nothing.
+type Log struct {
+	path       string
+	maxSize    int64 // Максимальный размер файла в байтах, 0 - без ротации
+	maxBackups int   // Сколько старых файлов хранить после ротации
+	now        func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Filter selects the events returned by Query.
+type Filter struct {
+	From   time.Time // Начало периода, нулевое значение - без ограничения
+	To     time.Time // Конец периода, нулевое значение - без ограничения
+	Client string    // Адрес или имя клиента, пустая строка - все клиенты
+	Limit  int       // Сколько последних событий вернуть, 0 - все
+}
+
+// Open opens the log file for appending, the file is created if it does not exist.
+func Open(path string, maxSize int64, maxBackups int) (*Log, error) {
+	l := &Log{path: path, maxSize: maxSize, maxBackups: maxBackups, now: time.Now}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("audit log %s: %w", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("audit log %s: %w", l.path, err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Record writes the event to the log. The time of the event is set to the current time if it is zero.
+// The errors are logged and do not fail the operation.
+func (l *Log) Record(e Event) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "write audit log"))
+		return
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(data); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "write audit log"))
+	}
+}
+
+func (l *Log) write(data []byte) error {
+	if l.file == nil {
+		// Файл не открылся после прошлой ротации, пробуем ещё раз
+		if err := l.open(); err != nil {
+			return err
+		}
+	}
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+// rotate renames the current file to the first backup and opens the new one.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	if l.maxBackups > 0 {
+		if err := os.Remove(l.backupPath(l.maxBackups)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for i := l.maxBackups - 1; i > 0; i-- {
+			if err := os.Rename(l.backupPath(i), l.backupPath(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+func (l *Log) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// Query returns the events matching the filter from the oldest to the newest, including the rotated files.
+func (l *Log) Query(f Filter) ([]Event, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	// Файлы открываются под блокировкой, чтобы ротация не сдвинула их во время чтения,
+	// а читаются без неё, чтобы не задерживать запись событий
+	files, err := l.openFiles()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFiles(files)
+
+	events := make([]Event, 0)
+	for _, file := range files {
+		events, err = readEvents(file, f, events)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[len(events)-f.Limit:]
+	}
+	return events, nil
+}
+
+// openFiles opens the log files for reading from the oldest to the newest, the missing files are skipped.
+func (l *Log) openFiles() ([]*os.File, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files := make([]*os.File, 0, l.maxBackups+1)
+	for i := l.maxBackups; i >= 0; i-- {
+		path := l.path
+		if i > 0 {
+			path = l.backupPath(i)
+		}
+
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+// readEvents appends the events of the file matching the filter to the events.
+// The lines that are not valid events are skipped.
+func readEvents(r io.Reader, f Filter, events []Event) ([]Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if f.match(e) {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+func (f Filter) match(e Event) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	if f.Client != "" && !strings.EqualFold(f.Client, e.ClientIP) && f.Client != e.Identity {
+		return false
+	}
+	return true
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+func TestQuery(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	require.NoError(t, err)
+	defer log.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Time: start, ClientIP: "10.0.0.1", Identity: "web1", Metrics: 1, Outcome: OutcomeSuccess},
+		{Time: start.Add(time.Minute), ClientIP: "10.0.0.2", Identity: "web2", Metrics: 2, Outcome: OutcomeSuccess},
+		{Time: start.Add(2 * time.Minute), ClientIP: "10.0.0.1", Identity: "web1", Metrics: 3, Outcome: OutcomeFailure},
+		{Time: start.Add(3 * time.Minute), ClientIP: "2001:db8::1", Metrics: 4, Outcome: OutcomeSuccess},
+	}
+	for _, e := range events {
+		log.Record(e)
+	}
+
+	testCases := []struct {
+		name   string
+		filter Filter
+		want   []int // Количество метрик найденных событий
+	}{
+		{name: "All events", filter: Filter{}, want: []int{1, 2, 3, 4}},
+		{name: "From", filter: Filter{From: start.Add(time.Minute)}, want: []int{2, 3, 4}},
+		{name: "To", filter: Filter{To: start.Add(time.Minute)}, want: []int{1, 2}},
+		{name: "Client address", filter: Filter{Client: "10.0.0.1"}, want: []int{1, 3}},
+		{name: "Client identity", filter: Filter{Client: "web2"}, want: []int{2}},
+		{name: "IPv6 address in another case", filter: Filter{Client: "2001:DB8::1"}, want: []int{4}},
+		{name: "Latest events", filter: Filter{Limit: 2}, want: []int{3, 4}},
+		{name: "Unknown client", filter: Filter{Client: "web3"}, want: []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := log.Query(tc.filter)
+			require.NoError(t, err)
+
+			got := make([]int, 0, len(found))
+			for _, e := range found {
+				got = append(got, e.Metrics)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, 300, 2)
+	require.NoError(t, err)
+	defer log.Close()
+
+	for i := 1; i <= 10; i++ {
+		log.Record(Event{Action: strings.Repeat("a", 100), Metrics: i})
+	}
+
+	_, err = os.Stat(path + ".1")
+	assert.NoError(t, err)
+	_, err = os.Stat(path + ".2")
+	assert.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.ErrorIs(t, err, os.ErrNotExist, "the oldest file is removed")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(300))
+
+	// Запрос читает и ротированные файлы, самые старые события потеряны
+	found, err := log.Query(Filter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, found)
+	assert.Less(t, len(found), 10)
+	assert.Equal(t, 10, found[len(found)-1].Metrics)
+	for i := 1; i < len(found); i++ {
+		assert.Equal(t, found[i-1].Metrics+1, found[i].Metrics)
+	}
+}
+
+func TestQueryWhileRecording(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.log"), 1000, 3)
+	require.NoError(t, err)
+	defer log.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 500; i++ {
+			log.Record(Event{Metrics: i})
+		}
+	}()
+
+	// События читаются во время записи и ротации, порядок событий сохраняется
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		found, err := log.Query(Filter{})
+		require.NoError(t, err)
+		for i := 1; i < len(found); i++ {
+			assert.Less(t, found[i-1].Metrics, found[i].Metrics)
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	log, err := Open(path, 0, 0)
+	require.NoError(t, err)
+	log.Record(Event{Metrics: 1})
+	require.NoError(t, log.Close())
+
+	log, err = Open(path, 0, 0)
+	require.NoError(t, err)
+	defer log.Close()
+	log.Record(Event{Metrics: 2})
+
+	found, err := log.Query(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.False(t, found[0].Time.IsZero(), "time is set on record")
+}
+
+func TestRecord(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	require.NoError(t, err)
+	defer log.Close()
+
+	ctx := NewContext(context.Background(), log, Event{Protocol: ProtocolREST, Action: "POST /updates/", RequestID: "req-1"})
+	SetMetrics(ctx, 5)
+	Record(ctx, 200, nil)
+	Record(ctx, 413, errors.New("Request Entity Too Large"))
+
+	found, err := log.Query(Filter{})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+
+	assert.Equal(t, "POST /updates/", found[0].Action)
+	assert.Equal(t, "req-1", found[0].RequestID)
+	assert.Equal(t, 5, found[0].Metrics)
+	assert.Equal(t, OutcomeSuccess, found[0].Outcome)
+	assert.Empty(t, found[0].Error)
+
+	assert.Equal(t, 413, found[1].Code)
+	assert.Equal(t, OutcomeFailure, found[1].Outcome)
+	assert.Equal(t, "Request Entity Too Large", found[1].Error)
+
+	// Без журнала события не записываются
+	ctx = NewContext(context.Background(), nil, Event{})
+	SetMetrics(ctx, 1)
+	Record(ctx, 200, nil)
+}
+
+func TestIdentity(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, Identity(ctx))
+
+	ctx = identity.NewContext(ctx, identity.Identity{Name: "web1"})
+	assert.Equal(t, "web1", Identity(ctx))
+
+	ctx = auth.NewContext(ctx, auth.Token{Name: "agent-1"})
+	assert.Equal(t, "agent-1", Identity(ctx))
+}
@@ -0,0 +1,15 @@
+// Package auth implements the per-agent API tokens with scopes.
+//
+// The tokens are declared in a JSON file by the SHA-256 hash of their value:
+//
+//	{
+//	    "tokens": [
+//	        {"name": "web1", "token_sha256": "9f86d08188...", "scopes": ["write"]},
+//	        {"name": "grafana", "token_sha256": "60303ae22b...", "scopes": ["read"]},
+//	        {"name": "old-agent", "token_sha256": "fd61a03af4...", "scopes": ["write"], "revoked": true}
+//	    ]
+//	}
+//
+// The file is re-read when it changes, so adding and revoking tokens takes effect without a restart.
+// The clients pass the token in the Authorization header (REST) or metadata (gRPC) as "Bearer <token>".
+package auth
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+)
+
+// reloadInterval limits how often the registry checks the file for changes.
+const reloadInterval = time.Second
+
+// Registry is the set of the API tokens loaded from the file.
+type Registry struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	tokens  map[string]Token // Токены по SHA-256 хешу значения
+	modTime time.Time        // Время изменения загруженного файла
+	checked time.Time        // Время последней проверки файла
+}
+
+// LoadRegistry loads the tokens from the file.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{
+		path: path,
+		now:  time.Now,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the tokens from the file.
+func (r *Registry) Reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("can't read tokens file: %w", err)
+	}
+
+	tokens, err := readTokens(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.tokens = tokens
+	r.modTime = info.ModTime()
+	r.checked = r.now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Authenticate returns the registered token with the value.
+// ErrUnauthenticated is returned for an unknown token and ErrRevoked for a revoked one.
+func (r *Registry) Authenticate(token string) (Token, error) {
+	if token == "" {
+		return Token{}, ErrUnauthenticated
+	}
+
+	r.reloadIfChanged()
+
+	r.mu.RLock()
+	t, ok := r.tokens[Hash(token)]
+	r.mu.RUnlock()
+
+	switch {
+	case !ok:
+		return Token{}, ErrUnauthenticated
+	case t.Revoked:
+		return Token{}, ErrRevoked
+	}
+	return t, nil
+}
+
+// Authorize authenticates the token and checks that it grants the scope.
+func (r *Registry) Authorize(token string, scope Scope) (Token, error) {
+	t, err := r.Authenticate(token)
+	if err != nil {
+		return t, err
+	}
+	if !t.Allows(scope) {
+		return t, ErrForbidden
+	}
+	return t, nil
+}
+
+// reloadIfChanged reloads the tokens if the file has been modified since it was loaded.
+// The previous tokens are kept if the modified file is invalid.
+func (r *Registry) reloadIfChanged() {
+	now := r.now()
+
+	r.mu.Lock()
+	if now.Sub(r.checked) < reloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checked = now
+	modTime := r.modTime
+	r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		logger.Log.Warn(err.Error(), logger.String("event", "check tokens file"))
+		return
+	}
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "reload tokens file"))
+		return
+	}
+	logger.Log.Info("Tokens file reloaded", logger.String("path", r.path), logger.String("event", "reload tokens file"))
+}
+
+func readTokens(path string) (map[string]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read tokens file: %w", err)
+	}
+
+	var file struct {
+		Tokens []struct {
+			Name    string   `json:"name"`
+			Hash    string   `json:"token_sha256"`
+			Scopes  []string `json:"scopes"`
+			Revoked bool     `json:"revoked"`
+		} `json:"tokens"`
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse json data from tokens file: %w", err)
+	}
+
+	tokens := make(map[string]Token, len(file.Tokens))
+	for _, t := range file.Tokens {
+		if t.Name == "" {
+			return nil, errors.New("token without name in tokens file")
+		}
+		if len(t.Hash) != 64 {
+			return nil, fmt.Errorf("invalid token_sha256 of token %q", t.Name)
+		}
+
+		token := Token{Name: t.Name, Revoked: t.Revoked}
+		for _, s := range t.Scopes {
+			scope, err := ParseScope(s)
+			if err != nil {
+				return nil, fmt.Errorf("token %q: %w", t.Name, err)
+			}
+			token.Scopes = append(token.Scopes, scope)
+		}
+
+		hash := strings.ToLower(t.Hash)
+		if _, exists := tokens[hash]; exists {
+			return nil, fmt.Errorf("duplicate token %q in tokens file", t.Name)
+		}
+		tokens[hash] = token
+	}
+	return tokens, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTokens(t *testing.T, path, data string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	writeTokens(t, path, `{"tokens": [
+		{"name": "web1", "token_sha256": "`+Hash("agent-token")+`", "scopes": ["write"]},
+		{"name": "grafana", "token_sha256": "`+Hash("reader-token")+`", "scopes": ["read"]},
+		{"name": "ops", "token_sha256": "`+Hash("admin-token")+`", "scopes": ["admin"]},
+		{"name": "old", "token_sha256": "`+Hash("old-token")+`", "scopes": ["write"], "revoked": true}
+	]}`, time.Now().Add(-time.Hour))
+
+	registry, err := LoadRegistry(path)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		token string
+		scope Scope
+		err   error
+	}{
+		{name: "Agent writes", token: "agent-token", scope: ScopeWrite},
+		{name: "Agent reads", token: "agent-token", scope: ScopeRead, err: ErrForbidden},
+		{name: "Reader reads", token: "reader-token", scope: ScopeRead},
+		{name: "Reader writes", token: "reader-token", scope: ScopeWrite, err: ErrForbidden},
+		{name: "Admin writes", token: "admin-token", scope: ScopeWrite},
+		{name: "Admin reads", token: "admin-token", scope: ScopeRead},
+		{name: "Agent is not admin", token: "agent-token", scope: ScopeAdmin, err: ErrForbidden},
+		{name: "Revoked token", token: "old-token", scope: ScopeWrite, err: ErrRevoked},
+		{name: "Unknown token", token: "unknown", scope: ScopeWrite, err: ErrUnauthenticated},
+		{name: "Missing token", token: "", scope: ScopeWrite, err: ErrUnauthenticated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := registry.Authorize(tc.token, tc.scope)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, token.Name)
+		})
+	}
+}
+
+func TestRegistryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	writeTokens(t, path, `{"tokens": [{"name": "web1", "token_sha256": "`+Hash("agent-token")+`", "scopes": ["write"]}]}`, time.Now().Add(-time.Hour))
+
+	registry, err := LoadRegistry(path)
+	require.NoError(t, err)
+
+	now := time.Now()
+	registry.now = func() time.Time { return now }
+
+	_, err = registry.Authorize("agent-token", ScopeWrite)
+	require.NoError(t, err)
+
+	// Отзыв токена применяется без перезапуска
+	writeTokens(t, path, `{"tokens": [{"name": "web1", "token_sha256": "`+Hash("agent-token")+`", "scopes": ["write"], "revoked": true}]}`, time.Now().Add(-time.Minute))
+	now = now.Add(2 * reloadInterval)
+	_, err = registry.Authorize("agent-token", ScopeWrite)
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	// Ошибочный файл не сбрасывает загруженные токены
+	writeTokens(t, path, `{"tokens": [`, time.Now())
+	now = now.Add(2 * reloadInterval)
+	_, err = registry.Authorize("agent-token", ScopeWrite)
+	assert.ErrorIs(t, err, ErrRevoked)
+}
+
+func TestLoadRegistryErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Invalid json", data: `{"tokens": `},
+		{name: "Unknown scope", data: `{"tokens": [{"name": "a", "token_sha256": "` + Hash("a") + `", "scopes": ["delete"]}]}`},
+		{name: "Invalid hash", data: `{"tokens": [{"name": "a", "token_sha256": "abc", "scopes": ["read"]}]}`},
+		{name: "Missing name", data: `{"tokens": [{"token_sha256": "` + Hash("a") + `", "scopes": ["read"]}]}`},
+		{name: "Duplicate token", data: `{"tokens": [{"name": "a", "token_sha256": "` + Hash("a") + `"}, {"name": "b", "token_sha256": "` + Hash("a") + `"}]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.json")
+			writeTokens(t, path, tc.data, time.Now())
+
+			_, err := LoadRegistry(path)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadRegistry(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestBearerToken(t *testing.T) {
+	assert.Equal(t, "abc", BearerToken("Bearer abc"))
+	assert.Equal(t, "abc", BearerToken("bearer  abc "))
+	assert.Equal(t, "", BearerToken("Basic abc"))
+	assert.Equal(t, "", BearerToken("Bearer "))
+	assert.Equal(t, "", BearerToken(""))
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scope is the permission granted to the token.
+type Scope string
+
+const (
+	ScopeWrite Scope = "write" // Отправка метрик
+	ScopeRead  Scope = "read"  // Чтение метрик
+	ScopeAdmin Scope = "admin" // Все операции, включая служебные
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid API token")
+	ErrRevoked         = errors.New("API token has been revoked")
+	ErrForbidden       = errors.New("API token does not allow the operation")
+	ErrNoRegistry      = errors.New("the operation requires the API tokens to be configured")
+)
+
+// Token is the registered API token.
+type Token struct {
+	Name    string  // Имя агента или клиента, которому выдан токен
+	Scopes  []Scope // Разрешения токена
+	Revoked bool    // Токен отозван
+}
+
+// Allows reports whether the token grants the scope. The admin scope grants all scopes.
+func (t Token) Allows(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the hex encoded SHA-256 hash of the token value as it is stored in the registry.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScope parses the name of the scope.
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(strings.ToLower(strings.TrimSpace(s))); scope {
+	case ScopeWrite, ScopeRead, ScopeAdmin:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown token scope %q", s)
+	}
+}
+
+// BearerToken returns the token of the "Bearer <token>" authorization value.
+func BearerToken(authorization string) string {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(authorization[len(prefix):])
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of the context with the authenticated token.
+func NewContext(ctx context.Context, t Token) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the authenticated token stored in the context.
+func FromContext(ctx context.Context) (Token, bool) {
+	t, ok := ctx.Value(contextKey{}).(Token)
+	return t, ok
+}
@@ -0,0 +1,126 @@
+// Package clientip resolves the address of the client behind the trusted proxies
+// and checks it against the lists of the trusted subnets.
+//
+// The forwarding headers can be set by any client, so they are honoured only when
+// the request comes from a trusted proxy. Otherwise the address of the TCP peer is used.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Headers with the address of the client set by the proxies.
+const (
+	ForwardedForHeader = "X-Forwarded-For"
+	RealIPHeader       = "X-Real-IP"
+)
+
+// Subnets is a list of the IPv4 and IPv6 subnets.
+type Subnets []netip.Prefix
+
+// ParseSubnets parses the comma separated list of the subnets in CIDR notation.
+// A single address is treated as the subnet of one host.
+// IPv4-mapped IPv6 subnets are converted to IPv4, they must be at least /96.
+func ParseSubnets(s string) (Subnets, error) {
+	var subnets Subnets
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid subnet %q: %w", item, err)
+			}
+			addr = addr.Unmap()
+			subnets = append(subnets, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", item, err)
+		}
+		if prefix.Addr().Is4In6() {
+			// Подсеть короче /96 выходит за пределы адресов IPv4
+			if prefix.Bits() < 96 {
+				return nil, fmt.Errorf("invalid subnet %q: IPv4-mapped prefix must be at least /96", item)
+			}
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		subnets = append(subnets, prefix.Masked())
+	}
+	return subnets, nil
+}
+
+// Contains reports whether the address belongs to any of the subnets.
+// IPv4-mapped IPv6 addresses are matched as IPv4 addresses.
+func (s Subnets) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range s {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Subnets) String() string {
+	items := make([]string, len(s))
+	for i, prefix := range s {
+		items[i] = prefix.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// Resolve returns the address of the client.
+// When the peer is a trusted proxy, the X-Forwarded-For addresses are checked from right to left
+// and the first one that is not a trusted proxy is the client; X-Real-IP is used if X-Forwarded-For is missing.
+// In all other cases the address of the peer is returned.
+func Resolve(peer netip.Addr, proxies Subnets, forwardedFor, realIP string) netip.Addr {
+	peer = peer.Unmap()
+	if !proxies.Contains(peer) {
+		return peer
+	}
+
+	if forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// Адреса левее испорченного могли быть подставлены клиентом
+				return peer
+			}
+			if !proxies.Contains(addr) {
+				return addr.Unmap()
+			}
+		}
+		return peer
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(realIP)); err == nil {
+		return addr.Unmap()
+	}
+	return peer
+}
+
+// ParseAddr returns the address of "host:port" or of the host, the zero address if it is invalid.
+func ParseAddr(hostport string) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(hostport); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	addr, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
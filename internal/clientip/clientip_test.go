@@ -0,0 +1,92 @@
+package clientip
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubnets(t *testing.T) {
+	testCases := []struct {
+		name    string
+		subnets string
+		want    string
+		wantErr bool
+	}{
+		{name: "Empty list", subnets: "", want: ""},
+		{name: "Single IPv4 subnet", subnets: "192.168.0.0/24", want: "192.168.0.0/24"},
+		{name: "IPv4 and IPv6 subnets", subnets: "10.0.0.0/8, 2001:db8::/32", want: "10.0.0.0/8,2001:db8::/32"},
+		{name: "Host bits are masked", subnets: "192.168.0.15/24", want: "192.168.0.0/24"},
+		{name: "Single addresses", subnets: "127.0.0.1,::1", want: "127.0.0.1/32,::1/128"},
+		{name: "IPv4-mapped subnet", subnets: "::ffff:10.0.0.0/104", want: "10.0.0.0/8"},
+		{name: "All IPv4-mapped addresses", subnets: "::ffff:0.0.0.0/96", want: "0.0.0.0/0"},
+		{name: "IPv4-mapped subnet shorter than /96", subnets: "::ffff:0.0.0.0/80", wantErr: true},
+		{name: "Invalid subnet", subnets: "10.0.0.0/33", wantErr: true},
+		{name: "Invalid address", subnets: "localhost", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subnets, err := ParseSubnets(tc.subnets)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, subnets.String())
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	subnets, err := ParseSubnets("192.168.0.0/24,2001:db8::/32")
+	require.NoError(t, err)
+
+	assert.True(t, subnets.Contains(netip.MustParseAddr("192.168.0.10")))
+	assert.True(t, subnets.Contains(netip.MustParseAddr("::ffff:192.168.0.10")))
+	assert.True(t, subnets.Contains(netip.MustParseAddr("2001:db8::1")))
+	assert.False(t, subnets.Contains(netip.MustParseAddr("192.168.1.10")))
+	assert.False(t, subnets.Contains(netip.MustParseAddr("2001:db9::1")))
+	assert.False(t, subnets.Contains(netip.Addr{}))
+}
+
+func TestResolve(t *testing.T) {
+	proxies, err := ParseSubnets("10.0.0.0/8,fd00::/8")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		peer         string
+		forwardedFor string
+		realIP       string
+		want         string
+	}{
+		{name: "Untrusted peer", peer: "192.168.0.10", forwardedFor: "1.1.1.1", realIP: "2.2.2.2", want: "192.168.0.10"},
+		{name: "Proxy without headers", peer: "10.0.0.1", want: "10.0.0.1"},
+		{name: "X-Real-IP of the proxy", peer: "10.0.0.1", realIP: "192.168.0.10", want: "192.168.0.10"},
+		{name: "X-Forwarded-For goes before X-Real-IP", peer: "10.0.0.1", forwardedFor: "192.168.0.10", realIP: "2.2.2.2", want: "192.168.0.10"},
+		{name: "Chain of the proxies", peer: "10.0.0.1", forwardedFor: "1.1.1.1, 192.168.0.10, 10.0.0.2, fd00::1", want: "192.168.0.10"},
+		{name: "IPv6 client", peer: "fd00::2", forwardedFor: "2001:db8::1", want: "2001:db8::1"},
+		{name: "IPv4-mapped peer", peer: "::ffff:10.0.0.1", realIP: "192.168.0.10", want: "192.168.0.10"},
+		{name: "Invalid address in the chain", peer: "10.0.0.1", forwardedFor: "192.168.0.10, unknown", want: "10.0.0.1"},
+		{name: "Only proxies in the chain", peer: "10.0.0.1", forwardedFor: "10.0.0.2", want: "10.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Resolve(netip.MustParseAddr(tc.peer), proxies, tc.forwardedFor, tc.realIP)
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	assert.Equal(t, "127.0.0.1", ParseAddr("127.0.0.1:8080").String())
+	assert.Equal(t, "::1", ParseAddr("[::1]:8080").String())
+	assert.Equal(t, "10.0.0.1", ParseAddr("[::ffff:10.0.0.1]:8080").String())
+	assert.Equal(t, "192.168.0.10", ParseAddr("192.168.0.10").String())
+	assert.Equal(t, "2001:db8::1", ParseAddr("2001:db8::1").String())
+	assert.False(t, ParseAddr("bufconn").IsValid())
+}
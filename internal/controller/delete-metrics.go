@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+// DeleteMetric removes the series of the metric with exactly the given labels together with its history.
+func (c Controller) DeleteMetric(ctx context.Context, mtype string, id string, labels metrics.Labels) (code int, err error) {
+	// При попытке передать запрос без имени метрики возвращать http.StatusNotFound.
+	if id == "" {
+		return http.StatusNotFound, errors.New(`ID not specified`)
+	}
+
+	ok, err := Storage.DeleteSeries(ctx, mtype, id, labels)
+	if errors.Is(err, store.ErrIncorrectType) {
+		return http.StatusBadRequest, err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !ok {
+		return http.StatusNotFound, fmt.Errorf(`metric '%s' not found`, metrics.SeriesKey(id, labels))
+	}
+
+	syncSave()
+
+	return http.StatusOK, nil
+}
+
+// DeleteMetrics removes the series selected either by the names or by the name prefix,
+// of all types if the type is empty. The labels narrow the selection down.
+// Returns the number of the removed series.
+func (c Controller) DeleteMetrics(ctx context.Context, mtype string, names []string, prefix string, labels metrics.Labels) (n int, code int, err error) {
+	if len(names) == 0 && prefix == "" {
+		return 0, http.StatusBadRequest, errors.New(`names or prefix not specified`)
+	}
+	if len(names) > 0 && prefix != "" {
+		return 0, http.StatusBadRequest, errors.New(`names and prefix are mutually exclusive`)
+	}
+
+	filters := []store.StorageFilter{store.FilterLabels(labels)}
+	if len(names) > 0 {
+		filters = append(filters, store.FilterNames(names))
+	} else {
+		filters = append(filters, store.FilterPrefix(prefix))
+	}
+
+	n, err = Storage.DeleteMetrics(ctx, mtype, filters...)
+	if errors.Is(err, store.ErrIncorrectType) {
+		return 0, http.StatusBadRequest, err
+	}
+	if err != nil {
+		return 0, http.StatusInternalServerError, err
+	}
+
+	if n > 0 {
+		syncSave()
+	}
+
+	return n, http.StatusOK, nil
+}
+
+// syncSave synchronously saves the metrics values into a file
+func syncSave() {
+	if s, ok := Storage.(store.SyncSaver); ok {
+		err := s.SyncSave()
+		if err != nil {
+			logger.Log.Error(err.Error(), logger.String("event", "synchronously save metrics into file"))
+		}
+	}
+}
@@ -23,19 +23,26 @@ func (c Controller) UpdateMetrics(ctx context.Context, metric metrics.Metrics) (
 
 	switch metric.MType {
 	case metrics.TypeCounter:
-		err = Storage.AddCounterContext(ctx, metric.ID, *metric.Delta)
+		err = Storage.AddCounterContext(ctx, metric.ID, metric.Labels, *metric.Delta)
 		if err != nil {
 			return m, http.StatusBadRequest, err
 		}
-		counterValue, _ := Storage.CounterValueContext(ctx, metric.ID)
+		counterValue, _ := Storage.CounterValueContext(ctx, metric.ID, metric.Labels)
 		m = metric.SetDelta(counterValue)
 	case metrics.TypeGauge:
-		err = Storage.SetGaugeContext(ctx, metric.ID, *metric.Value)
+		err = Storage.SetGaugeContext(ctx, metric.ID, metric.Labels, *metric.Value)
 		if err != nil {
 			return m, http.StatusBadRequest, err
 		}
-		gaugeValue, _ := Storage.GaugeValueContext(ctx, metric.ID)
+		gaugeValue, _ := Storage.GaugeValueContext(ctx, metric.ID, metric.Labels)
 		m = metric.SetValue(gaugeValue)
+	case metrics.TypeHistogram:
+		err = Storage.AddHistogramContext(ctx, metric.ID, metric.Labels, *metric.Histogram)
+		if err != nil {
+			return m, http.StatusBadRequest, err
+		}
+		histogram, _ := Storage.HistogramContext(ctx, metric.ID, metric.Labels)
+		m = metric.SetHistogram(histogram.Value())
 	}
 
 	// Synchronously save metrics values into a file
@@ -13,6 +13,7 @@ import (
 func (c Controller) UpdatesMetrics(ctx context.Context, metricsBatch []metrics.Metrics) (mb []metrics.Metrics, code int, err error) {
 	gaugesBatch := make([]metrics.Gauge, 0)
 	countersBatch := make([]metrics.Counter, 0)
+	histogramsBatch := make([]metrics.Histogram, 0)
 
 	{
 		for _, metric := range metricsBatch {
@@ -27,40 +28,58 @@ func (c Controller) UpdatesMetrics(ctx context.Context, metricsBatch []metrics.M
 
 			switch metric.MType {
 			case metrics.TypeCounter:
-				c, err := metrics.NewCounter(metric.ID, *metric.Delta)
+				c, err := metrics.NewCounterWithLabels(metric.ID, metric.Labels, *metric.Delta)
 				if err != nil {
 					return mb, http.StatusBadRequest, err
 				}
 				countersBatch = append(countersBatch, *c)
 			case metrics.TypeGauge:
-				g, err := metrics.NewGauge(metric.ID, *metric.Value)
+				g, err := metrics.NewGaugeWithLabels(metric.ID, metric.Labels, *metric.Value)
 				if err != nil {
 					return mb, http.StatusBadRequest, err
 				}
 				gaugesBatch = append(gaugesBatch, *g)
+			case metrics.TypeHistogram:
+				h, err := metrics.NewHistogramWithLabels(metric.ID, metric.Labels, *metric.Histogram)
+				if err != nil {
+					return mb, http.StatusBadRequest, err
+				}
+				histogramsBatch = append(histogramsBatch, *h)
 			}
 		}
 	}
 
-	err = Storage.InsertBatchContext(ctx, store.WithCounters(countersBatch), store.WithGauges(gaugesBatch))
+	err = Storage.InsertBatchContext(ctx, store.WithCounters(countersBatch), store.WithGauges(gaugesBatch), store.WithHistograms(histogramsBatch))
+	if errors.Is(err, metrics.ErrHistogramBoundsMismatch) {
+		return mb, http.StatusBadRequest, err
+	}
 	if err != nil {
 		return mb, http.StatusInternalServerError, err
 	}
 
-	if names := getBatchCounterNames(countersBatch); len(names) > 0 {
+	if names, keys := getBatchNames(countersBatch); len(names) > 0 {
 		counters := Storage.CountersContext(ctx, store.FilterNames(names))
-		for _, cn := range names {
-			if c, ok := counters[cn]; ok {
-				mb = append(mb, metrics.NewCounterMetric(c.Name()).SetDelta(c.Value()))
+		for _, key := range keys {
+			if c, ok := counters[key]; ok {
+				mb = append(mb, metrics.NewCounterMetric(c.Name()).WithLabels(c.Labels()).SetDelta(c.Value()))
 			}
 		}
 	}
 
-	if names := getBatchGaugeNames(gaugesBatch); len(names) > 0 {
+	if names, keys := getBatchNames(gaugesBatch); len(names) > 0 {
 		gauges := Storage.GaugesContext(ctx, store.FilterNames(names))
-		for _, cn := range names {
-			if g, ok := gauges[cn]; ok {
-				mb = append(mb, metrics.NewGaugeMetric(g.Name()).SetValue(g.Value()))
+		for _, key := range keys {
+			if g, ok := gauges[key]; ok {
+				mb = append(mb, metrics.NewGaugeMetric(g.Name()).WithLabels(g.Labels()).SetValue(g.Value()))
+			}
+		}
+	}
+
+	if names, keys := getBatchNames(histogramsBatch); len(names) > 0 {
+		histograms := Storage.HistogramsContext(ctx, store.FilterNames(names))
+		for _, key := range keys {
+			if h, ok := histograms[key]; ok {
+				mb = append(mb, metrics.NewHistogramMetric(h.Name()).WithLabels(h.Labels()).SetHistogram(h.Value()))
 			}
 		}
 	}
@@ -76,30 +95,29 @@ func (c Controller) UpdatesMetrics(ctx context.Context, metricsBatch []metrics.M
 	return mb, http.StatusOK, nil
 }
 
-func getBatchGaugeNames(batch []metrics.Gauge) []string {
-	names := make([]string, 0)
-	keys := map[string]bool{}
-
-	for _, m := range batch {
-		if !keys[m.Name()] {
-			keys[m.Name()] = true
-			names = append(names, m.Name())
-		}
-	}
-
-	return names
+// series is a metric series of the batch.
+type series interface {
+	Name() string
+	Key() string
 }
 
-func getBatchCounterNames(batch []metrics.Counter) []string {
-	names := make([]string, 0)
-	keys := map[string]bool{}
+// getBatchNames returns the unique metric names and series keys of the batch in order of appearance.
+func getBatchNames[S series](batch []S) (names []string, keys []string) {
+	names = make([]string, 0)
+	keys = make([]string, 0)
+	seenNames := map[string]bool{}
+	seenKeys := map[string]bool{}
 
 	for _, m := range batch {
-		if !keys[m.Name()] {
-			keys[m.Name()] = true
+		if !seenNames[m.Name()] {
+			seenNames[m.Name()] = true
 			names = append(names, m.Name())
 		}
+		if !seenKeys[m.Key()] {
+			seenKeys[m.Key()] = true
+			keys = append(keys, m.Key())
+		}
 	}
 
-	return names
+	return names, keys
 }
@@ -0,0 +1,132 @@
+package cryptokey
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encryption schemes negotiated by the agent and the server.
+const (
+	SchemeEnvelope = "envelope-v1" // RSA-OAEP обёртка случайного ключа AES-256-GCM
+	SchemeChunks   = "rsa-chunks"  // Устаревший формат: блоки RSA PKCS#1 v1.5
+)
+
+// HTTP headers of the negotiation.
+// The agent names the scheme of the request body in SchemeHeader,
+// the server lists the supported schemes in AcceptSchemeHeader of every response.
+const (
+	SchemeHeader       = "Content-Encryption"
+	AcceptSchemeHeader = "Accept-Encryption"
+)
+
+// Envelope layout (version 1):
+//
+//	magic "MENC" | version (1 byte) | wrapped key length (2 bytes, big endian) | wrapped key | nonce | AES-GCM ciphertext
+//
+// The header up to and including the wrapped key is authenticated as the additional data of AES-GCM.
+const (
+	envelopeMagic   = "MENC"
+	envelopeVersion = 1
+	envelopeKeySize = 32
+)
+
+var envelopeLabel = []byte("metrics envelope v1")
+
+var (
+	ErrUnsupportedEnvelope = errors.New("unsupported envelope version")
+	ErrMalformedEnvelope   = errors.New("malformed envelope")
+)
+
+// IsEnvelope reports whether the data starts with the envelope header.
+func IsEnvelope(data []byte) bool {
+	return len(data) > len(envelopeMagic) && bytes.HasPrefix(data, []byte(envelopeMagic))
+}
+
+// EncryptEnvelope encrypts the message with a random AES-256-GCM key wrapped by RSA-OAEP (SHA-256).
+func EncryptEnvelope(random io.Reader, pub *rsa.PublicKey, msg []byte) ([]byte, error) {
+	key := make([]byte, envelopeKeySize)
+	if _, err := io.ReadFull(random, key); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), random, pub, key, envelopeLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(envelopeMagic)+3+len(wrapped))
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeVersion)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(msg)+gcm.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, msg, header), nil
+}
+
+// DecryptEnvelope decrypts the message encrypted by EncryptEnvelope.
+func DecryptEnvelope(random io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error) {
+	if !IsEnvelope(msg) {
+		return nil, ErrMalformedEnvelope
+	}
+
+	pos := len(envelopeMagic)
+	if msg[pos] != envelopeVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedEnvelope, msg[pos])
+	}
+	pos++
+
+	if len(msg) < pos+2 {
+		return nil, ErrMalformedEnvelope
+	}
+	keyLen := int(binary.BigEndian.Uint16(msg[pos:]))
+	pos += 2
+
+	if len(msg) < pos+keyLen {
+		return nil, ErrMalformedEnvelope
+	}
+	header := msg[:pos+keyLen]
+	key, err := rsa.DecryptOAEP(sha256.New(), random, priv, msg[pos:pos+keyLen], envelopeLabel)
+	if err != nil {
+		return nil, err
+	}
+	pos += keyLen
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(msg) < pos+gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrMalformedEnvelope
+	}
+	nonce := msg[pos : pos+gcm.NonceSize()]
+	return gcm.Open(nil, nonce, msg[pos+gcm.NonceSize():], header)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 )
@@ -26,19 +27,34 @@ func DecodeKey(data []byte) ([]byte, error) {
 	return block.Bytes, nil
 }
 
+// Encrypt encrypts the data into the envelope with the public key.
 func Encrypt(data []byte, pubKey []byte) ([]byte, error) {
+	return EncryptScheme(data, pubKey, SchemeEnvelope)
+}
+
+// EncryptScheme encrypts the data with the public key using the scheme.
+func EncryptScheme(data []byte, pubKey []byte, scheme string) ([]byte, error) {
 	key, err := x509.ParsePKIXPublicKey(pubKey)
 	if err != nil {
 		return nil, err
 	}
-	data, err = EncryptChunks(rand.Reader, key.(*rsa.PublicKey), data)
-	if err != nil {
-		return nil, err
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+
+	switch scheme {
+	case SchemeEnvelope:
+		return EncryptEnvelope(rand.Reader, pub, data)
+	case SchemeChunks:
+		return EncryptChunks(rand.Reader, pub, data)
+	default:
+		return nil, fmt.Errorf("unknown encryption scheme %q", scheme)
 	}
-	return data, nil
 }
 
-// EncryptChunks encrypt the message in chunks if the message is larger than the key length
+// EncryptChunks encrypt the message in chunks if the message is larger than the key length.
+// It is only kept for the agents that do not support the envelope.
 func EncryptChunks(random io.Reader, pub *rsa.PublicKey, msg []byte) ([]byte, error) {
 	msgLen := len(msg)
 	step := pub.Size() - 11
@@ -61,16 +77,40 @@ func EncryptChunks(random io.Reader, pub *rsa.PublicKey, msg []byte) ([]byte, er
 	return encryptedBytes, nil
 }
 
+// Decrypt decrypts the data with the private key.
+// The envelope is detected by its header, any other data is decrypted as the legacy chunks,
+// so the agents that still use the chunks keep working.
 func Decrypt(data []byte, privateKey []byte) ([]byte, error) {
+	scheme := SchemeChunks
+	if IsEnvelope(data) {
+		scheme = SchemeEnvelope
+	}
+
+	decrypted, err := DecryptScheme(data, privateKey, scheme)
+	if err != nil && scheme == SchemeEnvelope {
+		// Зашифрованные блоками данные могут случайно начинаться с заголовка конверта
+		if legacy, lerr := DecryptScheme(data, privateKey, SchemeChunks); lerr == nil {
+			return legacy, nil
+		}
+	}
+	return decrypted, err
+}
+
+// DecryptScheme decrypts the data with the private key using the scheme.
+func DecryptScheme(data []byte, privateKey []byte, scheme string) ([]byte, error) {
 	key, err := x509.ParsePKCS1PrivateKey(privateKey)
 	if err != nil {
 		return nil, err
 	}
-	data, err = DecryptChunks(nil, key, data)
-	if err != nil {
-		return nil, err
+
+	switch scheme {
+	case SchemeEnvelope:
+		return DecryptEnvelope(nil, key, data)
+	case SchemeChunks:
+		return DecryptChunks(nil, key, data)
+	default:
+		return nil, fmt.Errorf("unknown encryption scheme %q", scheme)
 	}
-	return data, nil
 }
 
 // DecryptChunks decrypt the message in chunks if the message is larger than the key length
@@ -55,3 +55,82 @@ func TestEncryption(t *testing.T) {
 		})
 	}
 }
+
+func TestSchemes(t *testing.T) {
+	publicKey, err := DecodeKey(publicKeyRaw)
+	require.NoError(t, err)
+
+	privateKey, err := DecodeKey(privateKeyRaw)
+	require.NoError(t, err)
+
+	msg := []byte("Незакодированная строка")
+
+	for _, scheme := range []string{SchemeEnvelope, SchemeChunks} {
+		t.Run(scheme, func(t *testing.T) {
+			encoded, err := EncryptScheme(msg, publicKey, scheme)
+			require.NoError(t, err)
+			assert.Equal(t, scheme == SchemeEnvelope, IsEnvelope(encoded))
+
+			decoded, err := DecryptScheme(encoded, privateKey, scheme)
+			require.NoError(t, err)
+			assert.Equal(t, msg, decoded)
+
+			// Схема определяется автоматически
+			decoded, err = Decrypt(encoded, privateKey)
+			require.NoError(t, err)
+			assert.Equal(t, msg, decoded)
+		})
+	}
+}
+
+func TestEnvelopeIntegrity(t *testing.T) {
+	publicKey, err := DecodeKey(publicKeyRaw)
+	require.NoError(t, err)
+
+	privateKey, err := DecodeKey(privateKeyRaw)
+	require.NoError(t, err)
+
+	encoded, err := Encrypt([]byte(`{"id":"PollCount","type":"counter","delta":1}`), publicKey)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		modify func(data []byte) []byte
+	}{
+		{
+			name: "Modified ciphertext",
+			modify: func(data []byte) []byte {
+				data[len(data)-20] ^= 0x01
+				return data
+			},
+		},
+		{
+			name: "Modified wrapped key",
+			modify: func(data []byte) []byte {
+				data[len(envelopeMagic)+5] ^= 0x01
+				return data
+			},
+		},
+		{
+			name: "Unsupported version",
+			modify: func(data []byte) []byte {
+				data[len(envelopeMagic)] = 2
+				return data
+			},
+		},
+		{
+			name: "Truncated",
+			modify: func(data []byte) []byte {
+				return data[:len(envelopeMagic)+10]
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := tc.modify(append([]byte(nil), encoded...))
+			_, err := DecryptScheme(data, privateKey, SchemeEnvelope)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Package expfmt renders the metrics in the Prometheus text exposition format
+// and in the OpenMetrics text format.
+package expfmt
@@ -0,0 +1,197 @@
+package expfmt
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+type family struct {
+	name   string
+	mtype  string
+	series []series
+}
+
+type series struct {
+	labels metrics.Labels
+	write  func(w *bufio.Writer, name string, labels metrics.Labels)
+}
+
+// Encoder writes the metrics in the exposition format.
+type Encoder struct {
+	w        io.Writer
+	format   Format
+	families map[string]*family
+}
+
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{
+		w:        w,
+		format:   format,
+		families: make(map[string]*family),
+	}
+}
+
+// AddCounters adds the counter series.
+// In the OpenMetrics format the samples of the counters get the _total suffix.
+func (e *Encoder) AddCounters(counters map[string]metrics.Counter) {
+	for _, c := range counters {
+		name := SanitizeName(c.Name())
+		if e.format == FormatOpenMetrics {
+			name = strings.TrimSuffix(name, "_total")
+		}
+
+		value := strconv.FormatInt(c.Value(), 10)
+		e.add(name, metrics.TypeCounter, c.Labels(), func(w *bufio.Writer, name string, labels metrics.Labels) {
+			if e.format == FormatOpenMetrics {
+				name += "_total"
+			}
+			writeSample(w, name, labels, value)
+		})
+	}
+}
+
+// AddGauges adds the gauge series.
+func (e *Encoder) AddGauges(gauges map[string]metrics.Gauge) {
+	for _, g := range gauges {
+		value := formatFloat(g.Value())
+		e.add(SanitizeName(g.Name()), metrics.TypeGauge, g.Labels(), func(w *bufio.Writer, name string, labels metrics.Labels) {
+			writeSample(w, name, labels, value)
+		})
+	}
+}
+
+// AddHistograms adds the histogram series with cumulative buckets.
+// The label le of the series is renamed to exported_le, as le is the bound of the bucket.
+func (e *Encoder) AddHistograms(histograms map[string]metrics.Histogram) {
+	for _, h := range histograms {
+		v := h.Value()
+		e.add(SanitizeName(h.Name()), metrics.TypeHistogram, histogramLabels(h.Labels()), func(w *bufio.Writer, name string, labels metrics.Labels) {
+			var cumulative uint64
+			for i, count := range v.Counts {
+				cumulative += count
+
+				le := "+Inf"
+				if i < len(v.Bounds) {
+					le = formatFloat(v.Bounds[i])
+				}
+				bucketLabels := labels.Clone()
+				if bucketLabels == nil {
+					bucketLabels = metrics.Labels{}
+				}
+				bucketLabels["le"] = le
+
+				writeSample(w, name+"_bucket", bucketLabels, strconv.FormatUint(cumulative, 10))
+			}
+			writeSample(w, name+"_sum", labels, formatFloat(v.Sum))
+			writeSample(w, name+"_count", labels, strconv.FormatUint(v.Count, 10))
+		})
+	}
+}
+
+// histogramLabels returns the labels of the histogram series without the reserved label le.
+func histogramLabels(labels metrics.Labels) metrics.Labels {
+	le, ok := labels["le"]
+	if !ok {
+		return labels
+	}
+	labels = labels.Clone()
+	delete(labels, "le")
+	labels["exported_le"] = le
+	return labels
+}
+
+// add appends the series to its family. A series which name is already taken
+// by a family of another type is skipped, as the format does not allow that.
+func (e *Encoder) add(name, mtype string, labels metrics.Labels, write func(w *bufio.Writer, name string, labels metrics.Labels)) {
+	f, ok := e.families[name]
+	if !ok {
+		f = &family{name: name, mtype: mtype}
+		e.families[name] = f
+	}
+	if f.mtype != mtype {
+		return
+	}
+	f.series = append(f.series, series{labels: labels, write: write})
+}
+
+// Encode writes all the added families sorted by name.
+func (e *Encoder) Encode() error {
+	names := make([]string, 0, len(e.families))
+	for name := range e.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := bufio.NewWriter(e.w)
+	for _, name := range names {
+		f := e.families[name]
+		sort.Slice(f.series, func(i, j int) bool {
+			return f.series[i].labels.String() < f.series[j].labels.String()
+		})
+
+		w.WriteString("# TYPE ")
+		w.WriteString(f.name)
+		w.WriteByte(' ')
+		w.WriteString(f.mtype)
+		w.WriteByte('\n')
+
+		for _, s := range f.series {
+			s.write(w, f.name, s.labels)
+		}
+	}
+
+	if e.format == FormatOpenMetrics {
+		w.WriteString("# EOF\n")
+	}
+	return w.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, labels metrics.Labels, value string) {
+	w.WriteString(name)
+	w.WriteString(labels.String())
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// SanitizeName replaces the characters not allowed in a metric name with underscores.
+// A name starting with a digit gets an underscore prefix.
+func SanitizeName(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "_"
+	}
+	return sb.String()
+}
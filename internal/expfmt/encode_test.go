@@ -0,0 +1,139 @@
+package expfmt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func testMetrics(t *testing.T) (map[string]metrics.Counter, map[string]metrics.Gauge, map[string]metrics.Histogram) {
+	c1, err := metrics.NewCounter("PollCount", 5)
+	require.NoError(t, err)
+	c2, err := metrics.NewCounterWithLabels("PollCount", metrics.Labels{"host": "web\"1\"\n"}, 7)
+	require.NoError(t, err)
+	g1, err := metrics.NewGauge("Heap.Alloc", 1.5)
+	require.NoError(t, err)
+	g2, err := metrics.NewGauge("PollCount", 1) // Имя уже занято счётчиком
+	require.NoError(t, err)
+
+	hv, err := metrics.NewHistogramValue([]float64{0.1, 1})
+	require.NoError(t, err)
+	hv.Observe(0.05)
+	hv.Observe(0.5)
+	hv.Observe(3)
+	h, err := metrics.NewHistogramWithLabels("latency", metrics.Labels{"host": "web1"}, hv)
+	require.NoError(t, err)
+
+	return map[string]metrics.Counter{c1.Key(): *c1, c2.Key(): *c2},
+		map[string]metrics.Gauge{g1.Key(): *g1, g2.Key(): *g2},
+		map[string]metrics.Histogram{h.Key(): *h}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	counters, gauges, histograms := testMetrics(t)
+
+	testCases := []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{
+			name:   "Prometheus text format",
+			format: FormatText,
+			want: `# TYPE Heap_Alloc gauge
+Heap_Alloc 1.5
+# TYPE PollCount counter
+PollCount 5
+PollCount{host="web\"1\"\n"} 7
+# TYPE latency histogram
+latency_bucket{host="web1",le="0.1"} 1
+latency_bucket{host="web1",le="1"} 2
+latency_bucket{host="web1",le="+Inf"} 3
+latency_sum{host="web1"} 3.55
+latency_count{host="web1"} 3
+`,
+		},
+		{
+			name:   "OpenMetrics format",
+			format: FormatOpenMetrics,
+			want: `# TYPE Heap_Alloc gauge
+Heap_Alloc 1.5
+# TYPE PollCount counter
+PollCount_total 5
+PollCount_total{host="web\"1\"\n"} 7
+# TYPE latency histogram
+latency_bucket{host="web1",le="0.1"} 1
+latency_bucket{host="web1",le="1"} 2
+latency_bucket{host="web1",le="+Inf"} 3
+latency_sum{host="web1"} 3.55
+latency_count{host="web1"} 3
+# EOF
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			enc := NewEncoder(buf, tc.format)
+			enc.AddCounters(counters)
+			enc.AddGauges(gauges)
+			enc.AddHistograms(histograms)
+			require.NoError(t, enc.Encode())
+			assert.Equal(t, tc.want, buf.String())
+		})
+	}
+}
+
+func TestEncoder_HistogramLabelLe(t *testing.T) {
+	h, err := metrics.NewHistogramWithLabels("latency", metrics.Labels{"le": "user"},
+		metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 2, Sum: 2.5})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, FormatText)
+	enc.AddHistograms(map[string]metrics.Histogram{h.Key(): *h})
+	require.NoError(t, enc.Encode())
+
+	assert.Equal(t, `# TYPE latency histogram
+latency_bucket{exported_le="user",le="1"} 1
+latency_bucket{exported_le="user",le="+Inf"} 2
+latency_sum{exported_le="user"} 2.5
+latency_count{exported_le="user"} 2
+`, buf.String())
+	assert.Equal(t, "user", h.Labels()["le"], "the labels of the series are not changed")
+}
+
+func TestSanitizeName(t *testing.T) {
+	testCases := map[string]string{
+		"Alloc":           "Alloc",
+		"http:requests":   "http:requests",
+		"Heap.Alloc":      "Heap_Alloc",
+		"cpu-usage %":     "cpu_usage__",
+		"1minute":         "_1minute",
+		"CPUutilization1": "CPUutilization1",
+		"":                "_",
+	}
+	for name, want := range testCases {
+		assert.Equal(t, want, SanitizeName(name), name)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	testCases := map[string]Format{
+		"":                             FormatText,
+		"text/plain":                   FormatText,
+		"*/*":                          FormatText,
+		"application/openmetrics-text": FormatOpenMetrics,
+		"application/openmetrics-text; version=1.0.0; charset=utf-8,text/plain;version=0.0.4;q=0.5,*/*;q=0.1": FormatOpenMetrics,
+		"application/openmetrics-text;q=0.5,text/plain":                                                       FormatText,
+		"application/json": FormatText,
+	}
+	for accept, want := range testCases {
+		assert.Equal(t, want, Negotiate(accept), accept)
+	}
+}
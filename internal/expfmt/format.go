@@ -0,0 +1,43 @@
+package expfmt
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Format is the content type of the exposition.
+type Format string
+
+// Supported exposition formats.
+const (
+	FormatText        Format = "text/plain; version=0.0.4; charset=utf-8"
+	FormatOpenMetrics Format = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Negotiate returns the format preferred by the Accept header of the request.
+// The Prometheus text format is used by default.
+func Negotiate(accept string) Format {
+	format, best := FormatText, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		// При равном весе предпочтение отдаётся формату Prometheus
+		if mediaType == "application/openmetrics-text" && q > best {
+			format, best = FormatOpenMetrics, q
+		} else if (mediaType == "text/plain" || mediaType == "text/*" || mediaType == "*/*") && q >= best && q > 0 {
+			format, best = FormatText, q
+		}
+	}
+	return format
+}
@@ -0,0 +1,8 @@
+// Package graphite implements a listener of the Graphite plaintext protocol.
+//
+// Each line has the form path value [timestamp], the path may carry Graphite tags:
+// path;tag=value;tag2=value2. The values are stored as gauges.
+//
+// The dotted path becomes the metric name with the segments joined by underscores.
+// Templates extract the segments of the matching paths into labels, see ParseTemplate.
+package graphite
@@ -0,0 +1,227 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+const (
+	// maxBatchSize is the maximum number of series written in one batch.
+	maxBatchSize = 1000
+	// idleTimeout is the time after which an idle connection is closed.
+	idleTimeout = 5 * time.Minute
+	// maxLineSize is the maximum length of a line, the connection is closed on a longer line.
+	maxLineSize = 16 * 1024
+)
+
+type Config struct {
+	Addr           string     // Адрес для приёма метрик
+	Templates      []Template // Шаблоны выделения меток из пути метрики
+	MaxConnections int        // Максимальное число одновременных соединений, 0 - без ограничений
+}
+
+// Listener receives the Graphite plaintext lines over TCP and stores them as gauges.
+type Listener struct {
+	ln      net.Listener
+	storage store.MetricsStorager
+	sem     chan struct{}
+	conns   map[net.Conn]struct{}
+	config  Config
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	closed  bool
+}
+
+func NewListener(cfg Config, storage store.MetricsStorager) *Listener {
+	l := &Listener{
+		config:  cfg,
+		storage: storage,
+		conns:   make(map[net.Conn]struct{}),
+	}
+	if cfg.MaxConnections > 0 {
+		l.sem = make(chan struct{}, cfg.MaxConnections)
+	}
+	return l
+}
+
+// Listen opens the TCP socket.
+func (l *Listener) Listen() error {
+	ln, err := net.Listen("tcp", l.config.Addr)
+	if err != nil {
+		return err
+	}
+	l.ln = ln
+	return nil
+}
+
+// Addr returns the address of the socket.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Serve accepts the connections until the listener is shut down.
+func (l *Listener) Serve() error {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		if l.sem != nil {
+			select {
+			case l.sem <- struct{}{}:
+			default:
+				logger.Log.Warn("Too many graphite connections", logger.String("event", "accept graphite connection"),
+					logger.String("remote", conn.RemoteAddr().String()))
+				_ = conn.Close()
+				continue
+			}
+		}
+
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+
+		go l.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting the connections and waits for the open connections to store the received lines.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	l.closed = true
+	err := l.ln.Close()
+	for conn := range l.conns {
+		// Прерываем ожидание новых данных, уже полученные строки будут сохранены
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		l.mu.Lock()
+		for conn := range l.conns {
+			_ = conn.Close()
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (l *Listener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		_ = conn.Close()
+		if l.sem != nil {
+			<-l.sem
+		}
+	}()
+
+	batch := make(map[string]metrics.Gauge)
+	flush := func() {
+		if len(batch) > 0 {
+			l.write(batch)
+			batch = make(map[string]metrics.Gauge)
+		}
+	}
+
+	// Сохраняем накопленные значения, когда прочитаны все полученные данные
+	sc := bufio.NewScanner(&connReader{conn: conn, before: func() {
+		flush()
+		l.mu.Lock()
+		if !l.closed {
+			_ = conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		}
+		l.mu.Unlock()
+	}})
+	sc.Buffer(make([]byte, 0, 4096), maxLineSize)
+
+	for sc.Scan() {
+		if s := strings.TrimSpace(sc.Text()); s != "" {
+			if g, err := l.parse(s); err != nil {
+				logger.Log.Debug(err.Error(), logger.String("event", "parse graphite line"))
+			} else {
+				batch[g.Key()] = *g
+			}
+		}
+		if len(batch) >= maxBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	if err := sc.Err(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrDeadlineExceeded) {
+		logger.Log.Debug(err.Error(), logger.String("event", "read graphite connection"))
+	}
+}
+
+// connReader calls before prior to every read from the connection.
+type connReader struct {
+	conn   net.Conn
+	before func()
+}
+
+func (r *connReader) Read(p []byte) (int, error) {
+	r.before()
+	return r.conn.Read(p)
+}
+
+// parse converts the line into a gauge.
+func (l *Listener) parse(s string) (*metrics.Gauge, error) {
+	line, err := ParseLine(s)
+	if err != nil {
+		return nil, err
+	}
+
+	name, labels, err := Map(line, l.config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	return metrics.NewGaugeWithLabels(name, labels, line.Value)
+}
+
+func (l *Listener) write(batch map[string]metrics.Gauge) {
+	gauges := make([]metrics.Gauge, 0, len(batch))
+	for _, g := range batch {
+		gauges = append(gauges, g)
+	}
+
+	if err := l.storage.InsertBatchContext(context.Background(), store.WithGauges(gauges)); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "store graphite metrics"))
+	}
+}
@@ -0,0 +1,109 @@
+package graphite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func TestListener(t *testing.T) {
+	templates, err := ParseTemplates([]string{"servers.{host}.*"})
+	require.NoError(t, err)
+
+	storage := store.NewMemStorage()
+	l := NewListener(Config{Addr: "127.0.0.1:0", Templates: templates, MaxConnections: 1}, storage)
+	require.NoError(t, l.Listen())
+
+	served := make(chan error)
+	go func() {
+		served <- l.Serve()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(conn, "servers.web1.cpu.load 0.75 %d\nbackup.duration 42\ninvalid line\n", time.Now().Unix())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		_, ok := storage.GaugeValue("backup_duration")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	v, ok := storage.GaugeValueContext(ctx, "servers_cpu_load", metrics.Labels{"host": "web1"})
+	require.True(t, ok)
+	assert.Equal(t, 0.75, v)
+
+	// Превышение лимита соединений: новое соединение закрывается сервером
+	extra, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, extra.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = extra.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	_ = extra.Close()
+
+	// Строки, полученные до остановки, сохраняются
+	_, err = fmt.Fprint(conn, "backup.size 1024\n")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, ok := storage.GaugeValue("backup_size")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, l.Shutdown(shutdownCtx))
+	require.NoError(t, <-served)
+
+	// Соединение закрыто сервером
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	_ = conn.Close()
+}
+
+func TestListener_LongLine(t *testing.T) {
+	storage := store.NewMemStorage()
+	l := NewListener(Config{Addr: "127.0.0.1:0"}, storage)
+	require.NoError(t, l.Listen())
+
+	served := make(chan error)
+	go func() {
+		served <- l.Serve()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = fmt.Fprint(conn, "backup.duration 42\n")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, ok := storage.GaugeValue("backup_duration")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// Строка длиннее лимита: соединение закрывается, не дожидаясь её конца
+	_, err = conn.Write([]byte("backup.size " + strings.Repeat("1", maxLineSize)))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, l.Shutdown(ctx))
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,65 @@
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLine = errors.New("invalid graphite line")
+
+// Line is a parsed Graphite line.
+type Line struct {
+	Time  time.Time // Нулевое значение, если метка времени не указана
+	Tags  map[string]string
+	Path  string
+	Value float64
+}
+
+// ParseLine parses the line path value [timestamp].
+func ParseLine(s string) (Line, error) {
+	var line Line
+
+	parts := strings.Fields(s)
+	if len(parts) < 2 || len(parts) > 3 {
+		return line, fmt.Errorf("%w %q: expected path, value and timestamp", ErrInvalidLine, s)
+	}
+
+	path, tags, _ := strings.Cut(parts[0], ";")
+	if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+		return line, fmt.Errorf("%w %q: incorrect path", ErrInvalidLine, s)
+	}
+	line.Path = path
+
+	if tags != "" {
+		line.Tags = make(map[string]string)
+		for _, tag := range strings.Split(tags, ";") {
+			k, v, ok := strings.Cut(tag, "=")
+			if !ok || k == "" || v == "" {
+				return line, fmt.Errorf("%w %q: incorrect tag %q", ErrInvalidLine, s, tag)
+			}
+			line.Tags[k] = v
+		}
+	}
+
+	v, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.IsNaN(v) {
+		return line, fmt.Errorf("%w %q: incorrect value", ErrInvalidLine, s)
+	}
+	line.Value = v
+
+	// Метка времени -1 или N означает текущее время
+	if len(parts) == 3 && parts[2] != "-1" && parts[2] != "N" {
+		ts, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return line, fmt.Errorf("%w %q: incorrect timestamp", ErrInvalidLine, s)
+		}
+		sec, frac := math.Modf(ts)
+		line.Time = time.Unix(int64(sec), int64(frac*1e9))
+	}
+
+	return line, nil
+}
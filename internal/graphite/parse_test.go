@@ -0,0 +1,76 @@
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	testCases := []struct {
+		name    string
+		line    string
+		want    Line
+		wantErr bool
+	}{
+		{
+			name: "With timestamp",
+			line: "servers.web1.cpu.load 0.75 1704110400",
+			want: Line{Path: "servers.web1.cpu.load", Value: 0.75, Time: time.Unix(1704110400, 0)},
+		},
+		{
+			name: "Without timestamp",
+			line: "backup.duration 42",
+			want: Line{Path: "backup.duration", Value: 42},
+		},
+		{
+			name: "Current time",
+			line: "backup.duration 42 -1",
+			want: Line{Path: "backup.duration", Value: 42},
+		},
+		{
+			name: "With tags",
+			line: "backup.duration;host=db1;env=prod 42 1704110400",
+			want: Line{Path: "backup.duration", Value: 42, Tags: map[string]string{"host": "db1", "env": "prod"}, Time: time.Unix(1704110400, 0)},
+		},
+		{
+			name:    "Without value",
+			line:    "backup.duration",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect value",
+			line:    "backup.duration abc 1704110400",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect timestamp",
+			line:    "backup.duration 42 now",
+			wantErr: true,
+		},
+		{
+			name:    "Empty segment",
+			line:    "backup..duration 42",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect tag",
+			line:    "backup.duration;host 42",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			line, err := ParseLine(tc.line)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLine)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, line)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/expfmt"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+var ErrInvalidTemplate = errors.New("invalid graphite template")
+
+// Template maps the segments of the matching paths into labels.
+//
+// The template is a dotted pattern matched against the first segments of the path:
+// a literal segment must be equal to the segment of the path, * matches any segment,
+// {label} matches any segment and moves it into the label. For example, the template
+// servers.{host}.* maps servers.web1.cpu.load into the metric servers_cpu_load{host="web1"}.
+type Template struct {
+	pattern string
+	parts   []string
+}
+
+// ParseTemplate parses the dotted template pattern.
+func ParseTemplate(pattern string) (Template, error) {
+	t := Template{pattern: pattern, parts: strings.Split(pattern, ".")}
+
+	seen := make(map[string]bool)
+	for _, part := range t.parts {
+		if part == "" {
+			return t, fmt.Errorf("%w %q: empty segment", ErrInvalidTemplate, pattern)
+		}
+
+		if label, ok := templateLabel(part); ok {
+			if err := (metrics.Labels{label: "x"}).Validate(); err != nil {
+				return t, fmt.Errorf("%w %q: %w", ErrInvalidTemplate, pattern, err)
+			}
+			if seen[label] {
+				return t, fmt.Errorf("%w %q: duplicate label %q", ErrInvalidTemplate, pattern, label)
+			}
+			seen[label] = true
+		}
+	}
+
+	return t, nil
+}
+
+// ParseTemplates parses the list of the template patterns.
+func ParseTemplates(patterns []string) ([]Template, error) {
+	templates := make([]Template, 0, len(patterns))
+	for _, p := range patterns {
+		t, err := ParseTemplate(p)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func (t Template) String() string {
+	return t.pattern
+}
+
+// Apply returns the metric name and labels of the path if the path matches the template.
+func (t Template) Apply(path string) (string, metrics.Labels, bool) {
+	segments := strings.Split(path, ".")
+	if len(segments) < len(t.parts) {
+		return "", nil, false
+	}
+
+	labels := metrics.Labels{}
+	name := make([]string, 0, len(segments))
+	for i, part := range t.parts {
+		if label, ok := templateLabel(part); ok {
+			labels[label] = segments[i]
+			continue
+		}
+		if part != "*" && part != segments[i] {
+			return "", nil, false
+		}
+		name = append(name, segments[i])
+	}
+	name = append(name, segments[len(t.parts):]...)
+
+	// Имя метрики не может состоять только из меток
+	if len(name) == 0 {
+		return "", nil, false
+	}
+
+	return MetricName(name...), labels.Clone(), true
+}
+
+func templateLabel(part string) (string, bool) {
+	if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+		return part[1 : len(part)-1], true
+	}
+	return "", false
+}
+
+// MetricName joins the path segments into the metric name.
+func MetricName(segments ...string) string {
+	return expfmt.SanitizeName(strings.Join(segments, "_"))
+}
+
+// Map returns the metric name and labels of the line using the first matching template.
+// The Graphite tags of the line are added to the labels.
+func Map(line Line, templates []Template) (string, metrics.Labels, error) {
+	name, labels := "", metrics.Labels{}
+	matched := false
+	for _, t := range templates {
+		if n, l, ok := t.Apply(line.Path); ok {
+			name, labels, matched = n, l, true
+			break
+		}
+	}
+	if !matched {
+		name = MetricName(strings.Split(line.Path, ".")...)
+	}
+
+	for k, v := range line.Tags {
+		if labels == nil {
+			labels = metrics.Labels{}
+		}
+		labels[k] = v
+	}
+	if err := labels.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	return name, labels.Clone(), nil
+}
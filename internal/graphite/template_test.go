@@ -0,0 +1,75 @@
+package graphite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, pattern := range []string{"servers.{host}.*", "*.{env}", "{dc}"} {
+		_, err := ParseTemplate(pattern)
+		assert.NoError(t, err, pattern)
+	}
+
+	for _, pattern := range []string{"servers..*", "servers.{1host}", "{host}.{host}"} {
+		_, err := ParseTemplate(pattern)
+		assert.ErrorIs(t, err, ErrInvalidTemplate, pattern)
+	}
+}
+
+func TestMap(t *testing.T) {
+	templates, err := ParseTemplates([]string{"servers.{host}.*", "jobs.{job}"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		line       Line
+		wantName   string
+		wantLabels metrics.Labels
+	}{
+		{
+			name:       "Matching template",
+			line:       Line{Path: "servers.web1.cpu.load"},
+			wantName:   "servers_cpu_load",
+			wantLabels: metrics.Labels{"host": "web1"},
+		},
+		{
+			name:       "Second template",
+			line:       Line{Path: "jobs.backup.duration"},
+			wantName:   "jobs_duration",
+			wantLabels: metrics.Labels{"job": "backup"},
+		},
+		{
+			name:     "Only labels would remain",
+			line:     Line{Path: "servers.web1"},
+			wantName: "servers_web1",
+		},
+		{
+			name:     "No matching template",
+			line:     Line{Path: "cron.backup-db.duration"},
+			wantName: "cron_backup_db_duration",
+		},
+		{
+			name:       "Template and tags",
+			line:       Line{Path: "servers.web1.uptime", Tags: map[string]string{"env": "prod"}},
+			wantName:   "servers_uptime",
+			wantLabels: metrics.Labels{"host": "web1", "env": "prod"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, labels, err := Map(tc.line, templates)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantName, name)
+			assert.Equal(t, tc.wantLabels, labels)
+		})
+	}
+
+	_, _, err = Map(Line{Path: "cron.duration", Tags: map[string]string{"1env": "prod"}}, templates)
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+
+	"github.com/fishus/go-advanced-metrics/internal/cryptokey"
+)
+
+// EncryptCodec is the client codec that encrypts the request messages with the public key.
+// The response messages are not encrypted.
+type EncryptCodec struct {
+	PublicKey []byte
+}
+
+func (c EncryptCodec) Marshal(v any) ([]byte, error) {
+	data, err := encoding.GetCodec(proto.Name).Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return cryptokey.Encrypt(data, c.PublicKey)
+}
+
+func (c EncryptCodec) Unmarshal(data []byte, v any) error {
+	return encoding.GetCodec(proto.Name).Unmarshal(data, v)
+}
+
+func (c EncryptCodec) Name() string {
+	return proto.Name
+}
+
+// DecryptCodec is the server codec that decrypts the request messages with the private key.
+// The response messages are sent as is.
+type DecryptCodec struct {
+	PrivateKey []byte
+	Encrypted  func(v any) bool // Зашифровано ли сообщение, если не задано - расшифровываются все сообщения
+}
+
+func (c DecryptCodec) Marshal(v any) ([]byte, error) {
+	return encoding.GetCodec(proto.Name).Marshal(v)
+}
+
+func (c DecryptCodec) Unmarshal(data []byte, v any) error {
+	if c.Encrypted != nil && !c.Encrypted(v) {
+		return encoding.GetCodec(proto.Name).Unmarshal(data, v)
+	}
+
+	data, err := cryptokey.Decrypt(data, c.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt request data: %w", err)
+	}
+	return encoding.GetCodec(proto.Name).Unmarshal(data, v)
+}
+
+func (c DecryptCodec) Name() string {
+	return proto.Name
+}
@@ -0,0 +1,74 @@
+package interceptors
+
+import (
+	"context"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+)
+
+// RequestIDMDKey is the metadata key of the request ID, the same as the X-Request-Id header of the REST API.
+// The ID is generated when the client has not passed it and is returned in the response header.
+const RequestIDMDKey = "x-request-id"
+
+// AuditInterceptor records the calls of the methods to the audit log with their status code.
+// The methods set the number of the received metrics with audit.SetMetrics.
+// Nothing is recorded when the log is not set.
+func AuditInterceptor(log *audit.Log, proxies clientip.Subnets, methods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if log == nil || !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx = auditContext(ctx, log, proxies, info.FullMethod)
+		resp, err := handler(ctx, req)
+		audit.Record(ctx, int(status.Code(err)), err)
+		return resp, err
+	}
+}
+
+// AuditStreamInterceptor passes the audit context to the streaming methods, that record every received batch.
+// The stream that fails is recorded as well.
+func AuditStreamInterceptor(log *audit.Log, proxies clientip.Subnets, methods map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if log == nil || !methods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		wrapped := middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = auditContext(ss.Context(), log, proxies, info.FullMethod)
+
+		err := handler(srv, wrapped)
+		if err != nil {
+			audit.SetMetrics(wrapped.WrappedContext, 0)
+			audit.Record(wrapped.WrappedContext, int(status.Code(err)), err)
+		}
+		return err
+	}
+}
+
+func auditContext(ctx context.Context, log *audit.Log, proxies clientip.Subnets, method string) context.Context {
+	requestID := metadataValue(ctx, RequestIDMDKey)
+	if requestID == "" {
+		requestID = audit.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMDKey, requestID))
+
+	var clientIP string
+	if addr := clientAddr(ctx, proxies); addr.IsValid() {
+		clientIP = addr.String()
+	}
+
+	return audit.NewContext(ctx, log, audit.Event{
+		Protocol:  audit.ProtocolGRPC,
+		Action:    method,
+		ClientIP:  clientIP,
+		Identity:  audit.Identity(ctx),
+		RequestID: requestID,
+	})
+}
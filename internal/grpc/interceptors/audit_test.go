@@ -0,0 +1,114 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+type AuditSuite struct {
+	suite.Suite
+	log    *audit.Log
+	server *grpc.Server
+	client pb.MetricsClient
+}
+
+func (s *AuditSuite) SetupTest() {
+	log, err := audit.Open(filepath.Join(s.T().TempDir(), "audit.log"), 0, 0)
+	s.Require().NoError(err)
+	s.log = log
+
+	methods := map[string]bool{
+		pb.Metrics_Updates_FullMethodName:       true,
+		pb.Metrics_StreamUpdates_FullMethodName: true,
+	}
+	limiter := ratelimit.New(0.1, 1)
+
+	// Реальный TCP-адрес нужен, чтобы в журнал попал адрес клиента
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(AuditInterceptor(log, nil, methods)),
+		grpc.ChainStreamInterceptor(AuditStreamInterceptor(log, nil, methods), RateLimitStreamInterceptor(limiter, nil, methods)),
+	)
+	pb.RegisterMetricsServer(s.server, echoServer{})
+	go func() { _ = s.server.Serve(lis) }()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	s.client = pb.NewMetricsClient(conn)
+}
+
+func (s *AuditSuite) TearDownTest() {
+	s.server.Stop()
+	s.NoError(s.log.Close())
+}
+
+func (s *AuditSuite) TestUnary() {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMDKey, "req-1")
+	var header metadata.MD
+	_, err := s.client.Updates(ctx, &pb.UpdatesRequest{}, grpc.Header(&header))
+	s.Require().NoError(err)
+	s.Equal([]string{"req-1"}, header.Get(RequestIDMDKey))
+
+	// Без ID запроса он создаётся сервером
+	header = nil
+	_, err = s.client.Updates(context.Background(), &pb.UpdatesRequest{}, grpc.Header(&header))
+	s.Require().NoError(err)
+	s.Require().Len(header.Get(RequestIDMDKey), 1)
+	generated := header.Get(RequestIDMDKey)[0]
+
+	// Методы, не требующие аудита, не записываются
+	_, err = s.client.Value(context.Background(), &pb.ValueRequest{})
+	s.Equal(codes.Unimplemented, status.Code(err))
+
+	events, err := s.log.Query(audit.Filter{})
+	s.Require().NoError(err)
+	s.Require().Len(events, 2)
+
+	s.Equal(audit.ProtocolGRPC, events[0].Protocol)
+	s.Equal(pb.Metrics_Updates_FullMethodName, events[0].Action)
+	s.Equal("127.0.0.1", events[0].ClientIP)
+	s.Equal("req-1", events[0].RequestID)
+	s.Equal(audit.OutcomeSuccess, events[0].Outcome)
+	s.Equal(int(codes.OK), events[0].Code)
+
+	s.Equal(generated, events[1].RequestID)
+}
+
+func (s *AuditSuite) TestStreamFailure() {
+	stream, err := s.client.StreamUpdates(context.Background())
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: 1}))
+	s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: 2}))
+
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Equal(codes.ResourceExhausted, status.Code(err))
+
+	events, err := s.log.Query(audit.Filter{})
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(pb.Metrics_StreamUpdates_FullMethodName, events[0].Action)
+	s.Equal(audit.OutcomeFailure, events[0].Outcome)
+	s.Equal(int(codes.ResourceExhausted), events[0].Code)
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, new(AuditSuite))
+}
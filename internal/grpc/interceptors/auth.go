@@ -0,0 +1,101 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+)
+
+// AuthorizationMDKey is the metadata key of the API token, the same as the Authorization header of the REST API.
+const AuthorizationMDKey = "authorization"
+
+// AuthInterceptor checks that the API token of the call grants the scope of the method.
+// The methods missing from the scopes require the admin scope. When the registry is not set,
+// the admin methods are denied and the others are not checked.
+func AuthInterceptor(registry *auth.Registry, scopes map[string]auth.Scope) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		scope := methodScope(scopes, info.FullMethod)
+		if registry == nil {
+			if scope == auth.ScopeAdmin {
+				return nil, status.Error(codes.PermissionDenied, auth.ErrNoRegistry.Error())
+			}
+			return handler(ctx, req)
+		}
+
+		ctx, err := authorize(ctx, registry, scope)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor checks that the API token of the stream grants the scope of the method.
+func AuthStreamInterceptor(registry *auth.Registry, scopes map[string]auth.Scope) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		scope := methodScope(scopes, info.FullMethod)
+		if registry == nil {
+			if scope == auth.ScopeAdmin {
+				return status.Error(codes.PermissionDenied, auth.ErrNoRegistry.Error())
+			}
+			return handler(srv, ss)
+		}
+
+		ctx, err := authorize(ss.Context(), registry, scope)
+		if err != nil {
+			return err
+		}
+		wrapped := middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// TokenUnaryClientInterceptor passes the API token with every call.
+func TokenUnaryClientInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, AuthorizationMDKey, "Bearer "+token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// TokenStreamClientInterceptor passes the API token with every stream.
+func TokenStreamClientInterceptor(token string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, AuthorizationMDKey, "Bearer "+token)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func methodScope(scopes map[string]auth.Scope, method string) auth.Scope {
+	if scope, ok := scopes[method]; ok {
+		return scope
+	}
+	return auth.ScopeAdmin
+}
+
+// authorize returns the context with the token of the call if it grants the scope.
+func authorize(ctx context.Context, registry *auth.Registry, scope auth.Scope) (context.Context, error) {
+	token, err := registry.Authorize(auth.BearerToken(metadataValue(ctx, AuthorizationMDKey)), scope)
+	// Отказ в доступе тоже записывается в журнал аудита с именем токена
+	audit.SetIdentity(ctx, token.Name)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			return ctx, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return auth.NewContext(ctx, token), nil
+}
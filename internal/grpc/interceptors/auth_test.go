@@ -0,0 +1,170 @@
+package interceptors
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+type AuthSuite struct {
+	suite.Suite
+	lis    *bufconn.Listener
+	server *grpc.Server
+}
+
+func (s *AuthSuite) SetupSuite() {
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [
+		{"name": "web1", "token_sha256": "` + auth.Hash("agent-token") + `", "scopes": ["write"]},
+		{"name": "grafana", "token_sha256": "` + auth.Hash("reader-token") + `", "scopes": ["read"]},
+		{"name": "old", "token_sha256": "` + auth.Hash("old-token") + `", "scopes": ["write"], "revoked": true}
+	]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	scopes := map[string]auth.Scope{
+		pb.Metrics_Updates_FullMethodName:       auth.ScopeWrite,
+		pb.Metrics_StreamUpdates_FullMethodName: auth.ScopeWrite,
+		pb.Metrics_Value_FullMethodName:         auth.ScopeRead,
+	}
+
+	s.lis = bufconn.Listen(1024 * 1024)
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(AuthInterceptor(registry, scopes)),
+		grpc.ChainStreamInterceptor(AuthStreamInterceptor(registry, scopes)),
+	)
+	pb.RegisterMetricsServer(s.server, echoServer{})
+	go func() { _ = s.server.Serve(s.lis) }()
+}
+
+func (s *AuthSuite) TearDownSuite() {
+	s.server.Stop()
+}
+
+func (s *AuthSuite) client(token string) pb.MetricsClient {
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return s.lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(TokenUnaryClientInterceptor(token)),
+		grpc.WithChainStreamInterceptor(TokenStreamClientInterceptor(token)),
+	)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return pb.NewMetricsClient(conn)
+}
+
+func (s *AuthSuite) TestUnary() {
+	req := &pb.UpdatesRequest{Metrics: []*pb.Metric{{Id: "a", Mtype: pb.Mtype_TYPE_GAUGE}}}
+
+	testCases := []struct {
+		name  string
+		token string
+		code  codes.Code
+	}{
+		{name: "Write token", token: "agent-token", code: codes.OK},
+		{name: "Read token", token: "reader-token", code: codes.PermissionDenied},
+		{name: "Revoked token", token: "old-token", code: codes.Unauthenticated},
+		{name: "Unknown token", token: "unknown", code: codes.Unauthenticated},
+		{name: "Missing token", code: codes.Unauthenticated},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := s.client(tc.token).Updates(context.Background(), req)
+			s.Equal(tc.code, status.Code(err))
+		})
+	}
+}
+
+func (s *AuthSuite) TestMethodScopes() {
+	// echoServer does not implement Value, so the authorized call reaches the server and fails there
+	_, err := s.client("reader-token").Value(context.Background(), &pb.ValueRequest{})
+	s.Equal(codes.Unimplemented, status.Code(err))
+
+	_, err = s.client("agent-token").Value(context.Background(), &pb.ValueRequest{})
+	s.Equal(codes.PermissionDenied, status.Code(err))
+}
+
+func (s *AuthSuite) TestStream() {
+	testCases := []struct {
+		name  string
+		token string
+		code  codes.Code
+	}{
+		{name: "Write token", token: "agent-token", code: codes.OK},
+		{name: "Read token", token: "reader-token", code: codes.PermissionDenied},
+		{name: "Missing token", code: codes.Unauthenticated},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			stream, err := s.client(tc.token).StreamUpdates(context.Background())
+			s.Require().NoError(err)
+			s.Require().NoError(stream.CloseSend())
+
+			_, err = stream.Recv()
+			if tc.code == codes.OK {
+				s.ErrorIs(err, io.EOF)
+				return
+			}
+			s.Equal(tc.code, status.Code(err))
+		})
+	}
+}
+
+func (s *AuthSuite) TestNoRegistry() {
+	scopes := map[string]auth.Scope{
+		pb.Metrics_Updates_FullMethodName: auth.ScopeWrite,
+		pb.Metrics_Delete_FullMethodName:  auth.ScopeAdmin,
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(AuthInterceptor(nil, scopes)),
+		grpc.ChainStreamInterceptor(AuthStreamInterceptor(nil, scopes)),
+	)
+	pb.RegisterMetricsServer(server, echoServer{})
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	defer conn.Close()
+	client := pb.NewMetricsClient(conn)
+
+	// Без реестра токенов проверка не выполняется
+	_, err = client.Updates(context.Background(), &pb.UpdatesRequest{})
+	s.NoError(err)
+
+	// Методы администратора без реестра недоступны, в том числе отсутствующие в scopes
+	_, err = client.Delete(context.Background(), &pb.DeleteRequest{})
+	s.Equal(codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.StreamUpdates(context.Background())
+	s.Require().NoError(err)
+	s.Require().NoError(stream.CloseSend())
+	_, err = stream.Recv()
+	s.Equal(codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuth(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
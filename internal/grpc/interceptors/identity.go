@@ -0,0 +1,44 @@
+package interceptors
+
+import (
+	"context"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+// IdentityInterceptor puts the identity of the client authenticated by its TLS certificate into the context of the call.
+func IdentityInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withIdentity(ctx), req)
+	}
+}
+
+// IdentityStreamInterceptor puts the identity of the client authenticated by its TLS certificate into the context of the stream.
+func IdentityStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = withIdentity(ss.Context())
+		return handler(srv, wrapped)
+	}
+}
+
+func withIdentity(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ctx
+	}
+	id, ok := identity.FromConnectionState(info.State)
+	if !ok {
+		return ctx
+	}
+	return identity.NewContext(ctx, id)
+}
@@ -0,0 +1,20 @@
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataValue returns the first value of the key in the incoming metadata.
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
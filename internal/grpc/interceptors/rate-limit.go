@@ -0,0 +1,68 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+)
+
+// RateLimitInterceptor rejects the calls of the methods with ResourceExhausted when the client has exhausted its token bucket.
+// The client is identified by its API token, certificate or address, see ratelimit.ClientKey.
+// Nothing is checked when the limiter is not set.
+func RateLimitInterceptor(limiter *ratelimit.Limiter, proxies clientip.Subnets, methods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if limiter == nil || !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		if err := allow(ctx, limiter, proxies); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor takes a token from the bucket of the client for every message received over the stream.
+// The stream is closed with ResourceExhausted when the bucket is empty.
+func RateLimitStreamInterceptor(limiter *ratelimit.Limiter, proxies clientip.Subnets, methods map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if limiter == nil || !methods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		return handler(srv, &rateLimitedStream{
+			WrappedServerStream: middleware.WrapServerStream(ss),
+			limiter:             limiter,
+			proxies:             proxies,
+		})
+	}
+}
+
+type rateLimitedStream struct {
+	*middleware.WrappedServerStream
+	limiter *ratelimit.Limiter
+	proxies clientip.Subnets
+}
+
+func (s *rateLimitedStream) RecvMsg(m any) error {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return allow(s.Context(), s.limiter, s.proxies)
+}
+
+// allow takes a token from the bucket of the client of the call.
+func allow(ctx context.Context, limiter *ratelimit.Limiter, proxies clientip.Subnets) error {
+	key := ratelimit.ClientKey(ctx, clientAddr(ctx, proxies))
+	if ok, delay := limiter.Allow(key); !ok {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("Too many requests, retry after %d s", ratelimit.RetryAfter(delay)))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+type RateLimitSuite struct {
+	suite.Suite
+}
+
+// client starts the server with the limiter of the write methods and returns its client.
+func (s *RateLimitSuite) client(limiter *ratelimit.Limiter) pb.MetricsClient {
+	methods := map[string]bool{
+		pb.Metrics_Updates_FullMethodName:       true,
+		pb.Metrics_StreamUpdates_FullMethodName: true,
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(RateLimitInterceptor(limiter, nil, methods)),
+		grpc.ChainStreamInterceptor(RateLimitStreamInterceptor(limiter, nil, methods)),
+	)
+	pb.RegisterMetricsServer(server, echoServer{})
+	go func() { _ = server.Serve(lis) }()
+	s.T().Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return pb.NewMetricsClient(conn)
+}
+
+func (s *RateLimitSuite) TestUnary() {
+	client := s.client(ratelimit.New(0.1, 2))
+	req := &pb.UpdatesRequest{Metrics: []*pb.Metric{{Id: "a", Mtype: pb.Mtype_TYPE_GAUGE}}}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Updates(context.Background(), req)
+		s.Require().NoError(err)
+	}
+
+	_, err := client.Updates(context.Background(), req)
+	s.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// Методы чтения не ограничиваются: вызов доходит до сервера
+	_, err = client.Value(context.Background(), &pb.ValueRequest{})
+	s.Equal(codes.Unimplemented, status.Code(err))
+}
+
+func (s *RateLimitSuite) TestStream() {
+	client := s.client(ratelimit.New(0.1, 2))
+
+	stream, err := client.StreamUpdates(context.Background())
+	s.Require().NoError(err)
+
+	for i := uint64(1); i <= 3; i++ {
+		s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: i}))
+	}
+
+	for i := uint64(1); i <= 2; i++ {
+		ack, err := stream.Recv()
+		s.Require().NoError(err)
+		s.Equal(i, ack.BatchId)
+	}
+
+	_, err = stream.Recv()
+	s.Equal(codes.ResourceExhausted, status.Code(err))
+}
+
+func (s *RateLimitSuite) TestDisabled() {
+	client := s.client(nil)
+	req := &pb.UpdatesRequest{Metrics: []*pb.Metric{{Id: "a", Mtype: pb.Mtype_TYPE_GAUGE}}}
+
+	for i := 0; i < 10; i++ {
+		_, err := client.Updates(context.Background(), req)
+		s.Require().NoError(err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
@@ -0,0 +1,399 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+const (
+	// HashMDKey is the metadata key of the HMAC-SHA256 signature of the unary request and response,
+	// the same as the HashSHA256 header of the REST API.
+	HashMDKey = "hashsha256"
+
+	// SignedStreamMDKey marks the stream whose messages are sent in pb.SignedMessage envelopes.
+	// The client sets it in the request metadata, the server sets it in the header
+	// when it signs the messages it sends.
+	SignedStreamMDKey = "hashsha256-stream"
+
+	// TimestampMDKey and NonceMDKey are the metadata keys of the timestamp and the nonce
+	// that protect the signed call from being replayed, the same as the headers of the REST API.
+	// The signatures of the request and of every message of the stream cover them.
+	TimestampMDKey = "x-signature-timestamp"
+	NonceMDKey     = "x-signature-nonce"
+
+	// KeyIDMDKey is the metadata key of the ID of the key that signed the call, the same as the header of the REST API.
+	KeyIDMDKey = "x-signature-key-id"
+)
+
+var (
+	errIntegrity         = status.Error(codes.InvalidArgument, "Data integrity has been compromised")
+	errResponseIntegrity = status.Error(codes.DataLoss, "Data integrity has been compromised")
+)
+
+// SignUnaryServerInterceptor verifies the signature of the request with the keys of the keyring
+// if it was passed and signs the response. Nothing is done when the keyring is empty.
+// The response to the signed request is signed with the key of the request, the other responses
+// are signed with the signing key of the keyring.
+// When the replay guard is set, the signed requests must carry a fresh timestamp and an unused nonce.
+func SignUnaryServerInterceptor(keys *secure.Keyring, guard *secure.ReplayGuard) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if keys.Empty() {
+			return handler(ctx, req)
+		}
+
+		key := keys.Signing()
+		if hash := metadataValue(ctx, HashMDKey); hash != "" {
+			timestamp, nonce := metadataValue(ctx, TimestampMDKey), metadataValue(ctx, NonceMDKey)
+			var err error
+			key, err = verifyRequest(req, hash, keys, metadataValue(ctx, KeyIDMDKey), timestamp, nonce)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkReplay(guard, timestamp, nonce); err != nil {
+				return nil, err
+			}
+			ctx = secure.NewKeyContext(ctx, key)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+
+		hash, err := messageHash(resp, &messageSigner{key: key.Secret})
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		md := metadata.Pairs(HashMDKey, hex.EncodeToString(hash))
+		if key.ID != "" {
+			md.Set(KeyIDMDKey, key.ID)
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// SignStreamServerInterceptor unwraps the messages of the signed stream and verifies their signatures.
+// The messages sent to the client of the signed stream are signed when the key is set.
+// The signatures of the messages are bound to the timestamp and the nonce of the stream
+// and to the sequence number of the message, so that they can't be replayed in another stream.
+// The key of the stream is chosen by its key ID or by the signature of the first message,
+// the messages sent to the client are signed with the same key.
+func SignStreamServerInterceptor(keys *secure.Keyring, guard *secure.ReplayGuard) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if metadataValue(ctx, SignedStreamMDKey) == "" {
+			return handler(srv, ss)
+		}
+
+		timestamp, nonce := metadataValue(ctx, TimestampMDKey), metadataValue(ctx, NonceMDKey)
+		recv := &messageSigner{timestamp: timestamp, nonce: nonce}
+		if !keys.Empty() {
+			candidates, err := keys.Candidates(metadataValue(ctx, KeyIDMDKey))
+			if err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+			recv.candidates = candidates
+			if len(candidates) == 1 {
+				recv.key = candidates[0].Secret
+			}
+
+			if err := checkReplay(guard, timestamp, nonce); err != nil {
+				return err
+			}
+			if err := ss.SetHeader(metadata.Pairs(SignedStreamMDKey, "1")); err != nil {
+				return err
+			}
+		}
+		return handler(srv, &signedServerStream{
+			ServerStream: ss,
+			keys:         keys,
+			recv:         recv,
+		})
+	}
+}
+
+type signedServerStream struct {
+	grpc.ServerStream
+	keys *secure.Keyring
+	recv *messageSigner
+	send *messageSigner
+}
+
+func (s *signedServerStream) RecvMsg(m any) error {
+	return recvSigned(s.ServerStream.RecvMsg, m, s.recv, errIntegrity)
+}
+
+func (s *signedServerStream) SendMsg(m any) error {
+	if s.keys.Empty() {
+		return s.ServerStream.SendMsg(m)
+	}
+	if s.send == nil {
+		// Ключ потока определяется по первому сообщению клиента
+		key := s.recv.key
+		if len(key) == 0 {
+			key = s.keys.Signing().Secret
+		}
+		s.send = &messageSigner{key: key, timestamp: s.recv.timestamp, nonce: s.recv.nonce}
+	}
+	return sendSigned(s.ServerStream.SendMsg, m, s.send)
+}
+
+// SignUnaryClientInterceptor signs the request and verifies the signature of the response.
+// The ID of the key is passed with the request if it is set.
+func SignUnaryClientInterceptor(key secure.Key) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if len(key.Secret) == 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		timestamp, nonce, err := secure.NewNonce()
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		hash, err := messageHash(req, &messageSigner{key: key.Secret, timestamp: timestamp, nonce: nonce})
+		if err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			HashMDKey, hex.EncodeToString(hash),
+			TimestampMDKey, timestamp,
+			NonceMDKey, nonce,
+		)
+		if key.ID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, KeyIDMDKey, key.ID)
+		}
+
+		var header metadata.MD
+		if err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...); err != nil {
+			return err
+		}
+
+		if values := header.Get(HashMDKey); len(values) > 0 {
+			if err := verifyHash(reply, values[0], &messageSigner{key: key.Secret}); err != nil {
+				return errResponseIntegrity
+			}
+		}
+		return nil
+	}
+}
+
+// SignStreamClientInterceptor sends the messages of the stream signed in the envelopes
+// and verifies the messages received from the server if it signs them.
+func SignStreamClientInterceptor(key secure.Key) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if len(key.Secret) == 0 {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		timestamp, nonce, err := secure.NewNonce()
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			SignedStreamMDKey, "1",
+			TimestampMDKey, timestamp,
+			NonceMDKey, nonce,
+		)
+		if key.ID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, KeyIDMDKey, key.ID)
+		}
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &signedClientStream{
+			ClientStream: cs,
+			send:         &messageSigner{key: key.Secret, timestamp: timestamp, nonce: nonce},
+			recv:         &messageSigner{key: key.Secret, timestamp: timestamp, nonce: nonce},
+		}, nil
+	}
+}
+
+type signedClientStream struct {
+	grpc.ClientStream
+	send *messageSigner
+	recv *messageSigner
+}
+
+func (s *signedClientStream) SendMsg(m any) error {
+	return sendSigned(s.ClientStream.SendMsg, m, s.send)
+}
+
+func (s *signedClientStream) RecvMsg(m any) error {
+	// Заголовки ответа приходят до первого сообщения, ошибку вернёт RecvMsg.
+	header, err := s.ClientStream.Header()
+	if err != nil || len(header.Get(SignedStreamMDKey)) == 0 {
+		return s.ClientStream.RecvMsg(m)
+	}
+
+	return recvSigned(s.ClientStream.RecvMsg, m, s.recv, errResponseIntegrity)
+}
+
+// messageSigner signs the messages of a call.
+// The signatures are bound to the timestamp and the nonce of the call if they are set,
+// the messages of the stream are also bound to their sequence number.
+type messageSigner struct {
+	key        []byte
+	candidates []secure.Key // Ключи, одним из которых подписан поток, пока ключ не определён
+	timestamp  string
+	nonce      string
+	seq        uint64
+}
+
+// enabled reports whether the messages are signed.
+func (s *messageSigner) enabled() bool {
+	return len(s.key) > 0 || len(s.candidates) > 0
+}
+
+// hash returns the signature of the message.
+func (s *messageSigner) hash(payload []byte) []byte {
+	return secure.RequestHash(payload, s.key, s.timestamp, s.nonce)
+}
+
+// next returns the signature of the next message of the stream.
+func (s *messageSigner) next(payload []byte) []byte {
+	if s.timestamp == "" && s.nonce == "" {
+		return secure.Hash(payload, s.key)
+	}
+	nonce := s.nonce + ":" + strconv.FormatUint(s.seq, 10)
+	s.seq++
+	return secure.RequestHash(payload, s.key, s.timestamp, nonce)
+}
+
+// verifyNext verifies the signature of the next message of the stream.
+// If the key is not known yet, the key of the first message is chosen among the candidates.
+func (s *messageSigner) verifyNext(payload, hash []byte) bool {
+	if len(s.key) > 0 {
+		return hmac.Equal(hash, s.next(payload))
+	}
+	for _, key := range s.candidates {
+		signer := messageSigner{key: key.Secret, timestamp: s.timestamp, nonce: s.nonce, seq: s.seq}
+		if hmac.Equal(hash, signer.next(payload)) {
+			s.key, s.seq = signer.key, signer.seq
+			return true
+		}
+	}
+	return false
+}
+
+// checkReplay verifies the timestamp and the nonce of the signed call.
+func checkReplay(guard *secure.ReplayGuard, timestamp, nonce string) error {
+	if guard == nil {
+		return nil
+	}
+	if err := guard.Check(timestamp, nonce); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// sendSigned sends the message in the envelope with its signature.
+func sendSigned(send func(m any) error, m any, signer *messageSigner) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected message type %T", m)
+	}
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return send(&pb.SignedMessage{
+		Payload: payload,
+		Hash:    signer.next(payload),
+	})
+}
+
+// recvSigned receives the envelope, verifies the signature if the key is set and unwraps the message.
+// The integrity error is returned when the signature does not match.
+func recvSigned(recv func(m any) error, m any, signer *messageSigner, integrityErr error) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected message type %T", m)
+	}
+
+	var env pb.SignedMessage
+	if err := recv(&env); err != nil {
+		return err
+	}
+	if signer.enabled() && !signer.verifyNext(env.Payload, env.Hash) {
+		return integrityErr
+	}
+	if err := proto.Unmarshal(env.Payload, msg); err != nil {
+		return status.Error(status.Code(integrityErr), err.Error())
+	}
+	return nil
+}
+
+// messageHash returns the signature of the message.
+func messageHash(m any, signer *messageSigner) ([]byte, error) {
+	data, err := marshalMessage(m)
+	if err != nil {
+		return nil, err
+	}
+	return signer.hash(data), nil
+}
+
+// marshalMessage marshals the message deterministically so that both sides get the same bytes.
+func marshalMessage(m any) ([]byte, error) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", m)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+// verifyRequest verifies the hex encoded signature of the request with the keys of the keyring
+// and returns the key that signed it.
+func verifyRequest(req any, hash string, keys *secure.Keyring, keyID, timestamp, nonce string) (secure.Key, error) {
+	want, err := hex.DecodeString(hash)
+	if err != nil {
+		return secure.Key{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	data, err := marshalMessage(req)
+	if err != nil {
+		return secure.Key{}, status.Error(codes.Internal, err.Error())
+	}
+
+	key, err := keys.Verify(keyID, want, func(secret []byte) []byte {
+		return secure.RequestHash(data, secret, timestamp, nonce)
+	})
+	if errors.Is(err, secure.ErrUnknownKey) {
+		return secure.Key{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return secure.Key{}, errIntegrity
+	}
+	return key, nil
+}
+
+// verifyHash compares the hex encoded signature with the signature of the message.
+func verifyHash(m any, hash string, signer *messageSigner) error {
+	want, err := hex.DecodeString(hash)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	got, err := messageHash(m, signer)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !hmac.Equal(want, got) {
+		return errIntegrity
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+// echoServer returns the received metrics back to the client.
+type echoServer struct {
+	pb.UnimplementedMetricsServer
+}
+
+func (echoServer) Updates(_ context.Context, in *pb.UpdatesRequest) (*pb.UpdatesResponse, error) {
+	return &pb.UpdatesResponse{Metrics: in.Metrics}, nil
+}
+
+func (echoServer) StreamUpdates(stream pb.Metrics_StreamUpdatesServer) error {
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&pb.StreamUpdatesResponse{BatchId: in.BatchId}); err != nil {
+			return err
+		}
+	}
+}
+
+type SignSuite struct {
+	suite.Suite
+	key    []byte
+	guard  *secure.ReplayGuard
+	lis    *bufconn.Listener
+	server *grpc.Server
+}
+
+func (s *SignSuite) SetupSuite() {
+	s.key = []byte("secret")
+	s.guard = secure.NewReplayGuard(time.Minute, 1000)
+	s.lis = bufconn.Listen(1024 * 1024)
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(SignUnaryServerInterceptor(secure.NewKeyring(s.key), s.guard)),
+		grpc.ChainStreamInterceptor(SignStreamServerInterceptor(secure.NewKeyring(s.key), s.guard)),
+	)
+	pb.RegisterMetricsServer(s.server, echoServer{})
+	go func() { _ = s.server.Serve(s.lis) }()
+}
+
+func (s *SignSuite) TearDownSuite() {
+	s.server.Stop()
+}
+
+func (s *SignSuite) client(key []byte) pb.MetricsClient {
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return s.lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(SignUnaryClientInterceptor(secure.Key{Secret: key})),
+		grpc.WithChainStreamInterceptor(SignStreamClientInterceptor(secure.Key{Secret: key})),
+	)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return pb.NewMetricsClient(conn)
+}
+
+func (s *SignSuite) TestUnary() {
+	req := &pb.UpdatesRequest{Metrics: []*pb.Metric{{Id: "a", Mtype: pb.Mtype_TYPE_GAUGE}}}
+
+	testCases := []struct {
+		name string
+		key  []byte
+		hash string
+		code codes.Code
+	}{
+		{
+			name: "Signed request",
+			key:  s.key,
+			code: codes.OK,
+		},
+		{
+			name: "Unsigned request",
+			code: codes.OK,
+		},
+		{
+			name: "Wrong key",
+			key:  []byte("wrong"),
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Wrong hash",
+			hash: hex.EncodeToString(secure.Hash([]byte("data"), []byte("secret"))),
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Invalid hash",
+			hash: "xyz",
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx := context.Background()
+			if tc.hash != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, HashMDKey, tc.hash)
+			}
+
+			var header metadata.MD
+			resp, err := s.client(tc.key).Updates(ctx, req, grpc.Header(&header))
+			s.Equal(tc.code, status.Code(err))
+			if tc.code != codes.OK {
+				return
+			}
+			s.Len(resp.Metrics, 1)
+			s.NoError(verifyHash(resp, header.Get(HashMDKey)[0], &messageSigner{key: s.key}))
+		})
+	}
+}
+
+func (s *SignSuite) TestStream() {
+	testCases := []struct {
+		name string
+		key  []byte
+		code codes.Code
+	}{
+		{
+			name: "Signed stream",
+			key:  s.key,
+			code: codes.OK,
+		},
+		{
+			name: "Unsigned stream",
+			code: codes.OK,
+		},
+		{
+			name: "Wrong key",
+			key:  []byte("wrong"),
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			stream, err := s.client(tc.key).StreamUpdates(context.Background())
+			s.Require().NoError(err)
+
+			for id := uint64(1); id <= 2; id++ {
+				s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: id}))
+			}
+			s.Require().NoError(stream.CloseSend())
+
+			var ids []uint64
+			for {
+				ack, err := stream.Recv()
+				if err != nil {
+					if tc.code == codes.OK {
+						s.ErrorIs(err, io.EOF)
+					} else {
+						s.Equal(tc.code, status.Code(err))
+					}
+					break
+				}
+				ids = append(ids, ack.BatchId)
+			}
+			if tc.code == codes.OK {
+				s.Equal([]uint64{1, 2}, ids)
+			}
+		})
+	}
+}
+
+func (s *SignSuite) TestUnaryReplay() {
+	req := &pb.UpdatesRequest{Metrics: []*pb.Metric{{Id: "a", Mtype: pb.Mtype_TYPE_GAUGE}}}
+
+	// signedContext returns the context of the request signed as the client interceptor does.
+	signedContext := func(timestamp, nonce string) context.Context {
+		hash, err := messageHash(req, &messageSigner{key: s.key, timestamp: timestamp, nonce: nonce})
+		s.Require().NoError(err)
+
+		md := []string{HashMDKey, hex.EncodeToString(hash)}
+		if timestamp != "" {
+			md = append(md, TimestampMDKey, timestamp, NonceMDKey, nonce)
+		}
+		return metadata.AppendToOutgoingContext(context.Background(), md...)
+	}
+
+	client := s.client(nil)
+
+	s.Run("Replayed request", func() {
+		timestamp, nonce, err := secure.NewNonce()
+		s.Require().NoError(err)
+
+		_, err = client.Updates(signedContext(timestamp, nonce), req)
+		s.Require().NoError(err)
+
+		_, err = client.Updates(signedContext(timestamp, nonce), req)
+		s.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	s.Run("Stale request", func() {
+		timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		_, err := client.Updates(signedContext(timestamp, "stale"), req)
+		s.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	s.Run("Signature without nonce", func() {
+		_, err := client.Updates(signedContext("", ""), req)
+		s.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	s.Run("Timestamp is not signed", func() {
+		timestamp, nonce, err := secure.NewNonce()
+		s.Require().NoError(err)
+
+		ctx := signedContext("", "")
+		ctx = metadata.AppendToOutgoingContext(ctx, TimestampMDKey, timestamp, NonceMDKey, nonce)
+		_, err = client.Updates(ctx, req)
+		s.Equal(codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func (s *SignSuite) TestStreamReplay() {
+	client := s.client(nil)
+
+	// sendStream sends the envelopes in the stream with the timestamp and the nonce and returns the status of the stream.
+	sendStream := func(timestamp, nonce string, envelopes []*pb.SignedMessage) codes.Code {
+		ctx := metadata.AppendToOutgoingContext(context.Background(),
+			SignedStreamMDKey, "1",
+			TimestampMDKey, timestamp,
+			NonceMDKey, nonce,
+		)
+		stream, err := client.StreamUpdates(ctx)
+		s.Require().NoError(err)
+
+		for _, env := range envelopes {
+			if err := stream.SendMsg(env); err != nil {
+				break
+			}
+		}
+		_ = stream.CloseSend()
+
+		for {
+			var env pb.SignedMessage
+			if err := stream.RecvMsg(&env); err != nil {
+				if errors.Is(err, io.EOF) {
+					return codes.OK
+				}
+				return status.Code(err)
+			}
+		}
+	}
+
+	// envelopes returns the messages of the stream signed as the client interceptor does.
+	envelopes := func(timestamp, nonce string) []*pb.SignedMessage {
+		signer := &messageSigner{key: s.key, timestamp: timestamp, nonce: nonce}
+		var envs []*pb.SignedMessage
+		for id := uint64(1); id <= 2; id++ {
+			payload, err := proto.Marshal(&pb.StreamUpdatesRequest{BatchId: id})
+			s.Require().NoError(err)
+			envs = append(envs, &pb.SignedMessage{Payload: payload, Hash: signer.next(payload)})
+		}
+		return envs
+	}
+
+	timestamp, nonce, err := secure.NewNonce()
+	s.Require().NoError(err)
+	captured := envelopes(timestamp, nonce)
+
+	s.Equal(codes.OK, sendStream(timestamp, nonce, captured), "Original stream")
+	s.Equal(codes.InvalidArgument, sendStream(timestamp, nonce, captured), "Replayed stream")
+
+	timestamp, nonce, err = secure.NewNonce()
+	s.Require().NoError(err)
+	s.Equal(codes.InvalidArgument, sendStream(timestamp, nonce, captured), "Messages of another stream")
+
+	timestamp, nonce, err = secure.NewNonce()
+	s.Require().NoError(err)
+	reordered := envelopes(timestamp, nonce)
+	reordered[0], reordered[1] = reordered[1], reordered[0]
+	s.Equal(codes.InvalidArgument, sendStream(timestamp, nonce, reordered), "Reordered messages")
+}
+
+func TestSign(t *testing.T) {
+	suite.Run(t, new(SignSuite))
+}
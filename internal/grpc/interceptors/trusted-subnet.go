@@ -2,34 +2,65 @@ package interceptors
 
 import (
 	"context"
-	"net"
+	"net/netip"
+	"strings"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/realip"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+)
+
+// Metadata keys of the address of the client set by the proxies.
+const (
+	ForwardedForMDKey = "x-forwarded-for"
+	RealIPMDKey       = "x-real-ip"
 )
 
-func TrustedSubnetInterceptor(subnet *net.IPNet) grpc.UnaryServerInterceptor {
+// TrustedSubnetInterceptor rejects the calls from the addresses outside the trusted subnets.
+// The forwarding metadata is honoured only for the calls from the trusted proxies.
+func TrustedSubnetInterceptor(subnets, proxies clientip.Subnets) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		if subnet == nil {
-			return handler(ctx, req)
+		if !isTrusted(ctx, subnets, proxies) {
+			return nil, status.Error(codes.PermissionDenied, "The request from this ip-address was rejected")
 		}
 
-		var remoteAddr string
-		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			values := md.Get(realip.XRealIp)
-			if len(values) > 0 {
-				remoteAddr = values[0]
-			}
-		}
+		return handler(ctx, req)
+	}
+}
 
-		ip := net.ParseIP(remoteAddr)
-		if ip == nil || !subnet.Contains(ip) {
-			return nil, status.Error(codes.PermissionDenied, "The request from this ip-address was rejected")
+// TrustedSubnetStreamInterceptor rejects the streaming calls from the addresses outside the trusted subnets.
+func TrustedSubnetStreamInterceptor(subnets, proxies clientip.Subnets) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !isTrusted(ss.Context(), subnets, proxies) {
+			return status.Error(codes.PermissionDenied, "The request from this ip-address was rejected")
 		}
 
-		return handler(ctx, req)
+		return handler(srv, ss)
+	}
+}
+
+// isTrusted reports whether the address of the client belongs to the trusted subnets.
+// All calls are trusted when the subnets are not set.
+func isTrusted(ctx context.Context, subnets, proxies clientip.Subnets) bool {
+	if len(subnets) == 0 {
+		return true
+	}
+
+	addr := clientAddr(ctx, proxies)
+	return addr.IsValid() && subnets.Contains(addr)
+}
+
+// clientAddr returns the address of the client of the call, the zero address if the peer is unknown.
+func clientAddr(ctx context.Context, proxies clientip.Subnets) netip.Addr {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return netip.Addr{}
 	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	forwardedFor := strings.Join(md.Get(ForwardedForMDKey), ",")
+	return clientip.Resolve(clientip.ParseAddr(p.Addr.String()), proxies, forwardedFor, metadataValue(ctx, RealIPMDKey))
 }
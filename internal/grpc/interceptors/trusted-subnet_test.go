@@ -0,0 +1,115 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+type TrustedSubnetSuite struct {
+	suite.Suite
+	subnets clientip.Subnets
+}
+
+func (s *TrustedSubnetSuite) SetupSuite() {
+	subnets, err := clientip.ParseSubnets("192.168.0.0/24,2001:db8::/32")
+	s.Require().NoError(err)
+	s.subnets = subnets
+}
+
+// client returns the client of the server listening on the loopback address with the trusted proxies.
+func (s *TrustedSubnetSuite) client(subnets, proxies clientip.Subnets) pb.MetricsClient {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(TrustedSubnetInterceptor(subnets, proxies)),
+		grpc.ChainStreamInterceptor(TrustedSubnetStreamInterceptor(subnets, proxies)),
+	)
+	pb.RegisterMetricsServer(server, echoServer{})
+	go func() { _ = server.Serve(lis) }()
+	s.T().Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return pb.NewMetricsClient(conn)
+}
+
+func (s *TrustedSubnetSuite) TestTrustedSubnet() {
+	proxies, err := clientip.ParseSubnets("127.0.0.1")
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		name    string
+		proxies clientip.Subnets
+		md      []string
+		code    codes.Code
+	}{
+		{
+			name:    "X-Real-IP of the trusted proxy",
+			proxies: proxies,
+			md:      []string{RealIPMDKey, "192.168.0.123"},
+			code:    codes.OK,
+		},
+		{
+			name:    "X-Forwarded-For of the trusted proxy",
+			proxies: proxies,
+			md:      []string{ForwardedForMDKey, "2001:db8::1"},
+			code:    codes.OK,
+		},
+		{
+			name:    "Address outside the subnets",
+			proxies: proxies,
+			md:      []string{RealIPMDKey, "10.0.0.1"},
+			code:    codes.PermissionDenied,
+		},
+		{
+			name: "Metadata of the untrusted peer",
+			md:   []string{RealIPMDKey, "192.168.0.123"},
+			code: codes.PermissionDenied,
+		},
+	}
+
+	req := &pb.UpdatesRequest{Metrics: []*pb.Metric{{Id: "a", Mtype: pb.Mtype_TYPE_GAUGE}}}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			client := s.client(s.subnets, tc.proxies)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), tc.md...)
+
+			_, err := client.Updates(ctx, req)
+			s.Equal(tc.code, status.Code(err))
+
+			stream, err := client.StreamUpdates(ctx)
+			s.Require().NoError(err)
+			s.Require().NoError(stream.CloseSend())
+			_, err = stream.Recv()
+			if tc.code != codes.OK {
+				s.Equal(tc.code, status.Code(err))
+			}
+		})
+	}
+}
+
+func (s *TrustedSubnetSuite) TestPeerAddress() {
+	subnets, err := clientip.ParseSubnets("127.0.0.0/8")
+	s.Require().NoError(err)
+
+	_, err = s.client(subnets, nil).Updates(context.Background(), &pb.UpdatesRequest{})
+	s.NoError(err)
+}
+
+func TestTrustedSubnet(t *testing.T) {
+	suite.Run(t, new(TrustedSubnetSuite))
+}
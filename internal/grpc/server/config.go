@@ -1,9 +1,15 @@
 package server
 
 import (
-	"net"
+	"crypto/tls"
+	"time"
 
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
 	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
 	store "github.com/fishus/go-advanced-metrics/internal/storage"
 )
 
@@ -12,9 +18,18 @@ var Controller controller.Controller
 var config Config
 
 type Config struct {
-	ServerAddr    string
-	Storage       store.MetricsStorager // TODO remove
-	SecretKey     string
-	PrivateKey    []byte
-	TrustedSubnet *net.IPNet
+	ServerAddr     string
+	Storage        store.MetricsStorager // TODO remove
+	Keys           *secure.Keyring       // Ключи подписи, nil - данные не подписываются
+	PrivateKey     []byte
+	TrustedSubnets clientip.Subnets // Доверенные подсети, пустой список - запросы с любых адресов
+	TrustedProxies clientip.Subnets // Прокси, чьим заголовкам с адресом клиента можно доверять
+	WatchInterval  time.Duration
+	TLSConfig      *tls.Config
+	ReplayGuard    *secure.ReplayGuard // Защита подписанных запросов от повтора, nil - отключена
+	Tokens         *auth.Registry      // API-токены агентов, nil - запросы без токенов
+	MaxMsgSize     int                 // Максимальный размер распакованного сообщения в байтах, 0 - по умолчанию gRPC
+	MaxBatchSize   int                 // Максимальное количество метрик в пакете, 0 - без ограничений
+	RateLimiter    *ratelimit.Limiter  // Ограничение частоты записи для каждого клиента, nil - отключено
+	Audit          *audit.Log          // Журнал аудита операций записи и администрирования, nil - отключён
 }
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+// Delete removes the series identified by the metric type, name and labels together with its history.
+func (s *MetricsServer) Delete(ctx context.Context, in *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	audit.SetMetrics(ctx, 1)
+
+	code, err := Controller.DeleteMetric(ctx, protoToType(in.Mtype), in.Id, metrics.Labels(in.Labels).Clone())
+	if err != nil {
+		logger.Log.Debug(err.Error(), logger.String("id", in.Id))
+		return nil, status.Error(sg.HTTPCodeToGRPC(code), err.Error())
+	}
+	return &pb.DeleteResponse{}, nil
+}
+
+// DeleteMetrics removes the series selected either by the names or by the name prefix together with their history.
+// The series of all types are removed if the type is not specified, the labels narrow the selection down.
+func (s *MetricsServer) DeleteMetrics(ctx context.Context, in *pb.DeleteMetricsRequest) (*pb.DeleteMetricsResponse, error) {
+	n, code, err := Controller.DeleteMetrics(ctx, protoToType(in.Mtype), in.Names, in.Prefix, metrics.Labels(in.Labels).Clone())
+	if err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("names", in.Names), logger.String("prefix", in.Prefix))
+		return nil, status.Error(sg.HTTPCodeToGRPC(code), err.Error())
+	}
+
+	audit.SetMetrics(ctx, n)
+
+	return &pb.DeleteMetricsResponse{Deleted: uint64(n)}, nil
+}
+
+// protoToType returns the metric type of the storage, the empty type if the type is not specified.
+func protoToType(mtype pb.Mtype) string {
+	switch mtype {
+	case pb.Mtype_TYPE_UNSPECIFIED:
+		return ""
+	case pb.Mtype_TYPE_GAUGE:
+		return metrics.TypeGauge
+	case pb.Mtype_TYPE_COUNTER:
+		return metrics.TypeCounter
+	case pb.Mtype_TYPE_HISTOGRAM:
+		return metrics.TypeHistogram
+	}
+	return mtype.String()
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+func (s *MetricsServerSuite) TestDelete() {
+	testCases := []struct {
+		name string
+		req  *pb.DeleteRequest
+		code codes.Code
+	}{
+		{
+			name: "Gauge with labels",
+			req:  &pb.DeleteRequest{Id: "alloc", Mtype: pb.Mtype_TYPE_GAUGE, Labels: map[string]string{"host": "web1"}},
+			code: codes.OK,
+		},
+		{
+			name: "Already deleted",
+			req:  &pb.DeleteRequest{Id: "alloc", Mtype: pb.Mtype_TYPE_GAUGE, Labels: map[string]string{"host": "web1"}},
+			code: codes.NotFound,
+		},
+		{
+			name: "Counter",
+			req:  &pb.DeleteRequest{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER},
+			code: codes.OK,
+		},
+		{
+			name: "Empty name",
+			req:  &pb.DeleteRequest{Mtype: pb.Mtype_TYPE_GAUGE},
+			code: codes.NotFound,
+		},
+		{
+			name: "Unspecified type",
+			req:  &pb.DeleteRequest{Id: "alloc"},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := s.client.Delete(context.Background(), tc.req)
+			s.Equal(tc.code, status.Code(err))
+		})
+	}
+
+	_, ok := config.Storage.GaugeValueContext(context.Background(), "alloc", metrics.Labels{"host": "web1"})
+	s.False(ok)
+	_, ok = config.Storage.GaugeValue("alloc")
+	s.True(ok)
+	_, ok = config.Storage.CounterValue("requests")
+	s.False(ok)
+}
+
+func (s *MetricsServerSuite) TestDeleteMetrics() {
+	testCases := []struct {
+		name    string
+		req     *pb.DeleteMetricsRequest
+		code    codes.Code
+		deleted uint64
+	}{
+		{
+			name:    "Names",
+			req:     &pb.DeleteMetricsRequest{Mtype: pb.Mtype_TYPE_GAUGE, Names: []string{"alloc", "requests"}},
+			code:    codes.OK,
+			deleted: 2,
+		},
+		{
+			name:    "Prefix of all types",
+			req:     &pb.DeleteMetricsRequest{Prefix: "c"},
+			code:    codes.OK,
+			deleted: 1,
+		},
+		{
+			name:    "Prefix and labels",
+			req:     &pb.DeleteMetricsRequest{Prefix: "a", Labels: map[string]string{"host": "web1"}},
+			code:    codes.OK,
+			deleted: 1,
+		},
+		{
+			name: "No names and prefix",
+			req:  &pb.DeleteMetricsRequest{Mtype: pb.Mtype_TYPE_GAUGE},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			resp, err := s.client.DeleteMetrics(context.Background(), tc.req)
+			s.Equal(tc.code, status.Code(err))
+			if tc.code != codes.OK {
+				return
+			}
+			s.Equal(tc.deleted, resp.Deleted)
+		})
+	}
+}
+
+func (s *MetricsServerSuite) TestDeleteScopes() {
+	// Удаление доступно только администраторам и записывается в журнал аудита
+	for _, method := range []string{pb.Metrics_Delete_FullMethodName, pb.Metrics_DeleteMetrics_FullMethodName} {
+		s.Equal(auth.ScopeAdmin, methodScopes[method], method)
+		s.True(auditMethods[method], method)
+		s.False(writeMethods[method], method)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// series is a stored series with its position in the listing.
+type series struct {
+	key    string
+	metric *pb.Metric
+}
+
+// List returns a page of the series whose names start with the prefix.
+// The series are ordered by type and series key; the listing continues from
+// the next_page_token of the previous page and ends when the token is empty.
+func (s *MetricsServer) List(ctx context.Context, in *pb.ListRequest) (*pb.ListResponse, error) {
+	pageSize := int(in.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	var after string
+	if in.PageToken != "" {
+		b, err := base64.RawURLEncoding.DecodeString(in.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		after = string(b)
+	}
+
+	list, err := collectSeries(ctx, in.Mtype, store.FilterPrefix(in.Prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if after != "" {
+		start = sort.Search(len(list), func(i int) bool { return list[i].key > after })
+	}
+	end := min(start+pageSize, len(list))
+
+	response := &pb.ListResponse{
+		Metrics: make([]*pb.Metric, 0, end-start),
+	}
+	for _, sr := range list[start:end] {
+		response.Metrics = append(response.Metrics, sr.metric)
+	}
+	if end < len(list) {
+		response.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(list[end-1].key))
+	}
+	return response, nil
+}
+
+// collectSeries returns the stored series of the type selected by the filters ordered by key.
+// All types are returned for Mtype_TYPE_UNSPECIFIED.
+func collectSeries(ctx context.Context, mtype pb.Mtype, filters ...store.StorageFilter) ([]series, error) {
+	if mtype < pb.Mtype_TYPE_UNSPECIFIED || mtype > pb.Mtype_TYPE_HISTOGRAM {
+		return nil, status.Error(codes.InvalidArgument, "Incorrect metric type")
+	}
+
+	var list []series
+	add := func(t pb.Mtype, metric metrics.Metrics) error {
+		m, err := sg.MetricToProto(metric)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		list = append(list, series{key: fmt.Sprintf("%d\x00%s", t, metric.Key()), metric: &m})
+		return nil
+	}
+
+	if mtype == pb.Mtype_TYPE_UNSPECIFIED || mtype == pb.Mtype_TYPE_GAUGE {
+		for _, g := range config.Storage.GaugesContext(ctx, filters...) {
+			if err := add(pb.Mtype_TYPE_GAUGE, gaugeToMetric(g)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if mtype == pb.Mtype_TYPE_UNSPECIFIED || mtype == pb.Mtype_TYPE_COUNTER {
+		for _, c := range config.Storage.CountersContext(ctx, filters...) {
+			if err := add(pb.Mtype_TYPE_COUNTER, counterToMetric(c)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if mtype == pb.Mtype_TYPE_UNSPECIFIED || mtype == pb.Mtype_TYPE_HISTOGRAM {
+		for _, h := range config.Storage.HistogramsContext(ctx, filters...) {
+			if err := add(pb.Mtype_TYPE_HISTOGRAM, histogramToMetric(h)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].key < list[j].key })
+	return list, nil
+}
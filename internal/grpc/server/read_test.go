@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	ic "github.com/fishus/go-advanced-metrics/internal/grpc/interceptors"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+type MetricsServerSuite struct {
+	suite.Suite
+	srv    *server
+	conn   *grpc.ClientConn
+	client pb.MetricsClient
+}
+
+func (s *MetricsServerSuite) SetupSuite() {
+	// Удаление метрик доступно только при заданных токенах
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [{"name": "admin", "token_sha256": "` + auth.Hash("admin-token") + `", "scopes": ["admin"]}]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	s.srv = NewServer(Config{WatchInterval: 10 * time.Millisecond, Tokens: registry})
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() { _ = s.srv.server.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(ic.TokenUnaryClientInterceptor("admin-token")),
+		grpc.WithChainStreamInterceptor(ic.TokenStreamClientInterceptor("admin-token")),
+	)
+	s.Require().NoError(err)
+	s.conn = conn
+	s.client = pb.NewMetricsClient(conn)
+}
+
+func (s *MetricsServerSuite) TearDownSuite() {
+	_ = s.conn.Close()
+	s.srv.server.Stop()
+}
+
+func (s *MetricsServerSuite) SetupTest() {
+	ctx := context.Background()
+	config.Storage = store.NewMemStorage()
+	_ = config.Storage.AddCounter("requests", 5)
+	_ = config.Storage.SetGauge("alloc", 1.5)
+	_ = config.Storage.SetGaugeContext(ctx, "alloc", metrics.Labels{"host": "web1"}, 2.5)
+	_ = config.Storage.SetGauge("cpu", 0.5)
+	controller.Storage = config.Storage
+}
+
+func (s *MetricsServerSuite) TestValue() {
+	testCases := []struct {
+		name  string
+		req   *pb.ValueRequest
+		code  codes.Code
+		value float64
+		delta int64
+	}{
+		{
+			name:  "Gauge",
+			req:   &pb.ValueRequest{Id: "alloc", Mtype: pb.Mtype_TYPE_GAUGE},
+			code:  codes.OK,
+			value: 1.5,
+		},
+		{
+			name:  "Gauge with labels",
+			req:   &pb.ValueRequest{Id: "alloc", Mtype: pb.Mtype_TYPE_GAUGE, Labels: map[string]string{"host": "web1"}},
+			code:  codes.OK,
+			value: 2.5,
+		},
+		{
+			name:  "Counter",
+			req:   &pb.ValueRequest{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER},
+			code:  codes.OK,
+			delta: 5,
+		},
+		{
+			name: "Unknown metric",
+			req:  &pb.ValueRequest{Id: "unknown", Mtype: pb.Mtype_TYPE_GAUGE},
+			code: codes.NotFound,
+		},
+		{
+			name: "Unknown labels",
+			req:  &pb.ValueRequest{Id: "alloc", Mtype: pb.Mtype_TYPE_GAUGE, Labels: map[string]string{"host": "web2"}},
+			code: codes.NotFound,
+		},
+		{
+			name: "Empty name",
+			req:  &pb.ValueRequest{Mtype: pb.Mtype_TYPE_GAUGE},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Unspecified type",
+			req:  &pb.ValueRequest{Id: "alloc"},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			resp, err := s.client.Value(context.Background(), tc.req)
+			s.Equal(tc.code, status.Code(err))
+			if tc.code != codes.OK {
+				return
+			}
+			s.Require().NotNil(resp.Metric)
+			s.Equal(tc.req.Id, resp.Metric.Id)
+			s.Equal(tc.req.Mtype, resp.Metric.Mtype)
+			switch tc.req.Mtype {
+			case pb.Mtype_TYPE_GAUGE:
+				s.Equal(tc.value, resp.Metric.GetValue())
+			case pb.Mtype_TYPE_COUNTER:
+				s.Equal(tc.delta, resp.Metric.GetDelta())
+			}
+		})
+	}
+}
+
+func (s *MetricsServerSuite) TestList() {
+	ctx := context.Background()
+
+	var keys []string
+	var token string
+	for page := 0; ; page++ {
+		s.Require().Less(page, 10)
+		resp, err := s.client.List(ctx, &pb.ListRequest{PageSize: 2, PageToken: token})
+		s.Require().NoError(err)
+		s.LessOrEqual(len(resp.Metrics), 2)
+		for _, m := range resp.Metrics {
+			keys = append(keys, m.Mtype.String()+" "+metrics.SeriesKey(m.Id, m.Labels))
+		}
+		token = resp.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+	s.Equal([]string{
+		"TYPE_GAUGE alloc",
+		"TYPE_GAUGE alloc{host=\"web1\"}",
+		"TYPE_GAUGE cpu",
+		"TYPE_COUNTER requests",
+	}, keys)
+
+	resp, err := s.client.List(ctx, &pb.ListRequest{Mtype: pb.Mtype_TYPE_GAUGE, Prefix: "al"})
+	s.Require().NoError(err)
+	s.Len(resp.Metrics, 2)
+	s.Empty(resp.NextPageToken)
+
+	_, err = s.client.List(ctx, &pb.ListRequest{PageToken: "!"})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = s.client.List(ctx, &pb.ListRequest{PageSize: -1})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *MetricsServerSuite) TestWatch() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := s.client.Watch(ctx, &pb.WatchRequest{Names: []string{"requests"}, Prefixes: []string{"al"}})
+	s.Require().NoError(err)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Len(resp.Metrics, 3)
+
+	_ = config.Storage.SetGauge("cpu", 0.7)
+	_ = config.Storage.AddCounter("requests", 2)
+
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Len(resp.Metrics, 1)
+	s.Equal("requests", resp.Metrics[0].Id)
+	s.Equal(int64(7), resp.Metrics[0].GetDelta())
+	s.Empty(resp.Deleted)
+
+	// Удалённая серия передаётся в списке удалённых
+	_, err = config.Storage.DeleteSeries(ctx, "counter", "requests", nil)
+	s.Require().NoError(err)
+
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Empty(resp.Metrics)
+	s.Require().Len(resp.Deleted, 1)
+	s.Equal("requests", resp.Deleted[0].Id)
+	s.Equal(pb.Mtype_TYPE_COUNTER, resp.Deleted[0].Mtype)
+	s.Nil(resp.Deleted[0].Delta)
+}
+
+func (s *MetricsServerSuite) TestWatchOverlap() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Серия, подходящая и по имени, и по префиксу, передаётся один раз
+	stream, err := s.client.Watch(ctx, &pb.WatchRequest{Names: []string{"alloc"}, Prefixes: []string{"al", "allo"}})
+	s.Require().NoError(err)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Len(resp.Metrics, 2)
+}
+
+func TestMetricsServer(t *testing.T) {
+	suite.Run(t, new(MetricsServerSuite))
+}
@@ -2,11 +2,16 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net"
+	"slices"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	_ "google.golang.org/grpc/encoding/gzip"
 
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
 	ic "github.com/fishus/go-advanced-metrics/internal/grpc/interceptors"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	pb "github.com/fishus/go-advanced-metrics/proto"
@@ -14,11 +19,53 @@ import (
 )
 
 type server struct {
-	server *grpc.Server
+	server  *grpc.Server
+	metrics *MetricsServer
+	addr    string
 }
 
 type MetricsServer struct {
 	pb.UnimplementedMetricsServer
+	done chan struct{} // закрывается при остановке сервера, чтобы завершить потоковые вызовы
+}
+
+// methodScopes are the scopes of the API tokens required by the methods.
+var methodScopes = map[string]auth.Scope{
+	pb.Metrics_Update_FullMethodName:        auth.ScopeWrite,
+	pb.Metrics_Updates_FullMethodName:       auth.ScopeWrite,
+	pb.Metrics_StreamUpdates_FullMethodName: auth.ScopeWrite,
+	pb.Metrics_Value_FullMethodName:         auth.ScopeRead,
+	pb.Metrics_List_FullMethodName:          auth.ScopeRead,
+	pb.Metrics_Watch_FullMethodName:         auth.ScopeRead,
+	pb.Metrics_Delete_FullMethodName:        auth.ScopeAdmin,
+	pb.Metrics_DeleteMetrics_FullMethodName: auth.ScopeAdmin,
+}
+
+// writeMethods are the methods whose calls are rate limited.
+var writeMethods = scopeMethods(auth.ScopeWrite)
+
+// auditMethods are the methods whose calls are recorded to the audit log.
+var auditMethods = scopeMethods(auth.ScopeWrite, auth.ScopeAdmin)
+
+// scopeMethods returns the methods that require any of the scopes.
+func scopeMethods(scopes ...auth.Scope) map[string]bool {
+	methods := make(map[string]bool)
+	for method, scope := range methodScopes {
+		if slices.Contains(scopes, scope) {
+			methods[method] = true
+		}
+	}
+	return methods
+}
+
+// writeRequest reports whether the message is the request of the write methods, they are encrypted by the agent.
+// The envelopes of the signed streams are sent only by the agent in StreamUpdates.
+func writeRequest(v any) bool {
+	switch v.(type) {
+	case *pb.UpdateRequest, *pb.UpdatesRequest, *pb.StreamUpdatesRequest, *pb.SignedMessage:
+		return true
+	}
+	return false
 }
 
 func NewServer(cfg Config) *server {
@@ -31,34 +78,83 @@ func NewServer(cfg Config) *server {
 	}
 	interceptors = append(interceptors, grpc.ChainUnaryInterceptor(
 		logging.UnaryServerInterceptor(ic.InterceptorLogger(logger.Log), loggerOpts...),
-		ic.TrustedSubnetInterceptor(cfg.TrustedSubnet),
+		ic.IdentityInterceptor(),
+		ic.TrustedSubnetInterceptor(cfg.TrustedSubnets, cfg.TrustedProxies),
+		ic.AuditInterceptor(cfg.Audit, cfg.TrustedProxies, auditMethods),
+		ic.AuthInterceptor(cfg.Tokens, methodScopes),
+		ic.RateLimitInterceptor(cfg.RateLimiter, cfg.TrustedProxies, writeMethods),
+		ic.SignUnaryServerInterceptor(cfg.Keys, cfg.ReplayGuard),
+	))
+	interceptors = append(interceptors, grpc.ChainStreamInterceptor(
+		logging.StreamServerInterceptor(ic.InterceptorLogger(logger.Log), loggerOpts...),
+		ic.IdentityStreamInterceptor(),
+		ic.TrustedSubnetStreamInterceptor(cfg.TrustedSubnets, cfg.TrustedProxies),
+		ic.AuditStreamInterceptor(cfg.Audit, cfg.TrustedProxies, auditMethods),
+		ic.AuthStreamInterceptor(cfg.Tokens, methodScopes),
+		ic.RateLimitStreamInterceptor(cfg.RateLimiter, cfg.TrustedProxies, writeMethods),
+		ic.SignStreamServerInterceptor(cfg.Keys, cfg.ReplayGuard),
 	))
 
+	// Сообщения сверх лимита отклоняются с ResourceExhausted, в том числе после распаковки gzip
+	if cfg.MaxMsgSize > 0 {
+		interceptors = append(interceptors, grpc.MaxRecvMsgSize(cfg.MaxMsgSize))
+	}
+
+	if cfg.TLSConfig != nil {
+		interceptors = append(interceptors, grpc.Creds(credentials.NewTLS(cfg.TLSConfig)))
+	}
+
+	// Запросы, зашифрованные открытым ключом агента, расшифровываются при декодировании сообщений.
+	// Агент шифрует только запросы записи, запросы чтения принимаются как есть.
+	if len(cfg.PrivateKey) > 0 {
+		interceptors = append(interceptors, grpc.ForceServerCodec(sg.DecryptCodec{PrivateKey: cfg.PrivateKey, Encrypted: writeRequest}))
+	}
+
 	interceptors = append(interceptors, grpc.ChainUnaryInterceptor(
 		logging.UnaryServerInterceptor(ic.InterceptorLogger(logger.Log), loggerOpts...),
 	))
 
-	srv := &server{}
+	srv := &server{
+		addr:    cfg.ServerAddr,
+		metrics: &MetricsServer{done: make(chan struct{})},
+	}
 	srv.server = grpc.NewServer(interceptors...)
-	pb.RegisterMetricsServer(srv.server, &MetricsServer{})
+	pb.RegisterMetricsServer(srv.server, srv.metrics)
 	return srv
 }
 
-func (s *server) Run(ctx context.Context) {
-
-	listen, err := net.Listen("tcp", config.ServerAddr)
+// Run serves the requests until the server is shut down.
+func (s *server) Run(ctx context.Context) error {
+	listen, err := net.Listen("tcp", s.addr)
 	if err != nil {
-		logger.Log.Panic(err.Error())
+		return err
 	}
 
-	logger.Log.Info("Running gRPC server", logger.String("address", config.ServerAddr), logger.String("event", "start server"))
+	logger.Log.Info("Running gRPC server", logger.String("address", s.addr), logger.Bool("tls", config.TLSConfig != nil), logger.String("event", "start server"))
 
-	if err := s.server.Serve(listen); err != nil {
-		logger.Log.Error(err.Error())
+	err = s.server.Serve(listen)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
 	}
+	return nil
 }
 
+// Shutdown stops the server gracefully, the pending requests are cancelled when the context is done.
+// The streaming calls are ended with codes.Unavailable so that the clients reconnect.
 func (s *server) Shutdown(ctx context.Context) error {
-	s.server.GracefulStop()
-	return nil
+	close(s.metrics.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+func (s *MetricsServerSuite) TestAuditRejected() {
+	log, err := audit.Open(filepath.Join(s.T().TempDir(), "audit.log"), 0, 0)
+	s.Require().NoError(err)
+	defer log.Close()
+
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [{"name": "grafana", "token_sha256": "` + auth.Hash("read-token") + `", "scopes": ["read"]}]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	srv := NewServer(Config{Storage: config.Storage, WatchInterval: 10 * time.Millisecond, Audit: log, Tokens: registry})
+	defer srv.server.Stop()
+	defer func() { config.Audit, config.Tokens = nil, nil }()
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() { _ = srv.server.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := pb.NewMetricsClient(conn)
+
+	// Вызовы, отклонённые проверкой токена, тоже записываются в журнал
+	_, err = client.Updates(ctx, &pb.UpdatesRequest{})
+	s.Equal(codes.Unauthenticated, status.Code(err))
+
+	readCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer read-token")
+	_, err = client.Updates(readCtx, &pb.UpdatesRequest{})
+	s.Equal(codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.StreamUpdates(readCtx)
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Equal(codes.PermissionDenied, status.Code(err))
+
+	events, err := log.Query(audit.Filter{})
+	s.Require().NoError(err)
+	s.Require().Len(events, 3)
+
+	s.Equal(pb.Metrics_Updates_FullMethodName, events[0].Action)
+	s.Equal(audit.OutcomeFailure, events[0].Outcome)
+	s.Equal(int(codes.Unauthenticated), events[0].Code)
+	s.Empty(events[0].Identity)
+
+	s.Equal(int(codes.PermissionDenied), events[1].Code)
+	s.Equal("grafana", events[1].Identity)
+
+	s.Equal(pb.Metrics_StreamUpdates_FullMethodName, events[2].Action)
+	s.Equal(int(codes.PermissionDenied), events[2].Code)
+	s.Equal("grafana", events[2].Identity)
+}
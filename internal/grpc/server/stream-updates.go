@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+// errShuttingDown ends the streaming calls when the server stops.
+var errShuttingDown = status.Error(codes.Unavailable, "server is shutting down")
+
+// StreamUpdates saves the batches of metrics received over a long-lived stream.
+// Every batch is acknowledged with its batch_id and the status code of the update;
+// a rejected batch does not close the stream.
+func (s *MetricsServer) StreamUpdates(stream pb.Metrics_StreamUpdatesServer) error {
+	requests := make(chan *pb.StreamUpdatesRequest)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case requests <- in:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var in *pb.StreamUpdatesRequest
+		select {
+		case <-s.done:
+			return errShuttingDown
+		case err := <-errCh:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case in = <-requests:
+		}
+
+		ack := &pb.StreamUpdatesResponse{
+			BatchId: in.BatchId,
+			Code:    uint32(codes.OK),
+		}
+		audit.SetMetrics(stream.Context(), len(in.Metrics))
+		if err := updateBatch(stream, in.Metrics); err != nil {
+			ack.Code = uint32(err.code)
+			ack.Error = err.Error()
+			audit.Record(stream.Context(), int(err.code), err)
+		} else {
+			audit.Record(stream.Context(), int(codes.OK), nil)
+		}
+
+		if err := stream.Send(ack); err != nil {
+			return err
+		}
+	}
+}
+
+// batchError is the error of the batch update with its status code.
+type batchError struct {
+	error
+	code codes.Code
+}
+
+func updateBatch(stream pb.Metrics_StreamUpdatesServer, batch []*pb.Metric) *batchError {
+	if err := checkBatchSize(len(batch)); err != nil {
+		return &batchError{error: err, code: codes.ResourceExhausted}
+	}
+
+	metricsBatch := make([]metrics.Metrics, 0, len(batch))
+	for _, metric := range batch {
+		m, err := sg.ProtoToMetric(metric)
+		if err != nil {
+			return &batchError{error: err, code: codes.InvalidArgument}
+		}
+		metricsBatch = append(metricsBatch, m)
+	}
+
+	metricsBatch, code, err := Controller.UpdatesMetrics(stream.Context(), metricsBatch)
+	if err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("metrics", metricsBatch))
+		return &batchError{error: err, code: sg.HTTPCodeToGRPC(code)}
+	}
+	return nil
+}
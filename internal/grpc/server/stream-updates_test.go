@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+func (s *MetricsServerSuite) TestStreamUpdates() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := s.client.StreamUpdates(ctx)
+	s.Require().NoError(err)
+
+	delta := int64(3)
+	value := 4.5
+	batches := []*pb.StreamUpdatesRequest{
+		{BatchId: 1, Metrics: []*pb.Metric{{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER, Delta: &delta}}},
+		{BatchId: 2, Metrics: []*pb.Metric{{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER}}},
+		{BatchId: 3, Metrics: []*pb.Metric{{Id: "temp", Mtype: pb.Mtype_TYPE_GAUGE, Value: &value}}},
+	}
+	for _, batch := range batches {
+		s.Require().NoError(stream.Send(batch))
+	}
+	s.Require().NoError(stream.CloseSend())
+
+	var acks []*pb.StreamUpdatesResponse
+	for {
+		ack, err := stream.Recv()
+		if err != nil {
+			s.Require().ErrorIs(err, io.EOF)
+			break
+		}
+		acks = append(acks, ack)
+	}
+
+	s.Require().Len(acks, 3)
+	s.Equal(uint64(1), acks[0].BatchId)
+	s.Equal(uint32(codes.OK), acks[0].Code)
+	s.Equal(uint64(2), acks[1].BatchId)
+	s.Equal(uint32(codes.InvalidArgument), acks[1].Code)
+	s.NotEmpty(acks[1].Error)
+	s.Equal(uint64(3), acks[2].BatchId)
+	s.Equal(uint32(codes.OK), acks[2].Code)
+
+	counter, _ := config.Storage.CounterValue("requests")
+	s.Equal(int64(8), counter)
+	gauge, _ := config.Storage.GaugeValue("temp")
+	s.Equal(4.5, gauge)
+}
+
+func (s *MetricsServerSuite) TestMaxBatchSize() {
+	config.MaxBatchSize = 1
+	defer func() { config.MaxBatchSize = 0 }()
+
+	delta := int64(1)
+	batch := []*pb.Metric{
+		{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER, Delta: &delta},
+		{Id: "errors", Mtype: pb.Mtype_TYPE_COUNTER, Delta: &delta},
+	}
+
+	_, err := s.client.Updates(context.Background(), &pb.UpdatesRequest{Metrics: batch})
+	s.Equal(codes.ResourceExhausted, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := s.client.StreamUpdates(ctx)
+	s.Require().NoError(err)
+	s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: 1, Metrics: batch}))
+	s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: 2, Metrics: batch[:1]}))
+	s.Require().NoError(stream.CloseSend())
+
+	ack, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Equal(uint32(codes.ResourceExhausted), ack.Code)
+	ack, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Equal(uint32(codes.OK), ack.Code)
+
+	counter, _ := config.Storage.CounterValue("requests")
+	s.Equal(int64(6), counter)
+}
+
+func (s *MetricsServerSuite) TestAuditStreamUpdates() {
+	log, err := audit.Open(filepath.Join(s.T().TempDir(), "audit.log"), 0, 0)
+	s.Require().NoError(err)
+	defer log.Close()
+
+	srv := NewServer(Config{Storage: config.Storage, WatchInterval: 10 * time.Millisecond, Audit: log})
+	defer srv.server.Stop()
+	defer func() { config.Audit = nil }()
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() { _ = srv.server.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := pb.NewMetricsClient(conn).StreamUpdates(ctx)
+	s.Require().NoError(err)
+
+	delta := int64(1)
+	s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: 1, Metrics: []*pb.Metric{
+		{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER, Delta: &delta},
+		{Id: "errors", Mtype: pb.Mtype_TYPE_COUNTER, Delta: &delta},
+	}}))
+	s.Require().NoError(stream.Send(&pb.StreamUpdatesRequest{BatchId: 2, Metrics: []*pb.Metric{
+		{Id: "requests", Mtype: pb.Mtype_TYPE_COUNTER},
+	}}))
+	s.Require().NoError(stream.CloseSend())
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			s.Require().ErrorIs(err, io.EOF)
+			break
+		}
+	}
+
+	events, err := log.Query(audit.Filter{})
+	s.Require().NoError(err)
+	s.Require().Len(events, 2)
+
+	s.Equal(pb.Metrics_StreamUpdates_FullMethodName, events[0].Action)
+	s.Equal(2, events[0].Metrics)
+	s.Equal(audit.OutcomeSuccess, events[0].Outcome)
+	s.Equal(1, events[1].Metrics)
+	s.Equal(audit.OutcomeFailure, events[1].Outcome)
+	s.Equal(int(codes.InvalidArgument), events[1].Code)
+	s.Equal(events[0].RequestID, events[1].RequestID)
+}
@@ -6,6 +6,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/fishus/go-advanced-metrics/internal/audit"
 	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	pb "github.com/fishus/go-advanced-metrics/proto"
@@ -14,6 +15,8 @@ import (
 func (s *MetricsServer) Update(ctx context.Context, in *pb.UpdateRequest) (*pb.UpdateResponse, error) {
 	var response pb.UpdateResponse
 
+	audit.SetMetrics(ctx, 1)
+
 	metric, err := sg.ProtoToMetric(in.Metric)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
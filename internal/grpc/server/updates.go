@@ -2,10 +2,12 @@ package server
 
 import (
 	"context"
+	"fmt"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/fishus/go-advanced-metrics/internal/audit"
 	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
@@ -16,6 +18,12 @@ func (s *MetricsServer) Updates(ctx context.Context, in *pb.UpdatesRequest) (*pb
 	var response pb.UpdatesResponse
 	var metricsBatch []metrics.Metrics
 
+	audit.SetMetrics(ctx, len(in.Metrics))
+
+	if err := checkBatchSize(len(in.Metrics)); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
 	for _, metric := range in.Metrics {
 		m, err := sg.ProtoToMetric(metric)
 		if err != nil {
@@ -43,3 +51,11 @@ func (s *MetricsServer) Updates(ctx context.Context, in *pb.UpdatesRequest) (*pb
 	response.Metrics = mb
 	return &response, nil
 }
+
+// checkBatchSize rejects the batches with more metrics than Config.MaxBatchSize.
+func checkBatchSize(size int) error {
+	if config.MaxBatchSize > 0 && size > config.MaxBatchSize {
+		return fmt.Errorf("Batch of %d metrics exceeds the limit of %d", size, config.MaxBatchSize)
+	}
+	return nil
+}
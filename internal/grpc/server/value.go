@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	sg "github.com/fishus/go-advanced-metrics/internal/grpc"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+// Value returns the current value of the series identified by the metric type, name and labels.
+func (s *MetricsServer) Value(ctx context.Context, in *pb.ValueRequest) (*pb.ValueResponse, error) {
+	if in.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Metric name not specified")
+	}
+	labels := metrics.Labels(in.Labels).Clone()
+
+	var metric metrics.Metrics
+	switch in.Mtype {
+	case pb.Mtype_TYPE_GAUGE:
+		gauge, ok := config.Storage.GaugeContext(ctx, in.Id, labels)
+		if !ok {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("Gauge '%s' not found", metrics.SeriesKey(in.Id, labels)))
+		}
+		metric = gaugeToMetric(gauge)
+	case pb.Mtype_TYPE_COUNTER:
+		counter, ok := config.Storage.CounterContext(ctx, in.Id, labels)
+		if !ok {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("Counter '%s' not found", metrics.SeriesKey(in.Id, labels)))
+		}
+		metric = counterToMetric(counter)
+	case pb.Mtype_TYPE_HISTOGRAM:
+		histogram, ok := config.Storage.HistogramContext(ctx, in.Id, labels)
+		if !ok {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("Histogram '%s' not found", metrics.SeriesKey(in.Id, labels)))
+		}
+		metric = histogramToMetric(histogram)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Incorrect metric type")
+	}
+
+	m, err := sg.MetricToProto(metric)
+	if err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("metric", metric))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.ValueResponse{Metric: &m}, nil
+}
+
+func gaugeToMetric(g metrics.Gauge) metrics.Metrics {
+	return metrics.NewGaugeMetric(g.Name()).SetValue(g.Value()).WithLabels(g.Labels())
+}
+
+func counterToMetric(c metrics.Counter) metrics.Metrics {
+	return metrics.NewCounterMetric(c.Name()).SetDelta(c.Value()).WithLabels(c.Labels())
+}
+
+func histogramToMetric(h metrics.Histogram) metrics.Metrics {
+	return metrics.NewHistogramMetric(h.Name()).SetHistogram(h.Value()).WithLabels(h.Labels())
+}
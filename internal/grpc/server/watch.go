@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+	pb "github.com/fishus/go-advanced-metrics/proto"
+)
+
+const defaultWatchInterval = time.Second
+
+// Watch streams the changes of the series with the given names or name prefixes,
+// all series are watched when neither is set.
+// The first message contains the current state of the series, the next ones
+// contain the series that were added or changed since the previous message
+// and, in the deleted field, the names, types and labels of the removed series.
+// The storage is polled with Config.WatchInterval.
+func (s *MetricsServer) Watch(in *pb.WatchRequest, stream pb.Metrics_WatchServer) error {
+	ctx := stream.Context()
+
+	// Серии выбираются отдельно по именам и по каждому из префиксов
+	var queries [][]store.StorageFilter
+	if len(in.Names) > 0 || len(in.Prefixes) == 0 {
+		queries = append(queries, []store.StorageFilter{store.FilterNames(in.Names)})
+	}
+	for _, prefix := range in.Prefixes {
+		queries = append(queries, []store.StorageFilter{store.FilterPrefix(prefix)})
+	}
+
+	interval := config.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := make(map[string]*pb.Metric)
+	for {
+		changed, deleted, err := watchChanges(ctx, last, queries)
+		if err != nil {
+			return err
+		}
+		if len(changed) > 0 || len(deleted) > 0 {
+			if err := stream.Send(&pb.WatchResponse{Metrics: changed, Deleted: deleted}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return errShuttingDown
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchChanges returns the series that differ from the last sent state and the series
+// that have been removed since then, and updates the state.
+// The watched series are the union of the series selected by the queries.
+func watchChanges(ctx context.Context, last map[string]*pb.Metric, queries [][]store.StorageFilter) (changed, deleted []*pb.Metric, err error) {
+	var list []series
+	for _, filters := range queries {
+		found, err := collectSeries(ctx, pb.Mtype_TYPE_UNSPECIFIED, filters...)
+		if err != nil {
+			return nil, nil, err
+		}
+		list = append(list, found...)
+	}
+	if len(queries) > 1 {
+		// Серия может подходить под несколько запросов
+		slices.SortFunc(list, func(a, b series) int { return strings.Compare(a.key, b.key) })
+		list = slices.CompactFunc(list, func(a, b series) bool { return a.key == b.key })
+	}
+
+	current := make(map[string]bool, len(list))
+	for _, sr := range list {
+		current[sr.key] = true
+		if m, ok := last[sr.key]; ok && proto.Equal(m, sr.metric) {
+			continue
+		}
+		last[sr.key] = sr.metric
+		changed = append(changed, sr.metric)
+	}
+
+	keys := make([]string, 0)
+	for key := range last {
+		if !current[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		// Удалённая серия передаётся без значений
+		m := last[key]
+		deleted = append(deleted, &pb.Metric{Id: m.Id, Mtype: m.Mtype, Labels: m.Labels})
+		delete(last, key)
+	}
+	return changed, deleted, nil
+}
@@ -17,15 +17,29 @@ func ProtoToMetric(m *pb.Metric) (metrics.Metrics, error) {
 		mtype = metrics.TypeGauge
 	case pb.Mtype_TYPE_COUNTER:
 		mtype = metrics.TypeCounter
+	case pb.Mtype_TYPE_HISTOGRAM:
+		mtype = metrics.TypeHistogram
 	default:
 		return metrics.Metrics{}, fmt.Errorf("unknown metric type: %s", m.Mtype)
 	}
 
+	var histogram *metrics.HistogramValue
+	if m.Histogram != nil {
+		histogram = &metrics.HistogramValue{
+			Bounds: m.Histogram.Bounds,
+			Counts: m.Histogram.Counts,
+			Count:  m.Histogram.Count,
+			Sum:    m.Histogram.Sum,
+		}
+	}
+
 	return metrics.Metrics{
-		Delta: m.Delta,
-		Value: m.Value,
-		ID:    m.Id,
-		MType: mtype,
+		Delta:     m.Delta,
+		Value:     m.Value,
+		Histogram: histogram,
+		Labels:    metrics.Labels(m.Labels).Clone(),
+		ID:        m.Id,
+		MType:     mtype,
 	}, nil
 }
 
@@ -36,15 +50,29 @@ func MetricToProto(metric metrics.Metrics) (pb.Metric, error) {
 		mtype = pb.Mtype_TYPE_GAUGE
 	case metrics.TypeCounter:
 		mtype = pb.Mtype_TYPE_COUNTER
+	case metrics.TypeHistogram:
+		mtype = pb.Mtype_TYPE_HISTOGRAM
 	default:
 		mtype = pb.Mtype_TYPE_UNSPECIFIED
 	}
 
+	var histogram *pb.Histogram
+	if metric.Histogram != nil {
+		histogram = &pb.Histogram{
+			Bounds: metric.Histogram.Bounds,
+			Counts: metric.Histogram.Counts,
+			Count:  metric.Histogram.Count,
+			Sum:    metric.Histogram.Sum,
+		}
+	}
+
 	return pb.Metric{
-		Id:    metric.ID,
-		Mtype: mtype,
-		Delta: metric.Delta,
-		Value: metric.Value,
+		Id:        metric.ID,
+		Mtype:     mtype,
+		Delta:     metric.Delta,
+		Value:     metric.Value,
+		Labels:    metric.Labels.Clone(),
+		Histogram: histogram,
 	}, nil
 }
 
@@ -60,6 +88,8 @@ func HTTPCodeToGRPC(code int) codes.Code {
 		return codes.InvalidArgument
 	case http.StatusInternalServerError:
 		return codes.Internal
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
 	}
 	return codes.Unknown
 }
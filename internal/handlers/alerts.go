@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+)
+
+// AlertsHandler processes the request GET /api/v1/alerts.
+// Returns the pending, firing and resolved alerts in JSON format,
+// the resolved alerts are returned until their notification is sent.
+func AlertsHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Alerts []alerting.Alert `json:"alerts"`
+	}{
+		Alerts: []alerting.Alert{},
+	}
+	if config.Alerts != nil {
+		data.Alerts = config.Alerts.Alerts()
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("data", data))
+	}
+}
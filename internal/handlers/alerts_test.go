@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type AlertsHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *AlertsHandlerSuite) SetupSuite() {
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *AlertsHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+	config.Alerts = nil
+}
+
+func (s *AlertsHandlerSuite) requestAlerts() []alerting.Alert {
+	resp, err := s.client.R().Get("/api/v1/alerts")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode())
+
+	var data struct {
+		Alerts []alerting.Alert `json:"alerts"`
+	}
+	s.Require().NoError(json.Unmarshal(resp.Body(), &data))
+	s.Require().NotNil(data.Alerts)
+	return data.Alerts
+}
+
+func (s *AlertsHandlerSuite) TestAlertingDisabled() {
+	config.Alerts = nil
+	s.Empty(s.requestAlerts())
+}
+
+func (s *AlertsHandlerSuite) TestAlerts() {
+	storage := store.NewMemStorage()
+	config.Storage = storage
+
+	lowMemory, err := alerting.ParseRule("LowFreeMemory", "gauge FreeMemory < 100 for 1m")
+	s.Require().NoError(err)
+	highLoad, err := alerting.ParseRule("HighLoad", "gauge Load > 1")
+	s.Require().NoError(err)
+	config.Alerts = alerting.NewEngine(storage, []alerting.Rule{lowMemory, highLoad})
+
+	_ = storage.SetGauge("FreeMemory", 50)
+	_ = storage.SetGauge("Load", 2)
+	config.Alerts.Eval(context.Background(), time.Now())
+
+	alerts := s.requestAlerts()
+	s.Require().Len(alerts, 2)
+	s.Equal("HighLoad", alerts[0].Rule)
+	s.Equal(alerting.StateFiring, alerts[0].State)
+	s.Equal("LowFreeMemory", alerts[1].Rule)
+	s.Equal(alerting.StatePending, alerts[1].State)
+	s.Equal(float64(50), alerts[1].Value)
+}
+
+func TestAlertsHandlerSuite(t *testing.T) {
+	suite.Run(t, new(AlertsHandlerSuite))
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+)
+
+// defaultAuditLimit is the number of the latest events returned by default.
+const defaultAuditLimit = 100
+
+// AuditHandler processes the request GET /api/v1/audit.
+// Returns the events of the audit log from the oldest to the newest in JSON format.
+//
+// Parameters: from and to - RFC 3339 or Unix time (by default without limits), client - address or
+// name of the token or certificate of the client, limit - number of the latest events (by default 100, 0 - all).
+func AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if config.Audit == nil {
+		JSONError(w, "Audit log is disabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+
+	from, err := parseQueryTime(q.Get("from"), time.Time{})
+	if err != nil {
+		JSONError(w, fmt.Sprintf(`Incorrect parameter 'from': %s`, err), http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseQueryTime(q.Get("to"), time.Time{})
+	if err != nil {
+		JSONError(w, fmt.Sprintf(`Incorrect parameter 'to': %s`, err), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultAuditLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			JSONError(w, `Incorrect parameter 'limit'`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := config.Audit.Query(audit.Filter{From: from, To: to, Client: q.Get("client"), Limit: limit})
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusInternalServerError)
+		logger.Log.Error(err.Error(), logger.String("event", "audit handler"))
+		return
+	}
+
+	data := struct {
+		Events []audit.Event `json:"events"`
+	}{
+		Events: events,
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("data", data))
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type AuditHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *AuditHandlerSuite) SetupSuite() {
+	log, err := audit.Open(filepath.Join(s.T().TempDir(), "audit.log"), 0, 0)
+	s.Require().NoError(err)
+	config.Audit = log
+
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *AuditHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+	s.NoError(config.Audit.Close())
+	config.Audit = nil
+}
+
+func (s *AuditHandlerSuite) SetupTest() {
+	config.Storage = store.NewMemStorage()
+	controller.Storage = config.Storage
+}
+
+func (s *AuditHandlerSuite) requestAudit(query map[string]string) []audit.Event {
+	resp, err := s.client.R().SetQueryParams(query).Get("/api/v1/audit")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode())
+
+	var data struct {
+		Events []audit.Event `json:"events"`
+	}
+	s.Require().NoError(json.Unmarshal(resp.Body(), &data))
+	return data.Events
+}
+
+func (s *AuditHandlerSuite) TestAudit() {
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(`[{"id":"a", "type":"counter", "delta":1},{"id":"b", "type":"gauge", "value":2.5}]`).
+		Post("/updates/")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode())
+
+	resp, err = s.client.R().Post("/update/counter/a/none")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusBadRequest, resp.StatusCode())
+
+	// Чтение метрик не записывается в журнал
+	_, err = s.client.R().Get("/value/counter/a")
+	s.Require().NoError(err)
+
+	events := s.requestAudit(map[string]string{"client": "127.0.0.1", "limit": "2"})
+	s.Require().Len(events, 2)
+
+	s.Equal(audit.ProtocolREST, events[0].Protocol)
+	s.Equal("POST /updates/", events[0].Action)
+	s.Equal("127.0.0.1", events[0].ClientIP)
+	s.NotEmpty(events[0].RequestID)
+	s.Equal(2, events[0].Metrics)
+	s.Equal(audit.OutcomeSuccess, events[0].Outcome)
+	s.Equal(http.StatusOK, events[0].Code)
+
+	s.Equal("POST /update/counter/a/none", events[1].Action)
+	s.Equal(1, events[1].Metrics)
+	s.Equal(audit.OutcomeFailure, events[1].Outcome)
+	s.Equal(http.StatusBadRequest, events[1].Code)
+
+	// Запрос к журналу — действие администратора, он тоже записывается
+	events = s.requestAudit(map[string]string{"limit": "1"})
+	s.Require().Len(events, 1)
+	s.Equal("GET /api/v1/audit", events[0].Action)
+
+	s.Empty(s.requestAudit(map[string]string{"client": "10.0.0.1"}))
+}
+
+func (s *AuditHandlerSuite) TestAuditRejected() {
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [
+		{"name": "admin", "token_sha256": "` + auth.Hash("admin-token") + `", "scopes": ["admin"]},
+		{"name": "web1", "token_sha256": "` + auth.Hash("agent-token") + `", "scopes": ["write"]}
+	]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	config.Tokens = registry
+	defer func() { config.Tokens = nil }()
+
+	ts := httptest.NewServer(ServerRouter())
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Запросы, отклонённые проверкой токена, тоже записываются в журнал
+	resp, err := client.R().Post("/update/counter/a/1")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusUnauthorized, resp.StatusCode())
+
+	resp, err = client.R().SetAuthToken("agent-token").Delete("/value/counter/a")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusForbidden, resp.StatusCode())
+
+	resp, err = client.R().SetAuthToken("admin-token").SetQueryParam("limit", "2").Get("/api/v1/audit")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode())
+
+	var body struct {
+		Events []audit.Event `json:"events"`
+	}
+	s.Require().NoError(json.Unmarshal(resp.Body(), &body))
+	events := body.Events
+	s.Require().Len(events, 2)
+
+	s.Equal("POST /update/counter/a/1", events[0].Action)
+	s.Equal(audit.OutcomeFailure, events[0].Outcome)
+	s.Equal(http.StatusUnauthorized, events[0].Code)
+	s.Empty(events[0].Identity)
+
+	s.Equal("DELETE /value/counter/a", events[1].Action)
+	s.Equal(http.StatusForbidden, events[1].Code)
+	s.Equal("web1", events[1].Identity)
+}
+
+func (s *AuditHandlerSuite) TestAuditMiddlewareRejected() {
+	subnets, err := clientip.ParseSubnets("10.0.0.0/8")
+	s.Require().NoError(err)
+	config.TrustedSubnets, config.MaxBodySize = subnets, 16
+	defer func() { config.TrustedSubnets, config.MaxBodySize = nil, 0 }()
+
+	ts := httptest.NewServer(ServerRouter())
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Запросы, отклонённые до разбора тела, тоже записываются в журнал
+	resp, err := client.R().SetBody(strings.Repeat("a", 32)).Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusRequestEntityTooLarge, resp.StatusCode())
+
+	resp, err = client.R().Post("/update/counter/a/1")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusForbidden, resp.StatusCode())
+
+	events, err := config.Audit.Query(audit.Filter{Limit: 2})
+	s.Require().NoError(err)
+	s.Require().Len(events, 2)
+	s.Equal("POST /api/v2/write", events[0].Action)
+	s.Equal(http.StatusRequestEntityTooLarge, events[0].Code)
+	s.Equal("POST /update/counter/a/1", events[1].Action)
+	s.Equal(http.StatusForbidden, events[1].Code)
+}
+
+func (s *AuditHandlerSuite) TestInvalidParams() {
+	for _, query := range []map[string]string{
+		{"from": "yesterday"},
+		{"to": "tomorrow"},
+		{"limit": "-1"},
+	} {
+		resp, err := s.client.R().SetQueryParams(query).Get("/api/v1/audit")
+		s.Require().NoError(err)
+		s.Equal(http.StatusBadRequest, resp.StatusCode(), query)
+	}
+}
+
+func TestAuditHandlerSuite(t *testing.T) {
+	suite.Run(t, new(AuditHandlerSuite))
+}
@@ -1,9 +1,15 @@
 package handlers
 
 import (
-	"net"
+	"crypto/tls"
 
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
 	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
 	store "github.com/fishus/go-advanced-metrics/internal/storage"
 )
 
@@ -12,9 +18,20 @@ var Controller controller.Controller
 var config Config
 
 type Config struct {
-	ServerAddr    string
-	Storage       store.MetricsStorager // TODO remove
-	SecretKey     string
-	PrivateKey    []byte
-	TrustedSubnet *net.IPNet
+	ServerAddr     string
+	Storage        store.MetricsStorager // TODO remove
+	Alerts         *alerting.Engine
+	Keys           *secure.Keyring // Ключи подписи, nil - данные не подписываются
+	PrivateKey     []byte
+	TrustedSubnets clientip.Subnets // Доверенные подсети, пустой список - запросы с любых адресов
+	TrustedProxies clientip.Subnets // Прокси, чьим заголовкам с адресом клиента можно доверять
+	TLSConfig      *tls.Config
+	ReplayGuard    *secure.ReplayGuard // Защита подписанных запросов от повтора, nil - отключена
+	Tokens         *auth.Registry      // API-токены агентов, nil - запросы без токенов
+	RateLimiter    *ratelimit.Limiter  // Ограничение частоты записи для каждого клиента, nil - отключено
+	Audit          *audit.Log          // Журнал аудита операций записи и администрирования, nil - отключён
+
+	MaxBodySize         int64 // Максимальный размер тела запроса в байтах, 0 - без ограничений
+	MaxDecompressedSize int64 // Максимальный размер распакованного тела запроса в байтах, 0 - без ограничений
+	MaxBatchSize        int   // Максимальное количество метрик в пакете, 0 - без ограничений
 }
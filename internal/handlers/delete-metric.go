@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+)
+
+// deleteResponse is the response of the delete requests.
+type deleteResponse struct {
+	Deleted int `json:"deleted"` // Количество удалённых серий
+}
+
+// DeleteMetricHandler processes the request DELETE /value/{metricType}/{metricID}.
+// Removes the series of the metric together with its history.
+// Series labels can be passed in the query string, the series without labels is removed by default.
+func DeleteMetricHandler(w http.ResponseWriter, r *http.Request) {
+	metricType := chi.URLParam(r, "metricType")
+	metricID := chi.URLParam(r, "metricID")
+	labels := labelsFromQuery(r)
+
+	audit.SetMetrics(r.Context(), 1)
+
+	code, err := Controller.DeleteMetric(r.Context(), metricType, metricID, labels)
+	if err != nil {
+		logger.Log.Debug(err.Error(), logger.String("type", metricType), logger.String("id", metricID))
+		JSONError(w, err.Error(), code)
+		return
+	}
+
+	writeDeleteResponse(w, 1)
+}
+
+// DeleteMetricsHandler processes the request DELETE /value/.
+// Removes the series selected either by the names (parameter name, can be repeated) or by the name prefix
+// (parameter prefix) together with their history. The parameter type limits the metric type, all types by default.
+// The other parameters of the query string are the labels the series must have.
+func DeleteMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	labels := labelsFromQuery(r, "name", "prefix", "type")
+
+	n, code, err := Controller.DeleteMetrics(r.Context(), q.Get("type"), q["name"], q.Get("prefix"), labels)
+	if err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("query", q))
+		JSONError(w, err.Error(), code)
+		return
+	}
+
+	audit.SetMetrics(r.Context(), n)
+
+	writeDeleteResponse(w, n)
+}
+
+func writeDeleteResponse(w http.ResponseWriter, n int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(deleteResponse{Deleted: n}); err != nil {
+		logger.Log.Debug(err.Error(), logger.Int("deleted", n))
+	}
+}
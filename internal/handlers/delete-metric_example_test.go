@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/handlers"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func ExampleDeleteMetricHandler() {
+	storage := store.NewMemStorage()
+	_ = handlers.NewServer(handlers.Config{
+		Storage: storage,
+	})
+	controller.Storage = storage
+
+	_ = storage.SetGauge("a", 1.23)
+
+	metricType := "gauge"
+	metricID := "a"
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/value/%v/%v", metricType, metricID), nil)
+
+	chiCtx := chi.NewRouteContext()
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	chiCtx.URLParams.Add("metricType", metricType)
+	chiCtx.URLParams.Add("metricID", metricID)
+
+	w := httptest.NewRecorder()
+	handlers.DeleteMetricHandler(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	fmt.Println(res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+	fmt.Print(string(body))
+
+	// Output:
+	// 200
+	// {"deleted":1}
+}
+
+func ExampleDeleteMetricsHandler() {
+	storage := store.NewMemStorage()
+	_ = handlers.NewServer(handlers.Config{
+		Storage: storage,
+	})
+	controller.Storage = storage
+
+	_ = storage.SetGauge("HeapAlloc", 1)
+	_ = storage.SetGauge("HeapSys", 2)
+	_ = storage.AddCounter("HeapObjects", 3)
+	_ = storage.SetGauge("Alloc", 4)
+
+	req := httptest.NewRequest(http.MethodDelete, "/value/?prefix=Heap", nil)
+
+	w := httptest.NewRecorder()
+	handlers.DeleteMetricsHandler(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	fmt.Println(res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+	fmt.Print(string(body))
+
+	// Output:
+	// 200
+	// {"deleted":3}
+}
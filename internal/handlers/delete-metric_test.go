@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type DeleteMetricHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *DeleteMetricHandlerSuite) SetupSuite() {
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [
+		{"name": "admin", "token_sha256": "` + auth.Hash("admin-token") + `", "scopes": ["admin"]},
+		{"name": "web1", "token_sha256": "` + auth.Hash("agent-token") + `", "scopes": ["write"]}
+	]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+	config.Tokens = registry
+
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL).SetAuthToken("admin-token")
+}
+
+func (s *DeleteMetricHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+	config.Tokens = nil
+}
+
+func (s *DeleteMetricHandlerSuite) SetupTest() {
+	config.Storage = store.NewMemStorage()
+	controller.Storage = config.Storage
+
+	ctx := context.Background()
+	_ = config.Storage.SetGauge("HeapAlloc", 1)
+	_ = config.Storage.SetGaugeContext(ctx, "HeapAlloc", metrics.Labels{"host": "web1"}, 2)
+	_ = config.Storage.SetGauge("HeapSys", 3)
+	_ = config.Storage.SetGauge("Alloc", 4)
+	_ = config.Storage.AddCounter("HeapAlloc", 5)
+}
+
+func (s *DeleteMetricHandlerSuite) TestDeleteMetricHandler() {
+	testCases := []struct {
+		name   string
+		url    string
+		want   string
+		status int
+	}{
+		{
+			name:   "Positive case: Gauge",
+			url:    "/value/gauge/HeapAlloc",
+			want:   `{"deleted":1}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Positive case: Gauge with labels",
+			url:    "/value/gauge/HeapAlloc?host=web1",
+			want:   `{"deleted":1}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Negative case: Unknown labels",
+			url:    "/value/gauge/HeapAlloc?host=web2",
+			want:   `{"error":"metric 'HeapAlloc{host=\"web2\"}' not found"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Negative case: Unknown metric",
+			url:    "/value/counter/HeapSys",
+			want:   `{"error":"metric 'HeapSys' not found"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Negative case: Incorrect metric type",
+			url:    "/value/summary/HeapAlloc",
+			want:   `{"error":"incorrect metric type"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			resp, err := s.client.R().Delete(tc.url)
+			s.Require().NoError(err)
+			s.Equal(tc.status, resp.StatusCode())
+			s.JSONEq(tc.want, string(resp.Body()))
+		})
+	}
+
+	// Удаляется только указанная серия
+	_, err := s.client.R().Delete("/value/gauge/HeapAlloc")
+	s.Require().NoError(err)
+	_, ok := config.Storage.GaugeValue("HeapAlloc")
+	s.False(ok)
+	_, ok = config.Storage.GaugeValueContext(context.Background(), "HeapAlloc", metrics.Labels{"host": "web1"})
+	s.True(ok)
+	_, ok = config.Storage.CounterValue("HeapAlloc")
+	s.True(ok)
+}
+
+func (s *DeleteMetricHandlerSuite) TestDeleteMetricsHandler() {
+	testCases := []struct {
+		name   string
+		query  string
+		want   string
+		status int
+		left   int // Количество оставшихся датчиков
+	}{
+		{
+			name:   "Positive case: Names",
+			query:  "?type=gauge&name=HeapAlloc&name=Alloc",
+			want:   `{"deleted":3}`,
+			status: http.StatusOK,
+			left:   1,
+		},
+		{
+			name:   "Positive case: Prefix of all types",
+			query:  "?prefix=Heap",
+			want:   `{"deleted":4}`,
+			status: http.StatusOK,
+			left:   1,
+		},
+		{
+			name:   "Positive case: Prefix and labels",
+			query:  "?prefix=Heap&host=web1",
+			want:   `{"deleted":1}`,
+			status: http.StatusOK,
+			left:   3,
+		},
+		{
+			name:   "Positive case: Nothing found",
+			query:  "?prefix=Stack",
+			want:   `{"deleted":0}`,
+			status: http.StatusOK,
+			left:   4,
+		},
+		{
+			name:   "Negative case: No names and prefix",
+			query:  "?type=gauge",
+			want:   `{"error":"names or prefix not specified"}`,
+			status: http.StatusBadRequest,
+			left:   4,
+		},
+		{
+			name:   "Negative case: Names and prefix",
+			query:  "?name=Alloc&prefix=Heap",
+			want:   `{"error":"names and prefix are mutually exclusive"}`,
+			status: http.StatusBadRequest,
+			left:   4,
+		},
+		{
+			name:   "Negative case: Incorrect metric type",
+			query:  "?type=summary&prefix=Heap",
+			want:   `{"error":"incorrect metric type"}`,
+			status: http.StatusBadRequest,
+			left:   4,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			resp, err := s.client.R().Delete("/value/" + tc.query)
+			s.Require().NoError(err)
+			s.Equal(tc.status, resp.StatusCode())
+			s.JSONEq(tc.want, string(resp.Body()))
+			s.Len(config.Storage.Gauges(), tc.left)
+		})
+	}
+}
+
+func (s *DeleteMetricHandlerSuite) TestRequireAdmin() {
+	// Права записи недостаточно для удаления метрик
+	agent := resty.New().SetBaseURL(s.ts.URL).SetAuthToken("agent-token")
+	resp, err := agent.R().Delete("/value/?prefix=Heap")
+	s.Require().NoError(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode())
+
+	resp, err = agent.R().Delete("/value/gauge/HeapSys")
+	s.Require().NoError(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode())
+
+	resp, err = resty.New().SetBaseURL(s.ts.URL).R().Delete("/value/gauge/HeapSys")
+	s.Require().NoError(err)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode())
+
+	s.Len(config.Storage.Gauges(), 4, "nothing is deleted")
+}
+
+func TestDeleteMetricHandlerSuite(t *testing.T) {
+	suite.Run(t, new(DeleteMetricHandlerSuite))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/lineprotocol"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// InfluxWriteHandler processes the request POST /api/v2/write.
+// Accepts the InfluxDB line protocol, the optional parameter precision sets the precision of the timestamps (ns, us, ms, s).
+// Float and boolean fields are stored as gauges, integer and unsigned fields as cumulative counters.
+// If any line cannot be parsed, nothing is stored and the errors are returned with the line numbers.
+func InfluxWriteHandler(w http.ResponseWriter, r *http.Request) {
+	precision, err := lineprotocol.ParsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "influx write handler"))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "influx write handler"))
+		return
+	}
+
+	points, err := lineprotocol.Parse(string(body), precision, time.Now())
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "influx write handler"))
+		return
+	}
+
+	audit.SetMetrics(r.Context(), len(points))
+
+	if err := checkBatchSize(len(points)); err != nil {
+		JSONError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	code := http.StatusOK
+	update := func(ctx context.Context, batch []metrics.Metrics) (err error) {
+		_, code, err = Controller.UpdatesMetrics(ctx, batch)
+		return err
+	}
+
+	n, err := receivers.influx.Write(r.Context(), points, update)
+	if err != nil {
+		if code == http.StatusOK {
+			code = http.StatusInternalServerError
+		}
+		JSONError(w, err.Error(), code)
+		if code >= http.StatusInternalServerError && !errors.Is(err, context.Canceled) {
+			logger.Log.Error(err.Error(), logger.String("event", "influx write handler"))
+		} else {
+			logger.Log.Debug(err.Error(), logger.String("event", "influx write handler"))
+		}
+		return
+	}
+
+	logger.Log.Debug("Line protocol request stored", logger.String("event", "influx write handler"), logger.Int("series", n))
+	w.WriteHeader(http.StatusNoContent)
+}
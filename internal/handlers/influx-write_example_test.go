@@ -0,0 +1,33 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/handlers"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func ExampleInfluxWriteHandler() {
+	storage := store.NewMemStorage()
+	controller.Storage = storage
+	_ = handlers.NewServer(handlers.Config{
+		Storage: storage,
+	})
+
+	body := "cpu,host=web1 usage=0.5\nmem,host=web1 used=1024i\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/write", strings.NewReader(body))
+
+	w := httptest.NewRecorder()
+	handlers.InfluxWriteHandler(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	fmt.Println(res.StatusCode)
+
+	// Output:
+	// 204
+}
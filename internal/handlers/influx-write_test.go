@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/cryptokey"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type InfluxWriteHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *InfluxWriteHandlerSuite) SetupSuite() {
+	config.Keys = secure.NewKeyring([]byte("secret"))
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *InfluxWriteHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+	config.Keys = nil
+}
+
+func (s *InfluxWriteHandlerSuite) SetupTest() {
+	config.Storage = store.NewMemStorage()
+	receivers = newIngestion(config.Storage)
+	controller.Storage = config.Storage
+}
+
+func (s *InfluxWriteHandlerSuite) TestInfluxWriteHandler() {
+	body := []byte("cpu,host=web1 usage=0.5 1704110400\nnet,host=web1 bytes=100i 1704110400\nnet,host=web1 bytes=150i 1704110410\n")
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(body)
+	s.Require().NoError(err)
+	s.Require().NoError(zw.Close())
+
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "text/plain; charset=utf-8").
+		SetHeader("Content-Encoding", "gzip").
+		SetHeader("HashSHA256", hex.EncodeToString(secure.Hash(body, config.Keys.Signing().Secret))).
+		SetQueryParam("precision", "s").
+		SetBody(buf.Bytes()).
+		Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode(), resp.String())
+
+	v, ok := config.Storage.GaugeValueContext(context.Background(), "cpu_usage", metrics.Labels{"host": "web1"})
+	s.Require().True(ok)
+	s.Equal(0.5, v)
+
+	c, ok := config.Storage.CounterContext(context.Background(), "net_bytes", metrics.Labels{"host": "web1"})
+	s.Require().True(ok)
+	s.Equal(int64(150), c.Value())
+}
+
+func (s *InfluxWriteHandlerSuite) TestUnsigned() {
+	// Сторонние клиенты не подписывают запросы, а запросы агента без подписи отклоняются
+	resp, err := s.client.R().SetBody("cpu,host=web1 usage=0.5\n").Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Equal(http.StatusNoContent, resp.StatusCode(), resp.String())
+
+	resp, err = s.client.R().Post("/update/gauge/cpu_usage/1")
+	s.Require().NoError(err)
+	s.Equal(http.StatusBadRequest, resp.StatusCode())
+	_, ok := config.Storage.GaugeValue("cpu_usage")
+	s.False(ok)
+}
+
+func (s *InfluxWriteHandlerSuite) TestPrivateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	s.Require().NoError(err)
+
+	config.PrivateKey = x509.MarshalPKCS1PrivateKey(key)
+	defer func() { config.PrivateKey = nil }()
+	ts := httptest.NewServer(ServerRouter())
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Сторонние клиенты не шифруют запросы, даже если на сервере задан закрытый ключ
+	resp, err := client.R().SetBody("cpu,host=web1 usage=0.5\n").Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Equal(http.StatusNoContent, resp.StatusCode(), resp.String())
+
+	// Запросы агента расшифровываются до проверки подписи
+	body := []byte(`[{"id":"alloc","type":"gauge","value":1.5}]`)
+	encrypted, err := cryptokey.Encrypt(body, publicKey)
+	s.Require().NoError(err)
+	resp, err = client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("HashSHA256", hex.EncodeToString(secure.Hash(body, config.Keys.Signing().Secret))).
+		SetBody(encrypted).
+		Post("/updates/")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode(), resp.String())
+
+	v, ok := config.Storage.GaugeValue("alloc")
+	s.Require().True(ok)
+	s.Equal(1.5, v)
+}
+
+func (s *InfluxWriteHandlerSuite) TestRateLimit() {
+	config.RateLimiter = ratelimit.New(0.1, 1)
+	defer func() { config.RateLimiter = nil }()
+	ts := httptest.NewServer(ServerRouter())
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Лимит проверяется до распаковки тела запроса
+	body := []byte("not gzip")
+	resp, err := client.R().SetHeader("Content-Encoding", "gzip").SetBody(body).Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.NotEqual(http.StatusTooManyRequests, resp.StatusCode())
+
+	resp, err = client.R().SetHeader("Content-Encoding", "gzip").SetBody(body).Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Equal(http.StatusTooManyRequests, resp.StatusCode())
+
+	// Запросы чтения не ограничиваются
+	resp, err = client.R().Get("/metrics")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode())
+}
+
+func (s *InfluxWriteHandlerSuite) TestInfluxWriteHandler_Errors() {
+	testCases := []struct {
+		name     string
+		body     string
+		query    string
+		contains string
+		hash     string
+		want     int
+	}{
+		{
+			name:     "Parse errors with line numbers",
+			body:     "cpu usage=1\ncpu usage=\ncpu\n",
+			contains: "line 2:",
+			want:     http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid precision",
+			body:     "cpu usage=1",
+			query:    "h",
+			contains: "precision",
+			want:     http.StatusBadRequest,
+		},
+		{
+			name: "Invalid signature",
+			body: "cpu usage=1",
+			hash: "00",
+			want: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := s.client.R().SetBody(tc.body)
+			if tc.query != "" {
+				req.SetQueryParam("precision", tc.query)
+			}
+			if tc.hash != "" {
+				req.SetHeader("HashSHA256", tc.hash)
+			}
+
+			resp, err := req.Post("/api/v2/write")
+			s.Require().NoError(err)
+			s.Equal(tc.want, resp.StatusCode())
+			s.Contains(resp.String(), tc.contains)
+		})
+	}
+
+	// Строки с ошибками не сохраняются
+	_, ok := config.Storage.GaugeValue("cpu_usage")
+	s.False(ok)
+}
+
+func (s *InfluxWriteHandlerSuite) TestMaxBatchSize() {
+	config.MaxBatchSize = 2
+	defer func() { config.MaxBatchSize = 0 }()
+
+	resp, err := s.client.R().SetBody("cpu usage=1\nmem used=2\n").Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Equal(http.StatusNoContent, resp.StatusCode(), resp.String())
+
+	resp, err = s.client.R().SetBody("cpu usage=1\nmem used=2\ndisk used=3\n").Post("/api/v2/write")
+	s.Require().NoError(err)
+	s.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode())
+
+	_, ok := config.Storage.GaugeValue("disk_used")
+	s.False(ok)
+}
+
+func TestInfluxWriteHandlerSuite(t *testing.T) {
+	suite.Run(t, new(InfluxWriteHandlerSuite))
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// labelsFromQuery returns the series labels passed in the query string,
+// e.g. /value/gauge/Alloc?host=web1&env=prod.
+// The reserved parameters of the request are not treated as labels.
+func labelsFromQuery(r *http.Request, reserved ...string) metrics.Labels {
+	query := r.URL.Query()
+	for _, name := range reserved {
+		query.Del(name)
+	}
+	if len(query) == 0 {
+		return nil
+	}
+
+	labels := make(metrics.Labels, len(query))
+	for name := range query {
+		labels[name] = query.Get(name)
+	}
+	return labels
+}
@@ -16,13 +16,16 @@ func ListHandler(w http.ResponseWriter, r *http.Request) {
 
 	counters := config.Storage.CountersContext(r.Context())
 	gauges := config.Storage.GaugesContext(r.Context())
+	histograms := config.Storage.HistogramsContext(r.Context())
 
 	data := struct {
-		Counters map[string]metrics.Counter
-		Gauges   map[string]metrics.Gauge
+		Counters   map[string]metrics.Counter
+		Gauges     map[string]metrics.Gauge
+		Histograms map[string]metrics.Histogram
 	}{
-		Counters: counters,
-		Gauges:   gauges,
+		Counters:   counters,
+		Gauges:     gauges,
+		Histograms: histograms,
 	}
 
 	templates := template.Must(template.New("list.html").ParseFiles("templates/list.html"))
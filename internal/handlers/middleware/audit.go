@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+)
+
+// Audit records the request to the audit log with its status code once it is served.
+// The handlers set the number of the received metrics with audit.SetMetrics.
+// Only the requests for which match returns true are recorded, all requests if match is nil.
+// Nothing is recorded when the log is not set.
+func Audit(log *audit.Log, match func(r *http.Request) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if log == nil || (match != nil && !match(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var clientIP string
+			if addr := clientip.ParseAddr(r.RemoteAddr); addr.IsValid() {
+				clientIP = addr.String()
+			}
+
+			ctx := audit.NewContext(r.Context(), log, audit.Event{
+				Protocol:  audit.ProtocolREST,
+				Action:    r.Method + " " + r.URL.Path,
+				ClientIP:  clientIP,
+				Identity:  audit.Identity(r.Context()),
+				RequestID: middleware.GetReqID(r.Context()),
+			})
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			var err error
+			if status >= http.StatusBadRequest {
+				err = errors.New(http.StatusText(status))
+			}
+			audit.Record(ctx, status, err)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
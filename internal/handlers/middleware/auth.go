@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+)
+
+// RequireScope rejects the requests whose API token is missing, unknown, revoked or does not grant the scope.
+// The authenticated token is put into the request context. Nothing is checked when the registry is not set.
+func RequireScope(registry *auth.Registry, scope auth.Scope) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if registry == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := registry.Authorize(auth.BearerToken(r.Header.Get("Authorization")), scope)
+			// Отказ в доступе тоже записывается в журнал аудита с именем токена
+			audit.SetIdentity(r.Context(), token.Name)
+			if err != nil {
+				code := http.StatusUnauthorized
+				if errors.Is(err, auth.ErrForbidden) {
+					code = http.StatusForbidden
+				} else {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				}
+
+				if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+					JSONError(w, err.Error(), code)
+				} else {
+					http.Error(w, err.Error(), code)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), token)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireRegistry rejects the requests with 403 Forbidden when the registry is not set.
+// It closes the destructive routes of the server that is run without the API tokens.
+func RequireRegistry(registry *auth.Registry) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if registry == nil {
+				if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+					JSONError(w, auth.ErrNoRegistry.Error(), http.StatusForbidden)
+				} else {
+					http.Error(w, auth.ErrNoRegistry.Error(), http.StatusForbidden)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
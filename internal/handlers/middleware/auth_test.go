@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+)
+
+type AuthSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *AuthSuite) SetupSuite() {
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [
+		{"name": "web1", "token_sha256": "` + auth.Hash("agent-token") + `", "scopes": ["write"]},
+		{"name": "old", "token_sha256": "` + auth.Hash("old-token") + `", "scopes": ["write"], "revoked": true}
+	]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	r := chi.NewRouter()
+	r.Use(RequireScope(registry, auth.ScopeWrite))
+
+	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
+		token, ok := auth.FromContext(r.Context())
+		s.Require().True(ok)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(token.Name))
+	})
+
+	s.ts = httptest.NewServer(r)
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *AuthSuite) TearDownSuite() {
+	s.ts.Close()
+}
+
+func (s *AuthSuite) TestRequireScope() {
+	testCases := []struct {
+		name          string
+		authorization string
+		code          int
+	}{
+		{
+			name:          "Valid token",
+			authorization: "Bearer agent-token",
+			code:          http.StatusOK,
+		},
+		{
+			name: "Missing token",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:          "Unknown token",
+			authorization: "Bearer unknown",
+			code:          http.StatusUnauthorized,
+		},
+		{
+			name:          "Revoked token",
+			authorization: "Bearer old-token",
+			code:          http.StatusUnauthorized,
+		},
+		{
+			name:          "Not a bearer token",
+			authorization: "Basic agent-token",
+			code:          http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := s.client.R()
+			if tc.authorization != "" {
+				req.SetHeader("Authorization", tc.authorization)
+			}
+
+			resp, err := req.Post("test/")
+			s.Require().NoError(err)
+			s.Equal(tc.code, resp.StatusCode())
+			if tc.code == http.StatusOK {
+				s.Equal("web1", resp.String())
+			} else {
+				s.NotEmpty(resp.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func (s *AuthSuite) TestForbidden() {
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	data := `{"tokens": [{"name": "grafana", "token_sha256": "` + auth.Hash("reader-token") + `", "scopes": ["read"]}]}`
+	s.Require().NoError(os.WriteFile(path, []byte(data), 0600))
+
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	handler := RequireScope(registry, auth.ScopeWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/update/", nil)
+	req.Header.Set("Authorization", "Bearer reader-token")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Contains(w.Header().Get("Content-Type"), "application/json")
+}
+
+func (s *AuthSuite) TestRequireRegistry() {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Без реестра токенов запрос отклоняется
+	req := httptest.NewRequest(http.MethodDelete, "/value/gauge/a", nil)
+	w := httptest.NewRecorder()
+	RequireRegistry(nil)(ok).ServeHTTP(w, req)
+	s.Equal(http.StatusForbidden, w.Code)
+
+	path := filepath.Join(s.T().TempDir(), "tokens.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{"tokens": []}`), 0600))
+	registry, err := auth.LoadRegistry(path)
+	s.Require().NoError(err)
+
+	w = httptest.NewRecorder()
+	RequireRegistry(registry)(ok).ServeHTTP(w, req)
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
@@ -18,6 +18,9 @@ func Decrypt(privateKey []byte) func(next http.Handler) http.Handler {
 				return
 			}
 
+			// Сообщаем агенту, какие схемы шифрования поддерживает сервер
+			w.Header().Set(cryptokey.AcceptSchemeHeader, cryptokey.SchemeEnvelope+", "+cryptokey.SchemeChunks)
+
 			contentType := r.Header.Get("Content-Type")
 
 			body, err := io.ReadAll(r.Body)
@@ -30,7 +33,12 @@ func Decrypt(privateKey []byte) func(next http.Handler) http.Handler {
 				return
 			}
 
-			body, err = cryptokey.Decrypt(body, privateKey)
+			// Агенты старых версий не указывают схему, она определяется по заголовку конверта
+			if scheme := r.Header.Get(cryptokey.SchemeHeader); scheme != "" {
+				body, err = cryptokey.DecryptScheme(body, privateKey, scheme)
+			} else {
+				body, err = cryptokey.Decrypt(body, privateKey)
+			}
 			if err != nil {
 				if strings.Contains(contentType, "application/json") {
 					JSONError(w, "Failed to decrypt request data", http.StatusBadRequest)
@@ -108,6 +108,78 @@ func (s *DecryptSuite) TestDecrypt() {
 	}
 }
 
+func (s *DecryptSuite) TestSchemes() {
+	msg := []byte(`{"message":"Hello"}`)
+
+	chunks, err := cryptokey.EncryptScheme(msg, s.publicKey, cryptokey.SchemeChunks)
+	s.Require().NoError(err)
+
+	envelope, err := cryptokey.EncryptScheme(msg, s.publicKey, cryptokey.SchemeEnvelope)
+	s.Require().NoError(err)
+
+	tampered := append([]byte(nil), envelope...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	testCases := []struct {
+		name   string
+		scheme string
+		body   []byte
+		status int
+	}{
+		{
+			name:   "Legacy chunks without scheme",
+			body:   chunks,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Legacy chunks",
+			scheme: cryptokey.SchemeChunks,
+			body:   chunks,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Envelope without scheme",
+			body:   envelope,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Envelope",
+			scheme: cryptokey.SchemeEnvelope,
+			body:   envelope,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Tampered envelope",
+			scheme: cryptokey.SchemeEnvelope,
+			body:   tampered,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Unknown scheme",
+			scheme: "rot13",
+			body:   envelope,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := s.client.R().SetBody(tc.body)
+			if tc.scheme != "" {
+				req.SetHeader(cryptokey.SchemeHeader, tc.scheme)
+			}
+
+			resp, err := req.Post("test/")
+			s.Require().NoError(err)
+			s.Equal(tc.status, resp.StatusCode())
+			s.Contains(resp.Header().Get(cryptokey.AcceptSchemeHeader), cryptokey.SchemeEnvelope)
+			if tc.status == http.StatusOK {
+				s.Equal(msg, resp.Body())
+			}
+		})
+	}
+}
+
 func TestDecryptSuite(t *testing.T) {
 	suite.Run(t, new(DecryptSuite))
 }
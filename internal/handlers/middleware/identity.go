@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+// Identity puts the identity of the client authenticated by its TLS certificate into the request context.
+func Identity(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			if id, ok := identity.FromConnectionState(*r.TLS); ok {
+				r = r.WithContext(identity.NewContext(r.Context(), id))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
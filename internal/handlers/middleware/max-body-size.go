@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxBodySize rejects the requests whose body is larger than the limit in bytes with 413 Request Entity Too Large.
+// The body is read up to the limit before the next handler is called, so placed after Decompress
+// it limits the size of the decompressed body and stops the gzip bombs.
+// The snappy body of the remote write requests is not decompressed here, its decoder checks the same limit.
+// Nothing is checked when the limit is not positive.
+func MaxBodySize(limit int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			contentType := r.Header.Get("Content-Type")
+			tooLarge := fmt.Sprintf("Request body is larger than %d bytes", limit)
+
+			if r.ContentLength > limit {
+				if strings.Contains(contentType, "application/json") {
+					JSONError(w, tooLarge, http.StatusRequestEntityTooLarge)
+				} else {
+					http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+				}
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+			if err != nil {
+				code := http.StatusBadRequest
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					code = http.StatusRequestEntityTooLarge
+					err = errors.New(tooLarge)
+				}
+
+				if strings.Contains(contentType, "application/json") {
+					JSONError(w, err.Error(), code)
+				} else {
+					http.Error(w, err.Error(), code)
+				}
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
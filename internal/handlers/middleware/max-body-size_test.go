@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaxBodySizeSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *MaxBodySizeSuite) SetupSuite() {
+	r := chi.NewRouter()
+	r.Use(MaxBodySize(1024))
+	r.Use(Decompress)
+	r.Use(MaxBodySize(4096))
+
+	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err := io.Copy(w, r.Body)
+		s.Require().NoError(err)
+	})
+
+	s.ts = httptest.NewServer(r)
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *MaxBodySizeSuite) TearDownSuite() {
+	s.ts.Close()
+}
+
+func (s *MaxBodySizeSuite) gzipCompress(data []byte) []byte {
+	var b bytes.Buffer
+
+	w, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	s.Require().NoError(err)
+
+	_, err = w.Write(data)
+	s.Require().NoError(err)
+
+	err = w.Close()
+	s.Require().NoError(err)
+
+	return b.Bytes()
+}
+
+func (s *MaxBodySizeSuite) TestMaxBodySize() {
+	testCases := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+	}{
+		{
+			name:     "Positive: body within the limit",
+			body:     bytes.Repeat([]byte("a"), 1024),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Negative: body exceeds the limit",
+			body:     bytes.Repeat([]byte("a"), 1025),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "Positive: decompressed body within the limit",
+			encoding: "gzip",
+			body:     s.gzipCompress(bytes.Repeat([]byte("a"), 4096)),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Negative: gzip bomb",
+			encoding: "gzip",
+			body:     s.gzipCompress(bytes.Repeat([]byte("a"), 1<<20)),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "Negative: compressed body exceeds the limit",
+			encoding: "gzip",
+			body:     bytes.Repeat([]byte("a"), 2048),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := s.client.R().SetBody(tc.body)
+			if tc.encoding != "" {
+				req.SetHeader("Content-Encoding", tc.encoding)
+			}
+
+			resp, err := req.Post("test/")
+			s.Require().NoError(err)
+			s.Equal(tc.wantCode, resp.StatusCode())
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	suite.Run(t, new(MaxBodySizeSuite))
+}
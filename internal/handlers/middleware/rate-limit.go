@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+)
+
+// RateLimit rejects the requests of the client that has exhausted its token bucket with 429 Too Many Requests.
+// It is installed before the API token is checked and the request body is read,
+// so the client is identified by its certificate or address, see ratelimit.ClientKey.
+// Only the requests for which match returns true are limited, all requests if match is nil.
+// Nothing is checked when the limiter is not set.
+func RateLimit(limiter *ratelimit.Limiter, match func(r *http.Request) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if limiter == nil || (match != nil && !match(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !allow(w, r, limiter, ratelimit.ClientKey(r.Context(), clientip.ParseAddr(r.RemoteAddr))) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RateLimitToken rejects the requests of the API token that has exhausted its token bucket with 429 Too Many Requests.
+// It is installed after RequireScope, the requests without the token are limited by RateLimit only.
+func RateLimitToken(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token, ok := auth.FromContext(r.Context())
+			if limiter == nil || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !allow(w, r, limiter, ratelimit.TokenKey(token.Name)) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// allow takes a token from the bucket of the key, the rejected request is answered with 429 Too Many Requests.
+func allow(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, key string) bool {
+	ok, delay := limiter.Allow(key)
+	if ok {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfter(delay)))
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		JSONError(w, "Too many requests", http.StatusTooManyRequests)
+	} else {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	}
+	return false
+}
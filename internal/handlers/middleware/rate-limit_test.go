@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(ratelimit.New(0.1, 2), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/update/", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusOK, send("10.0.0.1:1001").Code)
+
+	w := send("10.0.0.1:1002")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Too many requests"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.2:1000").Code, "other client is not limited")
+}
+
+func TestRateLimitMatch(t *testing.T) {
+	write := func(r *http.Request) bool { return r.Method == http.MethodPost }
+	h := RateLimit(ratelimit.New(0.1, 1), write)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(method string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/update/", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost))
+	assert.Equal(t, http.StatusTooManyRequests, send(http.MethodPost))
+	// Остальные запросы не ограничиваются
+	assert.Equal(t, http.StatusOK, send(http.MethodGet))
+}
+
+func TestRateLimitToken(t *testing.T) {
+	h := RateLimitToken(ratelimit.New(0.1, 1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(name string) int {
+		r := httptest.NewRequest(http.MethodPost, "/update/", nil)
+		if name != "" {
+			r = r.WithContext(auth.NewContext(r.Context(), auth.Token{Name: name}))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("web1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("web1"))
+	assert.Equal(t, http.StatusOK, send("web2"), "other token is not limited")
+
+	// Запросы без токена ограничиваются только по адресу
+	assert.Equal(t, http.StatusOK, send(""))
+	assert.Equal(t, http.StatusOK, send(""))
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	h := RateLimit(nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/update/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
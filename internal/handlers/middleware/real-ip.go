@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
+)
+
+// RealIP sets the RemoteAddr of the request to the address of the client
+// from the forwarding headers when the request comes from one of the trusted proxies.
+// The headers of the other requests are ignored, so the address of the client can't be forged.
+func RealIP(proxies clientip.Subnets) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if len(proxies) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			peer := clientip.ParseAddr(r.RemoteAddr)
+			// Каждый прокси может добавить свой заголовок, их адреса идут по порядку
+			forwardedFor := strings.Join(r.Header.Values(clientip.ForwardedForHeader), ",")
+			addr := clientip.Resolve(peer, proxies, forwardedFor, r.Header.Get(clientip.RealIPHeader))
+			if addr != peer {
+				r.RemoteAddr = addr.String()
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
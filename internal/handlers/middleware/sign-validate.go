@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -10,12 +11,19 @@ import (
 	"github.com/fishus/go-advanced-metrics/internal/secure"
 )
 
-func ValidateSign(key []byte) func(next http.Handler) http.Handler {
+var errSignRequired = errors.New("request signature is required")
+
+// ValidateSign verifies the HashSHA256 signature of the request with the keys of the keyring.
+// The key is chosen by the key ID header, all the keys are tried when it was not passed.
+// The signature covers the timestamp and the nonce headers if they were passed.
+// When the replay guard is set, the signed requests must carry a fresh timestamp and an unused nonce.
+// The key that signed the request is put into the request context to sign the response with it.
+func ValidateSign(keys *secure.Keyring, guard *secure.ReplayGuard) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			hashString := r.Header.Get("HashSHA256")
 
-			if len(key) == 0 || hashString == "" {
+			if keys.Empty() || hashString == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -44,17 +52,52 @@ func ValidateSign(key []byte) func(next http.Handler) http.Handler {
 			// Restore the io.ReadCloser to it's original state
 			r.Body = io.NopCloser(bytes.NewBuffer(body))
 
-			bodyHash := secure.Hash(body, key)
-
-			if !bytes.Equal(headerHash, bodyHash) {
+			timestamp := r.Header.Get(secure.TimestampHeader)
+			nonce := r.Header.Get(secure.NonceHeader)
+			key, err := keys.Verify(r.Header.Get(secure.KeyIDHeader), headerHash, func(secret []byte) []byte {
+				return secure.RequestHash(body, secret, timestamp, nonce)
+			})
+			if err != nil {
 				if strings.Contains(contentType, "application/json") {
-					JSONError(w, "Data integrity has been compromised", http.StatusBadRequest)
+					JSONError(w, err.Error(), http.StatusBadRequest)
 				} else {
-					http.Error(w, "Data integrity has been compromised", http.StatusBadRequest)
+					http.Error(w, err.Error(), http.StatusBadRequest)
 				}
 				return
 			}
 
+			// Подпись верна, проверяем, что запрос не был отправлен повторно
+			if guard != nil {
+				if err := guard.Check(timestamp, nonce); err != nil {
+					if strings.Contains(contentType, "application/json") {
+						JSONError(w, err.Error(), http.StatusBadRequest)
+					} else {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					}
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(secure.NewKeyContext(r.Context(), key)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireSign rejects the requests without the HashSHA256 signature when the keyring is not empty,
+// so that the signature and the replay protection of ValidateSign can't be bypassed by removing the headers.
+// It is used on the routes of the agent, the third-party clients do not sign the requests.
+func RequireSign(keys *secure.Keyring) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if !keys.Empty() && r.Header.Get("HashSHA256") == "" {
+				if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+					JSONError(w, errSignRequired.Error(), http.StatusBadRequest)
+				} else {
+					http.Error(w, errSignRequired.Error(), http.StatusBadRequest)
+				}
+				return
+			}
 			next.ServeHTTP(w, r)
 		}
 		return http.HandlerFunc(fn)
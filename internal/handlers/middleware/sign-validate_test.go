@@ -4,7 +4,11 @@ import (
 	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
@@ -21,7 +25,7 @@ type ValidateSignSuite struct {
 
 func (s *ValidateSignSuite) SetupSuite() {
 	r := chi.NewRouter()
-	r.Use(ValidateSign([]byte("secret")))
+	r.Use(ValidateSign(secure.NewKeyring([]byte("secret")), nil))
 
 	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -94,6 +98,166 @@ func (s *ValidateSignSuite) TestValidateSign() {
 	}
 }
 
+func (s *ValidateSignSuite) TestReplay() {
+	key := []byte("secret")
+
+	r := chi.NewRouter()
+	r.Use(ValidateSign(secure.NewKeyring(key), secure.NewReplayGuard(time.Minute, 1000)))
+	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	data := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+
+	send := func(hash []byte, timestamp, nonce string) int {
+		req := client.R().SetBody(data).
+			SetHeader("HashSHA256", hex.EncodeToString(hash))
+		if timestamp != "" {
+			req.SetHeader(secure.TimestampHeader, timestamp)
+		}
+		if nonce != "" {
+			req.SetHeader(secure.NonceHeader, nonce)
+		}
+
+		resp, err := req.Post("test/")
+		s.Require().NoError(err)
+		return resp.StatusCode()
+	}
+
+	s.Run("Replayed request", func() {
+		timestamp, nonce, err := secure.NewNonce()
+		s.Require().NoError(err)
+		hash := secure.RequestHash(data, key, timestamp, nonce)
+
+		s.Equal(http.StatusOK, send(hash, timestamp, nonce))
+		s.Equal(http.StatusBadRequest, send(hash, timestamp, nonce))
+	})
+
+	s.Run("Stale request", func() {
+		timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		hash := secure.RequestHash(data, key, timestamp, "nonce")
+
+		s.Equal(http.StatusBadRequest, send(hash, timestamp, "nonce"))
+	})
+
+	s.Run("Timestamp is not signed", func() {
+		timestamp, nonce, err := secure.NewNonce()
+		s.Require().NoError(err)
+
+		s.Equal(http.StatusBadRequest, send(secure.Hash(data, key), timestamp, nonce))
+	})
+
+	s.Run("Signature without nonce", func() {
+		s.Equal(http.StatusBadRequest, send(secure.Hash(data, key), "", ""))
+	})
+}
+
+func (s *ValidateSignSuite) TestKeyring() {
+	path := filepath.Join(s.T().TempDir(), "keyring.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{"signing_key": "new", "keys": [
+		{"id": "old", "secret": "old secret"},
+		{"id": "new", "secret": "new secret"}
+	]}`), 0600))
+	keys, err := secure.LoadKeyring(path, nil)
+	s.Require().NoError(err)
+
+	r := chi.NewRouter()
+	r.Use(ValidateSign(keys, nil))
+	r.Use(Sign(keys))
+	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	data := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+
+	testCases := []struct {
+		name   string
+		keyID  string
+		secret string
+		code   int
+		signer string // Ключ, которым подписан ответ
+	}{
+		{name: "Old key", keyID: "old", secret: "old secret", code: http.StatusOK, signer: "old"},
+		{name: "New key", keyID: "new", secret: "new secret", code: http.StatusOK, signer: "new"},
+		{name: "Key without ID", secret: "old secret", code: http.StatusOK, signer: "old"},
+		{name: "Unsigned request", code: http.StatusOK, signer: "new"},
+		{name: "Unknown key ID", keyID: "unknown", secret: "old secret", code: http.StatusBadRequest},
+		{name: "Wrong secret of the key", keyID: "new", secret: "old secret", code: http.StatusBadRequest},
+	}
+
+	secrets := map[string]string{"old": "old secret", "new": "new secret"}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := client.R().SetBody(data)
+			if tc.secret != "" {
+				req.SetHeader("HashSHA256", hex.EncodeToString(secure.Hash(data, []byte(tc.secret))))
+			}
+			if tc.keyID != "" {
+				req.SetHeader(secure.KeyIDHeader, tc.keyID)
+			}
+
+			resp, err := req.Post("test/")
+			s.Require().NoError(err)
+			s.Equal(tc.code, resp.StatusCode())
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			s.Equal(tc.signer, resp.Header().Get(secure.KeyIDHeader))
+			hash := secure.Hash(resp.Body(), []byte(secrets[tc.signer]))
+			s.Equal(hex.EncodeToString(hash), resp.Header().Get("HashSHA256"))
+		})
+	}
+}
+
+func (s *ValidateSignSuite) TestRequireSign() {
+	keys := secure.NewKeyring([]byte("secret"))
+	r := chi.NewRouter()
+	r.Use(ValidateSign(keys, nil))
+	r.Use(RequireSign(keys))
+	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	data := []byte(`{"id":"a","type":"counter","delta":1}`)
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Запрос без подписи отклоняется, если на сервере задан ключ
+	resp, err := client.R().SetHeader("Content-Type", "application/json").SetBody(data).Post("/test/")
+	s.Require().NoError(err)
+	s.Equal(http.StatusBadRequest, resp.StatusCode())
+
+	resp, err = client.R().SetBody(data).
+		SetHeader("HashSHA256", hex.EncodeToString(secure.Hash(data, []byte("secret")))).
+		Post("/test/")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode())
+
+	// Без ключей подпись не требуется
+	r = chi.NewRouter()
+	r.Use(RequireSign(nil))
+	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	unsigned := httptest.NewServer(r)
+	defer unsigned.Close()
+
+	resp, err = resty.New().SetBaseURL(unsigned.URL).R().SetBody(data).Post("/test/")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode())
+}
+
 func TestValidateSignSuite(t *testing.T) {
 	suite.Run(t, new(ValidateSignSuite))
 }
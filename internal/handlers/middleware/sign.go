@@ -12,16 +12,23 @@ import (
 )
 
 // Sign adds a signature to the response contents in the headers.
-func Sign(key []byte) func(next http.Handler) http.Handler {
+// The response to the signed request is signed with the key of the request, so that the agent can verify it,
+// the other responses are signed with the signing key of the keyring. The key ID is passed in the header.
+func Sign(keys *secure.Keyring) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			if len(key) == 0 {
+			if keys.Empty() {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			sign := secure.NewSign(key)
-			hw := &signWriter{ResponseWriter: w, Sign: sign, Buf: &bytes.Buffer{}}
+			key, ok := secure.KeyFromContext(r.Context())
+			if !ok {
+				key = keys.Signing()
+			}
+
+			sign := secure.NewSign(key.Secret)
+			hw := &signWriter{ResponseWriter: w, Sign: sign, KeyID: key.ID, Buf: &bytes.Buffer{}}
 			defer hw.Close()
 
 			next.ServeHTTP(hw, r)
@@ -33,6 +40,7 @@ func Sign(key []byte) func(next http.Handler) http.Handler {
 type signWriter struct {
 	http.ResponseWriter
 	Sign       *secure.Sign
+	KeyID      string
 	Buf        *bytes.Buffer
 	statusCode int
 	once       sync.Once
@@ -54,6 +62,9 @@ func (w *signWriter) Close() error {
 		hash := w.Sign.Sum()
 		hashString := hex.EncodeToString(hash)
 		w.ResponseWriter.Header().Set("HashSHA256", hashString)
+		if w.KeyID != "" {
+			w.ResponseWriter.Header().Set(secure.KeyIDHeader, w.KeyID)
+		}
 
 		if w.statusCode > 0 {
 			w.ResponseWriter.WriteHeader(w.statusCode)
@@ -22,7 +22,7 @@ type SignSuite struct {
 
 func (s *SignSuite) SetupSuite() {
 	r := chi.NewRouter()
-	r.Use(Sign([]byte("secret")))
+	r.Use(Sign(secure.NewKeyring([]byte("secret"))))
 
 	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
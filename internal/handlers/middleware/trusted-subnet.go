@@ -1,33 +1,29 @@
 package middleware
 
 import (
-	"net"
 	"net/http"
 	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
 )
 
-func TrustedSubnet(subnet *net.IPNet) func(next http.Handler) http.Handler {
+// TrustedSubnet rejects the requests from the addresses outside the trusted subnets.
+// The address is taken from the RemoteAddr of the request, see RealIP for the requests behind the proxies.
+// All requests are trusted when the subnets are not set.
+func TrustedSubnet(subnets clientip.Subnets) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 
-			if subnet == nil {
+			if len(subnets) == 0 {
 				next.ServeHTTP(w, r)
 				return
 			}
 
 			contentType := r.Header.Get("Content-Type")
 
-			remoteAddr := r.Header.Get("X-Real-IP")
-			if remoteAddr == "" {
-				ra := strings.Split(r.RemoteAddr, ":")
-				if len(ra) == 2 {
-					remoteAddr = ra[0]
-				}
-			}
-
-			ip := net.ParseIP(remoteAddr)
+			addr := clientip.ParseAddr(r.RemoteAddr)
 
-			if ip == nil || !subnet.Contains(ip) {
+			if !addr.IsValid() || !subnets.Contains(addr) {
 				if strings.Contains(contentType, "application/json") {
 					JSONError(w, "The request from this ip-address was rejected.", http.StatusForbidden)
 				} else {
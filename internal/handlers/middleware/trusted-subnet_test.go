@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"io"
-	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,22 +9,26 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
 )
 
 type TrustedSubnetSuite struct {
 	suite.Suite
-	ts     *httptest.Server
-	client *resty.Client
-	subnet *net.IPNet
+	subnets clientip.Subnets
 }
 
 func (s *TrustedSubnetSuite) SetupSuite() {
-	_, subnet, err := net.ParseCIDR("192.168.0.0/24")
+	subnets, err := clientip.ParseSubnets("192.168.0.0/24, 2001:db8::/32")
 	s.Require().NoError(err)
-	s.subnet = subnet
+	s.subnets = subnets
+}
 
+// newClient returns the client of the test server that trusts the forwarding headers of the proxies.
+func (s *TrustedSubnetSuite) newClient(subnets, proxies clientip.Subnets) *resty.Client {
 	r := chi.NewRouter()
-	r.Use(TrustedSubnet(subnet))
+	r.Use(RealIP(proxies))
+	r.Use(TrustedSubnet(subnets))
 
 	r.Post("/test/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -34,49 +37,79 @@ func (s *TrustedSubnetSuite) SetupSuite() {
 		s.Require().NoError(err)
 	})
 
-	s.ts = httptest.NewServer(r)
-	s.client = resty.New().SetBaseURL(s.ts.URL)
-}
-
-func (s *TrustedSubnetSuite) TearDownSuite() {
-	s.ts.Close()
+	ts := httptest.NewServer(r)
+	s.T().Cleanup(ts.Close)
+	return resty.New().SetBaseURL(ts.URL)
 }
 
-func (s *TrustedSubnetSuite) sendRequest(ip string) *resty.Response {
-	req := s.client.R().SetHeader("X-Real-IP", ip)
-
-	resp, err := req.Post("test/")
+func (s *TrustedSubnetSuite) TestTrustedSubnet() {
+	proxies, err := clientip.ParseSubnets("127.0.0.1")
 	s.Require().NoError(err)
 
-	return resp
-}
-
-func (s *TrustedSubnetSuite) TestTrustedSubnet() {
 	testCases := []struct {
-		name string
-		ip   string
-		code int
+		name    string
+		proxies clientip.Subnets
+		headers map[string]string
+		code    int
 	}{
 		{
-			name: "Positive case",
-			ip:   "192.168.0.123",
-			code: 200,
+			name:    "X-Real-IP of the trusted proxy",
+			proxies: proxies,
+			headers: map[string]string{"X-Real-IP": "192.168.0.123"},
+			code:    http.StatusOK,
+		},
+		{
+			name:    "X-Forwarded-For of the trusted proxy",
+			proxies: proxies,
+			headers: map[string]string{"X-Forwarded-For": "2001:db8::1, 127.0.0.1"},
+			code:    http.StatusOK,
+		},
+		{
+			name:    "Address outside the subnets",
+			proxies: proxies,
+			headers: map[string]string{"X-Real-IP": "10.0.0.1"},
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "Forged address before the client",
+			proxies: proxies,
+			headers: map[string]string{"X-Forwarded-For": "192.168.0.123, 10.0.0.1"},
+			code:    http.StatusForbidden,
 		},
 		{
-			name: "Negative case",
-			ip:   "127.0.0.1",
-			code: 403,
+			name:    "Headers of the untrusted peer",
+			headers: map[string]string{"X-Real-IP": "192.168.0.123", "X-Forwarded-For": "192.168.0.123"},
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "Peer address outside the subnets",
+			proxies: proxies,
+			code:    http.StatusForbidden,
 		},
 	}
 
 	for _, tc := range testCases {
 		s.Run(tc.name, func() {
-			resp := s.sendRequest(tc.ip)
+			resp, err := s.newClient(s.subnets, tc.proxies).R().SetHeaders(tc.headers).Post("test/")
+			s.Require().NoError(err)
 			s.Equal(tc.code, resp.StatusCode())
 		})
 	}
 }
 
+func (s *TrustedSubnetSuite) TestPeerAddress() {
+	subnets, err := clientip.ParseSubnets("10.0.0.0/8,127.0.0.0/8")
+	s.Require().NoError(err)
+
+	resp, err := s.newClient(subnets, nil).R().Post("test/")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode())
+
+	resp, err = s.newClient(nil, nil).R().SetHeader("X-Real-IP", "10.0.0.1").Post("test/")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode(), "All addresses are trusted without subnets")
+}
+
 func TestTrustedSubnetSuite(t *testing.T) {
 	suite.Run(t, new(TrustedSubnetSuite))
 }
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/otlp"
+)
+
+// OTLPMetricsHandler processes the request POST /v1/metrics.
+// Accepts the OTLP ExportMetricsServiceRequest in the protobuf (application/x-protobuf) or the JSON (application/json) encoding.
+// Responds with the ExportMetricsServiceResponse in the encoding of the request,
+// the rejected data points are reported in its partial success.
+func OTLPMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-protobuf" && contentType != "application/json" {
+		http.Error(w, "unsupported content type, use application/x-protobuf or application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	isJSON := contentType == "application/json"
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "otlp metrics handler"))
+		return
+	}
+
+	var req *otlp.ExportRequest
+	if isJSON {
+		req, err = otlp.UnmarshalJSON(body)
+	} else {
+		req, err = otlp.UnmarshalProto(body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "otlp metrics handler"))
+		return
+	}
+
+	var n int
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			n += len(sm.Metrics)
+		}
+	}
+	audit.SetMetrics(r.Context(), n)
+
+	if err := checkBatchSize(n); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	resp, err := receivers.otlp.Write(r.Context(), req)
+	if err != nil {
+		// Экспортёры OTLP повторяют запросы, завершившиеся ошибкой 503
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		logger.Log.Error(err.Error(), logger.String("event", "otlp metrics handler"))
+		return
+	}
+
+	if resp.PartialSuccess != nil {
+		logger.Log.Debug(resp.PartialSuccess.ErrorMessage, logger.String("event", "otlp metrics handler"),
+			logger.Int("rejected", int(resp.PartialSuccess.RejectedDataPoints)))
+	}
+
+	var data []byte
+	if isJSON {
+		data, err = otlp.MarshalResponseJSON(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		data = otlp.MarshalResponseProto(resp)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/otlp"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type OTLPMetricsHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *OTLPMetricsHandlerSuite) SetupSuite() {
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *OTLPMetricsHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+}
+
+func (s *OTLPMetricsHandlerSuite) SetupTest() {
+	config.Storage = store.NewMemStorage()
+	receivers = newIngestion(config.Storage)
+}
+
+func (s *OTLPMetricsHandlerSuite) TestProtobuf() {
+	body := otlp.MarshalProto(&otlp.ExportRequest{
+		ResourceMetrics: []otlp.ResourceMetrics{{
+			Resource: []otlp.KeyValue{{Key: "service.name", Value: "checkout"}},
+			ScopeMetrics: []otlp.ScopeMetrics{{
+				Metrics: []otlp.Metric{
+					{Name: "queue.size", Gauge: &otlp.Gauge{DataPoints: []otlp.NumberDataPoint{{Value: 3}}}},
+					{Name: "http.requests", Sum: &otlp.Sum{
+						DataPoints:  []otlp.NumberDataPoint{{Value: 7}},
+						Temporality: otlp.TemporalityCumulative,
+						IsMonotonic: true,
+					}},
+				},
+			}},
+		}},
+	})
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(body)
+	s.Require().NoError(err)
+	s.Require().NoError(zw.Close())
+
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/x-protobuf").
+		SetHeader("Content-Encoding", "gzip").
+		SetBody(buf.Bytes()).
+		Post("/v1/metrics")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode(), resp.String())
+	s.Equal("application/x-protobuf", resp.Header().Get("Content-Type"))
+
+	res, err := otlp.UnmarshalResponseProto(resp.Body())
+	s.Require().NoError(err)
+	s.Nil(res.PartialSuccess)
+
+	labels := metrics.Labels{"service_name": "checkout"}
+	v, ok := config.Storage.GaugeValueContext(context.Background(), "queue_size", labels)
+	s.Require().True(ok)
+	s.Equal(float64(3), v)
+
+	c, ok := config.Storage.CounterValueContext(context.Background(), "http_requests", labels)
+	s.Require().True(ok)
+	s.Equal(int64(7), c)
+}
+
+func (s *OTLPMetricsHandlerSuite) TestJSONPartialSuccess() {
+	body := `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [
+		{"name": "temperature", "gauge": {"dataPoints": [{"asDouble": 21.5}]}},
+		{"name": "latency", "summary": {"dataPoints": [{"count": "1"}]}}
+	]}]}]}`
+
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post("/v1/metrics")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode(), resp.String())
+	s.Equal("application/json", resp.Header().Get("Content-Type"))
+	s.JSONEq(`{"partialSuccess":{"rejectedDataPoints":"1","errorMessage":"metric \"latency\": unsupported data type summary"}}`, resp.String())
+
+	v, ok := config.Storage.GaugeValue("temperature")
+	s.Require().True(ok)
+	s.Equal(21.5, v)
+}
+
+func (s *OTLPMetricsHandlerSuite) TestErrors() {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{
+			name:        "Unsupported content type",
+			contentType: "text/plain",
+			body:        "queue.size 3",
+			want:        http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Invalid protobuf",
+			contentType: "application/x-protobuf",
+			body:        "\x0a\x05\x01",
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "Invalid JSON",
+			contentType: "application/json",
+			body:        `{"resourceMetrics": {}}`,
+			want:        http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			resp, err := s.client.R().SetHeader("Content-Type", tc.contentType).SetBody(tc.body).Post("/v1/metrics")
+			s.Require().NoError(err)
+			s.Equal(tc.want, resp.StatusCode())
+		})
+	}
+}
+
+func (s *OTLPMetricsHandlerSuite) TestMaxBatchSize() {
+	config.MaxBatchSize = 1
+	defer func() { config.MaxBatchSize = 0 }()
+
+	body := `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [
+		{"name": "temperature", "gauge": {"dataPoints": [{"asDouble": 21.5}]}},
+		{"name": "humidity", "gauge": {"dataPoints": [{"asDouble": 40}]}}
+	]}]}]}`
+
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post("/v1/metrics")
+	s.Require().NoError(err)
+	s.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode())
+
+	_, ok := config.Storage.GaugeValue("temperature")
+	s.False(ok)
+}
+
+func TestOTLPMetricsHandlerSuite(t *testing.T) {
+	suite.Run(t, new(OTLPMetricsHandlerSuite))
+}
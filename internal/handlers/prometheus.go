@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/expfmt"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+)
+
+// PrometheusHandler processes the request GET /metrics.
+// Returns all counters, gauges and histograms in the Prometheus text format
+// or in the OpenMetrics format, depending on the Accept header.
+func PrometheusHandler(w http.ResponseWriter, r *http.Request) {
+	format := expfmt.Negotiate(r.Header.Get("Accept"))
+
+	enc := expfmt.NewEncoder(w, format)
+	enc.AddCounters(config.Storage.CountersContext(r.Context()))
+	enc.AddGauges(config.Storage.GaugesContext(r.Context()))
+	enc.AddHistograms(config.Storage.HistogramsContext(r.Context()))
+
+	w.Header().Set("Content-Type", string(format))
+	w.WriteHeader(http.StatusOK)
+
+	if err := enc.Encode(); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "prometheus handler"))
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/expfmt"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type PrometheusHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *PrometheusHandlerSuite) SetupSuite() {
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *PrometheusHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+}
+
+func (s *PrometheusHandlerSuite) SetupTest() {
+	config.Storage = store.NewMemStorage()
+	_ = config.Storage.AddCounter("PollCount", 5)
+	_ = config.Storage.SetGaugeContext(context.Background(), "Alloc", metrics.Labels{"host": "web1"}, 1.5)
+}
+
+func (s *PrometheusHandlerSuite) TestPrometheusHandler() {
+	testCases := []struct {
+		name        string
+		accept      string
+		contentType string
+		want        string
+	}{
+		{
+			name:        "Prometheus text format",
+			accept:      "text/plain",
+			contentType: string(expfmt.FormatText),
+			want:        "# TYPE Alloc gauge\nAlloc{host=\"web1\"} 1.5\n# TYPE PollCount counter\nPollCount 5\n",
+		},
+		{
+			name:        "OpenMetrics format",
+			accept:      "application/openmetrics-text;version=1.0.0,text/plain;q=0.5",
+			contentType: string(expfmt.FormatOpenMetrics),
+			want:        "# TYPE Alloc gauge\nAlloc{host=\"web1\"} 1.5\n# TYPE PollCount counter\nPollCount_total 5\n# EOF\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			resp, err := s.client.R().SetHeader("Accept", tc.accept).Get("/metrics")
+			s.Require().NoError(err)
+			s.Equal(http.StatusOK, resp.StatusCode())
+			s.Equal(tc.contentType, resp.Header().Get("Content-Type"))
+			s.Equal(tc.want, string(resp.Body()))
+		})
+	}
+}
+
+func (s *PrometheusHandlerSuite) TestPrometheusHandler_Gzip() {
+	// resty распаковывает gzip-ответ автоматически
+	resp, err := s.client.R().SetHeader("Accept-Encoding", "gzip").Get("/metrics")
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode())
+	s.Equal("gzip", resp.Header().Get("Content-Encoding"))
+	s.Contains(string(resp.Body()), "PollCount 5")
+}
+
+func TestPrometheusHandlerSuite(t *testing.T) {
+	suite.Run(t, new(PrometheusHandlerSuite))
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/query"
+)
+
+// Default range of the query.
+const (
+	defaultQueryRange = time.Hour
+	defaultQueryStep  = time.Minute
+)
+
+// QueryRangeResult is a series calculated by the range query.
+type QueryRangeResult struct {
+	Name   string           `json:"name"`
+	Labels metrics.Labels   `json:"labels,omitempty"`
+	MType  string           `json:"type"`
+	Func   query.Func       `json:"fn"`
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Step   float64          `json:"step"` // шаг в секундах
+	Values []metrics.Sample `json:"values"`
+}
+
+// QueryRangeHandler processes the request GET /api/v1/query_range.
+// Evaluates the function fn over the history of the series at every step of the range and
+// returns the result in JSON format.
+//
+// Parameters: name - metric name, fn - rate, increase (counters), avg, min, max, last (gauges),
+// from and to - RFC 3339 or Unix time (by default the last hour), step - duration like 30s
+// or the number of seconds (by default 1m). The rest of the parameters are series labels.
+func QueryRangeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	name := q.Get("name")
+	if name == "" {
+		JSONError(w, `Metric name not specified`, http.StatusBadRequest)
+		return
+	}
+
+	fn, err := query.ParseFunc(q.Get("fn"))
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseQueryTime(q.Get("to"), time.Now())
+	if err != nil {
+		JSONError(w, fmt.Sprintf(`Incorrect parameter 'to': %s`, err), http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseQueryTime(q.Get("from"), to.Add(-defaultQueryRange))
+	if err != nil {
+		JSONError(w, fmt.Sprintf(`Incorrect parameter 'from': %s`, err), http.StatusBadRequest)
+		return
+	}
+
+	step, err := parseQueryStep(q.Get("step"))
+	if err != nil {
+		JSONError(w, fmt.Sprintf(`Incorrect parameter 'step': %s`, err), http.StatusBadRequest)
+		return
+	}
+
+	// Диапазон проверяется до чтения истории из хранилища
+	if err := query.ValidateRange(from, to, step); err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	labels := labelsFromQuery(r, "name", "fn", "from", "to", "step")
+
+	// Отсчёты за шаг до начала диапазона нужны для расчёта первой точки
+	var samples []metrics.Sample
+	if fn.MetricType() == metrics.TypeCounter {
+		samples, err = config.Storage.CounterSamples(r.Context(), name, labels, from.Add(-step), to)
+	} else {
+		samples, err = config.Storage.GaugeSamples(r.Context(), name, labels, from.Add(-step), to)
+	}
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusInternalServerError)
+		logger.Log.Error(err.Error(), logger.String("event", "query range handler"), logger.String("name", name))
+		return
+	}
+
+	values, err := query.Range(fn, samples, from, to, step)
+	if err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := QueryRangeResult{
+		Name:   name,
+		Labels: labels,
+		MType:  fn.MetricType(),
+		Func:   fn,
+		From:   from,
+		To:     to,
+		Step:   step.Seconds(),
+		Values: values,
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Log.Debug(err.Error(), logger.Any("data", result))
+	}
+}
+
+// parseQueryTime parses the time in RFC 3339 format or as Unix time in seconds.
+// Unix time must be finite and fit into the nanoseconds of int64.
+func parseQueryTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		ns := sec * float64(time.Second)
+		if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+			return time.Time{}, errors.New("unix time is out of range")
+		}
+		return time.Unix(0, int64(ns)), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parseQueryStep parses the step as a duration (30s, 5m) or the number of seconds.
+func parseQueryStep(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultQueryStep, nil
+	}
+
+	step, err := time.ParseDuration(s)
+	if err != nil {
+		sec, errF := strconv.ParseFloat(s, 64)
+		if errF != nil {
+			return 0, err
+		}
+		step = time.Duration(sec * float64(time.Second))
+	}
+
+	if step <= 0 {
+		return 0, query.ErrInvalidStep
+	}
+	return step, nil
+}
@@ -0,0 +1,46 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/handlers"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func ExampleQueryRangeHandler() {
+	storage := store.NewMemStorage()
+	storage.SetRetention(time.Hour)
+	_ = handlers.NewServer(handlers.Config{
+		Storage: storage,
+	})
+
+	_ = storage.AddCounter("PollCount", 10)
+	_ = storage.AddCounter("PollCount", 20)
+
+	to := time.Now().Add(time.Second).Format(time.RFC3339Nano)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?name=PollCount&fn=increase&step=1m&to="+url.QueryEscape(to), nil)
+
+	w := httptest.NewRecorder()
+	handlers.QueryRangeHandler(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	fmt.Println(res.StatusCode)
+
+	var result handlers.QueryRangeResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return
+	}
+	for _, v := range result.Values {
+		fmt.Println(v.Value)
+	}
+
+	// Output:
+	// 200
+	// 20
+}
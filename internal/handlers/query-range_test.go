@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type QueryRangeHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *QueryRangeHandlerSuite) SetupSuite() {
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *QueryRangeHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+}
+
+func (s *QueryRangeHandlerSuite) SetupTest() {
+	storage := store.NewMemStorage()
+	storage.SetRetention(time.Hour)
+	config.Storage = storage
+
+	_ = config.Storage.AddCounter("a", 5)
+	_ = config.Storage.AddCounter("a", 3)
+	_ = config.Storage.SetGauge("a", 1)
+	_ = config.Storage.SetGauge("a", 3)
+}
+
+func (s *QueryRangeHandlerSuite) TestQueryRangeHandler() {
+	to := time.Now().Add(time.Second).Format(time.RFC3339Nano)
+
+	testCases := []struct {
+		name   string
+		params map[string]string
+		want   []float64
+		status int
+	}{
+		{
+			name:   "Positive case: increase",
+			params: map[string]string{"name": "a", "fn": "increase", "to": to, "step": "1m"},
+			want:   []float64{3},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Positive case: rate",
+			params: map[string]string{"name": "a", "fn": "rate", "to": to, "step": "60"},
+			want:   []float64{0.05},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Positive case: avg",
+			params: map[string]string{"name": "a", "fn": "avg", "to": to},
+			want:   []float64{2},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Positive case: unknown labels",
+			params: map[string]string{"name": "a", "fn": "max", "to": to, "host": "web1"},
+			want:   []float64{},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Negative case: name not specified",
+			params: map[string]string{"fn": "avg"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: unknown function",
+			params: map[string]string{"name": "a", "fn": "sum"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: incorrect time",
+			params: map[string]string{"name": "a", "fn": "avg", "from": "yesterday"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: NaN time",
+			params: map[string]string{"name": "a", "fn": "avg", "to": "NaN"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: infinite time",
+			params: map[string]string{"name": "a", "fn": "avg", "from": "-Inf"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: time out of range",
+			params: map[string]string{"name": "a", "fn": "avg", "from": "1e300"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: end before start",
+			params: map[string]string{"name": "a", "fn": "avg", "from": "1704110400", "to": "1704106800"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: incorrect step",
+			params: map[string]string{"name": "a", "fn": "avg", "step": "-1s"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: too many steps",
+			params: map[string]string{"name": "a", "fn": "avg", "step": "1ms"},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			resp, err := s.client.R().SetQueryParams(tc.params).Get("/api/v1/query_range")
+			s.Require().NoError(err)
+			s.Equal(tc.status, resp.StatusCode())
+
+			if resp.StatusCode() != http.StatusOK {
+				return
+			}
+
+			var result QueryRangeResult
+			s.Require().NoError(json.Unmarshal(resp.Body(), &result))
+			s.Equal(tc.params["name"], result.Name)
+
+			values := make([]float64, 0, len(result.Values))
+			for _, v := range result.Values {
+				values = append(values, v.Value)
+			}
+			s.InDeltaSlice(tc.want, values, 1e-9)
+		})
+	}
+}
+
+func (s *QueryRangeHandlerSuite) TestQueryRangeHandler_Labels() {
+	_ = config.Storage.SetGaugeContext(context.Background(), "a", metrics.Labels{"host": "web1"}, 7)
+
+	resp, err := s.client.R().
+		SetQueryParams(map[string]string{"name": "a", "fn": "last", "host": "web1", "to": time.Now().Add(time.Second).Format(time.RFC3339Nano)}).
+		Get("/api/v1/query_range")
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode())
+
+	var result QueryRangeResult
+	s.Require().NoError(json.Unmarshal(resp.Body(), &result))
+	s.Equal(metrics.Labels{"host": "web1"}, result.Labels)
+	s.Equal(metrics.TypeGauge, result.MType)
+	s.Require().Len(result.Values, 1)
+	s.Equal(float64(7), result.Values[0].Value)
+}
+
+func TestQueryRangeHandlerSuite(t *testing.T) {
+	suite.Run(t, new(QueryRangeHandlerSuite))
+}
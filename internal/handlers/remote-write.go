@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/remotewrite"
+)
+
+// RemoteWriteHandler processes the request POST /api/v1/write.
+// Accepts the Prometheus remote write request: a snappy-compressed protobuf WriteRequest message.
+// Counters are stored as counter series, the rest of the samples as gauges.
+func RemoteWriteHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := remotewrite.Decode(r.Body, config.MaxDecompressedSize)
+	if errors.Is(err, remotewrite.ErrTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		logger.Log.Debug(err.Error(), logger.String("event", "remote write handler"))
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "remote write handler"))
+		return
+	}
+
+	audit.SetMetrics(r.Context(), len(req.Timeseries))
+
+	if err := checkBatchSize(len(req.Timeseries)); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	n, err := receivers.remoteWrite.Write(r.Context(), req)
+	if errors.Is(err, remotewrite.ErrInvalidSeries) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.Log.Debug(err.Error(), logger.String("event", "remote write handler"))
+		return
+	}
+	if err != nil {
+		// Prometheus повторяет запросы, завершившиеся ошибкой 5xx
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Log.Error(err.Error(), logger.String("event", "remote write handler"))
+		return
+	}
+
+	logger.Log.Debug("Remote write request stored", logger.String("event", "remote write handler"), logger.Int("series", n))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/remotewrite"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+type RemoteWriteHandlerSuite struct {
+	suite.Suite
+	ts     *httptest.Server
+	client *resty.Client
+}
+
+func (s *RemoteWriteHandlerSuite) SetupSuite() {
+	config.Keys = secure.NewKeyring([]byte("secret"))
+	s.ts = httptest.NewServer(ServerRouter())
+	s.client = resty.New().SetBaseURL(s.ts.URL)
+}
+
+func (s *RemoteWriteHandlerSuite) TearDownSuite() {
+	s.ts.Close()
+	config.Keys = nil
+}
+
+func (s *RemoteWriteHandlerSuite) SetupTest() {
+	config.Storage = store.NewMemStorage()
+	receivers = newIngestion(config.Storage)
+}
+
+func (s *RemoteWriteHandlerSuite) write(body []byte, sign bool) *resty.Response {
+	req := s.client.R().
+		SetHeader("Content-Type", "application/x-protobuf").
+		SetHeader("Content-Encoding", "snappy").
+		SetHeader("X-Prometheus-Remote-Write-Version", "0.1.0").
+		SetBody(body)
+	if sign {
+		req.SetHeader("HashSHA256", hex.EncodeToString(secure.Hash(body, config.Keys.Signing().Secret)))
+	}
+
+	resp, err := req.Post("/api/v1/write")
+	s.Require().NoError(err)
+	return resp
+}
+
+func (s *RemoteWriteHandlerSuite) TestRemoteWriteHandler() {
+	body := snappy.Encode(nil, remotewrite.Marshal(&remotewrite.WriteRequest{
+		Timeseries: []remotewrite.TimeSeries{
+			{
+				Labels:  []remotewrite.Label{{Name: "__name__", Value: "node_load1"}, {Name: "instance", Value: "web1"}},
+				Samples: []remotewrite.Sample{{Value: 0.75, Timestamp: 1700000000000}},
+			},
+			{
+				Labels:  []remotewrite.Label{{Name: "__name__", Value: "http_requests_total"}},
+				Samples: []remotewrite.Sample{{Value: 42, Timestamp: 1700000000000}},
+			},
+		},
+	}))
+
+	resp := s.write(body, true)
+	s.Require().Equal(http.StatusNoContent, resp.StatusCode())
+
+	v, ok := config.Storage.GaugeValueContext(context.Background(), "node_load1", metrics.Labels{"instance": "web1"})
+	s.Require().True(ok)
+	s.Equal(0.75, v)
+
+	c, ok := config.Storage.CounterValue("http_requests_total")
+	s.Require().True(ok)
+	s.Equal(int64(42), c)
+}
+
+func (s *RemoteWriteHandlerSuite) TestRemoteWriteHandler_Errors() {
+	// Неверная подпись
+	body := snappy.Encode(nil, remotewrite.Marshal(&remotewrite.WriteRequest{}))
+	resp, err := s.client.R().SetHeader("HashSHA256", "00").SetBody(body).Post("/api/v1/write")
+	s.Require().NoError(err)
+	s.Equal(http.StatusBadRequest, resp.StatusCode())
+
+	// Данные не сжаты snappy
+	resp = s.write([]byte("not snappy"), true)
+	s.Equal(http.StatusBadRequest, resp.StatusCode())
+
+	// Серия без имени метрики
+	body = snappy.Encode(nil, remotewrite.Marshal(&remotewrite.WriteRequest{
+		Timeseries: []remotewrite.TimeSeries{{Labels: []remotewrite.Label{{Name: "job", Value: "api"}}, Samples: []remotewrite.Sample{{Value: 1}}}},
+	}))
+	resp = s.write(body, false)
+	s.Equal(http.StatusBadRequest, resp.StatusCode())
+}
+
+func (s *RemoteWriteHandlerSuite) TestRemoteWriteHandler_DecompressedSize() {
+	config.MaxDecompressedSize = 1024
+	defer func() { config.MaxDecompressedSize = 0 }()
+
+	// Заголовок snappy обещает почти 4 ГБ распакованных данных, память под них не выделяется
+	resp := s.write([]byte{0x80, 0x80, 0x80, 0x80, 0x0F, 0x00}, true)
+	s.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode())
+
+	body := snappy.Encode(nil, remotewrite.Marshal(&remotewrite.WriteRequest{
+		Timeseries: []remotewrite.TimeSeries{{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "node_load1"}},
+			Samples: []remotewrite.Sample{{Value: 0.75, Timestamp: 1700000000000}},
+		}},
+	}))
+	resp = s.write(body, true)
+	s.Equal(http.StatusNoContent, resp.StatusCode())
+}
+
+func (s *RemoteWriteHandlerSuite) TestMaxBatchSize() {
+	config.MaxBatchSize = 1
+	defer func() { config.MaxBatchSize = 0 }()
+
+	body := snappy.Encode(nil, remotewrite.Marshal(&remotewrite.WriteRequest{
+		Timeseries: []remotewrite.TimeSeries{
+			{Labels: []remotewrite.Label{{Name: "__name__", Value: "node_load1"}}, Samples: []remotewrite.Sample{{Value: 0.75}}},
+			{Labels: []remotewrite.Label{{Name: "__name__", Value: "node_load5"}}, Samples: []remotewrite.Sample{{Value: 0.5}}},
+		},
+	}))
+	resp := s.write(body, true)
+	s.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode())
+
+	_, ok := config.Storage.GaugeValue("node_load1")
+	s.False(ok)
+}
+
+func TestRemoteWriteHandlerSuite(t *testing.T) {
+	suite.Run(t, new(RemoteWriteHandlerSuite))
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"net/http"
+	"strings"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/fishus/go-advanced-metrics/internal/auth"
 	mw "github.com/fishus/go-advanced-metrics/internal/handlers/middleware"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 )
@@ -11,25 +15,103 @@ import (
 func ServerRouter() chi.Router {
 	r := chi.NewRouter()
 
-	r.Use(middleware.RealIP)
+	r.Use(mw.RealIP(config.TrustedProxies))
+	r.Use(mw.Identity)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
+	// Отклонённые запросы записи и администрирования тоже записываются в журнал аудита
+	r.Use(mw.Audit(config.Audit, auditRoute))
+	// Запросы записи ограничиваются до чтения и распаковки тела
+	r.Use(mw.RateLimit(config.RateLimiter, writeRoute))
+	r.Use(mw.MaxBodySize(config.MaxBodySize))
 	r.Use(mw.Decompress)
-	r.Use(mw.TrustedSubnet(config.TrustedSubnet))
-	r.Use(mw.Decrypt(config.PrivateKey))
-	r.Use(mw.ValidateSign([]byte(config.SecretKey)))
-	r.Use(mw.Sign([]byte(config.SecretKey)))
-	r.Use(middleware.Compress(9, "application/json", "text/html"))
+	r.Use(mw.MaxBodySize(config.MaxDecompressedSize))
+	r.Use(mw.TrustedSubnet(config.TrustedSubnets))
+	r.Use(mw.Sign(config.Keys))
+	r.Use(middleware.Compress(9, "application/json", "text/html", "text/plain", "application/openmetrics-text", "application/x-protobuf"))
 	r.Use(middleware.RequestLogger(&logger.LogFormatter{}))
 
-	r.Mount("/debug", middleware.Profiler())
+	r.Group(func(r chi.Router) {
+		r.Use(mw.RequireScope(config.Tokens, auth.ScopeAdmin))
+		r.Use(mw.ValidateSign(config.Keys, config.ReplayGuard))
+
+		r.Mount("/debug", middleware.Profiler())
+		r.Get("/api/v1/audit", AuditHandler)
+
+		// Без токенов удаление метрик недоступно
+		r.Group(func(r chi.Router) {
+			r.Use(mw.RequireRegistry(config.Tokens))
+
+			r.Delete("/value/", DeleteMetricsHandler)
+			r.Delete("/value/{metricType}/{metricID}", DeleteMetricHandler)
+		})
+	})
 
-	r.Post("/update/", UpdateMetricsHandler)
-	r.Post("/updates/", UpdatesMetricsHandler)
-	r.Post("/update/{metricType}/{metricID}/{metricValue}", UpdateMetricHandler)
-	r.Post("/value/", ValueMetricsHandler)
-	r.Get("/value/{metricType}/{metricID}", ValueMetricHandler)
-	r.Get("/", ListHandler)
 	r.Get("/ping", PingDBHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(mw.RequireScope(config.Tokens, auth.ScopeWrite))
+		r.Use(mw.RateLimitToken(config.RateLimiter))
+
+		// Запросы агента расшифровываются до проверки подписи и должны быть подписаны, если на сервере задан ключ
+		r.Group(func(r chi.Router) {
+			r.Use(mw.Decrypt(config.PrivateKey))
+			r.Use(mw.ValidateSign(config.Keys, config.ReplayGuard))
+			r.Use(mw.RequireSign(config.Keys))
+
+			r.Post("/update/", UpdateMetricsHandler)
+			r.Post("/updates/", UpdatesMetricsHandler)
+			r.Post("/update/{metricType}/{metricID}/{metricValue}", UpdateMetricHandler)
+		})
+
+		// Сторонние клиенты не шифруют запросы
+		r.Group(func(r chi.Router) {
+			r.Use(mw.ValidateSign(config.Keys, config.ReplayGuard))
+
+			r.Post("/api/v1/write", RemoteWriteHandler)
+			r.Post("/api/v2/write", InfluxWriteHandler)
+			r.Post("/v1/metrics", OTLPMetricsHandler)
+		})
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(mw.RequireScope(config.Tokens, auth.ScopeRead))
+		r.Use(mw.ValidateSign(config.Keys, config.ReplayGuard))
+
+		r.Post("/value/", ValueMetricsHandler)
+		r.Get("/value/{metricType}/{metricID}", ValueMetricHandler)
+		r.Get("/", ListHandler)
+		r.Get("/metrics", PrometheusHandler)
+		r.Get("/api/v1/query_range", QueryRangeHandler)
+		r.Get("/api/v1/alerts", AlertsHandler)
+	})
 	return r
 }
+
+// writeRoute reports whether the request is sent to one of the write routes.
+func writeRoute(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch r.URL.Path {
+	case "/updates/", "/api/v1/write", "/api/v2/write", "/v1/metrics":
+		return true
+	}
+	return strings.HasPrefix(r.URL.Path, "/update/")
+}
+
+// adminRoute reports whether the request is sent to one of the admin routes.
+func adminRoute(r *http.Request) bool {
+	switch {
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/value/"):
+		return true
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/audit":
+		return true
+	}
+	return r.URL.Path == "/debug" || strings.HasPrefix(r.URL.Path, "/debug/")
+}
+
+// auditRoute reports whether the request is recorded to the audit log.
+func auditRoute(r *http.Request) bool {
+	return writeRoute(r) || adminRoute(r)
+}
@@ -5,25 +5,58 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/fishus/go-advanced-metrics/internal/lineprotocol"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/otlp"
+	"github.com/fishus/go-advanced-metrics/internal/remotewrite"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
 )
 
 type server struct {
 	server *http.Server
 }
 
+// ingestion holds the receivers of the ingestion protocols.
+// They keep the last values of the cumulative counters between the requests.
+type ingestion struct {
+	remoteWrite *remotewrite.Receiver
+	influx      *lineprotocol.Writer
+	otlp        *otlp.Receiver
+}
+
+var receivers ingestion
+
+func newIngestion(storage store.MetricsStorager) ingestion {
+	return ingestion{
+		remoteWrite: remotewrite.NewReceiver(storage),
+		influx:      lineprotocol.NewWriter(storage),
+		otlp:        otlp.NewReceiver(storage),
+	}
+}
+
 func NewServer(cfg Config) *server {
 	config = cfg
-	return &server{}
+	receivers = newIngestion(config.Storage)
+	return &server{
+		server: &http.Server{Addr: config.ServerAddr, Handler: ServerRouter(), TLSConfig: config.TLSConfig},
+	}
 }
 
-func (s *server) Run(ctx context.Context) {
-	s.server = &http.Server{Addr: config.ServerAddr, Handler: ServerRouter()}
-	logger.Log.Info("Running rest server", logger.String("address", config.ServerAddr), logger.String("event", "start server"))
-	err := s.server.ListenAndServe()
+// Run serves the requests until the server is shut down.
+// The server accepts only TLS connections when the TLS configuration is set.
+func (s *server) Run(ctx context.Context) error {
+	logger.Log.Info("Running rest server", logger.String("address", s.server.Addr), logger.Bool("tls", s.server.TLSConfig != nil), logger.String("event", "start server"))
+
+	var err error
+	if s.server.TLSConfig != nil {
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
-		logger.Log.Error(err.Error(), logger.String("event", "start server"))
+		return err
 	}
+	return nil
 }
 
 func (s *server) Shutdown(ctx context.Context) error {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/fishus/go-advanced-metrics/internal/audit"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
 	store "github.com/fishus/go-advanced-metrics/internal/storage"
@@ -14,11 +15,15 @@ import (
 
 // UpdateMetricHandler processes the request POST /update/{metricType}/{metricID}/{metricValue}.
 // Receives metric data and stores its value.
+// Series labels can be passed in the query string.
+// For a histogram the value is a single observation; a new series gets metrics.DefaultHistogramBounds.
 func UpdateMetricHandler(w http.ResponseWriter, r *http.Request) {
 	var metric metrics.Metrics
 
 	metric.ID = chi.URLParam(r, "metricID")
 	metric.MType = chi.URLParam(r, "metricType")
+	metric.Labels = labelsFromQuery(r)
+	audit.SetMetrics(r.Context(), 1)
 
 	switch metric.MType {
 	case metrics.TypeCounter:
@@ -32,6 +37,19 @@ func UpdateMetricHandler(w http.ResponseWriter, r *http.Request) {
 		if val, err := strconv.ParseFloat(v, 64); v != "" && err == nil {
 			metric = metric.SetValue(val)
 		}
+
+	case metrics.TypeHistogram:
+		v := chi.URLParam(r, "metricValue")
+		if val, err := strconv.ParseFloat(v, 64); v != "" && err == nil {
+			bounds := metrics.DefaultHistogramBounds
+			if h, ok := config.Storage.HistogramContext(r.Context(), metric.ID, metric.Labels); ok {
+				bounds = h.Value().Bounds
+			}
+			if hv, err := metrics.NewHistogramValue(bounds); err == nil {
+				hv.Observe(val)
+				metric = metric.SetHistogram(hv)
+			}
+		}
 	}
 
 	if err := ValidateInputMetric(metric); err != nil {
@@ -47,14 +65,21 @@ func UpdateMetricHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch metric.MType {
 	case metrics.TypeCounter:
-		err := config.Storage.AddCounterContext(r.Context(), metric.ID, *metric.Delta)
+		err := config.Storage.AddCounterContext(r.Context(), metric.ID, metric.Labels, *metric.Delta)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			logger.Log.Debug(err.Error(), logger.Any("metric", metric))
 			return
 		}
 	case metrics.TypeGauge:
-		err := config.Storage.SetGaugeContext(r.Context(), metric.ID, *metric.Value)
+		err := config.Storage.SetGaugeContext(r.Context(), metric.ID, metric.Labels, *metric.Value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			logger.Log.Debug(err.Error(), logger.Any("metric", metric))
+			return
+		}
+	case metrics.TypeHistogram:
+		err := config.Storage.AddHistogramContext(r.Context(), metric.ID, metric.Labels, *metric.Histogram)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			logger.Log.Debug(err.Error(), logger.Any("metric", metric))
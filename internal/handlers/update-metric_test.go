@@ -41,6 +41,18 @@ func TestUpdateMetricHandler(t *testing.T) {
 			url:    "/update/gauge/someMetric/12.34",
 			status: http.StatusOK,
 		},
+		{
+			name:   "Positive case: Histogram",
+			method: http.MethodPost,
+			url:    "/update/histogram/someMetric/0.3",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Negative case: Incorrect histogram value",
+			method: http.MethodPost,
+			url:    "/update/histogram/someMetric/none",
+			status: http.StatusBadRequest,
+		},
 		{
 			name:   "Negative case: Method Get",
 			method: http.MethodGet,
@@ -110,7 +122,7 @@ func TestUpdateMetricHandler(t *testing.T) {
 		{
 			name:   "Negative case: Incorrect metric type",
 			method: http.MethodPost,
-			url:    "/update/histogram/someMetric/1",
+			url:    "/update/summary/someMetric/1",
 			status: http.StatusBadRequest,
 		},
 		{
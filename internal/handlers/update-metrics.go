@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/fishus/go-advanced-metrics/internal/audit"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
 )
@@ -19,6 +20,8 @@ func UpdateMetricsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.SetMetrics(r.Context(), 1)
+
 	m, code, err := Controller.UpdateMetrics(r.Context(), metric)
 	if err != nil {
 		logger.Log.Debug(err.Error(), logger.Any("metric", metric))
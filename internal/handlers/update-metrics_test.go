@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/fishus/go-advanced-metrics/internal/controller"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
 	store "github.com/fishus/go-advanced-metrics/internal/storage"
 )
 
@@ -31,6 +32,7 @@ func (s *UpdateMetricsHandlerSuite) SetupSubTest() {
 	config.Storage = store.NewMemStorage()
 	_ = config.Storage.AddCounter("a", 7)
 	_ = config.Storage.SetGauge("a", 11.15)
+	_ = config.Storage.AddHistogram("h", metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{1, 0, 0}, Count: 1, Sum: 0.5})
 	controller.Storage = config.Storage
 }
 
@@ -87,6 +89,24 @@ func (s *UpdateMetricsHandlerSuite) TestUpdateMetricsHandler() {
 			want:   `{"id":"a", "type":"gauge", "value":12.34}`,
 			status: http.StatusOK,
 		},
+		{
+			name:   "Positive case: Histogram merge",
+			input:  `{"id":"h", "type":"histogram", "histogram":{"bounds":[1,5], "counts":[0,2,1], "count":3, "sum":13}}`,
+			want:   `{"id":"h", "type":"histogram", "histogram":{"bounds":[1,5], "counts":[1,2,1], "count":4, "sum":13.5}}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Negative case: Histogram bounds mismatch",
+			input:  `{"id":"h", "type":"histogram", "histogram":{"bounds":[1,10], "counts":[0,2,1], "count":3, "sum":13}}`,
+			want:   "",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Negative case: Incorrect histogram count",
+			input:  `{"id":"h", "type":"histogram", "histogram":{"bounds":[1,5], "counts":[0,2,1], "count":2, "sum":13}}`,
+			want:   "",
+			status: http.StatusBadRequest,
+		},
 		{
 			name:   "Negative case: Metric name not specified",
 			input:  `{"id":"", "type":"counter", "delta":11, "value":12.34}`,
@@ -101,7 +121,7 @@ func (s *UpdateMetricsHandlerSuite) TestUpdateMetricsHandler() {
 		},
 		{
 			name:   "Negative case: Incorrect metric type",
-			input:  `{"id":"a", "type":"histogram", "delta":11, "value":12.34}`,
+			input:  `{"id":"a", "type":"summary", "delta":11, "value":12.34}`,
 			want:   "",
 			status: http.StatusBadRequest,
 		},
@@ -2,12 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/fishus/go-advanced-metrics/internal/audit"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
 )
 
+// checkBatchSize returns the error if the batch of n metrics exceeds the limit of the server.
+func checkBatchSize(n int) error {
+	if config.MaxBatchSize > 0 && n > config.MaxBatchSize {
+		return fmt.Errorf("Batch of %d metrics exceeds the limit of %d", n, config.MaxBatchSize)
+	}
+	return nil
+}
+
 // UpdatesMetricsHandler processes the request POST /updates/.
 // Receives a batch of metrics data in JSON format and stores their values.
 func UpdatesMetricsHandler(w http.ResponseWriter, r *http.Request) {
@@ -19,6 +29,13 @@ func UpdatesMetricsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.SetMetrics(r.Context(), len(metricsBatch))
+
+	if err := checkBatchSize(len(metricsBatch)); err != nil {
+		JSONError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
 	mb, code, err := Controller.UpdatesMetrics(r.Context(), metricsBatch)
 	if err != nil {
 		logger.Log.Debug(err.Error(), logger.Any("metrics", mb))
@@ -107,7 +107,7 @@ func (s *UpdatesMetricsHandlerSuite) TestUpdatesMetricsHandler() {
 		},
 		{
 			name:   "Negative case: Incorrect metric type",
-			input:  `[{"id":"a", "type":"histogram", "delta":11, "value":12.34}]`,
+			input:  `[{"id":"a", "type":"summary", "delta":11, "value":12.34}]`,
 			want:   "[]",
 			status: http.StatusBadRequest,
 		},
@@ -163,6 +163,24 @@ func (s *UpdatesMetricsHandlerSuite) TestUpdatesMetricsHandler() {
 	}
 }
 
+func (s *UpdatesMetricsHandlerSuite) TestMaxBatchSize() {
+	config.MaxBatchSize = 2
+	defer func() { config.MaxBatchSize = 0 }()
+
+	s.Run("Batch within the limit", func() {
+		resp := s.requestUpdate([]byte(`[{"id":"a", "type":"counter", "delta":1},{"id":"b", "type":"counter", "delta":2}]`))
+		s.Equal(http.StatusOK, resp.StatusCode())
+	})
+
+	s.Run("Batch exceeds the limit", func() {
+		resp := s.requestUpdate([]byte(`[{"id":"a", "type":"counter", "delta":1},{"id":"b", "type":"counter", "delta":2},{"id":"c", "type":"counter", "delta":3}]`))
+		s.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode())
+
+		_, ok := config.Storage.CounterValue("c")
+		s.False(ok)
+	})
+}
+
 func TestUpdatesMetricsHandlerSuite(t *testing.T) {
 	suite.Run(t, new(UpdatesMetricsHandlerSuite))
 }
@@ -48,10 +48,22 @@ func ValidateInputMetric(metric metrics.Metrics) error {
 		if metric.Value == nil {
 			return NewValidMetricError(http.StatusBadRequest, errors.New(`incorrect gauge value`))
 		}
+	case metrics.TypeHistogram:
+		if metric.Histogram == nil {
+			return NewValidMetricError(http.StatusBadRequest, errors.New(`incorrect histogram value`))
+		}
+		if err := metric.Histogram.Validate(); err != nil {
+			return NewValidMetricError(http.StatusBadRequest, err)
+		}
 	default:
 		// При попытке передать запрос с некорректным типом метрики http.StatusBadRequest.
 		return NewValidMetricError(http.StatusBadRequest, errors.New(`incorrect metric type`))
 	}
 
+	// При попытке передать метки с некорректными именами http.StatusBadRequest.
+	if err := metric.Labels.Validate(); err != nil {
+		return NewValidMetricError(http.StatusBadRequest, err)
+	}
+
 	return nil
 }
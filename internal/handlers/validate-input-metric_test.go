@@ -65,7 +65,7 @@ func TestValidateInputMetric(t *testing.T) {
 			name: "Negative case: wrong type",
 			metric: metrics.Metrics{
 				ID:    "PollCount",
-				MType: "histogram",
+				MType: "summary",
 				Delta: func() *int64 {
 					v := new(int64)
 					*v = 2
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,12 +14,14 @@ import (
 )
 
 // ValueMetricHandler processes the request GET /value/{metricType}/{metricID}.
-// Returns the metric value.
+// Returns the metric value. The value of a histogram is returned in JSON format.
+// Series labels can be passed in the query string.
 func ValueMetricHandler(w http.ResponseWriter, r *http.Request) {
 	var metricType, metricID string
 
 	metricType = chi.URLParam(r, "metricType")
 	metricID = chi.URLParam(r, "metricID")
+	labels := labelsFromQuery(r)
 
 	// При попытке передать запрос с некорректным типом метрики http.StatusBadRequest.
 	if metricType == "" {
@@ -34,10 +37,10 @@ func ValueMetricHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch metricType {
 	case metrics.TypeCounter:
-		Counter, ok := config.Storage.CounterContext(r.Context(), metricID)
+		Counter, ok := config.Storage.CounterContext(r.Context(), metricID, labels)
 		if !ok {
 			// При попытке запроса неизвестной метрики сервер должен возвращать http.StatusNotFound.
-			http.Error(w, fmt.Sprintf(`Counter '%s' not found`, metricID), http.StatusNotFound)
+			http.Error(w, fmt.Sprintf(`Counter '%s' not found`, metrics.SeriesKey(metricID, labels)), http.StatusNotFound)
 			return
 		}
 
@@ -49,10 +52,10 @@ func ValueMetricHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case metrics.TypeGauge:
-		Gauge, ok := config.Storage.GaugeContext(r.Context(), metricID)
+		Gauge, ok := config.Storage.GaugeContext(r.Context(), metricID, labels)
 		if !ok {
 			// При попытке запроса неизвестной метрики сервер должен возвращать http.StatusNotFound.
-			http.Error(w, fmt.Sprintf(`Gauge '%s' not found`, metricID), http.StatusNotFound)
+			http.Error(w, fmt.Sprintf(`Gauge '%s' not found`, metrics.SeriesKey(metricID, labels)), http.StatusNotFound)
 			return
 		}
 
@@ -63,6 +66,20 @@ func ValueMetricHandler(w http.ResponseWriter, r *http.Request) {
 			logger.Log.Error(err.Error(), logger.String("event", "value metric handler"), logger.Float64("value", Gauge.Value()))
 		}
 
+	case metrics.TypeHistogram:
+		Histogram, ok := config.Storage.HistogramContext(r.Context(), metricID, labels)
+		if !ok {
+			// При попытке запроса неизвестной метрики сервер должен возвращать http.StatusNotFound.
+			http.Error(w, fmt.Sprintf(`Histogram '%s' not found`, metrics.SeriesKey(metricID, labels)), http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(Histogram.Value()); err != nil {
+			logger.Log.Error(err.Error(), logger.String("event", "value metric handler"), logger.Any("value", Histogram.Value()))
+		}
+
 	default:
 		// При попытке передать запрос с некорректным типом метрики http.StatusBadRequest.
 		http.Error(w, `Incorrect metric type`, http.StatusBadRequest)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
 	store "github.com/fishus/go-advanced-metrics/internal/storage"
 )
 
@@ -30,6 +32,7 @@ func (s *ValueMetricHandlerSuite) SetupTest() {
 	config.Storage = store.NewMemStorage()
 	_ = config.Storage.AddCounter("a", 5)
 	_ = config.Storage.SetGauge("a", 1.5)
+	_ = config.Storage.SetGaugeContext(context.Background(), "a", metrics.Labels{"host": "web1"}, 2.5)
 }
 
 func (s *ValueMetricHandlerSuite) requestValue(url string) *resty.Response {
@@ -59,6 +62,18 @@ func (s *ValueMetricHandlerSuite) TestValueMetricHandler() {
 			want:   "1.5",
 			status: http.StatusOK,
 		},
+		{
+			name:   "Positive case: Gauge with labels",
+			url:    "/value/gauge/a?host=web1",
+			want:   "2.5",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Positive case: Gauge with unknown labels",
+			url:    "/value/gauge/a?host=web2",
+			want:   "",
+			status: http.StatusNotFound,
+		},
 		{
 			name:   "Positive case: Counter not found",
 			url:    "/value/counter/x",
@@ -73,7 +88,7 @@ func (s *ValueMetricHandlerSuite) TestValueMetricHandler() {
 		},
 		{
 			name:   "Negative case: Incorrect metric type",
-			url:    "/value/histogram/h",
+			url:    "/value/summary/h",
 			want:   "",
 			status: http.StatusBadRequest,
 		},
@@ -36,23 +36,32 @@ func ValueMetricsHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch metric.MType {
 	case metrics.TypeCounter:
-		counterValue, ok := config.Storage.CounterValueContext(r.Context(), metric.ID)
+		counterValue, ok := config.Storage.CounterValueContext(r.Context(), metric.ID, metric.Labels)
 		if !ok {
 			// При попытке запроса неизвестной метрики сервер должен возвращать http.StatusNotFound.
-			JSONError(w, fmt.Sprintf(`Counter '%s' not found`, metric.ID), http.StatusNotFound)
-			logger.Log.Debug(fmt.Sprintf(`Counter '%s' not found`, metric.ID), logger.Any("metric", metric))
+			JSONError(w, fmt.Sprintf(`Counter '%s' not found`, metric.Key()), http.StatusNotFound)
+			logger.Log.Debug(fmt.Sprintf(`Counter '%s' not found`, metric.Key()), logger.Any("metric", metric))
 			return
 		}
 		metric = metric.SetDelta(counterValue)
 	case metrics.TypeGauge:
-		gaugeValue, ok := config.Storage.GaugeValueContext(r.Context(), metric.ID)
+		gaugeValue, ok := config.Storage.GaugeValueContext(r.Context(), metric.ID, metric.Labels)
 		if !ok {
 			// При попытке запроса неизвестной метрики сервер должен возвращать http.StatusNotFound.
-			JSONError(w, fmt.Sprintf(`Gauge '%s' not found`, metric.ID), http.StatusNotFound)
-			logger.Log.Debug(fmt.Sprintf(`Gauge '%s' not found`, metric.ID), logger.Any("metric", metric))
+			JSONError(w, fmt.Sprintf(`Gauge '%s' not found`, metric.Key()), http.StatusNotFound)
+			logger.Log.Debug(fmt.Sprintf(`Gauge '%s' not found`, metric.Key()), logger.Any("metric", metric))
 			return
 		}
 		metric = metric.SetValue(gaugeValue)
+	case metrics.TypeHistogram:
+		histogram, ok := config.Storage.HistogramContext(r.Context(), metric.ID, metric.Labels)
+		if !ok {
+			// При попытке запроса неизвестной метрики сервер должен возвращать http.StatusNotFound.
+			JSONError(w, fmt.Sprintf(`Histogram '%s' not found`, metric.Key()), http.StatusNotFound)
+			logger.Log.Debug(fmt.Sprintf(`Histogram '%s' not found`, metric.Key()), logger.Any("metric", metric))
+			return
+		}
+		metric = metric.SetHistogram(histogram.Value())
 	default:
 		// При попытке передать запрос с некорректным типом метрики http.StatusBadRequest.
 		JSONError(w, `Incorrect metric type`, http.StatusBadRequest)
@@ -87,7 +87,7 @@ func (s *ValueMetricsHandlerSuite) TestValueMetricsHandler() {
 		},
 		{
 			name:   "Negative case: Incorrect metric type",
-			input:  `{"id":"a", "type":"histogram"}`,
+			input:  `{"id":"a", "type":"summary"}`,
 			want:   "",
 			status: http.StatusBadRequest,
 		},
@@ -0,0 +1,64 @@
+// Package identity describes the client authenticated by its TLS certificate.
+// The identity is put into the request context by the REST middleware and the gRPC interceptors
+// so that the handlers can authorize the requests.
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// Identity is the identity of the client taken from its verified certificate.
+type Identity struct {
+	Name     string   // CN сертификата, а если он пуст — первое имя из SAN
+	DNSNames []string // DNS-имена из SAN
+	URIs     []string // URI из SAN, например spiffe://metrics/agent/web1
+	Emails   []string // Адреса электронной почты из SAN
+}
+
+// FromCertificate returns the identity of the certificate.
+func FromCertificate(cert *x509.Certificate) Identity {
+	id := Identity{
+		Name:     cert.Subject.CommonName,
+		DNSNames: cert.DNSNames,
+		Emails:   cert.EmailAddresses,
+	}
+	for _, uri := range cert.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+
+	if id.Name == "" {
+		switch {
+		case len(id.DNSNames) > 0:
+			id.Name = id.DNSNames[0]
+		case len(id.URIs) > 0:
+			id.Name = id.URIs[0]
+		case len(id.Emails) > 0:
+			id.Name = id.Emails[0]
+		}
+	}
+	return id
+}
+
+// FromConnectionState returns the identity of the client certificate verified during the handshake.
+// It returns false when the client has not presented a certificate or it was not verified.
+func FromConnectionState(state tls.ConnectionState) (Identity, bool) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Identity{}, false
+	}
+	return FromCertificate(state.VerifiedChains[0][0]), true
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of the context with the identity.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity of the client stored in the context.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,63 @@
+package identity
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromCertificate(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://metrics/agent/web1")
+
+	testCases := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{
+			name: "Common name",
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}, DNSNames: []string{"web1.example.com"}},
+			want: "agent-1",
+		},
+		{
+			name: "DNS name",
+			cert: &x509.Certificate{DNSNames: []string{"web1.example.com"}, URIs: []*url.URL{spiffe}},
+			want: "web1.example.com",
+		},
+		{
+			name: "URI",
+			cert: &x509.Certificate{URIs: []*url.URL{spiffe}, EmailAddresses: []string{"ops@example.com"}},
+			want: "spiffe://metrics/agent/web1",
+		},
+		{
+			name: "Email",
+			cert: &x509.Certificate{EmailAddresses: []string{"ops@example.com"}},
+			want: "ops@example.com",
+		},
+		{
+			name: "No names",
+			cert: &x509.Certificate{},
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, FromCertificate(tc.cert).Name)
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewContext(context.Background(), Identity{Name: "agent-1"})
+	id, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "agent-1", id.Name)
+}
@@ -0,0 +1,10 @@
+// Package lineprotocol implements the receiver of the InfluxDB line protocol.
+//
+// Each line has the form measurement[,tag=value...] field=value[,field=value...] [timestamp].
+// A field becomes the metric named measurement_field, the field named value becomes
+// the metric named after the measurement. The tags become series labels.
+//
+// Float and boolean fields are stored as gauges. Integer and unsigned fields are
+// cumulative counters, their increments are added to the stored counters. String
+// fields are ignored.
+package lineprotocol
@@ -0,0 +1,270 @@
+package lineprotocol
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// FieldType is the type of the field value.
+type FieldType int
+
+const (
+	FieldFloat FieldType = iota
+	FieldInteger
+	FieldUnsigned
+	FieldBoolean
+	FieldString
+)
+
+// Field is a field of the point.
+type Field struct {
+	Key   string
+	Str   string // Значение строкового поля
+	Value float64
+	Type  FieldType
+}
+
+// Point is a parsed line.
+type Point struct {
+	Time        time.Time
+	Tags        metrics.Labels
+	Measurement string
+	Fields      []Field
+	Line        int // Номер строки в запросе
+}
+
+// Precision is the precision of the timestamps.
+type Precision string
+
+const (
+	PrecisionNanoseconds  Precision = "ns"
+	PrecisionMicroseconds Precision = "us"
+	PrecisionMilliseconds Precision = "ms"
+	PrecisionSeconds      Precision = "s"
+)
+
+var (
+	ErrInvalidLine      = errors.New("invalid line")
+	ErrInvalidPrecision = errors.New("invalid precision")
+)
+
+// ParsePrecision parses the precision, the empty string means nanoseconds.
+func ParsePrecision(s string) (Precision, error) {
+	switch p := Precision(s); p {
+	case "":
+		return PrecisionNanoseconds, nil
+	case PrecisionNanoseconds, PrecisionMicroseconds, PrecisionMilliseconds, PrecisionSeconds:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidPrecision, s)
+}
+
+func (p Precision) unit() time.Duration {
+	switch p {
+	case PrecisionMicroseconds:
+		return time.Microsecond
+	case PrecisionMilliseconds:
+		return time.Millisecond
+	case PrecisionSeconds:
+		return time.Second
+	}
+	return time.Nanosecond
+}
+
+// ParseError is the error of parsing a line.
+type ParseError struct {
+	Err  error
+	Line int
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrInvalidLine
+}
+
+// Parse parses the lines of the request body. The points without a timestamp get the time now.
+// All the parse errors are joined into the returned error.
+func Parse(data string, precision Precision, now time.Time) ([]Point, error) {
+	points := make([]Point, 0)
+	errs := make([]error, 0)
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := ParseLine(line, precision, now)
+		if err != nil {
+			errs = append(errs, &ParseError{Line: i + 1, Err: err})
+			continue
+		}
+		p.Line = i + 1
+		points = append(points, p)
+	}
+
+	return points, errors.Join(errs...)
+}
+
+// ParseLine parses the line measurement[,tags] fields [timestamp].
+func ParseLine(line string, precision Precision, now time.Time) (Point, error) {
+	p := Point{Time: now}
+
+	measurement, i := scanToken(line, 0, ", ")
+	if measurement == "" {
+		return p, errors.New("missing measurement")
+	}
+	p.Measurement = unescape(measurement)
+
+	tags := metrics.Labels{}
+	for i < len(line) && line[i] == ',' {
+		var tag string
+		tag, i = scanToken(line, i+1, ", ")
+		key, value, ok := cutUnescaped(tag, '=')
+		if !ok || key == "" || value == "" {
+			return p, fmt.Errorf("invalid tag %q", tag)
+		}
+		tags[unescape(key)] = unescape(value)
+	}
+	if err := tags.Validate(); err != nil {
+		return p, err
+	}
+	p.Tags = tags.Clone()
+
+	if i >= len(line) {
+		return p, errors.New("missing fields")
+	}
+	i++ // Пробел после тегов
+
+	for {
+		key, next := scanToken(line, i, "=, ")
+		if key == "" || next >= len(line) || line[next] != '=' {
+			return p, fmt.Errorf("invalid field %q", key)
+		}
+
+		f, next, err := parseFieldValue(line, next+1)
+		if err != nil {
+			return p, fmt.Errorf("invalid value of field %q: %w", unescape(key), err)
+		}
+		f.Key = unescape(key)
+		p.Fields = append(p.Fields, f)
+
+		i = next
+		if i >= len(line) || line[i] != ',' {
+			break
+		}
+		i++
+	}
+
+	if i < len(line) {
+		ts := strings.TrimSpace(line[i:])
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return p, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		p.Time = time.Unix(0, 0).Add(time.Duration(n) * precision.unit())
+	}
+
+	return p, nil
+}
+
+// parseFieldValue parses the value of the field starting at the position i.
+func parseFieldValue(line string, i int) (f Field, next int, err error) {
+	if i < len(line) && line[i] == '"' {
+		var sb strings.Builder
+		for j := i + 1; j < len(line); j++ {
+			switch c := line[j]; {
+			case c == '\\' && j+1 < len(line) && (line[j+1] == '"' || line[j+1] == '\\'):
+				sb.WriteByte(line[j+1])
+				j++
+			case c == '"':
+				return Field{Type: FieldString, Str: sb.String()}, j + 1, nil
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return f, len(line), errors.New("unterminated string")
+	}
+
+	raw, next := scanToken(line, i, ", ")
+	if raw == "" {
+		return f, next, errors.New("missing value")
+	}
+
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return Field{Type: FieldBoolean, Value: 1}, next, nil
+	case "f", "F", "false", "False", "FALSE":
+		return Field{Type: FieldBoolean, Value: 0}, next, nil
+	}
+
+	switch raw[len(raw)-1] {
+	case 'i':
+		v, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return f, next, err
+		}
+		return Field{Type: FieldInteger, Value: float64(v)}, next, nil
+	case 'u':
+		v, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return f, next, err
+		}
+		return Field{Type: FieldUnsigned, Value: float64(v)}, next, nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return f, next, err
+	}
+	return Field{Type: FieldFloat, Value: v}, next, nil
+}
+
+// scanToken returns the token from the position i to the first unescaped stop character and its position.
+func scanToken(line string, i int, stops string) (string, int) {
+	start := i
+	for i < len(line) {
+		if line[i] == '\\' && i+1 < len(line) {
+			i += 2
+			continue
+		}
+		if strings.IndexByte(stops, line[i]) >= 0 {
+			break
+		}
+		i++
+	}
+	return line[start:i], i
+}
+
+// cutUnescaped slices s around the first unescaped separator.
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	token, i := scanToken(s, 0, string(sep))
+	if i >= len(s) {
+		return s, "", false
+	}
+	return token, s[i+1:], true
+}
+
+// unescape removes the backslashes before the escaped characters.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`, ="\`, s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
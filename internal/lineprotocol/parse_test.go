@@ -0,0 +1,135 @@
+package lineprotocol
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestParseLine(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		line    string
+		want    Point
+		wantErr bool
+	}{
+		{
+			name: "Fields of all types",
+			line: `cpu,host=web1,region=eu usage=0.5,cores=8i,total=10u,up=true,model="Xeon, \"Gold\""`,
+			want: Point{
+				Time:        now,
+				Measurement: "cpu",
+				Tags:        metrics.Labels{"host": "web1", "region": "eu"},
+				Fields: []Field{
+					{Key: "usage", Type: FieldFloat, Value: 0.5},
+					{Key: "cores", Type: FieldInteger, Value: 8},
+					{Key: "total", Type: FieldUnsigned, Value: 10},
+					{Key: "up", Type: FieldBoolean, Value: 1},
+					{Key: "model", Type: FieldString, Str: `Xeon, "Gold"`},
+				},
+			},
+		},
+		{
+			name: "Without tags and with timestamp",
+			line: "load value=1.5 1704110400000000000",
+			want: Point{
+				Time:        time.Unix(1704110400, 0),
+				Measurement: "load",
+				Tags:        nil,
+				Fields:      []Field{{Key: "value", Type: FieldFloat, Value: 1.5}},
+			},
+		},
+		{
+			name: "Escaped characters",
+			line: `disk\ io,path=/var\,log read\ bytes=1i`,
+			want: Point{
+				Time:        now,
+				Measurement: "disk io",
+				Tags:        metrics.Labels{"path": "/var,log"},
+				Fields:      []Field{{Key: "read bytes", Type: FieldInteger, Value: 1}},
+			},
+		},
+		{
+			name:    "Without fields",
+			line:    "cpu,host=web1",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect field value",
+			line:    "cpu usage=abc",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect tag",
+			line:    "cpu,host usage=1",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect tag name",
+			line:    "cpu,1host=web1 usage=1",
+			wantErr: true,
+		},
+		{
+			name:    "Unterminated string",
+			line:    `cpu model="Xeon`,
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect timestamp",
+			line:    "cpu usage=1 yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseLine(tc.line, PrecisionNanoseconds, now)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tc.want.Time.Equal(p.Time))
+			p.Time = tc.want.Time
+			assert.Equal(t, tc.want, p)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := "# comment\ncpu usage=1 1704110400\n\nmem used=\ncpu usage=2 1704110460\ndisk free"
+
+	points, err := Parse(data, PrecisionSeconds, time.Now())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidLine)
+	assert.Contains(t, err.Error(), "line 4:")
+	assert.Contains(t, err.Error(), "line 6:")
+
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, 4, pe.Line)
+
+	require.Len(t, points, 2)
+	assert.Equal(t, 2, points[0].Line)
+	assert.True(t, time.Unix(1704110460, 0).Equal(points[1].Time))
+}
+
+func TestParsePrecision(t *testing.T) {
+	p, err := ParsePrecision("")
+	require.NoError(t, err)
+	assert.Equal(t, PrecisionNanoseconds, p)
+
+	p, err = ParsePrecision("ms")
+	require.NoError(t, err)
+	assert.Equal(t, PrecisionMilliseconds, p)
+
+	_, err = ParsePrecision("h")
+	assert.ErrorIs(t, err, ErrInvalidPrecision)
+}
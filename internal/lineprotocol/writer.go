@@ -0,0 +1,119 @@
+package lineprotocol
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+// UpdateFunc stores the batch of metrics.
+type UpdateFunc func(ctx context.Context, batch []metrics.Metrics) error
+
+// Writer converts the points into metrics.
+type Writer struct {
+	storage  store.MetricsStorager
+	counters *metrics.CumulativeCounters
+	mu       sync.Mutex
+}
+
+func NewWriter(storage store.MetricsStorager) *Writer {
+	return &Writer{
+		storage:  storage,
+		counters: metrics.NewCumulativeCounters(),
+	}
+}
+
+// Write converts the points into gauges and counter increments and stores them with update in one batch.
+// Returns the number of the stored series.
+func (wr *Writer) Write(ctx context.Context, points []Point, update UpdateFunc) (int, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	type value struct {
+		point *Point
+		value float64
+	}
+	type series struct {
+		labels  metrics.Labels
+		name    string
+		values  []value
+		counter bool
+	}
+
+	seriesMap := make(map[string]*series)
+	keys := make([]string, 0)
+
+	for i := range points {
+		p := &points[i]
+		for _, f := range p.Fields {
+			if f.Type == FieldString {
+				continue
+			}
+
+			name := MetricName(p.Measurement, f.Key)
+			counter := f.Type == FieldInteger || f.Type == FieldUnsigned
+			mtype := metrics.TypeGauge
+			if counter {
+				mtype = metrics.TypeCounter
+			}
+
+			key := mtype + " " + metrics.SeriesKey(name, p.Tags)
+			s, ok := seriesMap[key]
+			if !ok {
+				s = &series{name: name, labels: p.Tags, counter: counter}
+				seriesMap[key] = s
+				keys = append(keys, key)
+			}
+			s.values = append(s.values, value{point: p, value: f.Value})
+		}
+	}
+
+	batch := make([]metrics.Metrics, 0, len(keys))
+	last := make(map[string]float64)
+
+	for _, key := range keys {
+		s := seriesMap[key]
+		sort.SliceStable(s.values, func(i, j int) bool {
+			return s.values[i].point.Time.Before(s.values[j].point.Time)
+		})
+
+		if !s.counter {
+			batch = append(batch, metrics.NewGaugeMetric(s.name).WithLabels(s.labels).SetValue(s.values[len(s.values)-1].value))
+			continue
+		}
+
+		values := make([]float64, len(s.values))
+		for i, v := range s.values {
+			values[i] = v.value
+		}
+		delta := wr.counters.Delta(last, metrics.SeriesKey(s.name, s.labels), values, func() (float64, bool) {
+			c, found := wr.storage.CounterContext(ctx, s.name, s.labels)
+			return float64(c.Value()), found
+		})
+
+		batch = append(batch, metrics.NewCounterMetric(s.name).WithLabels(s.labels).SetDelta(int64(math.Round(delta))))
+	}
+
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	if err := update(ctx, batch); err != nil {
+		return 0, err
+	}
+
+	wr.counters.Commit(last)
+	return len(batch), nil
+}
+
+// MetricName returns the name of the metric of the field.
+func MetricName(measurement, field string) string {
+	if field == "value" {
+		return measurement
+	}
+	return measurement + "_" + field
+}
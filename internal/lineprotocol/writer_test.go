@@ -0,0 +1,63 @@
+package lineprotocol
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func TestWriter_Write(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+	require.NoError(t, storage.AddCounterContext(ctx, "net_bytes", metrics.Labels{"host": "web1"}, 100))
+
+	var batch []metrics.Metrics
+	update := func(ctx context.Context, b []metrics.Metrics) error {
+		batch = b
+		return nil
+	}
+
+	points, err := Parse("net,host=web1 bytes=150i,errors=0.5 2\nnet,host=web1 bytes=160i,errors=0.25 3\nnet,host=web1 bytes=90i 1\nnet,host=web1 state=\"up\"", PrecisionSeconds, time.Unix(10, 0))
+	require.NoError(t, err)
+
+	wr := NewWriter(storage)
+	n, err := wr.Write(ctx, points, update)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	got := make(map[string]metrics.Metrics)
+	for _, m := range batch {
+		got[m.MType+" "+m.ID] = m
+	}
+	// 100 -> 90 (сброс) -> 150 -> 160
+	assert.Equal(t, int64(90+60+10), *got["counter net_bytes"].Delta)
+	assert.Equal(t, 0.25, *got["gauge net_errors"].Value)
+
+	// Следующие значения считаются от последнего полученного
+	points, err = Parse("net,host=web1 bytes=170i", PrecisionNanoseconds, time.Now())
+	require.NoError(t, err)
+	_, err = wr.Write(ctx, points, update)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *batch[0].Delta)
+
+	// При ошибке записи последнее значение не меняется
+	points, err = Parse("net,host=web1 bytes=200i", PrecisionNanoseconds, time.Now())
+	require.NoError(t, err)
+	_, err = wr.Write(ctx, points, func(ctx context.Context, b []metrics.Metrics) error { return errors.New("failed") })
+	require.Error(t, err)
+	_, err = wr.Write(ctx, points, update)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), *batch[0].Delta)
+}
+
+func TestMetricName(t *testing.T) {
+	assert.Equal(t, "cpu_usage", MetricName("cpu", "usage"))
+	assert.Equal(t, "load", MetricName("load", "value"))
+}
@@ -7,16 +7,25 @@ import (
 
 // Counter implements the metric type Counter.
 type Counter struct {
-	name  string
-	value int64
+	labels Labels
+	name   string
+	value  int64
 }
 
 // NewCounter returns a pointer to the Counter structure.
 func NewCounter(name string, v int64) (*Counter, error) {
+	return NewCounterWithLabels(name, nil, v)
+}
+
+// NewCounterWithLabels returns a pointer to the Counter structure of a labeled series.
+func NewCounterWithLabels(name string, labels Labels, v int64) (*Counter, error) {
 	if name == "" {
 		return nil, errors.New("name cannot be empty")
 	}
-	counter := &Counter{name: name}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	counter := &Counter{name: name, labels: labels.Clone()}
 	if err := counter.AddValue(v); err != nil {
 		return nil, err
 	}
@@ -28,6 +37,16 @@ func (c Counter) Name() string {
 	return c.name
 }
 
+// Labels returns the labels of the counter.
+func (c Counter) Labels() Labels {
+	return c.labels
+}
+
+// Key returns the series key of the counter: the name followed by its labels.
+func (c Counter) Key() string {
+	return SeriesKey(c.name, c.labels)
+}
+
 // Value returns the counter value.
 func (c Counter) Value() int64 {
 	return c.value
@@ -45,19 +64,22 @@ func (c *Counter) AddValue(v int64) error {
 // MarshalJSON implements the Marshaler interface.
 func (c Counter) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Name  string `json:"name"`
-		Value int64  `json:"value"`
+		Name   string `json:"name"`
+		Labels Labels `json:"labels,omitempty"`
+		Value  int64  `json:"value"`
 	}{
-		Name:  c.name,
-		Value: c.value,
+		Name:   c.name,
+		Labels: c.labels,
+		Value:  c.value,
 	})
 }
 
 // UnmarshalJSON implements the Unmarshaler interface.
 func (c *Counter) UnmarshalJSON(data []byte) error {
 	aux := &struct {
-		Name  string `json:"name"`
-		Value int64  `json:"value"`
+		Name   string `json:"name"`
+		Labels Labels `json:"labels,omitempty"`
+		Value  int64  `json:"value"`
 	}{}
 
 	if err := json.Unmarshal(data, aux); err != nil {
@@ -65,6 +87,7 @@ func (c *Counter) UnmarshalJSON(data []byte) error {
 	}
 
 	c.name = aux.Name
+	c.labels = aux.Labels.Clone()
 	c.value = aux.Value
 
 	return nil
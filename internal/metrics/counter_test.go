@@ -57,21 +57,21 @@ func TestCounter_AddValue(t *testing.T) {
 		{
 			name:    "Positive case #1",
 			value:   1,
-			counter: Counter{"test", 2},
+			counter: Counter{name: "test", value: 2},
 			want:    3,
 			wantErr: false,
 		},
 		{
 			name:    "Positive case #2",
 			value:   0,
-			counter: Counter{"test", 1},
+			counter: Counter{name: "test", value: 1},
 			want:    1,
 			wantErr: false,
 		},
 		{
 			name:    "Negative case #1",
 			value:   -1,
-			counter: Counter{"test", 2},
+			counter: Counter{name: "test", value: 2},
 			wantErr: true,
 		},
 	}
@@ -0,0 +1,67 @@
+package metrics
+
+// maxCumulativeSeries is the maximum number of the counter series whose last value is kept.
+const maxCumulativeSeries = 100_000
+
+// CumulativeCounters converts the cumulative values of the counter series, as they are sent
+// by Prometheus, InfluxDB and OpenTelemetry clients, into the increments of the counters.
+//
+// The last received value is kept for at most maxCumulativeSeries series. When the limit is reached,
+// the value of an arbitrary series is forgotten and its next increment is counted from the stored counter,
+// the same way as for the series that has not been received yet.
+//
+// CumulativeCounters is not safe for concurrent use.
+type CumulativeCounters struct {
+	last      map[string]float64 // Последнее полученное значение счётчика по ключу серии
+	maxSeries int
+}
+
+func NewCumulativeCounters() *CumulativeCounters {
+	return &CumulativeCounters{
+		last:      make(map[string]float64),
+		maxSeries: maxCumulativeSeries,
+	}
+}
+
+// Delta returns the increment of the series by its values in chronological order.
+// The previous value is taken from pending, then from the kept values, and at last from stored.
+// The last of the values is put into pending, it is kept only after Commit,
+// so that the values of the request that has not been stored are not taken into account.
+func (cc *CumulativeCounters) Delta(pending map[string]float64, key string, values []float64, stored func() (float64, bool)) float64 {
+	prev, ok := pending[key]
+	if !ok {
+		prev, ok = cc.last[key]
+	}
+	if !ok {
+		// Серия ещё не приходила: продолжаем с сохранённого значения
+		if v, found := stored(); found {
+			prev = v
+		}
+	}
+
+	var delta float64
+	for _, v := range values {
+		if v < prev {
+			// Сброс счётчика
+			delta += v
+		} else {
+			delta += v - prev
+		}
+		prev = v
+	}
+	pending[key] = prev
+	return delta
+}
+
+// Commit keeps the last values of the stored request.
+func (cc *CumulativeCounters) Commit(pending map[string]float64) {
+	for key, v := range pending {
+		if _, exists := cc.last[key]; !exists && len(cc.last) >= cc.maxSeries {
+			for k := range cc.last {
+				delete(cc.last, k)
+				break
+			}
+		}
+		cc.last[key] = v
+	}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCumulativeCounters_Delta(t *testing.T) {
+	stored := func() (float64, bool) { return 10, true }
+	notStored := func() (float64, bool) { return 0, false }
+
+	testCases := []struct {
+		name   string
+		last   map[string]float64
+		values []float64
+		stored func() (float64, bool)
+		want   float64
+	}{
+		{
+			name:   "New series",
+			values: []float64{5, 8},
+			stored: notStored,
+			want:   8,
+		},
+		{
+			name:   "Continue from the stored counter",
+			values: []float64{15},
+			stored: stored,
+			want:   5,
+		},
+		{
+			name:   "Continue from the last value",
+			last:   map[string]float64{"a": 12},
+			values: []float64{15},
+			stored: stored,
+			want:   3,
+		},
+		{
+			name:   "Counter reset",
+			last:   map[string]float64{"a": 12},
+			values: []float64{3, 5},
+			stored: stored,
+			want:   5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := NewCumulativeCounters()
+			cc.Commit(tc.last)
+
+			pending := make(map[string]float64)
+			assert.Equal(t, tc.want, cc.Delta(pending, "a", tc.values, tc.stored))
+			assert.Equal(t, tc.values[len(tc.values)-1], pending["a"])
+		})
+	}
+}
+
+func TestCumulativeCounters_Commit(t *testing.T) {
+	cc := NewCumulativeCounters()
+	notStored := func() (float64, bool) { return 0, false }
+
+	// Значения не сохранённого запроса не учитываются
+	cc.Delta(make(map[string]float64), "a", []float64{10}, notStored)
+	assert.Equal(t, float64(10), cc.Delta(make(map[string]float64), "a", []float64{10}, notStored))
+
+	pending := make(map[string]float64)
+	cc.Delta(pending, "a", []float64{10}, notStored)
+	// Значение из незавершённого запроса используется в том же запросе
+	assert.Equal(t, float64(5), cc.Delta(pending, "a", []float64{15}, notStored))
+	cc.Commit(pending)
+	assert.Equal(t, float64(5), cc.Delta(make(map[string]float64), "a", []float64{20}, notStored))
+}
+
+func TestCumulativeCounters_MaxSeries(t *testing.T) {
+	cc := NewCumulativeCounters()
+	cc.maxSeries = 3
+
+	for i := 0; i < 10; i++ {
+		cc.Commit(map[string]float64{strconv.Itoa(i): float64(i)})
+	}
+	assert.Len(t, cc.last, 3)
+	assert.Equal(t, float64(9), cc.last["9"])
+}
@@ -1,2 +1,4 @@
-// Package metrics implements the types of metrics: gauge, counter.
+// Package metrics implements the types of metrics: gauge, counter, histogram.
+//
+// A metric series is identified by its name and an optional set of labels.
 package metrics
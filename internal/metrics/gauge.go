@@ -7,16 +7,25 @@ import (
 
 // Gauge implements the metric type Gauge.
 type Gauge struct {
-	name  string
-	value float64
+	labels Labels
+	name   string
+	value  float64
 }
 
 // NewGauge returns a pointer to the Gauge structure.
 func NewGauge(name string, v float64) (*Gauge, error) {
+	return NewGaugeWithLabels(name, nil, v)
+}
+
+// NewGaugeWithLabels returns a pointer to the Gauge structure of a labeled series.
+func NewGaugeWithLabels(name string, labels Labels, v float64) (*Gauge, error) {
 	if name == "" {
 		return nil, errors.New("name cannot be empty")
 	}
-	gauge := Gauge{name: name}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	gauge := Gauge{name: name, labels: labels.Clone()}
 	err := gauge.SetValue(v)
 	if err != nil {
 		return nil, err
@@ -29,6 +38,16 @@ func (g Gauge) Name() string {
 	return g.name
 }
 
+// Labels returns the labels of the gauge.
+func (g Gauge) Labels() Labels {
+	return g.labels
+}
+
+// Key returns the series key of the gauge: the name followed by its labels.
+func (g Gauge) Key() string {
+	return SeriesKey(g.name, g.labels)
+}
+
 // Value returns the gauge value.
 func (g Gauge) Value() float64 {
 	return g.value
@@ -43,19 +62,22 @@ func (g *Gauge) SetValue(v float64) error {
 // MarshalJSON implements the Marshaler interface.
 func (g Gauge) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Name  string  `json:"name"`
-		Value float64 `json:"value"`
+		Name   string  `json:"name"`
+		Labels Labels  `json:"labels,omitempty"`
+		Value  float64 `json:"value"`
 	}{
-		Name:  g.name,
-		Value: g.value,
+		Name:   g.name,
+		Labels: g.labels,
+		Value:  g.value,
 	})
 }
 
 // UnmarshalJSON implements the Unmarshaler interface.
 func (g *Gauge) UnmarshalJSON(data []byte) error {
 	aux := &struct {
-		Name  string  `json:"name"`
-		Value float64 `json:"value"`
+		Name   string  `json:"name"`
+		Labels Labels  `json:"labels,omitempty"`
+		Value  float64 `json:"value"`
 	}{}
 
 	if err := json.Unmarshal(data, aux); err != nil {
@@ -63,6 +85,7 @@ func (g *Gauge) UnmarshalJSON(data []byte) error {
 	}
 
 	g.name = aux.Name
+	g.labels = aux.Labels.Clone()
 	g.value = aux.Value
 
 	return nil
@@ -0,0 +1,218 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultHistogramBounds are the bucket upper bounds used when a histogram
+// is created from a single observation without explicit bounds.
+var DefaultHistogramBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ErrHistogramBoundsMismatch is returned when histograms with different bucket bounds are merged.
+var ErrHistogramBoundsMismatch = errors.New("histogram bucket bounds mismatch")
+
+// HistogramValue contains the state of a histogram.
+//
+// Bounds are the sorted upper bounds of the buckets. Counts holds the number of
+// observations per bucket (not cumulative); it has one more element than Bounds,
+// the last one is the +Inf bucket.
+type HistogramValue struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Count  uint64    `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+// NewHistogramValue returns an empty histogram value with the given bucket bounds.
+func NewHistogramValue(bounds []float64) (HistogramValue, error) {
+	if err := validateHistogramBounds(bounds); err != nil {
+		return HistogramValue{}, err
+	}
+	return HistogramValue{
+		Bounds: append([]float64(nil), bounds...),
+		Counts: make([]uint64, len(bounds)+1),
+	}, nil
+}
+
+// Validate checks that the bounds are sorted and that the bucket counts are consistent with the count.
+func (v HistogramValue) Validate() error {
+	if err := validateHistogramBounds(v.Bounds); err != nil {
+		return err
+	}
+	if len(v.Counts) != len(v.Bounds)+1 {
+		return fmt.Errorf("histogram must have %d bucket counts, got %d", len(v.Bounds)+1, len(v.Counts))
+	}
+	var total uint64
+	for _, c := range v.Counts {
+		total += c
+	}
+	if total != v.Count {
+		return fmt.Errorf("histogram count %d does not match the sum of bucket counts %d", v.Count, total)
+	}
+	if math.IsNaN(v.Sum) || math.IsInf(v.Sum, 0) {
+		return errors.New("histogram sum must be a finite number")
+	}
+	return nil
+}
+
+// Observe adds a single observation to the histogram.
+func (v *HistogramValue) Observe(x float64) {
+	i := sort.SearchFloat64s(v.Bounds, x)
+	v.Counts[i]++
+	v.Count++
+	v.Sum += x
+}
+
+// Merge adds the observations of o to the histogram. Both histograms must have the same bounds.
+func (v *HistogramValue) Merge(o HistogramValue) error {
+	if !v.SameBounds(o) {
+		return ErrHistogramBoundsMismatch
+	}
+	for i, c := range o.Counts {
+		v.Counts[i] += c
+	}
+	v.Count += o.Count
+	v.Sum += o.Sum
+	return nil
+}
+
+// SameBounds reports whether both histograms have the same bucket bounds.
+func (v HistogramValue) SameBounds(o HistogramValue) bool {
+	if len(v.Bounds) != len(o.Bounds) {
+		return false
+	}
+	for i := range v.Bounds {
+		if v.Bounds[i] != o.Bounds[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a deep copy of the histogram value.
+func (v HistogramValue) Clone() HistogramValue {
+	v.Bounds = append([]float64(nil), v.Bounds...)
+	v.Counts = append([]uint64(nil), v.Counts...)
+	return v
+}
+
+func validateHistogramBounds(bounds []float64) error {
+	for i, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return errors.New("histogram bounds must be finite numbers")
+		}
+		if i > 0 && b <= bounds[i-1] {
+			return errors.New("histogram bounds must be sorted in increasing order")
+		}
+	}
+	return nil
+}
+
+// Histogram implements the metric type Histogram.
+type Histogram struct {
+	labels Labels
+	name   string
+	value  HistogramValue
+}
+
+// NewHistogram returns a pointer to the Histogram structure.
+func NewHistogram(name string, v HistogramValue) (*Histogram, error) {
+	return NewHistogramWithLabels(name, nil, v)
+}
+
+// NewHistogramWithLabels returns a pointer to the Histogram structure of a labeled series.
+func NewHistogramWithLabels(name string, labels Labels, v HistogramValue) (*Histogram, error) {
+	if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	return &Histogram{name: name, labels: labels.Clone(), value: v.Clone()}, nil
+}
+
+// Name returns the name of the histogram.
+func (h Histogram) Name() string {
+	return h.name
+}
+
+// Labels returns the labels of the histogram.
+func (h Histogram) Labels() Labels {
+	return h.labels
+}
+
+// Key returns the series key of the histogram: the name followed by its labels.
+func (h Histogram) Key() string {
+	return SeriesKey(h.name, h.labels)
+}
+
+// Value returns a copy of the histogram state.
+func (h Histogram) Value() HistogramValue {
+	return h.value.Clone()
+}
+
+// Count returns the total number of observations.
+func (h Histogram) Count() uint64 {
+	return h.value.Count
+}
+
+// Sum returns the sum of all observations.
+func (h Histogram) Sum() float64 {
+	return h.value.Sum
+}
+
+// Merge adds the observations of v to the histogram.
+func (h *Histogram) Merge(v HistogramValue) error {
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	value := h.value.Clone()
+	if err := value.Merge(v); err != nil {
+		return err
+	}
+	h.value = value
+	return nil
+}
+
+// MarshalJSON implements the Marshaler interface.
+func (h Histogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Name   string         `json:"name"`
+		Labels Labels         `json:"labels,omitempty"`
+		Value  HistogramValue `json:"value"`
+	}{
+		Name:   h.name,
+		Labels: h.labels,
+		Value:  h.value,
+	})
+}
+
+// UnmarshalJSON implements the Unmarshaler interface.
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	aux := &struct {
+		Name   string         `json:"name"`
+		Labels Labels         `json:"labels,omitempty"`
+		Value  HistogramValue `json:"value"`
+	}{}
+
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+
+	if err := aux.Value.Validate(); err != nil {
+		return err
+	}
+
+	h.name = aux.Name
+	h.labels = aux.Labels.Clone()
+	h.value = aux.Value
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHistogramValue(t *testing.T) {
+	testCases := []struct {
+		name    string
+		bounds  []float64
+		wantErr bool
+	}{
+		{
+			name:   "Positive case: sorted bounds",
+			bounds: []float64{0.1, 0.5, 1},
+		},
+		{
+			name:   "Positive case: only +Inf bucket",
+			bounds: nil,
+		},
+		{
+			name:    "Negative case: unsorted bounds",
+			bounds:  []float64{1, 0.5},
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: duplicate bounds",
+			bounds:  []float64{1, 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewHistogramValue(tc.bounds)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, v.Counts, len(tc.bounds)+1)
+			assert.NoError(t, v.Validate())
+		})
+	}
+}
+
+func TestHistogramValue_Observe(t *testing.T) {
+	v, err := NewHistogramValue([]float64{0.1, 0.5, 1})
+	require.NoError(t, err)
+
+	for _, x := range []float64{0.05, 0.1, 0.3, 0.7, 2, 3} {
+		v.Observe(x)
+	}
+
+	assert.Equal(t, []uint64{2, 1, 1, 2}, v.Counts)
+	assert.Equal(t, uint64(6), v.Count)
+	assert.InDelta(t, 6.15, v.Sum, 1e-9)
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	h, err := NewHistogramWithLabels("latency", Labels{"host": "web1"}, HistogramValue{
+		Bounds: []float64{1, 5},
+		Counts: []uint64{1, 2, 0},
+		Count:  3,
+		Sum:    6,
+	})
+	require.NoError(t, err)
+
+	err = h.Merge(HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{0, 1, 1}, Count: 2, Sum: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 3, 1}, h.Value().Counts)
+	assert.Equal(t, uint64(5), h.Count())
+	assert.Equal(t, float64(16), h.Sum())
+
+	err = h.Merge(HistogramValue{Bounds: []float64{1, 10}, Counts: []uint64{0, 1, 1}, Count: 2, Sum: 10})
+	require.ErrorIs(t, err, ErrHistogramBoundsMismatch)
+	assert.Equal(t, uint64(5), h.Count())
+
+	err = h.Merge(HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{0, 1, 1}, Count: 3, Sum: 10})
+	require.Error(t, err)
+	assert.Equal(t, uint64(5), h.Count())
+}
+
+func TestHistogram_JSON(t *testing.T) {
+	h, err := NewHistogramWithLabels("latency", Labels{"host": "web1"}, HistogramValue{
+		Bounds: []float64{1, 5},
+		Counts: []uint64{1, 2, 0},
+		Count:  3,
+		Sum:    6,
+	})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(h)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"latency","labels":{"host":"web1"},"value":{"bounds":[1,5],"counts":[1,2,0],"count":3,"sum":6}}`, string(data))
+
+	var got Histogram
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, *h, got)
+
+	err = json.Unmarshal([]byte(`{"name":"latency","value":{"bounds":[1,5],"counts":[1,2],"count":3,"sum":6}}`), &got)
+	assert.Error(t, err)
+}
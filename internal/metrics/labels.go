@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Labels is a set of label name/value pairs (e.g. host, service, env).
+// Together with the metric name it identifies a single series.
+type Labels map[string]string
+
+// ParseLabels parses labels from a comma-separated list of name=value pairs,
+// for example "host=web1,env=prod".
+func ParseLabels(s string) (Labels, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	labels := Labels{}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("metrics: invalid label pair %q", pair)
+		}
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// Validate checks that all label names are valid identifiers.
+func (l Labels) Validate() error {
+	for name := range l {
+		if !labelNameRe.MatchString(name) {
+			return fmt.Errorf("metrics: invalid label name %q", name)
+		}
+	}
+	return nil
+}
+
+// Names returns the sorted label names.
+func (l Labels) Names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a copy of the label set. Empty label sets are returned as nil.
+func (l Labels) Clone() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	c := make(Labels, len(l))
+	for k, v := range l {
+		c[k] = v
+	}
+	return c
+}
+
+// Equal reports whether both label sets contain the same pairs.
+func (l Labels) Equal(o Labels) bool {
+	if len(l) != len(o) {
+		return false
+	}
+	return l.Contains(o)
+}
+
+// Contains reports whether every pair of o is present in l.
+func (l Labels) Contains(o Labels) bool {
+	for k, v := range o {
+		if lv, ok := l[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the canonical representation of the label set: {a="1",b="2"}.
+// Labels are sorted by name. An empty label set is represented by an empty string.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range l.Names() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(EscapeLabelValue(l[name]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// MarshalJSON implements the Marshaler interface.
+// A nil label set is encoded as an empty object.
+func (l Labels) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]string(l))
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// EscapeLabelValue escapes backslashes, double quotes and line feeds in a label value.
+func EscapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+// SeriesKey returns the key that identifies a series by metric name and labels,
+// e.g. Alloc{host="web1"}. For a series without labels the key is the metric name.
+func SeriesKey(name string, labels Labels) string {
+	return name + labels.String()
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabels(t *testing.T) {
+	testCases := []struct {
+		name    string
+		value   string
+		want    Labels
+		wantErr bool
+	}{
+		{
+			name:  "Positive case: empty",
+			value: "",
+			want:  nil,
+		},
+		{
+			name:  "Positive case: several labels",
+			value: "host=web1, env=prod",
+			want:  Labels{"host": "web1", "env": "prod"},
+		},
+		{
+			name:  "Positive case: empty value",
+			value: "host=",
+			want:  Labels{"host": ""},
+		},
+		{
+			name:    "Negative case: no separator",
+			value:   "host",
+			wantErr: true,
+		},
+		{
+			name:    "Negative case: invalid name",
+			value:   "1host=web1",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			labels, err := ParseLabels(tc.value)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, labels)
+		})
+	}
+}
+
+func TestLabels_String(t *testing.T) {
+	testCases := []struct {
+		name   string
+		labels Labels
+		want   string
+	}{
+		{
+			name:   "No labels",
+			labels: nil,
+			want:   "",
+		},
+		{
+			name:   "Sorted by name",
+			labels: Labels{"service": "api", "env": "prod", "host": "web1"},
+			want:   `{env="prod",host="web1",service="api"}`,
+		},
+		{
+			name:   "Escaped value",
+			labels: Labels{"path": "C:\\tmp\n\"x\""},
+			want:   `{path="C:\\tmp\n\"x\""}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.labels.String())
+		})
+	}
+}
+
+func TestLabels_Contains(t *testing.T) {
+	labels := Labels{"host": "web1", "env": "prod"}
+
+	assert.True(t, labels.Contains(nil))
+	assert.True(t, labels.Contains(Labels{"env": "prod"}))
+	assert.False(t, labels.Contains(Labels{"env": "dev"}))
+	assert.False(t, labels.Contains(Labels{"dc": "eu"}))
+	assert.True(t, labels.Equal(Labels{"env": "prod", "host": "web1"}))
+	assert.False(t, labels.Equal(Labels{"env": "prod"}))
+}
+
+func TestSeriesKey(t *testing.T) {
+	assert.Equal(t, "Alloc", SeriesKey("Alloc", nil))
+	assert.Equal(t, "Alloc", SeriesKey("Alloc", Labels{}))
+	assert.Equal(t, `Alloc{host="web1"}`, SeriesKey("Alloc", Labels{"host": "web1"}))
+
+	g, err := NewGaugeWithLabels("Alloc", Labels{"host": "web1"}, 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, `Alloc{host="web1"}`, g.Key())
+
+	_, err = NewCounterWithLabels("PollCount", Labels{"bad-name": "x"}, 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,9 @@
+package metrics
+
+import "time"
+
+// Sample is a value of a series at a point in time.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
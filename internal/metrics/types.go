@@ -2,21 +2,25 @@ package metrics
 
 // Available metric types.
 const (
-	TypeGauge   = "gauge"
-	TypeCounter = "counter"
+	TypeGauge     = "gauge"
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
 )
 
 // Metrics structure is used to process incoming data and return results in handlers.
 type Metrics struct {
-	Delta *int64   `json:"delta,omitempty"` // значение метрики в случае передачи counter
-	Value *float64 `json:"value,omitempty"` // значение метрики в случае передачи gauge
-	ID    string   `json:"id"`              // имя метрики
-	MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
+	Delta     *int64          `json:"delta,omitempty"`     // значение метрики в случае передачи counter
+	Value     *float64        `json:"value,omitempty"`     // значение метрики в случае передачи gauge
+	Histogram *HistogramValue `json:"histogram,omitempty"` // значение метрики в случае передачи histogram
+	Labels    Labels          `json:"labels,omitempty"`    // метки серии (host, service, env...)
+	ID        string          `json:"id"`                  // имя метрики
+	MType     string          `json:"type"`                // параметр, принимающий значение gauge, counter или histogram
 }
 
 // SetValue sets a new value for a metric of type gauge.
 func (m Metrics) SetValue(value float64) Metrics {
 	m.Delta = nil
+	m.Histogram = nil
 	if m.Value == nil {
 		m.Value = new(float64)
 	}
@@ -27,6 +31,7 @@ func (m Metrics) SetValue(value float64) Metrics {
 // SetDelta sets a new value for a metric of type counter.
 func (m Metrics) SetDelta(delta int64) Metrics {
 	m.Value = nil
+	m.Histogram = nil
 	if m.Delta == nil {
 		m.Delta = new(int64)
 	}
@@ -34,6 +39,26 @@ func (m Metrics) SetDelta(delta int64) Metrics {
 	return m
 }
 
+// SetHistogram sets a new value for a metric of type histogram.
+func (m Metrics) SetHistogram(value HistogramValue) Metrics {
+	m.Delta = nil
+	m.Value = nil
+	v := value.Clone()
+	m.Histogram = &v
+	return m
+}
+
+// WithLabels sets the labels of the metric series.
+func (m Metrics) WithLabels(labels Labels) Metrics {
+	m.Labels = labels.Clone()
+	return m
+}
+
+// Key returns the series key of the metric: the ID followed by its labels.
+func (m Metrics) Key() string {
+	return SeriesKey(m.ID, m.Labels)
+}
+
 // NewGaugeMetric returns a metric data structure of type gauge.
 //
 // The value of the Metrics.MType field is TypeGauge ("gauge").
@@ -61,3 +86,16 @@ func NewCounterMetric(id string) Metrics {
 	}
 	return m
 }
+
+// NewHistogramMetric returns a metric data structure of type histogram.
+//
+// The value of the Metrics.MType field is TypeHistogram ("histogram").
+// Metric data of type histogram is stored in the Metrics.Histogram field.
+func NewHistogramMetric(id string) Metrics {
+	m := Metrics{
+		ID:        id,
+		MType:     TypeHistogram,
+		Histogram: nil,
+	}
+	return m
+}
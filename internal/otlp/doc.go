@@ -0,0 +1,11 @@
+// Package otlp implements the receiver of the OpenTelemetry metrics (OTLP/HTTP).
+//
+// The ExportMetricsServiceRequest message is decoded from the protobuf or the JSON
+// encoding. Only the fields used by the receiver are decoded.
+//
+// The data points are converted as follows: Gauge and non-monotonic Sum become gauges,
+// monotonic Sum becomes a counter and Histogram becomes a histogram. The cumulative
+// values are converted into increments. The metric names and attribute keys are
+// sanitized, the resource attributes and the data point attributes become labels.
+// Exponential histograms and summaries are rejected.
+package otlp
@@ -0,0 +1,295 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The OTLP/JSON encoding follows the Protobuf JSON mapping: 64-bit integers may be strings,
+// enums may be names and special float values are strings.
+
+type jsonUint64 uint64
+
+func (v *jsonUint64) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	x, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: incorrect integer %s", ErrInvalidMessage, data)
+	}
+	*v = jsonUint64(x)
+	return nil
+}
+
+type jsonInt64 int64
+
+func (v *jsonInt64) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	x, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: incorrect integer %s", ErrInvalidMessage, data)
+	}
+	*v = jsonInt64(x)
+	return nil
+}
+
+type jsonFloat float64
+
+func (v *jsonFloat) UnmarshalJSON(data []byte) error {
+	switch s := string(bytes.Trim(data, `"`)); s {
+	case "NaN":
+		*v = jsonFloat(math.NaN())
+	case "Infinity":
+		*v = jsonFloat(math.Inf(1))
+	case "-Infinity":
+		*v = jsonFloat(math.Inf(-1))
+	default:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%w: incorrect number %s", ErrInvalidMessage, data)
+		}
+		*v = jsonFloat(x)
+	}
+	return nil
+}
+
+type jsonTemporality Temporality
+
+func (v *jsonTemporality) UnmarshalJSON(data []byte) error {
+	switch s := string(bytes.Trim(data, `"`)); s {
+	case "AGGREGATION_TEMPORALITY_UNSPECIFIED":
+		*v = jsonTemporality(TemporalityUnspecified)
+	case "AGGREGATION_TEMPORALITY_DELTA":
+		*v = jsonTemporality(TemporalityDelta)
+	case "AGGREGATION_TEMPORALITY_CUMULATIVE":
+		*v = jsonTemporality(TemporalityCumulative)
+	default:
+		x, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%w: incorrect aggregation temporality %s", ErrInvalidMessage, data)
+		}
+		*v = jsonTemporality(x)
+	}
+	return nil
+}
+
+type jsonAnyValue struct {
+	StringValue *string        `json:"stringValue"`
+	BoolValue   *bool          `json:"boolValue"`
+	IntValue    *jsonInt64     `json:"intValue"`
+	DoubleValue *jsonFloat     `json:"doubleValue"`
+	BytesValue  *string        `json:"bytesValue"`
+	ArrayValue  *jsonArray     `json:"arrayValue"`
+	KvlistValue *jsonKeyValues `json:"kvlistValue"`
+}
+
+type jsonArray struct {
+	Values []jsonAnyValue `json:"values"`
+}
+
+type jsonKeyValues struct {
+	Values []jsonKeyValue `json:"values"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+func (v jsonAnyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return strconv.FormatInt(int64(*v.IntValue), 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(float64(*v.DoubleValue), 'g', -1, 64)
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		values := make([]string, 0, len(v.ArrayValue.Values))
+		for _, x := range v.ArrayValue.Values {
+			values = append(values, x.String())
+		}
+		return strings.Join(values, ",")
+	case v.KvlistValue != nil:
+		values := make([]string, 0, len(v.KvlistValue.Values))
+		for _, kv := range v.KvlistValue.Values {
+			values = append(values, kv.Key+"="+kv.Value.String())
+		}
+		return strings.Join(values, ",")
+	}
+	return ""
+}
+
+func convertAttributes(attrs []jsonKeyValue) []KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, KeyValue{Key: a.Key, Value: a.Value.String()})
+	}
+	return kvs
+}
+
+type jsonNumberDataPoint struct {
+	Attributes        []jsonKeyValue `json:"attributes"`
+	StartTimeUnixNano jsonUint64     `json:"startTimeUnixNano"`
+	TimeUnixNano      jsonUint64     `json:"timeUnixNano"`
+	AsDouble          *jsonFloat     `json:"asDouble"`
+	AsInt             *jsonInt64     `json:"asInt"`
+	Flags             uint32         `json:"flags"`
+}
+
+func (dp jsonNumberDataPoint) convert() NumberDataPoint {
+	p := NumberDataPoint{
+		Attributes:        convertAttributes(dp.Attributes),
+		StartTimeUnixNano: uint64(dp.StartTimeUnixNano),
+		TimeUnixNano:      uint64(dp.TimeUnixNano),
+		Flags:             dp.Flags,
+	}
+	switch {
+	case dp.AsDouble != nil:
+		p.Value = float64(*dp.AsDouble)
+	case dp.AsInt != nil:
+		p.Value = float64(*dp.AsInt)
+	}
+	return p
+}
+
+type jsonHistogramDataPoint struct {
+	Attributes        []jsonKeyValue `json:"attributes"`
+	BucketCounts      []jsonUint64   `json:"bucketCounts"`
+	ExplicitBounds    []jsonFloat    `json:"explicitBounds"`
+	StartTimeUnixNano jsonUint64     `json:"startTimeUnixNano"`
+	TimeUnixNano      jsonUint64     `json:"timeUnixNano"`
+	Count             jsonUint64     `json:"count"`
+	Sum               jsonFloat      `json:"sum"`
+	Flags             uint32         `json:"flags"`
+}
+
+func (dp jsonHistogramDataPoint) convert() HistogramDataPoint {
+	p := HistogramDataPoint{
+		Attributes:        convertAttributes(dp.Attributes),
+		StartTimeUnixNano: uint64(dp.StartTimeUnixNano),
+		TimeUnixNano:      uint64(dp.TimeUnixNano),
+		Count:             uint64(dp.Count),
+		Sum:               float64(dp.Sum),
+		Flags:             dp.Flags,
+	}
+	for _, c := range dp.BucketCounts {
+		p.BucketCounts = append(p.BucketCounts, uint64(c))
+	}
+	for _, b := range dp.ExplicitBounds {
+		p.ExplicitBounds = append(p.ExplicitBounds, float64(b))
+	}
+	return p
+}
+
+// jsonDataPoints is used to count the data points of the unsupported types.
+type jsonDataPoints struct {
+	DataPoints []json.RawMessage `json:"dataPoints"`
+}
+
+type jsonMetric struct {
+	Gauge *struct {
+		DataPoints []jsonNumberDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+	Sum *struct {
+		DataPoints  []jsonNumberDataPoint `json:"dataPoints"`
+		Temporality jsonTemporality       `json:"aggregationTemporality"`
+		IsMonotonic bool                  `json:"isMonotonic"`
+	} `json:"sum"`
+	Histogram *struct {
+		DataPoints  []jsonHistogramDataPoint `json:"dataPoints"`
+		Temporality jsonTemporality          `json:"aggregationTemporality"`
+	} `json:"histogram"`
+	ExponentialHistogram *jsonDataPoints `json:"exponentialHistogram"`
+	Summary              *jsonDataPoints `json:"summary"`
+	Name                 string          `json:"name"`
+}
+
+func (jm jsonMetric) convert() Metric {
+	m := Metric{Name: jm.Name}
+	switch {
+	case jm.Gauge != nil:
+		m.Gauge = &Gauge{}
+		for _, dp := range jm.Gauge.DataPoints {
+			m.Gauge.DataPoints = append(m.Gauge.DataPoints, dp.convert())
+		}
+	case jm.Sum != nil:
+		m.Sum = &Sum{Temporality: Temporality(jm.Sum.Temporality), IsMonotonic: jm.Sum.IsMonotonic}
+		for _, dp := range jm.Sum.DataPoints {
+			m.Sum.DataPoints = append(m.Sum.DataPoints, dp.convert())
+		}
+	case jm.Histogram != nil:
+		m.Histogram = &Histogram{Temporality: Temporality(jm.Histogram.Temporality)}
+		for _, dp := range jm.Histogram.DataPoints {
+			m.Histogram.DataPoints = append(m.Histogram.DataPoints, dp.convert())
+		}
+	case jm.ExponentialHistogram != nil:
+		m.Unsupported = "exponential histogram"
+		m.UnsupportedPoints = len(jm.ExponentialHistogram.DataPoints)
+	case jm.Summary != nil:
+		m.Unsupported = "summary"
+		m.UnsupportedPoints = len(jm.Summary.DataPoints)
+	}
+	return m
+}
+
+type jsonRequest struct {
+	ResourceMetrics []struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeMetrics []struct {
+			Metrics []jsonMetric `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+// UnmarshalJSON decodes the ExportMetricsServiceRequest message in the OTLP/JSON encoding.
+func UnmarshalJSON(data []byte) (*ExportRequest, error) {
+	var jr jsonRequest
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	req := &ExportRequest{}
+	for _, jrm := range jr.ResourceMetrics {
+		rm := ResourceMetrics{Resource: convertAttributes(jrm.Resource.Attributes)}
+		for _, jsm := range jrm.ScopeMetrics {
+			sm := ScopeMetrics{}
+			for _, jm := range jsm.Metrics {
+				sm.Metrics = append(sm.Metrics, jm.convert())
+			}
+			rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+		}
+		req.ResourceMetrics = append(req.ResourceMetrics, rm)
+	}
+	return req, nil
+}
+
+// MarshalResponseJSON encodes the ExportMetricsServiceResponse message in the OTLP/JSON encoding.
+func MarshalResponseJSON(resp *ExportResponse) ([]byte, error) {
+	type jsonPartialSuccess struct {
+		RejectedDataPoints string `json:"rejectedDataPoints,omitempty"`
+		ErrorMessage       string `json:"errorMessage,omitempty"`
+	}
+	var jr struct {
+		PartialSuccess *jsonPartialSuccess `json:"partialSuccess,omitempty"`
+	}
+	if ps := resp.PartialSuccess; ps != nil {
+		jr.PartialSuccess = &jsonPartialSuccess{
+			RejectedDataPoints: strconv.FormatInt(ps.RejectedDataPoints, 10),
+			ErrorMessage:       ps.ErrorMessage,
+		}
+	}
+	return json.Marshal(jr)
+}
@@ -0,0 +1,65 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	data := `{
+		"resourceMetrics": [{
+			"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}, {"key": "pid", "value": {"intValue": "42"}}]},
+			"scopeMetrics": [{
+				"scope": {"name": "app"},
+				"metrics": [
+					{"name": "queue.size", "gauge": {"dataPoints": [{"timeUnixNano": "10", "asInt": "3"}]}},
+					{"name": "http.requests", "sum": {"aggregationTemporality": "AGGREGATION_TEMPORALITY_CUMULATIVE", "isMonotonic": true,
+						"dataPoints": [{"attributes": [{"key": "ok", "value": {"boolValue": true}}], "startTimeUnixNano": 1, "timeUnixNano": "10", "asDouble": 42}]}},
+					{"name": "http.duration", "histogram": {"aggregationTemporality": 1,
+						"dataPoints": [{"timeUnixNano": "10", "count": "3", "sum": 1.5, "bucketCounts": ["1", "2", 0], "explicitBounds": [0.1, 1]}]}},
+					{"name": "latency", "summary": {"dataPoints": [{}, {}]}}
+				]
+			}]
+		}]
+	}`
+
+	req, err := UnmarshalJSON([]byte(data))
+	require.NoError(t, err)
+
+	want := &ExportRequest{
+		ResourceMetrics: []ResourceMetrics{{
+			Resource: []KeyValue{{Key: "service.name", Value: "checkout"}, {Key: "pid", Value: "42"}},
+			ScopeMetrics: []ScopeMetrics{{
+				Metrics: []Metric{
+					{Name: "queue.size", Gauge: &Gauge{DataPoints: []NumberDataPoint{{TimeUnixNano: 10, Value: 3}}}},
+					{Name: "http.requests", Sum: &Sum{
+						DataPoints:  []NumberDataPoint{{Attributes: []KeyValue{{Key: "ok", Value: "true"}}, StartTimeUnixNano: 1, TimeUnixNano: 10, Value: 42}},
+						Temporality: TemporalityCumulative,
+						IsMonotonic: true,
+					}},
+					{Name: "http.duration", Histogram: &Histogram{
+						DataPoints:  []HistogramDataPoint{{TimeUnixNano: 10, Count: 3, Sum: 1.5, BucketCounts: []uint64{1, 2, 0}, ExplicitBounds: []float64{0.1, 1}}},
+						Temporality: TemporalityDelta,
+					}},
+					{Name: "latency", Unsupported: "summary", UnsupportedPoints: 2},
+				},
+			}},
+		}},
+	}
+	assert.Equal(t, want, req)
+
+	_, err = UnmarshalJSON([]byte(`{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{"name": "a", "gauge": {"dataPoints": [{"asInt": "x"}]}}]}]}]}`))
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestMarshalResponseJSON(t *testing.T) {
+	data, err := MarshalResponseJSON(&ExportResponse{PartialSuccess: &PartialSuccess{RejectedDataPoints: 2, ErrorMessage: "unsupported"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"partialSuccess":{"rejectedDataPoints":"2","errorMessage":"unsupported"}}`, string(data))
+
+	data, err = MarshalResponseJSON(&ExportResponse{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(data))
+}
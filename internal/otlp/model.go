@@ -0,0 +1,93 @@
+package otlp
+
+// Temporality is the aggregation temporality of Sum and Histogram.
+type Temporality int32
+
+const (
+	TemporalityUnspecified Temporality = 0
+	TemporalityDelta       Temporality = 1
+	TemporalityCumulative  Temporality = 2
+)
+
+// flagNoRecordedValue marks the data point without a value.
+const flagNoRecordedValue = 1
+
+// ExportRequest is the ExportMetricsServiceRequest message.
+type ExportRequest struct {
+	ResourceMetrics []ResourceMetrics
+}
+
+// ResourceMetrics is the collection of metrics of a resource.
+type ResourceMetrics struct {
+	Resource     []KeyValue // Атрибуты ресурса
+	ScopeMetrics []ScopeMetrics
+}
+
+// ScopeMetrics is the collection of metrics of an instrumentation scope.
+type ScopeMetrics struct {
+	Metrics []Metric
+}
+
+// Metric is a metric with its data points. One of Gauge, Sum and Histogram is set,
+// Unsupported holds the name of any other data type and UnsupportedPoints the number of its data points.
+type Metric struct {
+	Gauge             *Gauge
+	Sum               *Sum
+	Histogram         *Histogram
+	Name              string
+	Unsupported       string
+	UnsupportedPoints int
+}
+
+type Gauge struct {
+	DataPoints []NumberDataPoint
+}
+
+type Sum struct {
+	DataPoints  []NumberDataPoint
+	Temporality Temporality
+	IsMonotonic bool
+}
+
+type Histogram struct {
+	DataPoints  []HistogramDataPoint
+	Temporality Temporality
+}
+
+// NumberDataPoint is a data point of Gauge and Sum.
+type NumberDataPoint struct {
+	Attributes        []KeyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Value             float64
+	Flags             uint32
+}
+
+// HistogramDataPoint is a data point of Histogram.
+type HistogramDataPoint struct {
+	Attributes        []KeyValue
+	BucketCounts      []uint64
+	ExplicitBounds    []float64
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Count             uint64
+	Sum               float64
+	Flags             uint32
+}
+
+// KeyValue is an attribute, the value of any type is converted into a string.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// ExportResponse is the ExportMetricsServiceResponse message.
+type ExportResponse struct {
+	PartialSuccess *PartialSuccess
+}
+
+// PartialSuccess describes the data points rejected by the receiver.
+type PartialSuccess struct {
+	ErrorMessage       string
+	RejectedDataPoints int64
+}
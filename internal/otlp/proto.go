@@ -0,0 +1,492 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/fishus/go-advanced-metrics/internal/protowalk"
+)
+
+var ErrInvalidMessage = errors.New("invalid otlp message")
+
+// UnmarshalProto decodes the ExportMetricsServiceRequest message. Unknown fields are skipped.
+func UnmarshalProto(data []byte) (*ExportRequest, error) {
+	req := &ExportRequest{}
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			rm, err := unmarshalResourceMetrics(v)
+			if err != nil {
+				return err
+			}
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, invalidMessage(err)
+	}
+	return req, nil
+}
+
+func unmarshalResourceMetrics(data []byte) (ResourceMetrics, error) {
+	var rm ResourceMetrics
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			// Resource: атрибуты в поле 1
+			return protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					kv, err := unmarshalKeyValue(v)
+					if err != nil {
+						return err
+					}
+					rm.Resource = append(rm.Resource, kv)
+				}
+				return nil
+			})
+		case 2:
+			var sm ScopeMetrics
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 2 && typ == protowire.BytesType {
+					m, err := unmarshalMetric(v)
+					if err != nil {
+						return err
+					}
+					sm.Metrics = append(sm.Metrics, m)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+		}
+		return nil
+	})
+	return rm, err
+}
+
+func unmarshalMetric(data []byte) (Metric, error) {
+	var m Metric
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			m.Name = string(v)
+		case 5:
+			g := &Gauge{}
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					dp, err := unmarshalNumberDataPoint(v)
+					if err != nil {
+						return err
+					}
+					g.DataPoints = append(g.DataPoints, dp)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m.Gauge = g
+		case 7:
+			s := &Sum{}
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					dp, err := unmarshalNumberDataPoint(v)
+					if err != nil {
+						return err
+					}
+					s.DataPoints = append(s.DataPoints, dp)
+				case num == 2 && typ == protowire.VarintType:
+					s.Temporality = Temporality(x)
+				case num == 3 && typ == protowire.VarintType:
+					s.IsMonotonic = x != 0
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m.Sum = s
+		case 9:
+			h := &Histogram{}
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					dp, err := unmarshalHistogramDataPoint(v)
+					if err != nil {
+						return err
+					}
+					h.DataPoints = append(h.DataPoints, dp)
+				case num == 2 && typ == protowire.VarintType:
+					h.Temporality = Temporality(x)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m.Histogram = h
+		case 10, 11:
+			m.Unsupported = "summary"
+			if num == 10 {
+				m.Unsupported = "exponential histogram"
+			}
+			// Считаем точки данных, чтобы сообщить их число в ответе
+			return protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, _ []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					m.UnsupportedPoints++
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return m, err
+}
+
+func unmarshalNumberDataPoint(data []byte) (NumberDataPoint, error) {
+	var dp NumberDataPoint
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch {
+		case num == 7 && typ == protowire.BytesType:
+			kv, err := unmarshalKeyValue(v)
+			if err != nil {
+				return err
+			}
+			dp.Attributes = append(dp.Attributes, kv)
+		case num == 2 && typ == protowire.Fixed64Type:
+			dp.StartTimeUnixNano = x
+		case num == 3 && typ == protowire.Fixed64Type:
+			dp.TimeUnixNano = x
+		case num == 4 && typ == protowire.Fixed64Type:
+			dp.Value = math.Float64frombits(x)
+		case num == 6 && typ == protowire.Fixed64Type:
+			dp.Value = float64(int64(x))
+		case num == 8 && typ == protowire.VarintType:
+			dp.Flags = uint32(x)
+		}
+		return nil
+	})
+	return dp, err
+}
+
+func unmarshalHistogramDataPoint(data []byte) (HistogramDataPoint, error) {
+	var dp HistogramDataPoint
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch {
+		case num == 9 && typ == protowire.BytesType:
+			kv, err := unmarshalKeyValue(v)
+			if err != nil {
+				return err
+			}
+			dp.Attributes = append(dp.Attributes, kv)
+		case num == 2 && typ == protowire.Fixed64Type:
+			dp.StartTimeUnixNano = x
+		case num == 3 && typ == protowire.Fixed64Type:
+			dp.TimeUnixNano = x
+		case num == 4 && typ == protowire.Fixed64Type:
+			dp.Count = x
+		case num == 5 && typ == protowire.Fixed64Type:
+			dp.Sum = math.Float64frombits(x)
+		case num == 6 && typ == protowire.Fixed64Type:
+			dp.BucketCounts = append(dp.BucketCounts, x)
+		case num == 6 && typ == protowire.BytesType:
+			values, err := consumePackedFixed64(v)
+			if err != nil {
+				return err
+			}
+			dp.BucketCounts = append(dp.BucketCounts, values...)
+		case num == 7 && typ == protowire.Fixed64Type:
+			dp.ExplicitBounds = append(dp.ExplicitBounds, math.Float64frombits(x))
+		case num == 7 && typ == protowire.BytesType:
+			values, err := consumePackedFixed64(v)
+			if err != nil {
+				return err
+			}
+			for _, b := range values {
+				dp.ExplicitBounds = append(dp.ExplicitBounds, math.Float64frombits(b))
+			}
+		case num == 10 && typ == protowire.VarintType:
+			dp.Flags = uint32(x)
+		}
+		return nil
+	})
+	return dp, err
+}
+
+func unmarshalKeyValue(data []byte) (KeyValue, error) {
+	var kv KeyValue
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			kv.Key = string(v)
+		case 2:
+			s, err := unmarshalAnyValue(v)
+			if err != nil {
+				return err
+			}
+			kv.Value = s
+		}
+		return nil
+	})
+	return kv, err
+}
+
+// unmarshalAnyValue decodes the AnyValue message into a string.
+func unmarshalAnyValue(data []byte) (string, error) {
+	var s string
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			s = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			s = strconv.FormatBool(x != 0)
+		case num == 3 && typ == protowire.VarintType:
+			s = strconv.FormatInt(int64(x), 10)
+		case num == 4 && typ == protowire.Fixed64Type:
+			s = strconv.FormatFloat(math.Float64frombits(x), 'g', -1, 64)
+		case num == 5 && typ == protowire.BytesType:
+			// ArrayValue: значения через запятую
+			values := make([]string, 0)
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					value, err := unmarshalAnyValue(v)
+					if err != nil {
+						return err
+					}
+					values = append(values, value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s = strings.Join(values, ",")
+		case num == 6 && typ == protowire.BytesType:
+			// KeyValueList: пары key=value через запятую
+			values := make([]string, 0)
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					kv, err := unmarshalKeyValue(v)
+					if err != nil {
+						return err
+					}
+					values = append(values, kv.Key+"="+kv.Value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s = strings.Join(values, ",")
+		case num == 7 && typ == protowire.BytesType:
+			s = base64.StdEncoding.EncodeToString(v)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func consumePackedFixed64(data []byte) ([]uint64, error) {
+	values := make([]uint64, 0, len(data)/8)
+	for len(data) > 0 {
+		x, n := protowire.ConsumeFixed64(data)
+		if n < 0 {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+		}
+		values = append(values, x)
+		data = data[n:]
+	}
+	return values, nil
+}
+
+// MarshalProto encodes the ExportMetricsServiceRequest message.
+func MarshalProto(req *ExportRequest) []byte {
+	var b []byte
+	for _, rm := range req.ResourceMetrics {
+		var brm []byte
+
+		var bres []byte
+		for _, kv := range rm.Resource {
+			bres = appendMessage(bres, 1, marshalKeyValue(kv))
+		}
+		brm = appendMessage(brm, 1, bres)
+
+		for _, sm := range rm.ScopeMetrics {
+			var bsm []byte
+			for _, m := range sm.Metrics {
+				bsm = appendMessage(bsm, 2, marshalMetric(m))
+			}
+			brm = appendMessage(brm, 2, bsm)
+		}
+
+		b = appendMessage(b, 1, brm)
+	}
+	return b
+}
+
+func marshalMetric(m Metric) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, m.Name)
+
+	switch {
+	case m.Gauge != nil:
+		var bg []byte
+		for _, dp := range m.Gauge.DataPoints {
+			bg = appendMessage(bg, 1, marshalNumberDataPoint(dp))
+		}
+		b = appendMessage(b, 5, bg)
+	case m.Sum != nil:
+		var bs []byte
+		for _, dp := range m.Sum.DataPoints {
+			bs = appendMessage(bs, 1, marshalNumberDataPoint(dp))
+		}
+		bs = protowire.AppendTag(bs, 2, protowire.VarintType)
+		bs = protowire.AppendVarint(bs, uint64(m.Sum.Temporality))
+		bs = protowire.AppendTag(bs, 3, protowire.VarintType)
+		bs = protowire.AppendVarint(bs, protowire.EncodeBool(m.Sum.IsMonotonic))
+		b = appendMessage(b, 7, bs)
+	case m.Histogram != nil:
+		var bh []byte
+		for _, dp := range m.Histogram.DataPoints {
+			bh = appendMessage(bh, 1, marshalHistogramDataPoint(dp))
+		}
+		bh = protowire.AppendTag(bh, 2, protowire.VarintType)
+		bh = protowire.AppendVarint(bh, uint64(m.Histogram.Temporality))
+		b = appendMessage(b, 9, bh)
+	}
+	return b
+}
+
+func marshalNumberDataPoint(dp NumberDataPoint) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 2, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, dp.StartTimeUnixNano)
+	b = protowire.AppendTag(b, 3, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, dp.TimeUnixNano)
+	b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(dp.Value))
+	for _, kv := range dp.Attributes {
+		b = appendMessage(b, 7, marshalKeyValue(kv))
+	}
+	if dp.Flags != 0 {
+		b = protowire.AppendTag(b, 8, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(dp.Flags))
+	}
+	return b
+}
+
+func marshalHistogramDataPoint(dp HistogramDataPoint) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 2, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, dp.StartTimeUnixNano)
+	b = protowire.AppendTag(b, 3, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, dp.TimeUnixNano)
+	b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, dp.Count)
+	b = protowire.AppendTag(b, 5, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(dp.Sum))
+
+	var counts []byte
+	for _, c := range dp.BucketCounts {
+		counts = protowire.AppendFixed64(counts, c)
+	}
+	b = appendMessage(b, 6, counts)
+
+	var bounds []byte
+	for _, v := range dp.ExplicitBounds {
+		bounds = protowire.AppendFixed64(bounds, math.Float64bits(v))
+	}
+	b = appendMessage(b, 7, bounds)
+
+	for _, kv := range dp.Attributes {
+		b = appendMessage(b, 9, marshalKeyValue(kv))
+	}
+	if dp.Flags != 0 {
+		b = protowire.AppendTag(b, 10, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(dp.Flags))
+	}
+	return b
+}
+
+// marshalKeyValue encodes the attribute with the string value.
+func marshalKeyValue(kv KeyValue) []byte {
+	var value []byte
+	value = protowire.AppendTag(value, 1, protowire.BytesType)
+	value = protowire.AppendString(value, kv.Value)
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, kv.Key)
+	return appendMessage(b, 2, value)
+}
+
+// MarshalResponseProto encodes the ExportMetricsServiceResponse message.
+func MarshalResponseProto(resp *ExportResponse) []byte {
+	var b []byte
+	if resp.PartialSuccess != nil {
+		var ps []byte
+		ps = protowire.AppendTag(ps, 1, protowire.VarintType)
+		ps = protowire.AppendVarint(ps, uint64(resp.PartialSuccess.RejectedDataPoints))
+		ps = protowire.AppendTag(ps, 2, protowire.BytesType)
+		ps = protowire.AppendString(ps, resp.PartialSuccess.ErrorMessage)
+		b = appendMessage(b, 1, ps)
+	}
+	return b
+}
+
+// UnmarshalResponseProto decodes the ExportMetricsServiceResponse message.
+func UnmarshalResponseProto(data []byte) (*ExportResponse, error) {
+	resp := &ExportResponse{}
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		ps := &PartialSuccess{}
+		resp.PartialSuccess = ps
+		return protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				ps.RejectedDataPoints = int64(x)
+			case num == 2 && typ == protowire.BytesType:
+				ps.ErrorMessage = string(v)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, invalidMessage(err)
+	}
+	return resp, nil
+}
+
+// invalidMessage wraps the decoding error of the message into ErrInvalidMessage.
+func invalidMessage(err error) error {
+	if errors.Is(err, protowalk.ErrInvalidMessage) {
+		return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	return err
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
@@ -0,0 +1,95 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestMarshalUnmarshalProto(t *testing.T) {
+	req := &ExportRequest{
+		ResourceMetrics: []ResourceMetrics{{
+			Resource: []KeyValue{{Key: "service.name", Value: "checkout"}},
+			ScopeMetrics: []ScopeMetrics{{
+				Metrics: []Metric{
+					{
+						Name:  "queue.size",
+						Gauge: &Gauge{DataPoints: []NumberDataPoint{{TimeUnixNano: 10, Value: 3}}},
+					},
+					{
+						Name: "http.requests",
+						Sum: &Sum{
+							DataPoints:  []NumberDataPoint{{Attributes: []KeyValue{{Key: "method", Value: "GET"}}, StartTimeUnixNano: 1, TimeUnixNano: 10, Value: 42}},
+							Temporality: TemporalityCumulative,
+							IsMonotonic: true,
+						},
+					},
+					{
+						Name: "http.duration",
+						Histogram: &Histogram{
+							DataPoints:  []HistogramDataPoint{{TimeUnixNano: 10, Count: 3, Sum: 1.5, BucketCounts: []uint64{1, 2, 0}, ExplicitBounds: []float64{0.1, 1}}},
+							Temporality: TemporalityDelta,
+						},
+					},
+				},
+			}},
+		}},
+	}
+
+	got, err := UnmarshalProto(MarshalProto(req))
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+
+	_, err = UnmarshalProto([]byte{0x0a, 0x05, 0x01})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestUnmarshalProto_Values(t *testing.T) {
+	// Атрибут с целым значением и точка данных as_int
+	var value []byte
+	value = protowire.AppendTag(value, 3, protowire.VarintType)
+	value = protowire.AppendVarint(value, 7)
+	var kv []byte
+	kv = protowire.AppendTag(kv, 1, protowire.BytesType)
+	kv = protowire.AppendString(kv, "shard")
+	kv = appendMessage(kv, 2, value)
+
+	var dp []byte
+	dp = appendMessage(dp, 7, kv)
+	dp = protowire.AppendTag(dp, 6, protowire.Fixed64Type)
+	dp = protowire.AppendFixed64(dp, uint64(5))
+
+	var summary []byte
+	summary = appendMessage(summary, 1, nil)
+	summary = appendMessage(summary, 1, nil)
+
+	var m1, m2 []byte
+	m1 = protowire.AppendTag(m1, 1, protowire.BytesType)
+	m1 = protowire.AppendString(m1, "jobs")
+	m1 = appendMessage(m1, 5, appendMessage(nil, 1, dp))
+	m2 = protowire.AppendTag(m2, 1, protowire.BytesType)
+	m2 = protowire.AppendString(m2, "latency")
+	m2 = appendMessage(m2, 11, summary)
+
+	scope := append(appendMessage(nil, 2, m1), appendMessage(nil, 2, m2)...)
+	data := appendMessage(nil, 1, appendMessage(nil, 2, scope))
+
+	req, err := UnmarshalProto(data)
+	require.NoError(t, err)
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+	assert.Equal(t, NumberDataPoint{Attributes: []KeyValue{{Key: "shard", Value: "7"}}, Value: 5}, metrics[0].Gauge.DataPoints[0])
+	assert.Equal(t, "summary", metrics[1].Unsupported)
+	assert.Equal(t, 2, metrics[1].UnsupportedPoints)
+}
+
+func TestMarshalResponseProto(t *testing.T) {
+	resp := &ExportResponse{PartialSuccess: &PartialSuccess{RejectedDataPoints: 2, ErrorMessage: "unsupported"}}
+	got, err := UnmarshalResponseProto(MarshalResponseProto(resp))
+	require.NoError(t, err)
+	assert.Equal(t, resp, got)
+
+	assert.Empty(t, MarshalResponseProto(&ExportResponse{}))
+}
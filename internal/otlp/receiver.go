@@ -0,0 +1,411 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fishus/go-advanced-metrics/internal/expfmt"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+// maxErrorMessages is the maximum number of the distinct errors reported in the partial success.
+const maxErrorMessages = 3
+
+// Receiver stores the data points of the export requests.
+type Receiver struct {
+	storage    store.MetricsStorager
+	counters   *metrics.CumulativeCounters
+	histograms map[string]metrics.HistogramValue // Последнее кумулятивное значение гистограммы по ключу серии
+	mu         sync.Mutex
+}
+
+func NewReceiver(storage store.MetricsStorager) *Receiver {
+	return &Receiver{
+		storage:    storage,
+		counters:   metrics.NewCumulativeCounters(),
+		histograms: make(map[string]metrics.HistogramValue),
+	}
+}
+
+type numberSeries struct {
+	labels      metrics.Labels
+	name        string
+	points      []NumberDataPoint
+	temporality Temporality
+	kind        string // Тип метрики проекта: gauge или counter
+}
+
+type histogramSeries struct {
+	labels      metrics.Labels
+	name        string
+	points      []HistogramDataPoint
+	temporality Temporality
+}
+
+// batch holds the converted series of a request.
+type batch struct {
+	gauges     map[string]metrics.Gauge
+	counters   map[string]metrics.Counter
+	histograms map[string]metrics.Histogram
+	lastCount  map[string]float64
+	lastHist   map[string]metrics.HistogramValue
+	errs       []string
+	rejected   int64
+}
+
+func (b *batch) reject(n int, err error) {
+	b.rejected += int64(n)
+	msg := err.Error()
+	for _, e := range b.errs {
+		if e == msg {
+			return
+		}
+	}
+	if len(b.errs) < maxErrorMessages {
+		b.errs = append(b.errs, msg)
+	}
+}
+
+// Write converts the data points and stores them in one batch.
+// The data points that cannot be stored are reported in the partial success of the response.
+func (rc *Receiver) Write(ctx context.Context, req *ExportRequest) (*ExportResponse, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	b := &batch{
+		gauges:     make(map[string]metrics.Gauge),
+		counters:   make(map[string]metrics.Counter),
+		histograms: make(map[string]metrics.Histogram),
+		lastCount:  make(map[string]float64),
+		lastHist:   make(map[string]metrics.HistogramValue),
+	}
+
+	numbers, histograms := rc.group(req, b)
+
+	for _, key := range sortedKeys(numbers) {
+		rc.convertNumbers(ctx, numbers[key], b)
+	}
+	for _, key := range sortedKeys(histograms) {
+		rc.convertHistograms(ctx, histograms[key], b)
+	}
+
+	gauges := make([]metrics.Gauge, 0, len(b.gauges))
+	for _, g := range b.gauges {
+		gauges = append(gauges, g)
+	}
+	counters := make([]metrics.Counter, 0, len(b.counters))
+	for _, c := range b.counters {
+		counters = append(counters, c)
+	}
+	hists := make([]metrics.Histogram, 0, len(b.histograms))
+	for _, h := range b.histograms {
+		hists = append(hists, h)
+	}
+
+	err := rc.storage.InsertBatchContext(ctx, store.WithGauges(gauges), store.WithCounters(counters), store.WithHistograms(hists))
+	if err != nil {
+		return nil, err
+	}
+
+	rc.counters.Commit(b.lastCount)
+	for key, v := range b.lastHist {
+		rc.histograms[key] = v
+	}
+
+	resp := &ExportResponse{}
+	if b.rejected > 0 {
+		resp.PartialSuccess = &PartialSuccess{
+			RejectedDataPoints: b.rejected,
+			ErrorMessage:       strings.Join(b.errs, "; "),
+		}
+	}
+	return resp, nil
+}
+
+// group groups the data points of the request by series.
+func (rc *Receiver) group(req *ExportRequest, b *batch) (map[string]*numberSeries, map[string]*histogramSeries) {
+	numbers := make(map[string]*numberSeries)
+	histograms := make(map[string]*histogramSeries)
+
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Unsupported != "" {
+					b.reject(m.UnsupportedPoints, fmt.Errorf("metric %q: unsupported data type %s", m.Name, m.Unsupported))
+					continue
+				}
+
+				name := m.Name
+				if name != "" {
+					name = expfmt.SanitizeName(name)
+				}
+
+				var (
+					points      []NumberDataPoint
+					temporality Temporality
+					kind        = metrics.TypeGauge
+				)
+				switch {
+				case m.Gauge != nil:
+					points = m.Gauge.DataPoints
+				case m.Sum != nil:
+					points = m.Sum.DataPoints
+					temporality = m.Sum.Temporality
+					if m.Sum.IsMonotonic {
+						kind = metrics.TypeCounter
+					}
+				case m.Histogram != nil:
+					if name == "" {
+						b.reject(len(m.Histogram.DataPoints), errors.New("metric name not specified"))
+						continue
+					}
+					for _, dp := range m.Histogram.DataPoints {
+						if dp.Flags&flagNoRecordedValue != 0 {
+							continue
+						}
+						labels := seriesLabels(rm.Resource, dp.Attributes)
+						key := metrics.SeriesKey(name, labels)
+						s, ok := histograms[key]
+						if !ok {
+							s = &histogramSeries{name: name, labels: labels, temporality: m.Histogram.Temporality}
+							histograms[key] = s
+						}
+						s.points = append(s.points, dp)
+					}
+					continue
+				}
+
+				if name == "" {
+					b.reject(len(points), errors.New("metric name not specified"))
+					continue
+				}
+				for _, dp := range points {
+					if dp.Flags&flagNoRecordedValue != 0 {
+						continue
+					}
+					labels := seriesLabels(rm.Resource, dp.Attributes)
+					key := kind + " " + metrics.SeriesKey(name, labels)
+					s, ok := numbers[key]
+					if !ok {
+						s = &numberSeries{name: name, labels: labels, temporality: temporality, kind: kind}
+						numbers[key] = s
+					}
+					s.points = append(s.points, dp)
+				}
+			}
+		}
+	}
+
+	return numbers, histograms
+}
+
+func (rc *Receiver) convertNumbers(ctx context.Context, s *numberSeries, b *batch) {
+	sort.SliceStable(s.points, func(i, j int) bool {
+		return s.points[i].TimeUnixNano < s.points[j].TimeUnixNano
+	})
+
+	points := s.points[:0]
+	for _, dp := range s.points {
+		if math.IsNaN(dp.Value) || math.IsInf(dp.Value, 0) {
+			b.reject(1, fmt.Errorf("metric %q: invalid value %v", s.name, dp.Value))
+			continue
+		}
+		points = append(points, dp)
+	}
+	if len(points) == 0 {
+		return
+	}
+
+	key := metrics.SeriesKey(s.name, s.labels)
+
+	if s.kind == metrics.TypeGauge {
+		value := points[len(points)-1].Value
+		if s.temporality == TemporalityDelta {
+			// Изменения немонотонной суммы добавляются к текущему значению
+			value, _ = rc.storage.GaugeValueContext(ctx, s.name, s.labels)
+			if g, ok := b.gauges[key]; ok {
+				value = g.Value()
+			}
+			for _, dp := range points {
+				value += dp.Value
+			}
+		}
+
+		g, err := metrics.NewGaugeWithLabels(s.name, s.labels, value)
+		if err != nil {
+			b.reject(len(points), err)
+			return
+		}
+		b.gauges[key] = *g
+		return
+	}
+
+	var delta float64
+	if s.temporality == TemporalityDelta {
+		for _, dp := range points {
+			delta += dp.Value
+		}
+	} else {
+		values := make([]float64, len(points))
+		for i, dp := range points {
+			values[i] = dp.Value
+		}
+		delta = rc.counters.Delta(b.lastCount, key, values, func() (float64, bool) {
+			c, found := rc.storage.CounterContext(ctx, s.name, s.labels)
+			return float64(c.Value()), found
+		})
+	}
+
+	inc := int64(math.Round(delta))
+	if c, exists := b.counters[key]; exists {
+		inc += c.Value()
+	}
+	c, err := metrics.NewCounterWithLabels(s.name, s.labels, inc)
+	if err != nil {
+		delete(b.lastCount, key)
+		b.reject(len(points), err)
+		return
+	}
+	b.counters[key] = *c
+}
+
+func (rc *Receiver) convertHistograms(ctx context.Context, s *histogramSeries, b *batch) {
+	sort.SliceStable(s.points, func(i, j int) bool {
+		return s.points[i].TimeUnixNano < s.points[j].TimeUnixNano
+	})
+
+	key := metrics.SeriesKey(s.name, s.labels)
+
+	var stored *metrics.HistogramValue
+	if h, ok := rc.storage.HistogramContext(ctx, s.name, s.labels); ok {
+		v := h.Value()
+		stored = &v
+	}
+
+	prev, hasPrev := rc.histograms[key]
+	if !hasPrev && stored != nil {
+		// Серия ещё не приходила: продолжаем с сохранённого значения
+		prev, hasPrev = *stored, true
+	}
+
+	var total *metrics.HistogramValue
+	for _, dp := range s.points {
+		v := metrics.HistogramValue{
+			Bounds: dp.ExplicitBounds,
+			Counts: dp.BucketCounts,
+			Count:  dp.Count,
+			Sum:    dp.Sum,
+		}
+		if len(v.Bounds) == 0 && len(v.Counts) == 0 {
+			// Гистограмма без корзин: все наблюдения в +Inf
+			v.Counts = []uint64{dp.Count}
+		}
+		if err := v.Validate(); err != nil {
+			b.reject(1, fmt.Errorf("metric %q: %w", s.name, err))
+			continue
+		}
+		if stored != nil && !sameBounds(stored.Bounds, v.Bounds) {
+			b.reject(1, fmt.Errorf("metric %q: %w", s.name, metrics.ErrHistogramBoundsMismatch))
+			continue
+		}
+		// Точки одного запроса складываются по корзинам первой принятой точки
+		if total != nil && !sameBounds(total.Bounds, v.Bounds) {
+			b.reject(1, fmt.Errorf("metric %q: %w", s.name, metrics.ErrHistogramBoundsMismatch))
+			continue
+		}
+
+		inc := v
+		if s.temporality != TemporalityDelta {
+			if hasPrev && sameBounds(prev.Bounds, v.Bounds) {
+				inc = histogramDelta(prev, v)
+			}
+			prev, hasPrev = v, true
+			b.lastHist[key] = v
+		}
+
+		if total == nil {
+			t := metrics.HistogramValue{Bounds: inc.Bounds, Counts: append([]uint64(nil), inc.Counts...), Count: inc.Count, Sum: inc.Sum}
+			total = &t
+			continue
+		}
+		for i, c := range inc.Counts {
+			total.Counts[i] += c
+		}
+		total.Count += inc.Count
+		total.Sum += inc.Sum
+	}
+
+	if total == nil {
+		return
+	}
+
+	h, err := metrics.NewHistogramWithLabels(s.name, s.labels, *total)
+	if err != nil {
+		delete(b.lastHist, key)
+		b.reject(len(s.points), err)
+		return
+	}
+	b.histograms[key] = *h
+}
+
+// histogramDelta returns the increment between the cumulative values, the decrease of any count means a reset.
+func histogramDelta(prev, cur metrics.HistogramValue) metrics.HistogramValue {
+	if cur.Count < prev.Count {
+		return cur
+	}
+	d := metrics.HistogramValue{Bounds: cur.Bounds, Counts: make([]uint64, len(cur.Counts)), Count: cur.Count - prev.Count, Sum: cur.Sum - prev.Sum}
+	for i := range cur.Counts {
+		if cur.Counts[i] < prev.Counts[i] {
+			return cur
+		}
+		d.Counts[i] = cur.Counts[i] - prev.Counts[i]
+	}
+	return d
+}
+
+func sameBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// seriesLabels returns the labels of the resource attributes and the data point attributes.
+// The attribute keys are sanitized, the data point attributes override the resource ones.
+func seriesLabels(resource, attrs []KeyValue) metrics.Labels {
+	labels := make(metrics.Labels, len(resource)+len(attrs))
+	for _, list := range [][]KeyValue{resource, attrs} {
+		for _, kv := range list {
+			if kv.Key == "" || kv.Value == "" {
+				continue
+			}
+			labels[LabelName(kv.Key)] = kv.Value
+		}
+	}
+	return labels.Clone()
+}
+
+// LabelName converts the attribute key into a label name, e.g. service.name into service_name.
+func LabelName(key string) string {
+	return strings.ReplaceAll(expfmt.SanitizeName(key), ":", "_")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
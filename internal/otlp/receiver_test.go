@@ -0,0 +1,130 @@
+package otlp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func TestReceiver_Write(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+	require.NoError(t, storage.SetGaugeContext(ctx, "active_users", metrics.Labels{"service_name": "checkout"}, 10))
+
+	resource := []KeyValue{{Key: "service.name", Value: "checkout"}}
+	request := func(requests, histCount float64, extra ...Metric) *ExportRequest {
+		ms := []Metric{
+			{Name: "queue.size", Gauge: &Gauge{DataPoints: []NumberDataPoint{{TimeUnixNano: 2, Value: 5}, {TimeUnixNano: 1, Value: 3}}}},
+			{Name: "http.requests", Sum: &Sum{
+				DataPoints:  []NumberDataPoint{{Attributes: []KeyValue{{Key: "http.method", Value: "GET"}}, TimeUnixNano: 1, Value: requests}},
+				Temporality: TemporalityCumulative,
+				IsMonotonic: true,
+			}},
+			{Name: "active.users", Sum: &Sum{
+				DataPoints:  []NumberDataPoint{{TimeUnixNano: 1, Value: 2}, {TimeUnixNano: 2, Value: -1}},
+				Temporality: TemporalityDelta,
+			}},
+			{Name: "http.duration", Histogram: &Histogram{
+				DataPoints: []HistogramDataPoint{{
+					TimeUnixNano:   1,
+					Count:          uint64(histCount),
+					Sum:            histCount,
+					BucketCounts:   []uint64{uint64(histCount), 0},
+					ExplicitBounds: []float64{1},
+				}},
+				Temporality: TemporalityCumulative,
+			}},
+		}
+		ms = append(ms, extra...)
+		return &ExportRequest{ResourceMetrics: []ResourceMetrics{{Resource: resource, ScopeMetrics: []ScopeMetrics{{Metrics: ms}}}}}
+	}
+
+	rc := NewReceiver(storage)
+	resp, err := rc.Write(ctx, request(10, 2))
+	require.NoError(t, err)
+	assert.Nil(t, resp.PartialSuccess)
+
+	labels := metrics.Labels{"service_name": "checkout"}
+
+	v, ok := storage.GaugeValueContext(ctx, "queue_size", labels)
+	require.True(t, ok)
+	assert.Equal(t, float64(5), v)
+
+	v, ok = storage.GaugeValueContext(ctx, "active_users", labels)
+	require.True(t, ok)
+	assert.Equal(t, float64(11), v)
+
+	c, ok := storage.CounterValueContext(ctx, "http_requests", metrics.Labels{"service_name": "checkout", "http_method": "GET"})
+	require.True(t, ok)
+	assert.Equal(t, int64(10), c)
+
+	h, ok := storage.HistogramContext(ctx, "http_duration", labels)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), h.Count())
+
+	// Кумулятивные значения преобразуются в приращения, недопустимые точки отклоняются
+	resp, err = rc.Write(ctx, request(15, 5,
+		Metric{Name: "latency", Unsupported: "summary", UnsupportedPoints: 2},
+		Metric{Name: "", Gauge: &Gauge{DataPoints: []NumberDataPoint{{Value: 1}}}},
+		Metric{Name: "http.duration", Histogram: &Histogram{DataPoints: []HistogramDataPoint{{
+			Attributes: []KeyValue{{Key: "route", Value: "/"}}, Count: 2, BucketCounts: []uint64{1, 0},
+		}}}},
+	))
+	require.NoError(t, err)
+	require.NotNil(t, resp.PartialSuccess)
+	assert.Equal(t, int64(4), resp.PartialSuccess.RejectedDataPoints)
+	assert.Contains(t, resp.PartialSuccess.ErrorMessage, "unsupported data type summary")
+
+	c, _ = storage.CounterValueContext(ctx, "http_requests", metrics.Labels{"service_name": "checkout", "http_method": "GET"})
+	assert.Equal(t, int64(15), c)
+
+	h, _ = storage.HistogramContext(ctx, "http_duration", labels)
+	assert.Equal(t, uint64(5), h.Count())
+
+	// Сброс счётчика
+	_, err = rc.Write(ctx, request(3, 1))
+	require.NoError(t, err)
+	c, _ = storage.CounterValueContext(ctx, "http_requests", metrics.Labels{"service_name": "checkout", "http_method": "GET"})
+	assert.Equal(t, int64(18), c)
+	h, _ = storage.HistogramContext(ctx, "http_duration", labels)
+	assert.Equal(t, uint64(6), h.Count())
+}
+
+func TestReceiver_WriteHistogramBoundsMismatch(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+
+	// Новая серия с разными корзинами у точек одного запроса
+	points := []HistogramDataPoint{
+		{TimeUnixNano: 1, Count: 2, Sum: 1, BucketCounts: []uint64{2, 0}, ExplicitBounds: []float64{1}},
+		{TimeUnixNano: 2, Count: 3, Sum: 4, BucketCounts: []uint64{1, 1, 1}, ExplicitBounds: []float64{1, 2}},
+		{TimeUnixNano: 3, Count: 1, Sum: 5, BucketCounts: []uint64{0, 1}, ExplicitBounds: []float64{5}},
+		{TimeUnixNano: 4, Count: 1, Sum: 0.5, BucketCounts: []uint64{1, 0}, ExplicitBounds: []float64{1}},
+	}
+	req := &ExportRequest{ResourceMetrics: []ResourceMetrics{{ScopeMetrics: []ScopeMetrics{{Metrics: []Metric{
+		{Name: "latency", Histogram: &Histogram{DataPoints: points, Temporality: TemporalityDelta}},
+	}}}}}}
+
+	resp, err := NewReceiver(storage).Write(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, resp.PartialSuccess)
+	assert.Equal(t, int64(2), resp.PartialSuccess.RejectedDataPoints)
+	assert.Contains(t, resp.PartialSuccess.ErrorMessage, metrics.ErrHistogramBoundsMismatch.Error())
+
+	h, ok := storage.HistogramContext(ctx, "latency", nil)
+	require.True(t, ok)
+	assert.Equal(t, []float64{1}, h.Value().Bounds)
+	assert.Equal(t, []uint64{3, 0}, h.Value().Counts)
+	assert.Equal(t, uint64(3), h.Count())
+}
+
+func TestLabelName(t *testing.T) {
+	assert.Equal(t, "service_name", LabelName("service.name"))
+	assert.Equal(t, "k8s_pod_name", LabelName("k8s.pod.name"))
+	assert.Equal(t, "a_b", LabelName("a:b"))
+}
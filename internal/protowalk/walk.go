@@ -0,0 +1,51 @@
+// Package protowalk decodes the protobuf messages field by field without the generated code.
+package protowalk
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var ErrInvalidMessage = errors.New("invalid protobuf message")
+
+// Walk calls fn for every field of the message. Length-delimited fields are passed in v,
+// varint and fixed fields in x. Groups are skipped.
+func Walk(data []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var (
+			v []byte
+			x uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			x, n = protowire.ConsumeFixed64(data)
+		case protowire.Fixed32Type:
+			var x32 uint32
+			x32, n = protowire.ConsumeFixed32(data)
+			x = uint64(x32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package protowalk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestWalk(t *testing.T) {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, 150)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, "cpu")
+	b = protowire.AppendTag(b, 3, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, 7)
+	b = protowire.AppendTag(b, 4, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 9)
+
+	type field struct {
+		num protowire.Number
+		v   string
+		x   uint64
+	}
+	var fields []field
+	err := Walk(b, func(num protowire.Number, _ protowire.Type, v []byte, x uint64) error {
+		fields = append(fields, field{num: num, v: string(v), x: x})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []field{{num: 1, x: 150}, {num: 2, v: "cpu"}, {num: 3, x: 7}, {num: 4, x: 9}}, fields)
+}
+
+func TestWalkErrors(t *testing.T) {
+	// Поле обрезано посередине
+	b := protowire.AppendTag(nil, 2, protowire.BytesType)
+	b = protowire.AppendVarint(b, 10)
+	err := Walk(append(b, 'a'), func(protowire.Number, protowire.Type, []byte, uint64) error { return nil })
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	// Ошибка fn возвращается как есть
+	errStop := errors.New("stop")
+	b = protowire.AppendTag(nil, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+	err = Walk(b, func(protowire.Number, protowire.Type, []byte, uint64) error { return errStop })
+	assert.ErrorIs(t, err, errStop)
+	assert.NotErrorIs(t, err, ErrInvalidMessage)
+}
@@ -0,0 +1,2 @@
+// Package query implements functions evaluated over the history of a metric series.
+package query
@@ -0,0 +1,154 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// Func is a function evaluated over the samples of a series within a step window.
+type Func string
+
+// Available range functions.
+// Rate and Increase are applied to counters, the rest to gauges.
+const (
+	FuncRate     Func = "rate"
+	FuncIncrease Func = "increase"
+	FuncAvg      Func = "avg"
+	FuncMin      Func = "min"
+	FuncMax      Func = "max"
+	FuncLast     Func = "last"
+)
+
+// MaxPoints limits the number of steps in the range.
+const MaxPoints = 11000
+
+var (
+	ErrUnknownFunc   = errors.New("unknown range function")
+	ErrInvalidStep   = errors.New("step must be positive")
+	ErrInvalidTime   = errors.New("the end of the range is before its start")
+	ErrTooManyPoints = fmt.Errorf("the range exceeds %d steps, increase the step", MaxPoints)
+)
+
+// ParseFunc returns the range function by its name.
+func ParseFunc(name string) (Func, error) {
+	switch fn := Func(name); fn {
+	case FuncRate, FuncIncrease, FuncAvg, FuncMin, FuncMax, FuncLast:
+		return fn, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFunc, name)
+}
+
+// MetricType returns the type of the metrics the function is applied to.
+func (fn Func) MetricType() string {
+	if fn == FuncRate || fn == FuncIncrease {
+		return metrics.TypeCounter
+	}
+	return metrics.TypeGauge
+}
+
+// ValidateRange checks the range before its samples are read: the step must be positive,
+// the range must not end before its start and must not exceed MaxPoints steps.
+func ValidateRange(from, to time.Time, step time.Duration) error {
+	if step <= 0 {
+		return ErrInvalidStep
+	}
+	if to.Before(from) {
+		return ErrInvalidTime
+	}
+	if to.Sub(from)/step >= MaxPoints {
+		return ErrTooManyPoints
+	}
+	return nil
+}
+
+// Range evaluates fn at every step from the start to the end of the range.
+// The point at time t is calculated over the samples in the (t-step, t] window,
+// windows without samples are skipped. Samples must be sorted by time and
+// should begin one step before the start so that the first window is complete.
+func Range(fn Func, samples []metrics.Sample, from, to time.Time, step time.Duration) ([]metrics.Sample, error) {
+	if err := ValidateRange(from, to, step); err != nil {
+		return nil, err
+	}
+
+	points := make([]metrics.Sample, 0)
+	start := 0 // Первый отсчёт, который ещё не попал ни в одно окно
+	for t := from; !t.After(to); t = t.Add(step) {
+		windowStart := t.Add(-step)
+
+		// Отсчёты до начала окна нужны только как базовое значение для счётчиков
+		for start < len(samples) && !samples[start].Time.After(windowStart) {
+			start++
+		}
+		end := start
+		for end < len(samples) && !samples[end].Time.After(t) {
+			end++
+		}
+		if start == end {
+			continue
+		}
+
+		var base *metrics.Sample
+		if start > 0 {
+			base = &samples[start-1]
+		}
+
+		points = append(points, metrics.Sample{Time: t, Value: fn.eval(base, samples[start:end], step)})
+	}
+	return points, nil
+}
+
+// eval calculates the value of fn over the non-empty window.
+// base is the last sample before the window or nil if there is none.
+func (fn Func) eval(base *metrics.Sample, window []metrics.Sample, step time.Duration) float64 {
+	switch fn {
+	case FuncRate:
+		return increase(base, window) / step.Seconds()
+	case FuncIncrease:
+		return increase(base, window)
+	case FuncAvg:
+		var sum float64
+		for _, s := range window {
+			sum += s.Value
+		}
+		return sum / float64(len(window))
+	case FuncMin:
+		v := window[0].Value
+		for _, s := range window[1:] {
+			v = min(v, s.Value)
+		}
+		return v
+	case FuncMax:
+		v := window[0].Value
+		for _, s := range window[1:] {
+			v = max(v, s.Value)
+		}
+		return v
+	default:
+		return window[len(window)-1].Value
+	}
+}
+
+// increase returns the growth of the counter over the window.
+// A decrease of the value is treated as a counter reset: the counter started from zero again.
+func increase(base *metrics.Sample, window []metrics.Sample) float64 {
+	prev := window[0].Value
+	if base != nil {
+		prev = base.Value
+	} else {
+		window = window[1:]
+	}
+
+	var inc float64
+	for _, s := range window {
+		if s.Value < prev {
+			inc += s.Value
+		} else {
+			inc += s.Value - prev
+		}
+		prev = s.Value
+	}
+	return inc
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestParseFunc(t *testing.T) {
+	fn, err := ParseFunc("rate")
+	require.NoError(t, err)
+	assert.Equal(t, FuncRate, fn)
+	assert.Equal(t, metrics.TypeCounter, fn.MetricType())
+
+	fn, err = ParseFunc("avg")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.TypeGauge, fn.MetricType())
+
+	_, err = ParseFunc("sum")
+	assert.ErrorIs(t, err, ErrUnknownFunc)
+}
+
+func TestRange(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec int, v float64) metrics.Sample {
+		return metrics.Sample{Time: t0.Add(time.Duration(sec) * time.Second), Value: v}
+	}
+
+	gauge := []metrics.Sample{at(5, 1), at(10, 3), at(15, 2), at(25, 6)}
+	// Счётчик сбрасывается между 20 и 25 секундами
+	counter := []metrics.Sample{at(5, 10), at(10, 12), at(20, 20), at(25, 4)}
+
+	testCases := []struct {
+		name    string
+		fn      Func
+		samples []metrics.Sample
+		want    []metrics.Sample
+	}{
+		{
+			name:    "avg",
+			fn:      FuncAvg,
+			samples: gauge,
+			want:    []metrics.Sample{at(10, 2), at(20, 2), at(30, 6)},
+		},
+		{
+			name:    "min",
+			fn:      FuncMin,
+			samples: gauge,
+			want:    []metrics.Sample{at(10, 1), at(20, 2), at(30, 6)},
+		},
+		{
+			name:    "max",
+			fn:      FuncMax,
+			samples: gauge,
+			want:    []metrics.Sample{at(10, 3), at(20, 2), at(30, 6)},
+		},
+		{
+			name:    "last",
+			fn:      FuncLast,
+			samples: gauge,
+			want:    []metrics.Sample{at(10, 3), at(20, 2), at(30, 6)},
+		},
+		{
+			name:    "increase with counter reset",
+			fn:      FuncIncrease,
+			samples: counter,
+			want:    []metrics.Sample{at(10, 2), at(20, 8), at(30, 4)},
+		},
+		{
+			name:    "rate with counter reset",
+			fn:      FuncRate,
+			samples: counter,
+			want:    []metrics.Sample{at(10, 0.2), at(20, 0.8), at(30, 0.4)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Range(tc.fn, tc.samples, t0.Add(10*time.Second), t0.Add(30*time.Second), 10*time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRange_Errors(t *testing.T) {
+	t0 := time.Now()
+
+	_, err := Range(FuncAvg, nil, t0, t0.Add(time.Minute), 0)
+	assert.ErrorIs(t, err, ErrInvalidStep)
+
+	_, err = Range(FuncAvg, nil, t0, t0.Add(-time.Minute), time.Second)
+	assert.ErrorIs(t, err, ErrInvalidTime)
+
+	_, err = Range(FuncAvg, nil, t0, t0.Add(time.Hour), time.Millisecond)
+	assert.ErrorIs(t, err, ErrTooManyPoints)
+
+	got, err := Range(FuncAvg, nil, t0, t0.Add(time.Minute), time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
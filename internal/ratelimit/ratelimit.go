@@ -0,0 +1,138 @@
+// Package ratelimit limits the rate of the requests of every client with its own token bucket.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+// minIdleTTL is the minimal time after which the bucket of an idle client is forgotten.
+const minIdleTTL = time.Minute
+
+// Limiter is the set of the token buckets of the clients.
+// The bucket of the client is refilled with the rate tokens per second up to the burst.
+// The buckets of the clients that have been idle long enough to refill them are removed.
+//
+// The nil Limiter allows all requests.
+type Limiter struct {
+	limit   rate.Limit
+	burst   int
+	idleTTL time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*bucket
+	swept   time.Time // Время последней очистки неактивных клиентов
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// New returns the limiter of the requests per second with the burst, nil if the rate is not positive.
+// The burst is at least one request.
+func New(perSecond float64, burst int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	// Через это время ведро неактивного клиента заполнено полностью и его можно забыть
+	idleTTL := time.Duration(float64(burst) / perSecond * float64(time.Second))
+	if idleTTL < minIdleTTL {
+		idleTTL = minIdleTTL
+	}
+
+	return &Limiter{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		idleTTL: idleTTL,
+		now:     time.Now,
+		clients: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of the client.
+// If the bucket is empty, it returns false and the time after which the request may be retried.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.clients[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = b
+	}
+	b.seen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Len returns the number of the tracked clients.
+func (l *Limiter) Len() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.clients)
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.idleTTL {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.clients {
+		if now.Sub(b.seen) >= l.idleTTL {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// ClientKey returns the key of the bucket of the client.
+// The client is identified by its API token, then by its certificate and then by its address.
+func ClientKey(ctx context.Context, addr netip.Addr) string {
+	if token, ok := auth.FromContext(ctx); ok {
+		return TokenKey(token.Name)
+	}
+	if id, ok := identity.FromContext(ctx); ok && id.Name != "" {
+		return "cert:" + id.Name
+	}
+	return "ip:" + addr.String()
+}
+
+// TokenKey returns the key of the bucket of the API token.
+func TokenKey(name string) string {
+	return "token:" + name
+}
+
+// RetryAfter returns the value of the Retry-After header in whole seconds.
+func RetryAfter(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
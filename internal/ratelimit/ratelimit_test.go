@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fishus/go-advanced-metrics/internal/auth"
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+func newTestLimiter(perSecond float64, burst int) (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(perSecond, burst)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAllow(t *testing.T) {
+	l, now := newTestLimiter(1, 3)
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("ip:10.0.0.1")
+		assert.Truef(t, ok, "request #%d within the burst", i+1)
+	}
+
+	ok, delay := l.Allow("ip:10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	// Ведро другого клиента не зависит от первого
+	ok, _ = l.Allow("ip:10.0.0.2")
+	assert.True(t, ok)
+
+	// Отклонённый запрос не расходует токены: через секунду запрос снова проходит
+	*now = now.Add(time.Second)
+	ok, _ = l.Allow("ip:10.0.0.1")
+	assert.True(t, ok)
+	ok, _ = l.Allow("ip:10.0.0.1")
+	assert.False(t, ok)
+}
+
+func TestSweep(t *testing.T) {
+	l, now := newTestLimiter(10, 5)
+
+	l.Allow("ip:10.0.0.1")
+	l.Allow("ip:10.0.0.2")
+	assert.Equal(t, 2, l.Len())
+
+	*now = now.Add(minIdleTTL / 2)
+	l.Allow("ip:10.0.0.2")
+
+	*now = now.Add(minIdleTTL / 2)
+	l.Allow("ip:10.0.0.3")
+	assert.Equal(t, 2, l.Len(), "idle client is forgotten")
+}
+
+func TestNil(t *testing.T) {
+	l := New(0, 10)
+	assert.Nil(t, l)
+
+	for i := 0; i < 100; i++ {
+		ok, _ := l.Allow("ip:10.0.0.1")
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestClientKey(t *testing.T) {
+	addr := netip.MustParseAddr("10.0.0.1")
+	ctx := context.Background()
+
+	assert.Equal(t, "ip:10.0.0.1", ClientKey(ctx, addr))
+
+	ctx = identity.NewContext(ctx, identity.Identity{Name: "web1"})
+	assert.Equal(t, "cert:web1", ClientKey(ctx, addr))
+
+	ctx = auth.NewContext(ctx, auth.Token{Name: "agent-1"})
+	assert.Equal(t, "token:agent-1", ClientKey(ctx, addr))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 0, RetryAfter(0))
+	assert.Equal(t, 1, RetryAfter(100*time.Millisecond))
+	assert.Equal(t, 2, RetryAfter(1500*time.Millisecond))
+}
@@ -0,0 +1,7 @@
+// Package remotewrite implements a receiver of the Prometheus remote write protocol.
+//
+// The body of the request is a snappy-compressed protobuf WriteRequest message.
+// Samples of the counters are cumulative values, they are converted into the increments
+// of the counter series. The rest of the samples are stored as gauges. The timestamps
+// of the samples are not kept: the last sample of a series in the request becomes its value.
+package remotewrite
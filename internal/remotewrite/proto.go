@@ -0,0 +1,182 @@
+package remotewrite
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/fishus/go-advanced-metrics/internal/protowalk"
+)
+
+// MetricType is the type of a metric family in the metadata of the request.
+type MetricType int32
+
+// Metric types of the remote write protocol.
+const (
+	MetricTypeUnknown        MetricType = 0
+	MetricTypeCounter        MetricType = 1
+	MetricTypeGauge          MetricType = 2
+	MetricTypeHistogram      MetricType = 3
+	MetricTypeGaugeHistogram MetricType = 4
+	MetricTypeSummary        MetricType = 5
+	MetricTypeInfo           MetricType = 6
+	MetricTypeStateset       MetricType = 7
+)
+
+// WriteRequest is the message of the remote write protocol (prometheus.WriteRequest).
+// Only the fields used by the receiver are decoded.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+	Metadata   []MetricMetadata
+}
+
+// TimeSeries is a series identified by its labels with its samples.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Label is a name/value pair of a series. The metric name is the __name__ label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a series with the timestamp in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// MetricMetadata describes the type of a metric family.
+type MetricMetadata struct {
+	MetricFamilyName string
+	Type             MetricType
+}
+
+var ErrInvalidMessage = protowalk.ErrInvalidMessage
+
+// Unmarshal decodes the WriteRequest message. Unknown fields are skipped.
+func Unmarshal(data []byte) (*WriteRequest, error) {
+	req := &WriteRequest{}
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			ts, err := unmarshalTimeSeries(v)
+			if err != nil {
+				return err
+			}
+			req.Timeseries = append(req.Timeseries, ts)
+		case num == 3 && typ == protowire.BytesType:
+			md, err := unmarshalMetadata(v)
+			if err != nil {
+				return err
+			}
+			req.Metadata = append(req.Metadata, md)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func unmarshalTimeSeries(data []byte) (TimeSeries, error) {
+	var ts TimeSeries
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var l Label
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if typ != protowire.BytesType {
+					return nil
+				}
+				switch num {
+				case 1:
+					l.Name = string(v)
+				case 2:
+					l.Value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.Labels = append(ts.Labels, l)
+		case num == 2 && typ == protowire.BytesType:
+			var s Sample
+			err := protowalk.Walk(v, func(num protowire.Number, typ protowire.Type, _ []byte, x uint64) error {
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					s.Value = math.Float64frombits(x)
+				case num == 2 && typ == protowire.VarintType:
+					s.Timestamp = int64(x)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.Samples = append(ts.Samples, s)
+		}
+		return nil
+	})
+	return ts, err
+}
+
+func unmarshalMetadata(data []byte) (MetricMetadata, error) {
+	var md MetricMetadata
+	err := protowalk.Walk(data, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			md.Type = MetricType(x)
+		case num == 2 && typ == protowire.BytesType:
+			md.MetricFamilyName = string(v)
+		}
+		return nil
+	})
+	return md, err
+}
+
+// Marshal encodes the WriteRequest message.
+func Marshal(req *WriteRequest) []byte {
+	var b []byte
+	for _, ts := range req.Timeseries {
+		var bts []byte
+		for _, l := range ts.Labels {
+			var bl []byte
+			bl = protowire.AppendTag(bl, 1, protowire.BytesType)
+			bl = protowire.AppendString(bl, l.Name)
+			bl = protowire.AppendTag(bl, 2, protowire.BytesType)
+			bl = protowire.AppendString(bl, l.Value)
+
+			bts = protowire.AppendTag(bts, 1, protowire.BytesType)
+			bts = protowire.AppendBytes(bts, bl)
+		}
+		for _, s := range ts.Samples {
+			var bs []byte
+			bs = protowire.AppendTag(bs, 1, protowire.Fixed64Type)
+			bs = protowire.AppendFixed64(bs, math.Float64bits(s.Value))
+			bs = protowire.AppendTag(bs, 2, protowire.VarintType)
+			bs = protowire.AppendVarint(bs, uint64(s.Timestamp))
+
+			bts = protowire.AppendTag(bts, 2, protowire.BytesType)
+			bts = protowire.AppendBytes(bts, bs)
+		}
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, bts)
+	}
+	for _, md := range req.Metadata {
+		var bmd []byte
+		bmd = protowire.AppendTag(bmd, 1, protowire.VarintType)
+		bmd = protowire.AppendVarint(bmd, uint64(md.Type))
+		bmd = protowire.AppendTag(bmd, 2, protowire.BytesType)
+		bmd = protowire.AppendString(bmd, md.MetricFamilyName)
+
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, bmd)
+	}
+	return b
+}
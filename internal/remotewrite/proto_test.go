@@ -0,0 +1,29 @@
+package remotewrite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	req := &WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels:  []Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}},
+				Samples: []Sample{{Value: 10, Timestamp: 1700000000000}, {Value: 12.5, Timestamp: 1700000015000}},
+			},
+		},
+		Metadata: []MetricMetadata{{MetricFamilyName: "http_requests", Type: MetricTypeCounter}},
+	}
+
+	got, err := Unmarshal(Marshal(req))
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	_, err := Unmarshal([]byte{0x0a, 0x05, 0x01})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
@@ -0,0 +1,187 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+// ErrTooLarge is returned when the decompressed message is larger than the limit.
+var ErrTooLarge = errors.New("decompressed message is too large")
+
+// Decode reads the snappy-compressed WriteRequest message.
+// The message whose decompressed size is larger than maxSize bytes is rejected with ErrTooLarge
+// before it is decompressed. Nothing is checked when maxSize is not positive.
+func Decode(r io.Reader, maxSize int64) (*WriteRequest, error) {
+	if maxSize > 0 {
+		// Сжатые данные не бывают больше, чем snappy.MaxEncodedLen от распакованных
+		r = io.LimitReader(r, int64(snappy.MaxEncodedLen(int(maxSize)))+1)
+	}
+	compressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snappy data: %w", err)
+	}
+	if maxSize > 0 && (int64(size) > maxSize || int64(len(compressed)) > int64(snappy.MaxEncodedLen(int(maxSize)))) {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snappy data: %w", err)
+	}
+
+	return Unmarshal(data)
+}
+
+// ErrInvalidSeries is returned when a series of the request cannot be stored.
+var ErrInvalidSeries = errors.New("invalid series")
+
+// Receiver stores the samples of the remote write requests.
+type Receiver struct {
+	storage  store.MetricsStorager
+	counters *metrics.CumulativeCounters
+	mu       sync.Mutex
+}
+
+func NewReceiver(storage store.MetricsStorager) *Receiver {
+	return &Receiver{
+		storage:  storage,
+		counters: metrics.NewCumulativeCounters(),
+	}
+}
+
+// Write converts the samples into gauges and counter increments and stores them in one batch.
+// Returns the number of the stored series.
+func (rc *Receiver) Write(ctx context.Context, req *WriteRequest) (int, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	types := make(map[string]MetricType, len(req.Metadata))
+	for _, md := range req.Metadata {
+		types[md.MetricFamilyName] = md.Type
+	}
+
+	gauges := make(map[string]metrics.Gauge)
+	counters := make(map[string]metrics.Counter)
+	last := make(map[string]float64)
+
+	for _, ts := range req.Timeseries {
+		name, labels := seriesLabels(ts.Labels)
+		if name == "" {
+			return 0, fmt.Errorf("%w %v: no metric name", ErrInvalidSeries, ts.Labels)
+		}
+
+		samples := make([]Sample, 0, len(ts.Samples))
+		for _, s := range ts.Samples {
+			// NaN используется как маркер устаревшей серии
+			if !math.IsNaN(s.Value) {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].Timestamp < samples[j].Timestamp
+		})
+
+		if !isCounter(name, types) {
+			g, err := metrics.NewGaugeWithLabels(name, labels, samples[len(samples)-1].Value)
+			if err != nil {
+				return 0, fmt.Errorf("%w %s: %w", ErrInvalidSeries, name, err)
+			}
+			gauges[g.Key()] = *g
+			continue
+		}
+
+		values := make([]float64, len(samples))
+		for i, s := range samples {
+			values[i] = s.Value
+		}
+		key := metrics.SeriesKey(name, labels)
+		delta := rc.counters.Delta(last, key, values, func() (float64, bool) {
+			c, found := rc.storage.CounterContext(ctx, name, labels)
+			return float64(c.Value()), found
+		})
+
+		inc := int64(math.Round(delta))
+		if c, exists := counters[key]; exists {
+			inc += c.Value()
+		}
+		c, err := metrics.NewCounterWithLabels(name, labels, inc)
+		if err != nil {
+			return 0, fmt.Errorf("%w %s: %w", ErrInvalidSeries, name, err)
+		}
+		counters[key] = *c
+	}
+
+	gaugesBatch := make([]metrics.Gauge, 0, len(gauges))
+	for _, g := range gauges {
+		gaugesBatch = append(gaugesBatch, g)
+	}
+	countersBatch := make([]metrics.Counter, 0, len(counters))
+	for _, c := range counters {
+		countersBatch = append(countersBatch, c)
+	}
+
+	err := rc.storage.InsertBatchContext(ctx, store.WithGauges(gaugesBatch), store.WithCounters(countersBatch))
+	if err != nil {
+		return 0, err
+	}
+
+	rc.counters.Commit(last)
+	return len(gaugesBatch) + len(countersBatch), nil
+}
+
+// seriesLabels returns the metric name and the rest of the labels of the series.
+func seriesLabels(labels []Label) (string, metrics.Labels) {
+	var name string
+	ml := make(metrics.Labels, len(labels))
+	for _, l := range labels {
+		if l.Name == "__name__" {
+			name = l.Value
+			continue
+		}
+		ml[l.Name] = l.Value
+	}
+	return name, ml.Clone()
+}
+
+// isCounter reports whether the series is a counter according to the metadata of its family
+// or, without the metadata, to the naming conventions of Prometheus.
+func isCounter(name string, types map[string]MetricType) bool {
+	family := name
+	for _, suffix := range []string{"_total", "_bucket", "_count", "_sum"} {
+		if f, ok := strings.CutSuffix(name, suffix); ok {
+			if _, exists := types[f]; exists {
+				family = f
+			}
+			break
+		}
+	}
+
+	switch types[family] {
+	case MetricTypeCounter:
+		return true
+	case MetricTypeHistogram, MetricTypeSummary:
+		return !strings.HasSuffix(name, "_sum") && family != name
+	case MetricTypeUnknown:
+		return strings.HasSuffix(name, "_total")
+	}
+	return false
+}
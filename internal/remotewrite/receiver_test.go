@@ -0,0 +1,127 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	store "github.com/fishus/go-advanced-metrics/internal/storage"
+)
+
+func series(name string, labels map[string]string, values ...float64) TimeSeries {
+	ts := TimeSeries{Labels: []Label{{Name: "__name__", Value: name}}}
+	for k, v := range labels {
+		ts.Labels = append(ts.Labels, Label{Name: k, Value: v})
+	}
+	for i, v := range values {
+		ts.Samples = append(ts.Samples, Sample{Value: v, Timestamp: int64(i) * 1000})
+	}
+	return ts
+}
+
+func TestDecode(t *testing.T) {
+	req := &WriteRequest{Timeseries: []TimeSeries{series("up", nil, 1)}}
+	body := snappy.Encode(nil, Marshal(req))
+
+	got, err := Decode(bytes.NewReader(body), 0)
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+
+	got, err = Decode(bytes.NewReader(body), 1024)
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+
+	_, err = Decode(bytes.NewReader([]byte("not snappy")), 0)
+	assert.Error(t, err)
+}
+
+func TestDecodeTooLarge(t *testing.T) {
+	// Заголовок snappy обещает почти 4 ГБ распакованных данных
+	_, err := Decode(bytes.NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x0F, 0x00}), 1<<20)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	body := snappy.Encode(nil, make([]byte, 2048))
+	_, err = Decode(bytes.NewReader(body), 1024)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	// Сжатое тело больше, чем может занимать сообщение допустимого размера
+	_, err = Decode(bytes.NewReader(make([]byte, 4096)), 1024)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestReceiver_Write(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+	rc := NewReceiver(storage)
+
+	n, err := rc.Write(ctx, &WriteRequest{
+		Timeseries: []TimeSeries{
+			series("node_memory_free_bytes", map[string]string{"instance": "web1"}, 100, 200),
+			series("http_requests_total", map[string]string{"job": "api"}, 10, 15),
+			series("jobs_processed", nil, 7),
+			series("go_gc_duration_seconds_sum", nil, 1.5),
+			series("go_gc_duration_seconds_count", nil, 3),
+			series("stale", nil, math.NaN()),
+		},
+		Metadata: []MetricMetadata{
+			{MetricFamilyName: "jobs_processed", Type: MetricTypeCounter},
+			{MetricFamilyName: "go_gc_duration_seconds", Type: MetricTypeSummary},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	v, ok := storage.GaugeValueContext(ctx, "node_memory_free_bytes", metrics.Labels{"instance": "web1"})
+	require.True(t, ok)
+	assert.Equal(t, float64(200), v)
+
+	c, ok := storage.CounterValueContext(ctx, "http_requests_total", metrics.Labels{"job": "api"})
+	require.True(t, ok)
+	assert.Equal(t, int64(15), c)
+
+	c, ok = storage.CounterValue("jobs_processed")
+	require.True(t, ok)
+	assert.Equal(t, int64(7), c)
+
+	_, ok = storage.GaugeValue("go_gc_duration_seconds_sum")
+	assert.True(t, ok)
+	_, ok = storage.CounterValue("go_gc_duration_seconds_count")
+	assert.True(t, ok)
+	_, ok = storage.GaugeValue("stale")
+	assert.False(t, ok)
+
+	// Следующие значения счётчика добавляются как приращения, с учётом сброса
+	_, err = rc.Write(ctx, &WriteRequest{
+		Timeseries: []TimeSeries{series("http_requests_total", map[string]string{"job": "api"}, 20, 3)},
+	})
+	require.NoError(t, err)
+
+	c, ok = storage.CounterValueContext(ctx, "http_requests_total", metrics.Labels{"job": "api"})
+	require.True(t, ok)
+	assert.Equal(t, int64(23), c)
+}
+
+func TestReceiver_WriteContinuesStoredCounter(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemStorage()
+	require.NoError(t, storage.AddCounter("requests_total", 50))
+
+	_, err := NewReceiver(storage).Write(ctx, &WriteRequest{Timeseries: []TimeSeries{series("requests_total", nil, 60)}})
+	require.NoError(t, err)
+
+	c, _ := storage.CounterValue("requests_total")
+	assert.Equal(t, int64(60), c)
+}
+
+func TestReceiver_WriteWithoutName(t *testing.T) {
+	_, err := NewReceiver(store.NewMemStorage()).Write(context.Background(), &WriteRequest{
+		Timeseries: []TimeSeries{{Labels: []Label{{Name: "job", Value: "api"}}, Samples: []Sample{{Value: 1}}}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidSeries)
+}
@@ -0,0 +1,204 @@
+package secure
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// KeyIDHeader is the header with the ID of the key that signed the request or the response.
+// It is not sent when the key has no ID, e.g. the single secret key of the older versions.
+const KeyIDHeader = "X-Signature-Key-Id"
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrIntegrity  = errors.New("Data integrity has been compromised")
+)
+
+// Key is the HMAC secret key with its ID.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring holds the keys accepted for the signature verification and the key that signs the responses.
+// Several keys allow to rotate the secret without restarting the server and all the agents at the same time:
+// the new key is added to the keyring, the agents are switched to it one by one, and the old key is removed.
+//
+// The keyring is loaded from the JSON file:
+//
+//	{
+//	  "signing_key": "2024-02",
+//	  "keys": [
+//	    {"id": "2024-01", "secret": "old secret"},
+//	    {"id": "2024-02", "secret": "new secret"}
+//	  ]
+//	}
+//
+// The nil keyring has no keys, nothing is signed or verified with it.
+type Keyring struct {
+	path   string
+	legacy []byte // Ключ без ID из настроек сервера, принимается вместе с ключами из файла
+
+	mu      sync.RWMutex
+	keys    []Key
+	signing Key
+}
+
+type keyringFile struct {
+	SigningKey string `json:"signing_key"`
+	Keys       []struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	} `json:"keys"`
+}
+
+// NewKeyring returns the keyring with the single key without ID, nil if the key is empty.
+func NewKeyring(secret []byte) *Keyring {
+	if len(secret) == 0 {
+		return nil
+	}
+	key := Key{Secret: secret}
+	return &Keyring{legacy: secret, keys: []Key{key}, signing: key}
+}
+
+// LoadKeyring reads the keyring from the file. The legacy key without ID is accepted
+// along with the keys of the file, so the agents that do not send the key ID keep working.
+func LoadKeyring(path string, legacy []byte) (*Keyring, error) {
+	k := &Keyring{path: path, legacy: legacy}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads the keyring file again. The loaded keys are kept if the file is invalid.
+func (k *Keyring) Reload() error {
+	if k == nil || k.path == "" {
+		return nil
+	}
+
+	keys, signing, err := readKeyring(k.path)
+	if err != nil {
+		return err
+	}
+	if len(k.legacy) > 0 {
+		keys = append(keys, Key{Secret: k.legacy})
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.signing = signing
+	return nil
+}
+
+// Empty reports whether the keyring has no keys.
+func (k *Keyring) Empty() bool {
+	if k == nil {
+		return true
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys) == 0
+}
+
+// Signing returns the key that signs the responses.
+func (k *Keyring) Signing() Key {
+	if k == nil {
+		return Key{}
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.signing
+}
+
+// Candidates returns the keys that may have signed the request with the key ID.
+// All the keys are returned when the ID was not passed. ErrUnknownKey is returned for the unknown ID.
+func (k *Keyring) Candidates(id string) ([]Key, error) {
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if id == "" {
+		return append([]Key(nil), k.keys...), nil
+	}
+	for _, key := range k.keys {
+		if key.ID == id {
+			return []Key{key}, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// Verify returns the key whose signature calculated by sum is equal to the hash.
+// The key ID narrows the keys to check, ErrIntegrity is returned when none of them matches.
+func (k *Keyring) Verify(id string, hash []byte, sum func(secret []byte) []byte) (Key, error) {
+	keys, err := k.Candidates(id)
+	if err != nil {
+		return Key{}, err
+	}
+	for _, key := range keys {
+		if hmac.Equal(hash, sum(key.Secret)) {
+			return key, nil
+		}
+	}
+	return Key{}, ErrIntegrity
+}
+
+type keyContextKey struct{}
+
+// NewKeyContext returns the context with the key that signed the request.
+func NewKeyContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the key that signed the request.
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(Key)
+	return key, ok
+}
+
+func readKeyring(path string) ([]Key, Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Key{}, err
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, Key{}, fmt.Errorf("keyring %s: %w", path, err)
+	}
+
+	var signing Key
+	keys := make([]Key, 0, len(file.Keys))
+	seen := make(map[string]bool, len(file.Keys))
+	for i, key := range file.Keys {
+		if key.ID == "" {
+			return nil, Key{}, fmt.Errorf("keyring %s: key #%d has no id", path, i+1)
+		}
+		if key.Secret == "" {
+			return nil, Key{}, fmt.Errorf("keyring %s: key %q has no secret", path, key.ID)
+		}
+		if seen[key.ID] {
+			return nil, Key{}, fmt.Errorf("keyring %s: duplicate key %q", path, key.ID)
+		}
+		seen[key.ID] = true
+
+		keys = append(keys, Key{ID: key.ID, Secret: []byte(key.Secret)})
+		if key.ID == file.SigningKey {
+			signing = keys[len(keys)-1]
+		}
+	}
+
+	if signing.ID == "" {
+		return nil, Key{}, fmt.Errorf("keyring %s: signing key %q is not found", path, file.SigningKey)
+	}
+	return keys, signing, nil
+}
@@ -0,0 +1,112 @@
+package secure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyring(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
+
+func TestKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, `{"signing_key": "new", "keys": [
+		{"id": "old", "secret": "old secret"},
+		{"id": "new", "secret": "new secret"}
+	]}`)
+
+	keys, err := LoadKeyring(path, []byte("legacy secret"))
+	require.NoError(t, err)
+	assert.False(t, keys.Empty())
+	assert.Equal(t, Key{ID: "new", Secret: []byte("new secret")}, keys.Signing())
+
+	data := []byte("data")
+	sum := func(secret []byte) []byte { return Hash(data, secret) }
+
+	testCases := []struct {
+		name   string
+		id     string
+		secret string
+		want   string
+		err    error
+	}{
+		{name: "Old key", id: "old", secret: "old secret", want: "old"},
+		{name: "New key", id: "new", secret: "new secret", want: "new"},
+		{name: "Key without ID", secret: "old secret", want: "old"},
+		{name: "Legacy key", secret: "legacy secret", want: ""},
+		{name: "Legacy key with ID", id: "old", secret: "legacy secret", err: ErrIntegrity},
+		{name: "Unknown key ID", id: "unknown", secret: "old secret", err: ErrUnknownKey},
+		{name: "Unknown secret", secret: "unknown secret", err: ErrIntegrity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := keys.Verify(tc.id, Hash(data, []byte(tc.secret)), sum)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, key.ID)
+		})
+	}
+}
+
+func TestKeyringReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, `{"signing_key": "old", "keys": [{"id": "old", "secret": "old secret"}]}`)
+
+	keys, err := LoadKeyring(path, nil)
+	require.NoError(t, err)
+
+	writeKeyring(t, path, `{"signing_key": "new", "keys": [{"id": "new", "secret": "new secret"}]}`)
+	require.NoError(t, keys.Reload())
+	assert.Equal(t, "new", keys.Signing().ID)
+	_, err = keys.Candidates("old")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// Ошибочный файл не сбрасывает загруженные ключи
+	writeKeyring(t, path, `{"signing_key": "new", "keys": [`)
+	assert.Error(t, keys.Reload())
+	assert.Equal(t, "new", keys.Signing().ID)
+}
+
+func TestLoadKeyringErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Invalid json", data: `{"keys": `},
+		{name: "Missing signing key", data: `{"keys": [{"id": "a", "secret": "a"}]}`},
+		{name: "Unknown signing key", data: `{"signing_key": "b", "keys": [{"id": "a", "secret": "a"}]}`},
+		{name: "Missing id", data: `{"signing_key": "a", "keys": [{"id": "a", "secret": "a"}, {"secret": "b"}]}`},
+		{name: "Missing secret", data: `{"signing_key": "a", "keys": [{"id": "a"}]}`},
+		{name: "Duplicate id", data: `{"signing_key": "a", "keys": [{"id": "a", "secret": "a"}, {"id": "a", "secret": "b"}]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keyring.json")
+			writeKeyring(t, path, tc.data)
+
+			_, err := LoadKeyring(path, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	assert.Nil(t, NewKeyring(nil))
+	assert.True(t, NewKeyring(nil).Empty())
+	assert.Equal(t, Key{}, NewKeyring(nil).Signing())
+
+	keys := NewKeyring([]byte("secret"))
+	assert.Equal(t, Key{Secret: []byte("secret")}, keys.Signing())
+	assert.NoError(t, keys.Reload())
+}
@@ -0,0 +1,154 @@
+package secure
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of the signed request that protect it from being replayed.
+// Both values are covered by the HashSHA256 signature.
+const (
+	TimestampHeader = "X-Signature-Timestamp"
+	NonceHeader     = "X-Signature-Nonce"
+)
+
+var (
+	ErrMissingNonce    = errors.New("request timestamp or nonce is missing")
+	ErrStaleRequest    = errors.New("request timestamp is outside of the allowed window")
+	ErrReplayedRequest = errors.New("request has already been received")
+)
+
+// RequestHash returns the signature of the request body bound to the timestamp and the nonce.
+// Without them the signature is the same as Hash of the body.
+func RequestHash(data, key []byte, timestamp, nonce string) []byte {
+	if timestamp == "" && nonce == "" {
+		return Hash(data, key)
+	}
+
+	sign := NewSign(key)
+	sign.Write([]byte(timestamp))
+	sign.Write([]byte{'\n'})
+	sign.Write([]byte(nonce))
+	sign.Write([]byte{'\n'})
+	sign.Write(data)
+	return sign.Sum()
+}
+
+// NewNonce returns the timestamp and the random nonce of a new signed request.
+func NewNonce() (timestamp, nonce string, err error) {
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return strconv.FormatInt(time.Now().Unix(), 10), hex.EncodeToString(b), nil
+}
+
+// ReplayGuard rejects the signed requests whose timestamp is outside of the clock skew window
+// and the requests whose nonce has already been seen within the window.
+//
+// The nonce cache is bounded. When it is full, the oldest nonce is evicted and the requests
+// not newer than its timestamp are rejected from then on, so an evicted nonce can't be replayed.
+type ReplayGuard struct {
+	window time.Duration
+	size   int
+	now    func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]*list.Element
+	order  *list.List // Порядок добавления nonce, в начале самые старые
+	floor  int64      // Запросы с меткой времени не новее вытесненной отклоняются
+}
+
+type nonceEntry struct {
+	nonce     string
+	timestamp int64
+	expires   time.Time
+}
+
+// NewReplayGuard returns the guard with the clock skew window and the nonce cache size.
+func NewReplayGuard(window time.Duration, size int) *ReplayGuard {
+	if size < 1 {
+		size = 1
+	}
+	return &ReplayGuard{
+		window: window,
+		size:   size,
+		now:    time.Now,
+		nonces: make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// Check verifies the timestamp and remembers the nonce of the request.
+// The timestamp is the number of seconds since the Unix epoch.
+func (g *ReplayGuard) Check(timestamp, nonce string) error {
+	if timestamp == "" || nonce == "" {
+		return ErrMissingNonce
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleRequest
+	}
+
+	now := g.now()
+	if diff := now.Sub(time.Unix(ts, 0)); diff > g.window || diff < -g.window {
+		return ErrStaleRequest
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.evictExpired(now)
+
+	if ts <= g.floor {
+		return ErrStaleRequest
+	}
+	if _, ok := g.nonces[nonce]; ok {
+		return ErrReplayedRequest
+	}
+
+	if g.order.Len() >= g.size {
+		oldest := g.order.Front()
+		entry := oldest.Value.(nonceEntry)
+		if entry.timestamp > g.floor {
+			g.floor = entry.timestamp
+		}
+		g.remove(oldest)
+
+		if ts <= g.floor {
+			return ErrStaleRequest
+		}
+	}
+
+	// После выхода метки времени из окна запрос будет отклонён и без nonce
+	expires := time.Unix(ts, 0).Add(g.window)
+	g.nonces[nonce] = g.order.PushBack(nonceEntry{nonce: nonce, timestamp: ts, expires: expires})
+	return nil
+}
+
+// Len returns the number of the remembered nonces.
+func (g *ReplayGuard) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.order.Len()
+}
+
+func (g *ReplayGuard) evictExpired(now time.Time) {
+	for e := g.order.Front(); e != nil; e = g.order.Front() {
+		if e.Value.(nonceEntry).expires.After(now) {
+			return
+		}
+		g.remove(e)
+	}
+}
+
+func (g *ReplayGuard) remove(e *list.Element) {
+	delete(g.nonces, e.Value.(nonceEntry).nonce)
+	g.order.Remove(e)
+}
@@ -0,0 +1,96 @@
+package secure
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestHash(t *testing.T) {
+	key := []byte("secret")
+	data := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+
+	assert.Equal(t, Hash(data, key), RequestHash(data, key, "", ""))
+	assert.NotEqual(t, Hash(data, key), RequestHash(data, key, "1700000000", "abc"))
+	assert.NotEqual(t, RequestHash(data, key, "1700000000", "abc"), RequestHash(data, key, "1700000001", "abc"))
+	assert.NotEqual(t, RequestHash(data, key, "1700000000", "abc"), RequestHash(data, key, "1700000000", "abd"))
+}
+
+func TestNewNonce(t *testing.T) {
+	ts1, nonce1, err := NewNonce()
+	require.NoError(t, err)
+	_, nonce2, err := NewNonce()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, nonce1, nonce2)
+	_, err = strconv.ParseInt(ts1, 10, 64)
+	assert.NoError(t, err)
+}
+
+func TestReplayGuard(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	stamp := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+
+	newGuard := func(size int) *ReplayGuard {
+		g := NewReplayGuard(time.Minute, size)
+		g.now = func() time.Time { return now }
+		return g
+	}
+
+	t.Run("Fresh request", func(t *testing.T) {
+		g := newGuard(10)
+		assert.NoError(t, g.Check(stamp(0), "a"))
+		assert.NoError(t, g.Check(stamp(-30*time.Second), "b"))
+		assert.NoError(t, g.Check(stamp(30*time.Second), "c"))
+	})
+
+	t.Run("Replayed request", func(t *testing.T) {
+		g := newGuard(10)
+		require.NoError(t, g.Check(stamp(0), "a"))
+		assert.ErrorIs(t, g.Check(stamp(0), "a"), ErrReplayedRequest)
+	})
+
+	t.Run("Stale request", func(t *testing.T) {
+		g := newGuard(10)
+		assert.ErrorIs(t, g.Check(stamp(-2*time.Minute), "a"), ErrStaleRequest)
+		assert.ErrorIs(t, g.Check(stamp(2*time.Minute), "b"), ErrStaleRequest)
+		assert.ErrorIs(t, g.Check("yesterday", "c"), ErrStaleRequest)
+	})
+
+	t.Run("Missing nonce", func(t *testing.T) {
+		g := newGuard(10)
+		assert.ErrorIs(t, g.Check(stamp(0), ""), ErrMissingNonce)
+		assert.ErrorIs(t, g.Check("", "a"), ErrMissingNonce)
+	})
+
+	t.Run("Expired nonces are evicted", func(t *testing.T) {
+		g := newGuard(10)
+		require.NoError(t, g.Check(stamp(0), "a"))
+		require.NoError(t, g.Check(stamp(0), "b"))
+
+		now = now.Add(2 * time.Minute)
+		defer func() { now = now.Add(-2 * time.Minute) }()
+
+		require.NoError(t, g.Check(stamp(0), "c"))
+		assert.Equal(t, 1, g.Len())
+		// Повтор старого запроса отклоняется по времени
+		assert.ErrorIs(t, g.Check(stamp(-2*time.Minute), "a"), ErrStaleRequest)
+	})
+
+	t.Run("Full cache", func(t *testing.T) {
+		g := newGuard(2)
+		require.NoError(t, g.Check(stamp(-3*time.Second), "a"))
+		require.NoError(t, g.Check(stamp(-2*time.Second), "b"))
+		require.NoError(t, g.Check(stamp(-1*time.Second), "c"))
+		assert.Equal(t, 2, g.Len())
+
+		// Вытесненный nonce нельзя повторить
+		assert.ErrorIs(t, g.Check(stamp(-3*time.Second), "a"), ErrStaleRequest)
+		assert.NoError(t, g.Check(stamp(0), "d"))
+	})
+}
@@ -1,22 +1,54 @@
 package server
 
 import (
-	"net"
+	"strings"
 	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/clientip"
 )
 
 type config struct {
-	serverAddr      string        // serverAddr store address and port to send requests to a server
-	fileStoragePath string        // Полное имя файла, куда сохраняются текущие значения
-	databaseDSN     string        // Строка подключения к БД
-	secretKey       string        // Ключ для подписи данных
-	privateKeyPath  string        // Путь до файла с приватным ключом
-	logLevel        string        //
-	configFile      string        // Путь к файлу конфигурации
-	trustedSubnet   *net.IPNet    // Доверенная подсеть
-	storeInterval   time.Duration // Периодичность, с которой текущие показания сервера сохраняются на диск (в секундах)
-	isReqRestore    bool          // Загружать ранее сохранённые значения из файла при старте сервера
-	serverType      ServerType
+	serverAddr          string        // serverAddr store address and port to send requests to a server
+	grpcAddr            string        // Адрес gRPC-сервера, запускаемого вместе с REST-сервером
+	fileStoragePath     string        // Полное имя файла, куда сохраняются текущие значения
+	databaseDSN         string        // Строка подключения к БД
+	secretKey           string        // Ключ для подписи данных
+	keyringPath         string        // Путь до файла со связкой ключей подписи, перечитывается по SIGHUP
+	privateKeyPath      string        // Путь до файла с приватным ключом
+	tokensPath          string        // Путь до файла с API-токенами агентов, пустое значение отключает проверку токенов
+	auditPath           string        // Путь до файла журнала аудита, пустое значение отключает аудит
+	tlsCertPath         string        // Путь до файла с сертификатом сервера для TLS
+	tlsKeyPath          string        // Путь до файла с ключом сертификата сервера
+	tlsClientCAPath     string        // Путь до файла с сертификатами CA клиентов, включает mTLS
+	logLevel            string        //
+	configFile          string        // Путь к файлу конфигурации
+	alertRulesPath      string        // Путь к файлу с правилами алертинга
+	alertFile           string        // Файл для уведомлений об алертах, "-" - stdout
+	alertWebhooks       []string      // Адреса вебхуков для уведомлений об алертах
+	statsdUDPAddr       string        // Адрес для приёма метрик StatsD по UDP
+	statsdTCPAddr       string        // Адрес для приёма метрик StatsD по TCP
+	graphiteAddr        string        // Адрес для приёма метрик Graphite по TCP
+	graphiteTemplates   []string      // Шаблоны выделения меток из пути метрики Graphite
+	replayWindow        time.Duration // Допустимое расхождение часов для подписанных запросов, 0 - без защиты от повтора
+	storeInterval       time.Duration // Периодичность, с которой текущие показания сервера сохраняются на диск (в секундах)
+	retention           time.Duration // Срок хранения истории значений метрик, 0 - история не ведётся
+	alertInterval       time.Duration // Периодичность проверки правил алертинга
+	alertGroupInterval  time.Duration // Минимальный интервал между уведомлениями об алертах одного правила
+	alertRepeatInterval time.Duration // Через сколько повторить уведомление о сработавших алертах
+	statsdFlushInterval time.Duration // Периодичность записи накопленных метрик StatsD
+	graphiteMaxConns    int           // Максимальное число соединений Graphite, 0 - без ограничений
+	nonceCacheSize      int           // Максимальное число запоминаемых nonce подписанных запросов
+	isReqRestore        bool          // Загружать ранее сохранённые значения из файла при старте сервера
+	serverType          ServerType
+	trustedSubnets      clientip.Subnets // Доверенные подсети IPv4 и IPv6
+	trustedProxies      clientip.Subnets // Прокси, заголовкам X-Forwarded-For и X-Real-IP которых можно доверять
+	maxBodySize         int64            // Максимальный размер тела запроса в байтах, 0 - без ограничений
+	maxDecompressedSize int64            // Максимальный размер распакованного тела запроса в байтах, 0 - без ограничений
+	maxBatchSize        int              // Максимальное количество метрик в пакете, 0 - без ограничений
+	clientRateLimit     float64          // Запросов записи в секунду от одного клиента, 0 - без ограничений
+	clientRateBurst     int              // Сколько запросов записи клиент может отправить разом сверх лимита
+	auditMaxSize        int64            // Размер файла журнала аудита в байтах, при котором он ротируется, 0 - без ротации
+	auditMaxBackups     int              // Сколько старых файлов журнала аудита хранить после ротации
 }
 
 type ServerType string
@@ -28,12 +60,24 @@ const (
 
 func newConfig() config {
 	return config{
-		serverAddr:      "localhost:8080",
-		fileStoragePath: "/tmp/metrics-db.json",
-		logLevel:        "info",
-		storeInterval:   300 * time.Second,
-		isReqRestore:    true,
-		serverType:      ServerTypeREST,
+		serverAddr:          "localhost:8080",
+		fileStoragePath:     "/tmp/metrics-db.json",
+		logLevel:            "info",
+		storeInterval:       300 * time.Second,
+		alertInterval:       15 * time.Second,
+		alertGroupInterval:  time.Minute,
+		alertRepeatInterval: 4 * time.Hour,
+		statsdFlushInterval: 10 * time.Second,
+		graphiteMaxConns:    100,
+		nonceCacheSize:      100000,
+		isReqRestore:        true,
+		serverType:          ServerTypeREST,
+		maxBodySize:         10 << 20,
+		maxDecompressedSize: 64 << 20,
+		maxBatchSize:        10000,
+		clientRateBurst:     10,
+		auditMaxSize:        10 << 20,
+		auditMaxBackups:     5,
 	}
 }
 
@@ -60,6 +104,154 @@ func (c config) SetStoreIntervalInSeconds(s uint) config {
 	return c
 }
 
+func (c config) Retention() time.Duration {
+	return c.retention
+}
+
+func (c config) SetRetention(t time.Duration) config {
+	c.retention = t
+	return c
+}
+
+func (c config) AlertRulesPath() string {
+	return c.alertRulesPath
+}
+
+func (c config) SetAlertRulesPath(path string) config {
+	c.alertRulesPath = path
+	return c
+}
+
+func (c config) AlertInterval() time.Duration {
+	return c.alertInterval
+}
+
+func (c config) SetAlertInterval(t time.Duration) config {
+	c.alertInterval = t
+	return c
+}
+
+func (c config) AlertFile() string {
+	return c.alertFile
+}
+
+func (c config) SetAlertFile(path string) config {
+	c.alertFile = path
+	return c
+}
+
+func (c config) AlertWebhooks() []string {
+	return c.alertWebhooks
+}
+
+func (c config) SetAlertWebhooks(urls []string) config {
+	c.alertWebhooks = urls
+	return c
+}
+
+// SetAlertWebhooksFromString sets the webhook URLs from a comma-separated list.
+func (c config) SetAlertWebhooksFromString(urls string) config {
+	c.alertWebhooks = nil
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			c.alertWebhooks = append(c.alertWebhooks, url)
+		}
+	}
+	return c
+}
+
+func (c config) AlertGroupInterval() time.Duration {
+	return c.alertGroupInterval
+}
+
+func (c config) SetAlertGroupInterval(t time.Duration) config {
+	c.alertGroupInterval = t
+	return c
+}
+
+func (c config) AlertRepeatInterval() time.Duration {
+	return c.alertRepeatInterval
+}
+
+func (c config) SetAlertRepeatInterval(t time.Duration) config {
+	c.alertRepeatInterval = t
+	return c
+}
+
+func (c config) StatsdUDPAddr() string {
+	return c.statsdUDPAddr
+}
+
+func (c config) SetStatsdUDPAddr(addr string) config {
+	c.statsdUDPAddr = addr
+	return c
+}
+
+func (c config) StatsdTCPAddr() string {
+	return c.statsdTCPAddr
+}
+
+func (c config) SetStatsdTCPAddr(addr string) config {
+	c.statsdTCPAddr = addr
+	return c
+}
+
+func (c config) StatsdFlushInterval() time.Duration {
+	return c.statsdFlushInterval
+}
+
+func (c config) SetStatsdFlushInterval(t time.Duration) config {
+	c.statsdFlushInterval = t
+	return c
+}
+
+func (c config) GraphiteAddr() string {
+	return c.graphiteAddr
+}
+
+func (c config) SetGraphiteAddr(addr string) config {
+	c.graphiteAddr = addr
+	return c
+}
+
+func (c config) GraphiteTemplates() []string {
+	return c.graphiteTemplates
+}
+
+func (c config) SetGraphiteTemplates(templates []string) config {
+	c.graphiteTemplates = templates
+	return c
+}
+
+// SetGraphiteTemplatesFromString sets the Graphite templates from a comma-separated list.
+func (c config) SetGraphiteTemplatesFromString(templates string) config {
+	c.graphiteTemplates = nil
+	for _, t := range strings.Split(templates, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			c.graphiteTemplates = append(c.graphiteTemplates, t)
+		}
+	}
+	return c
+}
+
+func (c config) GraphiteMaxConns() int {
+	return c.graphiteMaxConns
+}
+
+func (c config) SetGraphiteMaxConns(n int) config {
+	c.graphiteMaxConns = n
+	return c
+}
+
+func (c config) GRPCAddr() string {
+	return c.grpcAddr
+}
+
+func (c config) SetGRPCAddr(addr string) config {
+	c.grpcAddr = addr
+	return c
+}
+
 func (c config) FileStoragePath() string {
 	return c.fileStoragePath
 }
@@ -105,26 +297,178 @@ func (c config) SetPrivateKeyPath(path string) config {
 	return c
 }
 
-func (c config) TrustedSubnet() *net.IPNet {
-	return c.trustedSubnet
+func (c config) TLSCertPath() string {
+	return c.tlsCertPath
+}
+
+func (c config) SetTLSCertPath(path string) config {
+	c.tlsCertPath = path
+	return c
+}
+
+func (c config) TLSKeyPath() string {
+	return c.tlsKeyPath
+}
+
+func (c config) SetTLSKeyPath(path string) config {
+	c.tlsKeyPath = path
+	return c
+}
+
+func (c config) TLSClientCAPath() string {
+	return c.tlsClientCAPath
+}
+
+func (c config) SetTLSClientCAPath(path string) config {
+	c.tlsClientCAPath = path
+	return c
+}
+
+func (c config) KeyringPath() string {
+	return c.keyringPath
+}
+
+func (c config) SetKeyringPath(path string) config {
+	c.keyringPath = path
+	return c
+}
+
+func (c config) TokensPath() string {
+	return c.tokensPath
+}
+
+func (c config) SetTokensPath(path string) config {
+	c.tokensPath = path
+	return c
+}
+
+func (c config) ReplayWindow() time.Duration {
+	return c.replayWindow
+}
+
+func (c config) SetReplayWindow(t time.Duration) config {
+	c.replayWindow = t
+	return c
+}
+
+func (c config) NonceCacheSize() int {
+	return c.nonceCacheSize
+}
+
+func (c config) SetNonceCacheSize(n int) config {
+	c.nonceCacheSize = n
+	return c
+}
+
+func (c config) AuditPath() string {
+	return c.auditPath
+}
+
+func (c config) SetAuditPath(path string) config {
+	c.auditPath = path
+	return c
+}
+
+func (c config) AuditMaxSize() int64 {
+	return c.auditMaxSize
+}
+
+func (c config) SetAuditMaxSize(n int64) config {
+	c.auditMaxSize = n
+	return c
+}
+
+func (c config) AuditMaxBackups() int {
+	return c.auditMaxBackups
+}
+
+func (c config) SetAuditMaxBackups(n int) config {
+	c.auditMaxBackups = n
+	return c
+}
+
+func (c config) MaxBodySize() int64 {
+	return c.maxBodySize
+}
+
+func (c config) SetMaxBodySize(n int64) config {
+	c.maxBodySize = n
+	return c
+}
+
+func (c config) MaxDecompressedSize() int64 {
+	return c.maxDecompressedSize
+}
+
+func (c config) SetMaxDecompressedSize(n int64) config {
+	c.maxDecompressedSize = n
+	return c
+}
+
+func (c config) MaxBatchSize() int {
+	return c.maxBatchSize
+}
+
+func (c config) SetMaxBatchSize(n int) config {
+	c.maxBatchSize = n
+	return c
+}
+
+func (c config) ClientRateLimit() float64 {
+	return c.clientRateLimit
 }
 
-func (c config) SetTrustedSubnet(subnet *net.IPNet) config {
-	c.trustedSubnet = subnet
+func (c config) SetClientRateLimit(r float64) config {
+	c.clientRateLimit = r
 	return c
 }
 
-func (c config) SetTrustedSubnetFromString(subnet string) (config, error) {
-	if subnet == "" {
-		return c, nil
+func (c config) ClientRateBurst() int {
+	return c.clientRateBurst
+}
+
+func (c config) SetClientRateBurst(n int) config {
+	c.clientRateBurst = n
+	return c
+}
+
+func (c config) TrustedSubnets() clientip.Subnets {
+	return c.trustedSubnets
+}
+
+func (c config) SetTrustedSubnets(subnets clientip.Subnets) config {
+	c.trustedSubnets = subnets
+	return c
+}
+
+// SetTrustedSubnetsFromString sets the trusted subnets from the comma separated list in CIDR notation.
+func (c config) SetTrustedSubnetsFromString(subnets string) (config, error) {
+	s, err := clientip.ParseSubnets(subnets)
+	if err != nil {
+		return c, err
 	}
 
-	_, s, err := net.ParseCIDR(subnet)
+	c.trustedSubnets = s
+	return c, nil
+}
+
+func (c config) TrustedProxies() clientip.Subnets {
+	return c.trustedProxies
+}
+
+func (c config) SetTrustedProxies(proxies clientip.Subnets) config {
+	c.trustedProxies = proxies
+	return c
+}
+
+// SetTrustedProxiesFromString sets the trusted proxies from the comma separated list of the addresses or subnets.
+func (c config) SetTrustedProxiesFromString(proxies string) (config, error) {
+	p, err := clientip.ParseSubnets(proxies)
 	if err != nil {
 		return c, err
 	}
 
-	c.trustedSubnet = s
+	c.trustedProxies = p
 	return c, nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v10"
@@ -46,6 +47,10 @@ func parseConfigFile(config config) (config, error) {
 		config.serverAddr = cf.serverAddr
 	}
 
+	if config.grpcAddr == defaults.grpcAddr && cf.grpcAddr != defaults.grpcAddr {
+		config.grpcAddr = cf.grpcAddr
+	}
+
 	if config.isReqRestore == defaults.isReqRestore && cf.isReqRestore != defaults.isReqRestore {
 		config.isReqRestore = cf.isReqRestore
 	}
@@ -54,6 +59,58 @@ func parseConfigFile(config config) (config, error) {
 		config.storeInterval = cf.storeInterval
 	}
 
+	if config.retention == defaults.retention && cf.retention != defaults.retention {
+		config.retention = cf.retention
+	}
+
+	if config.alertRulesPath == defaults.alertRulesPath && cf.alertRulesPath != defaults.alertRulesPath {
+		config.alertRulesPath = cf.alertRulesPath
+	}
+
+	if config.alertInterval == defaults.alertInterval && cf.alertInterval != defaults.alertInterval {
+		config.alertInterval = cf.alertInterval
+	}
+
+	if config.alertFile == defaults.alertFile && cf.alertFile != defaults.alertFile {
+		config.alertFile = cf.alertFile
+	}
+
+	if len(config.alertWebhooks) == 0 && len(cf.alertWebhooks) > 0 {
+		config.alertWebhooks = cf.alertWebhooks
+	}
+
+	if config.alertGroupInterval == defaults.alertGroupInterval && cf.alertGroupInterval != defaults.alertGroupInterval {
+		config.alertGroupInterval = cf.alertGroupInterval
+	}
+
+	if config.alertRepeatInterval == defaults.alertRepeatInterval && cf.alertRepeatInterval != defaults.alertRepeatInterval {
+		config.alertRepeatInterval = cf.alertRepeatInterval
+	}
+
+	if config.statsdUDPAddr == defaults.statsdUDPAddr && cf.statsdUDPAddr != defaults.statsdUDPAddr {
+		config.statsdUDPAddr = cf.statsdUDPAddr
+	}
+
+	if config.statsdTCPAddr == defaults.statsdTCPAddr && cf.statsdTCPAddr != defaults.statsdTCPAddr {
+		config.statsdTCPAddr = cf.statsdTCPAddr
+	}
+
+	if config.statsdFlushInterval == defaults.statsdFlushInterval && cf.statsdFlushInterval != defaults.statsdFlushInterval {
+		config.statsdFlushInterval = cf.statsdFlushInterval
+	}
+
+	if config.graphiteAddr == defaults.graphiteAddr && cf.graphiteAddr != defaults.graphiteAddr {
+		config.graphiteAddr = cf.graphiteAddr
+	}
+
+	if len(config.graphiteTemplates) == 0 && len(cf.graphiteTemplates) > 0 {
+		config.graphiteTemplates = cf.graphiteTemplates
+	}
+
+	if config.graphiteMaxConns == defaults.graphiteMaxConns && cf.graphiteMaxConns != defaults.graphiteMaxConns {
+		config.graphiteMaxConns = cf.graphiteMaxConns
+	}
+
 	if config.fileStoragePath == defaults.fileStoragePath && cf.fileStoragePath != defaults.fileStoragePath {
 		config.fileStoragePath = cf.fileStoragePath
 	}
@@ -66,8 +123,72 @@ func parseConfigFile(config config) (config, error) {
 		config.privateKeyPath = cf.privateKeyPath
 	}
 
-	if config.trustedSubnet.String() == defaults.trustedSubnet.String() && cf.trustedSubnet.String() != defaults.trustedSubnet.String() {
-		config.trustedSubnet = cf.trustedSubnet
+	if config.keyringPath == defaults.keyringPath && cf.keyringPath != defaults.keyringPath {
+		config.keyringPath = cf.keyringPath
+	}
+
+	if config.tokensPath == defaults.tokensPath && cf.tokensPath != defaults.tokensPath {
+		config.tokensPath = cf.tokensPath
+	}
+
+	if config.auditPath == defaults.auditPath && cf.auditPath != defaults.auditPath {
+		config.auditPath = cf.auditPath
+	}
+
+	if config.auditMaxSize == defaults.auditMaxSize && cf.auditMaxSize != defaults.auditMaxSize {
+		config.auditMaxSize = cf.auditMaxSize
+	}
+
+	if config.auditMaxBackups == defaults.auditMaxBackups && cf.auditMaxBackups != defaults.auditMaxBackups {
+		config.auditMaxBackups = cf.auditMaxBackups
+	}
+
+	if config.replayWindow == defaults.replayWindow && cf.replayWindow != defaults.replayWindow {
+		config.replayWindow = cf.replayWindow
+	}
+
+	if config.nonceCacheSize == defaults.nonceCacheSize && cf.nonceCacheSize != defaults.nonceCacheSize {
+		config.nonceCacheSize = cf.nonceCacheSize
+	}
+
+	if config.maxBodySize == defaults.maxBodySize && cf.maxBodySize != defaults.maxBodySize {
+		config.maxBodySize = cf.maxBodySize
+	}
+
+	if config.maxDecompressedSize == defaults.maxDecompressedSize && cf.maxDecompressedSize != defaults.maxDecompressedSize {
+		config.maxDecompressedSize = cf.maxDecompressedSize
+	}
+
+	if config.maxBatchSize == defaults.maxBatchSize && cf.maxBatchSize != defaults.maxBatchSize {
+		config.maxBatchSize = cf.maxBatchSize
+	}
+
+	if config.clientRateLimit == defaults.clientRateLimit && cf.clientRateLimit != defaults.clientRateLimit {
+		config.clientRateLimit = cf.clientRateLimit
+	}
+
+	if config.clientRateBurst == defaults.clientRateBurst && cf.clientRateBurst != defaults.clientRateBurst {
+		config.clientRateBurst = cf.clientRateBurst
+	}
+
+	if config.tlsCertPath == defaults.tlsCertPath && cf.tlsCertPath != defaults.tlsCertPath {
+		config.tlsCertPath = cf.tlsCertPath
+	}
+
+	if config.tlsKeyPath == defaults.tlsKeyPath && cf.tlsKeyPath != defaults.tlsKeyPath {
+		config.tlsKeyPath = cf.tlsKeyPath
+	}
+
+	if config.tlsClientCAPath == defaults.tlsClientCAPath && cf.tlsClientCAPath != defaults.tlsClientCAPath {
+		config.tlsClientCAPath = cf.tlsClientCAPath
+	}
+
+	if config.trustedSubnets.String() == defaults.trustedSubnets.String() && cf.trustedSubnets.String() != defaults.trustedSubnets.String() {
+		config.trustedSubnets = cf.trustedSubnets
+	}
+
+	if config.trustedProxies.String() == defaults.trustedProxies.String() && cf.trustedProxies.String() != defaults.trustedProxies.String() {
+		config.trustedProxies = cf.trustedProxies
 	}
 
 	return config, nil
@@ -80,13 +201,43 @@ func loadConfigFile(path string, config config) (config, error) {
 	}
 
 	type Conf struct {
-		Address       string `json:"address,omitempty"`
-		ReqRestore    bool   `json:"restore,omitempty"`
-		StoreInterval string `json:"store_interval,omitempty"`
-		StoreFile     string `json:"store_file,omitempty"`
-		DatabaseDSN   string `json:"database_dsn,omitempty"`
-		CryptoKey     string `json:"crypto_key,omitempty"`
-		TrustedSubnet string `json:"trusted_subnet,omitempty"`
+		Address       string   `json:"address,omitempty"`
+		GRPCAddress   string   `json:"grpc_address,omitempty"`
+		ReqRestore    bool     `json:"restore,omitempty"`
+		StoreInterval string   `json:"store_interval,omitempty"`
+		Retention     string   `json:"retention,omitempty"`
+		AlertRules    string   `json:"alert_rules,omitempty"`
+		AlertInterval string   `json:"alert_interval,omitempty"`
+		AlertFile     string   `json:"alert_file,omitempty"`
+		AlertWebhooks []string `json:"alert_webhooks,omitempty"`
+		AlertGroup    string   `json:"alert_group_interval,omitempty"`
+		AlertRepeat   string   `json:"alert_repeat_interval,omitempty"`
+		StatsdUDP     string   `json:"statsd_udp,omitempty"`
+		StatsdTCP     string   `json:"statsd_tcp,omitempty"`
+		StatsdFlush   string   `json:"statsd_flush_interval,omitempty"`
+		Graphite      string   `json:"graphite,omitempty"`
+		GraphiteTmpl  []string `json:"graphite_templates,omitempty"`
+		GraphiteConns *int     `json:"graphite_max_connections,omitempty"`
+		StoreFile     string   `json:"store_file,omitempty"`
+		DatabaseDSN   string   `json:"database_dsn,omitempty"`
+		CryptoKey     string   `json:"crypto_key,omitempty"`
+		KeyringFile   string   `json:"keyring_file,omitempty"`
+		TokensFile    string   `json:"tokens_file,omitempty"`
+		AuditFile     string   `json:"audit_file,omitempty"`
+		AuditMaxSize  *int64   `json:"audit_max_size,omitempty"`
+		AuditBackups  *int     `json:"audit_max_backups,omitempty"`
+		ReplayWindow  string   `json:"replay_window,omitempty"`
+		NonceCache    *int     `json:"nonce_cache_size,omitempty"`
+		MaxBody       *int64   `json:"max_body_size,omitempty"`
+		MaxDecompr    *int64   `json:"max_decompressed_size,omitempty"`
+		MaxBatch      *int     `json:"max_batch_size,omitempty"`
+		RateLimit     *float64 `json:"client_rate_limit,omitempty"`
+		RateBurst     *int     `json:"client_rate_burst,omitempty"`
+		TLSCert       string   `json:"tls_cert,omitempty"`
+		TLSKey        string   `json:"tls_key,omitempty"`
+		TLSClientCA   string   `json:"tls_client_ca,omitempty"`
+		TrustedSubnet string   `json:"trusted_subnet,omitempty"`
+		TrustedProxy  string   `json:"trusted_proxies,omitempty"`
 	}
 	var conf Conf
 	if err = json.Unmarshal(data, &conf); err != nil {
@@ -97,6 +248,10 @@ func loadConfigFile(path string, config config) (config, error) {
 		config = config.SetServerAddr(conf.Address)
 	}
 
+	if conf.GRPCAddress != "" {
+		config = config.SetGRPCAddr(conf.GRPCAddress)
+	}
+
 	config = config.SetIsReqRestore(conf.ReqRestore)
 
 	if conf.StoreInterval != "" {
@@ -107,6 +262,78 @@ func loadConfigFile(path string, config config) (config, error) {
 		config = config.SetStoreInterval(p)
 	}
 
+	if conf.Retention != "" {
+		p, err := time.ParseDuration(conf.Retention)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse duration in retention when processing config file: %w", err)
+		}
+		config = config.SetRetention(p)
+	}
+
+	if conf.AlertRules != "" {
+		config = config.SetAlertRulesPath(conf.AlertRules)
+	}
+
+	if conf.AlertInterval != "" {
+		p, err := time.ParseDuration(conf.AlertInterval)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse duration in alert_interval when processing config file: %w", err)
+		}
+		config = config.SetAlertInterval(p)
+	}
+
+	if conf.AlertFile != "" {
+		config = config.SetAlertFile(conf.AlertFile)
+	}
+
+	if len(conf.AlertWebhooks) > 0 {
+		config = config.SetAlertWebhooks(conf.AlertWebhooks)
+	}
+
+	if conf.AlertGroup != "" {
+		p, err := time.ParseDuration(conf.AlertGroup)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse duration in alert_group_interval when processing config file: %w", err)
+		}
+		config = config.SetAlertGroupInterval(p)
+	}
+
+	if conf.AlertRepeat != "" {
+		p, err := time.ParseDuration(conf.AlertRepeat)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse duration in alert_repeat_interval when processing config file: %w", err)
+		}
+		config = config.SetAlertRepeatInterval(p)
+	}
+
+	if conf.StatsdUDP != "" {
+		config = config.SetStatsdUDPAddr(conf.StatsdUDP)
+	}
+
+	if conf.StatsdTCP != "" {
+		config = config.SetStatsdTCPAddr(conf.StatsdTCP)
+	}
+
+	if conf.StatsdFlush != "" {
+		p, err := time.ParseDuration(conf.StatsdFlush)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse duration in statsd_flush_interval when processing config file: %w", err)
+		}
+		config = config.SetStatsdFlushInterval(p)
+	}
+
+	if conf.Graphite != "" {
+		config = config.SetGraphiteAddr(conf.Graphite)
+	}
+
+	if len(conf.GraphiteTmpl) > 0 {
+		config = config.SetGraphiteTemplates(conf.GraphiteTmpl)
+	}
+
+	if conf.GraphiteConns != nil {
+		config = config.SetGraphiteMaxConns(*conf.GraphiteConns)
+	}
+
 	if conf.StoreFile != "" {
 		config = config.SetFileStoragePath(conf.StoreFile)
 	}
@@ -119,13 +346,84 @@ func loadConfigFile(path string, config config) (config, error) {
 		config = config.SetPrivateKeyPath(conf.CryptoKey)
 	}
 
+	if conf.KeyringFile != "" {
+		config = config.SetKeyringPath(conf.KeyringFile)
+	}
+
+	if conf.TokensFile != "" {
+		config = config.SetTokensPath(conf.TokensFile)
+	}
+
+	if conf.AuditFile != "" {
+		config = config.SetAuditPath(conf.AuditFile)
+	}
+
+	if conf.AuditMaxSize != nil {
+		config = config.SetAuditMaxSize(*conf.AuditMaxSize)
+	}
+
+	if conf.AuditBackups != nil {
+		config = config.SetAuditMaxBackups(*conf.AuditBackups)
+	}
+
+	if conf.ReplayWindow != "" {
+		p, err := time.ParseDuration(conf.ReplayWindow)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse duration in replay_window when processing config file: %w", err)
+		}
+		config = config.SetReplayWindow(p)
+	}
+
+	if conf.NonceCache != nil {
+		config = config.SetNonceCacheSize(*conf.NonceCache)
+	}
+
+	if conf.MaxBody != nil {
+		config = config.SetMaxBodySize(*conf.MaxBody)
+	}
+
+	if conf.MaxDecompr != nil {
+		config = config.SetMaxDecompressedSize(*conf.MaxDecompr)
+	}
+
+	if conf.MaxBatch != nil {
+		config = config.SetMaxBatchSize(*conf.MaxBatch)
+	}
+
+	if conf.RateLimit != nil {
+		config = config.SetClientRateLimit(*conf.RateLimit)
+	}
+
+	if conf.RateBurst != nil {
+		config = config.SetClientRateBurst(*conf.RateBurst)
+	}
+
+	if conf.TLSCert != "" {
+		config = config.SetTLSCertPath(conf.TLSCert)
+	}
+
+	if conf.TLSKey != "" {
+		config = config.SetTLSKeyPath(conf.TLSKey)
+	}
+
+	if conf.TLSClientCA != "" {
+		config = config.SetTLSClientCAPath(conf.TLSClientCA)
+	}
+
 	if conf.TrustedSubnet != "" {
-		config, err = config.SetTrustedSubnetFromString(conf.TrustedSubnet)
+		config, err = config.SetTrustedSubnetsFromString(conf.TrustedSubnet)
 		if err != nil {
 			return config, fmt.Errorf("failed to parse subnet in trusted_subnet when processing config file: %w", err)
 		}
 	}
 
+	if conf.TrustedProxy != "" {
+		config, err = config.SetTrustedProxiesFromString(conf.TrustedProxy)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse trusted_proxies when processing config file: %w", err)
+		}
+	}
+
 	return config, nil
 }
 
@@ -135,10 +433,52 @@ func parseFlags(config config) (config, error) {
 	// Флаг -a=<ЗНАЧЕНИЕ> отвечает за адрес эндпоинта HTTP-сервера (по умолчанию localhost:8080).
 	serverAddr := flag.String("a", config.serverAddr, "address and port to run the server")
 
+	// Флаг -grpc-address=<ЗНАЧЕНИЕ> - адрес gRPC-сервера, запускаемого вместе с REST-сервером (пустое значение отключает gRPC-сервер)
+	grpcAddr := flag.String("grpc-address", config.grpcAddr, "address and port to run the gRPC server alongside the REST server")
+
 	// Флаг -i=<ЗНАЧЕНИЕ> - интервал времени в секундах, по истечении которого
 	// текущие показания сервера сохраняются на диск (по умолчанию 300 секунд, значение 0 делает запись синхронной)
 	storeInterval := flag.Uint("i", uint(config.storeInterval.Seconds()), "time interval after which the current metrics values are saved to disk (in seconds)")
 
+	// Флаг -retention=<ЗНАЧЕНИЕ> - срок хранения истории значений метрик (по умолчанию 0 - история не ведётся)
+	retention := flag.Duration("retention", config.retention, "retention period of the metrics history (e.g. 1h, 0 disables the history)")
+
+	// Флаг -alert-rules=<ЗНАЧЕНИЕ> - путь к файлу с правилами алертинга (пустое значение отключает алертинг)
+	alertRulesPath := flag.String("alert-rules", config.alertRulesPath, "Path to the alerting rules file")
+
+	// Флаг -alert-interval=<ЗНАЧЕНИЕ> - периодичность проверки правил алертинга (по умолчанию 15s)
+	alertInterval := flag.Duration("alert-interval", config.alertInterval, "interval of the alerting rules evaluation")
+
+	// Флаг -alert-file=<ЗНАЧЕНИЕ> - файл для уведомлений об алертах ("-" - стандартный вывод)
+	alertFile := flag.String("alert-file", config.alertFile, "file to write the alert notifications to (- for stdout)")
+
+	// Флаг -alert-webhook=<ЗНАЧЕНИЕ> - адреса вебхуков для уведомлений об алертах через запятую
+	alertWebhooks := flag.String("alert-webhook", strings.Join(config.alertWebhooks, ","), "comma-separated webhook URLs to post the alert notifications to")
+
+	// Флаг -alert-group-interval=<ЗНАЧЕНИЕ> - минимальный интервал между уведомлениями по одному правилу (по умолчанию 1m)
+	alertGroupInterval := flag.Duration("alert-group-interval", config.alertGroupInterval, "minimum interval between the notifications of the same alerting rule")
+
+	// Флаг -alert-repeat-interval=<ЗНАЧЕНИЕ> - через сколько повторить уведомление о сработавших алертах (по умолчанию 4h, 0 - не повторять)
+	alertRepeatInterval := flag.Duration("alert-repeat-interval", config.alertRepeatInterval, "interval to repeat the notifications of the firing alerts (0 disables repeating)")
+
+	// Флаг -statsd-udp=<ЗНАЧЕНИЕ> - адрес для приёма метрик StatsD по UDP (пустое значение отключает приём)
+	statsdUDPAddr := flag.String("statsd-udp", config.statsdUDPAddr, "address to receive the StatsD metrics over UDP")
+
+	// Флаг -statsd-tcp=<ЗНАЧЕНИЕ> - адрес для приёма метрик StatsD по TCP (пустое значение отключает приём)
+	statsdTCPAddr := flag.String("statsd-tcp", config.statsdTCPAddr, "address to receive the StatsD metrics over TCP")
+
+	// Флаг -statsd-flush=<ЗНАЧЕНИЕ> - периодичность записи накопленных метрик StatsD (по умолчанию 10s)
+	statsdFlushInterval := flag.Duration("statsd-flush", config.statsdFlushInterval, "interval of writing the aggregated StatsD metrics")
+
+	// Флаг -graphite=<ЗНАЧЕНИЕ> - адрес для приёма метрик Graphite по TCP (пустое значение отключает приём)
+	graphiteAddr := flag.String("graphite", config.graphiteAddr, "address to receive the Graphite plaintext metrics over TCP")
+
+	// Флаг -graphite-template=<ЗНАЧЕНИЕ> - шаблоны выделения меток из пути метрики через запятую (например servers.{host}.*)
+	graphiteTemplates := flag.String("graphite-template", strings.Join(config.graphiteTemplates, ","), "comma-separated templates to extract the labels from the Graphite paths (e.g. servers.{host}.*)")
+
+	// Флаг -graphite-max-conns=<ЗНАЧЕНИЕ> - максимальное число соединений Graphite (по умолчанию 100, 0 - без ограничений)
+	graphiteMaxConns := flag.Int("graphite-max-conns", config.graphiteMaxConns, "maximum number of the concurrent Graphite connections (0 means no limit)")
+
 	// Флаг -f=<ЗНАЧЕНИЕ> - полное имя файла, куда сохраняются текущие значения
 	// (по умолчанию /tmp/metrics-db.json, пустое значение отключает функцию записи на диск).
 	fileStoragePath := flag.String("f", config.fileStoragePath, "full filename where the current metrics values are saved")
@@ -152,17 +492,63 @@ func parseFlags(config config) (config, error) {
 	// Флаг -k=<КЛЮЧ> Ключ для подписи данных
 	secretKey := flag.String("k", config.secretKey, "Secret key for signing data")
 
+	// Флаг -keyring=<ЗНАЧЕНИЕ> - путь до файла со связкой ключей подписи (перечитывается по SIGHUP)
+	keyringPath := flag.String("keyring", config.keyringPath, "Path to the keyring file with the secret keys for signing data (reloaded on SIGHUP)")
+
 	// Флаг -crypto-key путь до файла с приватным ключом
 	privateKeyPath := flag.String("crypto-key", config.privateKeyPath, "Path to the private key file")
 
-	// Строковое представление бесклассовой адресации (CIDR).
-	var t string
-	if config.trustedSubnet != nil {
-		t = config.trustedSubnet.String()
-	}
-	trustedSubnet := flag.String("t", t, "Trusted subnet (CIDR)")
+	// Флаг -tokens=<ЗНАЧЕНИЕ> - путь до файла с API-токенами агентов (пустое значение отключает проверку токенов)
+	tokensPath := flag.String("tokens", config.tokensPath, "Path to the API tokens file (empty disables the token checks)")
+
+	// Флаг -audit-file=<ЗНАЧЕНИЕ> - путь до файла журнала аудита операций записи и администрирования (пустое значение отключает аудит)
+	auditPath := flag.String("audit-file", config.auditPath, "Path to the audit log file of the write and admin operations (empty disables the audit)")
+
+	// Флаг -audit-max-size=<ЗНАЧЕНИЕ> - размер файла журнала аудита в байтах, при котором он ротируется (0 - без ротации)
+	auditMaxSize := flag.Int64("audit-max-size", config.auditMaxSize, "size of the audit log file in bytes that triggers the rotation (0 - no rotation)")
+
+	// Флаг -audit-max-backups=<ЗНАЧЕНИЕ> - сколько старых файлов журнала аудита хранить после ротации
+	auditMaxBackups := flag.Int("audit-max-backups", config.auditMaxBackups, "number of the rotated audit log files to keep")
+
+	// Флаг -replay-window=<ЗНАЧЕНИЕ> - допустимое расхождение часов агента и сервера для подписанных запросов
+	// (по умолчанию 0 - защита от повтора отключена и подписи без метки времени принимаются;
+	// включается после перевода агентов на подпись с меткой времени и nonce)
+	replayWindow := flag.Duration("replay-window", config.replayWindow, "allowed clock skew of the signed requests (0 disables the replay protection)")
+
+	// Флаг -nonce-cache-size=<ЗНАЧЕНИЕ> - максимальное число запоминаемых nonce подписанных запросов
+	nonceCacheSize := flag.Int("nonce-cache-size", config.nonceCacheSize, "maximum number of the remembered nonces of the signed requests")
+
+	// Флаг -max-body-size=<ЗНАЧЕНИЕ> - максимальный размер тела запроса в байтах (0 - без ограничений)
+	maxBodySize := flag.Int64("max-body-size", config.maxBodySize, "maximum size of the request body in bytes (0 - unlimited)")
+
+	// Флаг -max-decompressed-size=<ЗНАЧЕНИЕ> - максимальный размер распакованного тела запроса в байтах, защищает от gzip-бомб
+	maxDecompressedSize := flag.Int64("max-decompressed-size", config.maxDecompressedSize, "maximum size of the decompressed request body in bytes (0 - unlimited)")
+
+	// Флаг -max-batch-size=<ЗНАЧЕНИЕ> - максимальное количество метрик в пакете (0 - без ограничений)
+	maxBatchSize := flag.Int("max-batch-size", config.maxBatchSize, "maximum number of the metrics in a batch (0 - unlimited)")
+
+	// Флаг -client-rate-limit=<ЗНАЧЕНИЕ> - запросов записи в секунду от одного клиента (токена, сертификата или адреса), 0 - без ограничений
+	clientRateLimit := flag.Float64("client-rate-limit", config.clientRateLimit, "write requests per second allowed for a client (0 - unlimited)")
+
+	// Флаг -client-rate-burst=<ЗНАЧЕНИЕ> - сколько запросов записи клиент может отправить разом
+	clientRateBurst := flag.Int("client-rate-burst", config.clientRateBurst, "burst of the write requests allowed for a client")
+
+	// Флаг -tls-cert путь до файла с сертификатом сервера, включает TLS для REST и gRPC
+	tlsCertPath := flag.String("tls-cert", config.tlsCertPath, "Path to the TLS certificate file of the server")
 
-	// Флаг -g запускать gRPC сервер
+	// Флаг -tls-key путь до файла с ключом сертификата сервера
+	tlsKeyPath := flag.String("tls-key", config.tlsKeyPath, "Path to the TLS key file of the server")
+
+	// Флаг -tls-client-ca путь до файла с сертификатами CA, которыми подписаны сертификаты агентов (mTLS)
+	tlsClientCAPath := flag.String("tls-client-ca", config.tlsClientCAPath, "Path to the CA bundle to verify the client certificates (enables mutual TLS)")
+
+	// Флаг -t=<ЗНАЧЕНИЕ> - доверенные подсети IPv4 и IPv6 через запятую (CIDR)
+	trustedSubnets := flag.String("t", config.trustedSubnets.String(), "Comma separated list of the trusted subnets (CIDR)")
+
+	// Флаг -trusted-proxies=<ЗНАЧЕНИЕ> - адреса или подсети прокси через запятую, заголовкам X-Forwarded-For и X-Real-IP которых можно доверять
+	trustedProxies := flag.String("trusted-proxies", config.trustedProxies.String(), "Comma separated list of the trusted proxies whose X-Forwarded-For and X-Real-IP headers are honoured")
+
+	// Флаг -g запускать только gRPC сервер (на адресе -grpc-address, если он задан, иначе на адресе -a)
 	useGRPC := flag.Bool("g", false, "run gRPC server instead of REST")
 
 	// Флаг -config путь к файлу конфигурации
@@ -176,35 +562,102 @@ func parseFlags(config config) (config, error) {
 		config = config.SetServerType(ServerTypeGRPC)
 	}
 
-	if *trustedSubnet != "" {
-		c, err := config.SetTrustedSubnetFromString(*trustedSubnet)
+	if *trustedSubnets != "" {
+		c, err := config.SetTrustedSubnetsFromString(*trustedSubnets)
 		if err != nil {
 			return config, fmt.Errorf("failed to parse trusted subnet: %w", err)
 		}
 		config = c
 	}
 
+	if *trustedProxies != "" {
+		c, err := config.SetTrustedProxiesFromString(*trustedProxies)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse trusted proxies: %w", err)
+		}
+		config = c
+	}
+
 	return config.
 		SetServerAddr(*serverAddr).
+		SetGRPCAddr(*grpcAddr).
 		SetStoreIntervalInSeconds(*storeInterval).
+		SetRetention(*retention).
+		SetAlertRulesPath(*alertRulesPath).
+		SetAlertInterval(*alertInterval).
+		SetAlertFile(*alertFile).
+		SetAlertWebhooksFromString(*alertWebhooks).
+		SetAlertGroupInterval(*alertGroupInterval).
+		SetAlertRepeatInterval(*alertRepeatInterval).
+		SetStatsdUDPAddr(*statsdUDPAddr).
+		SetStatsdTCPAddr(*statsdTCPAddr).
+		SetStatsdFlushInterval(*statsdFlushInterval).
+		SetGraphiteAddr(*graphiteAddr).
+		SetGraphiteTemplatesFromString(*graphiteTemplates).
+		SetGraphiteMaxConns(*graphiteMaxConns).
 		SetFileStoragePath(*fileStoragePath).
 		SetIsReqRestore(*isReqRestore).
 		SetDatabaseDSN(*databaseDSN).
 		SetSecretKey(*secretKey).
-		SetPrivateKeyPath(*privateKeyPath), nil
+		SetPrivateKeyPath(*privateKeyPath).
+		SetKeyringPath(*keyringPath).
+		SetTokensPath(*tokensPath).
+		SetAuditPath(*auditPath).
+		SetAuditMaxSize(*auditMaxSize).
+		SetAuditMaxBackups(*auditMaxBackups).
+		SetReplayWindow(*replayWindow).
+		SetNonceCacheSize(*nonceCacheSize).
+		SetMaxBodySize(*maxBodySize).
+		SetMaxDecompressedSize(*maxDecompressedSize).
+		SetMaxBatchSize(*maxBatchSize).
+		SetClientRateLimit(*clientRateLimit).
+		SetClientRateBurst(*clientRateBurst).
+		SetTLSCertPath(*tlsCertPath).
+		SetTLSKeyPath(*tlsKeyPath).
+		SetTLSClientCAPath(*tlsClientCAPath), nil
 }
 
 func parseEnvs(config config) (config, error) {
 	var cfg struct {
-		ServerAddr      string `env:"ADDRESS"`
-		FileStoragePath string `env:"FILE_STORAGE_PATH"`
-		DatabaseDSN     string `env:"DATABASE_DSN"`
-		SecretKey       string `env:"KEY"`
-		PrivateKeyPath  string `env:"CRYPTO_KEY"`
-		TrustedSubnet   string `env:"TRUSTED_SUBNET"`
-		ConfigFile      string `env:"CONFIG"`
-		StoreInterval   uint   `env:"STORE_INTERVAL"`
-		IsReqRestore    bool   `env:"RESTORE"`
+		ServerAddr      string        `env:"ADDRESS"`
+		GRPCAddr        string        `env:"GRPC_ADDRESS"`
+		FileStoragePath string        `env:"FILE_STORAGE_PATH"`
+		DatabaseDSN     string        `env:"DATABASE_DSN"`
+		SecretKey       string        `env:"KEY"`
+		PrivateKeyPath  string        `env:"CRYPTO_KEY"`
+		KeyringPath     string        `env:"KEYRING_FILE"`
+		TokensPath      string        `env:"TOKENS_FILE"`
+		AuditPath       string        `env:"AUDIT_FILE"`
+		AuditMaxSize    int64         `env:"AUDIT_MAX_SIZE"`
+		AuditMaxBackups int           `env:"AUDIT_MAX_BACKUPS"`
+		ReplayWindow    time.Duration `env:"REPLAY_WINDOW"`
+		NonceCacheSize  int           `env:"NONCE_CACHE_SIZE"`
+		MaxBodySize     int64         `env:"MAX_BODY_SIZE"`
+		MaxDecompressed int64         `env:"MAX_DECOMPRESSED_SIZE"`
+		MaxBatchSize    int           `env:"MAX_BATCH_SIZE"`
+		ClientRateLimit float64       `env:"CLIENT_RATE_LIMIT"`
+		ClientRateBurst int           `env:"CLIENT_RATE_BURST"`
+		TLSCertPath     string        `env:"TLS_CERT"`
+		TLSKeyPath      string        `env:"TLS_KEY"`
+		TLSClientCAPath string        `env:"TLS_CLIENT_CA"`
+		TrustedSubnet   string        `env:"TRUSTED_SUBNET"`
+		TrustedProxies  string        `env:"TRUSTED_PROXIES"`
+		ConfigFile      string        `env:"CONFIG"`
+		AlertRulesPath  string        `env:"ALERT_RULES"`
+		AlertInterval   time.Duration `env:"ALERT_INTERVAL"`
+		AlertFile       string        `env:"ALERT_FILE"`
+		AlertWebhooks   string        `env:"ALERT_WEBHOOKS"`
+		AlertGroup      time.Duration `env:"ALERT_GROUP_INTERVAL"`
+		AlertRepeat     time.Duration `env:"ALERT_REPEAT_INTERVAL"`
+		StatsdUDPAddr   string        `env:"STATSD_UDP_ADDRESS"`
+		StatsdTCPAddr   string        `env:"STATSD_TCP_ADDRESS"`
+		StatsdFlush     time.Duration `env:"STATSD_FLUSH_INTERVAL"`
+		GraphiteAddr    string        `env:"GRAPHITE_ADDRESS"`
+		GraphiteTmpl    string        `env:"GRAPHITE_TEMPLATES"`
+		GraphiteConns   int           `env:"GRAPHITE_MAX_CONNECTIONS"`
+		StoreInterval   uint          `env:"STORE_INTERVAL"`
+		Retention       time.Duration `env:"RETENTION"`
+		IsReqRestore    bool          `env:"RESTORE"`
 	}
 	err := env.Parse(&cfg)
 	if err != nil {
@@ -215,10 +668,66 @@ func parseEnvs(config config) (config, error) {
 		config = config.SetServerAddr(cfg.ServerAddr)
 	}
 
+	if _, exists := os.LookupEnv("GRPC_ADDRESS"); exists {
+		config = config.SetGRPCAddr(cfg.GRPCAddr)
+	}
+
 	if _, exists := os.LookupEnv("STORE_INTERVAL"); exists {
 		config = config.SetStoreIntervalInSeconds(cfg.StoreInterval)
 	}
 
+	if _, exists := os.LookupEnv("RETENTION"); exists {
+		config = config.SetRetention(cfg.Retention)
+	}
+
+	if _, exists := os.LookupEnv("ALERT_RULES"); exists {
+		config = config.SetAlertRulesPath(cfg.AlertRulesPath)
+	}
+
+	if _, exists := os.LookupEnv("ALERT_INTERVAL"); exists {
+		config = config.SetAlertInterval(cfg.AlertInterval)
+	}
+
+	if _, exists := os.LookupEnv("ALERT_FILE"); exists {
+		config = config.SetAlertFile(cfg.AlertFile)
+	}
+
+	if _, exists := os.LookupEnv("ALERT_WEBHOOKS"); exists {
+		config = config.SetAlertWebhooksFromString(cfg.AlertWebhooks)
+	}
+
+	if _, exists := os.LookupEnv("ALERT_GROUP_INTERVAL"); exists {
+		config = config.SetAlertGroupInterval(cfg.AlertGroup)
+	}
+
+	if _, exists := os.LookupEnv("ALERT_REPEAT_INTERVAL"); exists {
+		config = config.SetAlertRepeatInterval(cfg.AlertRepeat)
+	}
+
+	if _, exists := os.LookupEnv("STATSD_UDP_ADDRESS"); exists {
+		config = config.SetStatsdUDPAddr(cfg.StatsdUDPAddr)
+	}
+
+	if _, exists := os.LookupEnv("STATSD_TCP_ADDRESS"); exists {
+		config = config.SetStatsdTCPAddr(cfg.StatsdTCPAddr)
+	}
+
+	if _, exists := os.LookupEnv("STATSD_FLUSH_INTERVAL"); exists {
+		config = config.SetStatsdFlushInterval(cfg.StatsdFlush)
+	}
+
+	if _, exists := os.LookupEnv("GRAPHITE_ADDRESS"); exists {
+		config = config.SetGraphiteAddr(cfg.GraphiteAddr)
+	}
+
+	if _, exists := os.LookupEnv("GRAPHITE_TEMPLATES"); exists {
+		config = config.SetGraphiteTemplatesFromString(cfg.GraphiteTmpl)
+	}
+
+	if _, exists := os.LookupEnv("GRAPHITE_MAX_CONNECTIONS"); exists {
+		config = config.SetGraphiteMaxConns(cfg.GraphiteConns)
+	}
+
 	if _, exists := os.LookupEnv("FILE_STORAGE_PATH"); exists {
 		config = config.SetFileStoragePath(cfg.FileStoragePath)
 	}
@@ -239,14 +748,82 @@ func parseEnvs(config config) (config, error) {
 		config = config.SetPrivateKeyPath(cfg.PrivateKeyPath)
 	}
 
+	if _, exists := os.LookupEnv("KEYRING_FILE"); exists {
+		config = config.SetKeyringPath(cfg.KeyringPath)
+	}
+
+	if _, exists := os.LookupEnv("TOKENS_FILE"); exists {
+		config = config.SetTokensPath(cfg.TokensPath)
+	}
+
+	if _, exists := os.LookupEnv("AUDIT_FILE"); exists {
+		config = config.SetAuditPath(cfg.AuditPath)
+	}
+
+	if _, exists := os.LookupEnv("AUDIT_MAX_SIZE"); exists {
+		config = config.SetAuditMaxSize(cfg.AuditMaxSize)
+	}
+
+	if _, exists := os.LookupEnv("AUDIT_MAX_BACKUPS"); exists {
+		config = config.SetAuditMaxBackups(cfg.AuditMaxBackups)
+	}
+
+	if _, exists := os.LookupEnv("REPLAY_WINDOW"); exists {
+		config = config.SetReplayWindow(cfg.ReplayWindow)
+	}
+
+	if _, exists := os.LookupEnv("NONCE_CACHE_SIZE"); exists {
+		config = config.SetNonceCacheSize(cfg.NonceCacheSize)
+	}
+
+	if _, exists := os.LookupEnv("MAX_BODY_SIZE"); exists {
+		config = config.SetMaxBodySize(cfg.MaxBodySize)
+	}
+
+	if _, exists := os.LookupEnv("MAX_DECOMPRESSED_SIZE"); exists {
+		config = config.SetMaxDecompressedSize(cfg.MaxDecompressed)
+	}
+
+	if _, exists := os.LookupEnv("MAX_BATCH_SIZE"); exists {
+		config = config.SetMaxBatchSize(cfg.MaxBatchSize)
+	}
+
+	if _, exists := os.LookupEnv("CLIENT_RATE_LIMIT"); exists {
+		config = config.SetClientRateLimit(cfg.ClientRateLimit)
+	}
+
+	if _, exists := os.LookupEnv("CLIENT_RATE_BURST"); exists {
+		config = config.SetClientRateBurst(cfg.ClientRateBurst)
+	}
+
+	if _, exists := os.LookupEnv("TLS_CERT"); exists {
+		config = config.SetTLSCertPath(cfg.TLSCertPath)
+	}
+
+	if _, exists := os.LookupEnv("TLS_KEY"); exists {
+		config = config.SetTLSKeyPath(cfg.TLSKeyPath)
+	}
+
+	if _, exists := os.LookupEnv("TLS_CLIENT_CA"); exists {
+		config = config.SetTLSClientCAPath(cfg.TLSClientCAPath)
+	}
+
 	if _, exists := os.LookupEnv("TRUSTED_SUBNET"); exists {
-		c, err := config.SetTrustedSubnetFromString(cfg.TrustedSubnet)
+		c, err := config.SetTrustedSubnetsFromString(cfg.TrustedSubnet)
 		if err != nil {
 			return config, fmt.Errorf("failed to parse trusted subnet: %w", err)
 		}
 		config = c
 	}
 
+	if _, exists := os.LookupEnv("TRUSTED_PROXIES"); exists {
+		c, err := config.SetTrustedProxiesFromString(cfg.TrustedProxies)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse trusted proxies: %w", err)
+		}
+		config = c
+	}
+
 	if _, exists := os.LookupEnv("CONFIG"); exists {
 		config.configFile = cfg.ConfigFile
 	}
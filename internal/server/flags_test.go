@@ -26,13 +26,43 @@ func (suite *FlagsTestSuite) SetupSuite() {
 	suite.osEnviron = make(map[string]string)
 	for _, e := range []string{
 		"ADDRESS",
+		"GRPC_ADDRESS",
 		"STORE_INTERVAL",
 		"FILE_STORAGE_PATH",
 		"RESTORE",
 		"DATABASE_DSN",
 		"KEY",
 		"CRYPTO_KEY",
+		"KEYRING_FILE",
+		"TOKENS_FILE",
+		"AUDIT_FILE",
+		"AUDIT_MAX_SIZE",
+		"AUDIT_MAX_BACKUPS",
+		"REPLAY_WINDOW",
+		"NONCE_CACHE_SIZE",
+		"MAX_BODY_SIZE",
+		"MAX_DECOMPRESSED_SIZE",
+		"MAX_BATCH_SIZE",
+		"CLIENT_RATE_LIMIT",
+		"CLIENT_RATE_BURST",
+		"TLS_CERT",
+		"TLS_KEY",
+		"TLS_CLIENT_CA",
 		"TRUSTED_SUBNET",
+		"TRUSTED_PROXIES",
+		"RETENTION",
+		"ALERT_RULES",
+		"ALERT_INTERVAL",
+		"ALERT_FILE",
+		"ALERT_WEBHOOKS",
+		"ALERT_GROUP_INTERVAL",
+		"ALERT_REPEAT_INTERVAL",
+		"STATSD_UDP_ADDRESS",
+		"STATSD_TCP_ADDRESS",
+		"STATSD_FLUSH_INTERVAL",
+		"GRAPHITE_ADDRESS",
+		"GRAPHITE_TEMPLATES",
+		"GRAPHITE_MAX_CONNECTIONS",
 	} {
 		suite.osEnviron[e] = os.Getenv(e)
 	}
@@ -75,13 +105,40 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 			name: "Positive case: Default values",
 			args: nil,
 			want: map[string]interface{}{
-				"serverAddr":      "localhost:8080",
-				"storeInterval":   300 * time.Second,
-				"fileStoragePath": "/tmp/metrics-db.json",
-				"isReqRestore":    true,
-				"databaseDSN":     "",
-				"secretKey":       "",
-				"privateKeyPath":  "",
+				"serverAddr":          "localhost:8080",
+				"grpcAddr":            "",
+				"storeInterval":       300 * time.Second,
+				"retention":           time.Duration(0),
+				"alertRulesPath":      "",
+				"alertInterval":       15 * time.Second,
+				"alertFile":           "",
+				"alertGroupInterval":  time.Minute,
+				"alertRepeatInterval": 4 * time.Hour,
+				"statsdUDPAddr":       "",
+				"statsdTCPAddr":       "",
+				"statsdFlushInterval": 10 * time.Second,
+				"graphiteAddr":        "",
+				"graphiteMaxConns":    100,
+				"fileStoragePath":     "/tmp/metrics-db.json",
+				"isReqRestore":        true,
+				"databaseDSN":         "",
+				"secretKey":           "",
+				"privateKeyPath":      "",
+				"tlsCertPath":         "",
+				"tlsKeyPath":          "",
+				"tlsClientCAPath":     "",
+				"keyringPath":         "",
+				"tokensPath":          "",
+				"auditPath":           "",
+				"auditMaxSize":        int64(10 << 20),
+				"auditMaxBackups":     5,
+				"replayWindow":        time.Duration(0),
+				"nonceCacheSize":      100000,
+				"maxBodySize":         int64(10 << 20),
+				"maxDecompressedSize": int64(64 << 20),
+				"maxBatchSize":        10000,
+				"clientRateLimit":     float64(0),
+				"clientRateBurst":     10,
 			},
 		},
 		{
@@ -89,11 +146,49 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 			args: []string{"-a=example.com:8181"},
 			want: map[string]interface{}{"serverAddr": "example.com:8181"},
 		},
+		{
+			name: "Positive case: Set flag -grpc-address",
+			args: []string{"-grpc-address=:3200"},
+			want: map[string]interface{}{"grpcAddr": ":3200"},
+		},
 		{
 			name: "Positive case: Set flag -i",
 			args: []string{"-i=10"},
 			want: map[string]interface{}{"storeInterval": 10 * time.Second},
 		},
+		{
+			name: "Positive case: Set flag -retention",
+			args: []string{"-retention=30m"},
+			want: map[string]interface{}{"retention": 30 * time.Minute},
+		},
+		{
+			name: "Positive case: Set flags -alert-rules and -alert-interval",
+			args: []string{"-alert-rules=/etc/metrics/alert-rules.json", "-alert-interval=1m"},
+			want: map[string]interface{}{"alertRulesPath": "/etc/metrics/alert-rules.json", "alertInterval": time.Minute},
+		},
+		{
+			name: "Positive case: Set alert notification flags",
+			args: []string{"-alert-file=-", "-alert-webhook=http://a/hook, http://b/hook", "-alert-group-interval=30s", "-alert-repeat-interval=1h"},
+			want: map[string]interface{}{
+				"alertFile":           "-",
+				"alertGroupInterval":  30 * time.Second,
+				"alertRepeatInterval": time.Hour,
+			},
+		},
+		{
+			name: "Positive case: Set StatsD flags",
+			args: []string{"-statsd-udp=:8125", "-statsd-tcp=:8126", "-statsd-flush=1m"},
+			want: map[string]interface{}{
+				"statsdUDPAddr":       ":8125",
+				"statsdTCPAddr":       ":8126",
+				"statsdFlushInterval": time.Minute,
+			},
+		},
+		{
+			name: "Positive case: Set Graphite flags",
+			args: []string{"-graphite=:2003", "-graphite-max-conns=10"},
+			want: map[string]interface{}{"graphiteAddr": ":2003", "graphiteMaxConns": 10},
+		},
 		{
 			name: "Positive case: Set flag -f",
 			args: []string{"-f=/temp/metrics-db.test.json"},
@@ -124,6 +219,66 @@ func (suite *FlagsTestSuite) TestParseFlags() {
 			args: []string{"-crypto-key=/tmp/key"},
 			want: map[string]interface{}{"privateKeyPath": "/tmp/key"},
 		},
+		{
+			name: "Positive case: Set flag -keyring",
+			args: []string{"-keyring=/tmp/keyring.json"},
+			want: map[string]interface{}{"keyringPath": "/tmp/keyring.json"},
+		},
+		{
+			name: "Positive case: Set flag -tokens",
+			args: []string{"-tokens=/tmp/tokens.json"},
+			want: map[string]interface{}{"tokensPath": "/tmp/tokens.json"},
+		},
+		{
+			name: "Positive case: Set flag -replay-window",
+			args: []string{"-replay-window=30s"},
+			want: map[string]interface{}{"replayWindow": 30 * time.Second},
+		},
+		{
+			name: "Positive case: Set flag -nonce-cache-size",
+			args: []string{"-nonce-cache-size=500"},
+			want: map[string]interface{}{"nonceCacheSize": 500},
+		},
+		{
+			name: "Positive case: Set audit flags",
+			args: []string{"-audit-file=/tmp/audit.log", "-audit-max-size=1024", "-audit-max-backups=0"},
+			want: map[string]interface{}{"auditPath": "/tmp/audit.log", "auditMaxSize": int64(1024), "auditMaxBackups": 0},
+		},
+		{
+			name: "Positive case: Set flag -max-body-size",
+			args: []string{"-max-body-size=1024"},
+			want: map[string]interface{}{"maxBodySize": int64(1024)},
+		},
+		{
+			name: "Positive case: Set flag -max-decompressed-size",
+			args: []string{"-max-decompressed-size=0"},
+			want: map[string]interface{}{"maxDecompressedSize": int64(0)},
+		},
+		{
+			name: "Positive case: Set flag -max-batch-size",
+			args: []string{"-max-batch-size=50"},
+			want: map[string]interface{}{"maxBatchSize": 50},
+		},
+		{
+			name: "Positive case: Set flags -client-rate-limit and -client-rate-burst",
+			args: []string{"-client-rate-limit=2.5", "-client-rate-burst=5"},
+			want: map[string]interface{}{"clientRateLimit": 2.5, "clientRateBurst": 5},
+		},
+		{
+			name: "Positive case: Set flag -tls-cert",
+			args: []string{"-tls-cert=/tmp/server.crt"},
+			want: map[string]interface{}{"tlsCertPath": "/tmp/server.crt"},
+		},
+		{
+			name: "Positive case: Set flag -tls-key",
+			args: []string{"-tls-key=/tmp/server.key"},
+			want: map[string]interface{}{"tlsKeyPath": "/tmp/server.key"},
+		},
+		{
+			name: "Positive case: Set flag -tls-client-ca",
+			args: []string{"-tls-client-ca=/tmp/ca.crt"},
+			want: map[string]interface{}{"tlsClientCAPath": "/tmp/ca.crt"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -157,11 +312,49 @@ func (suite *FlagsTestSuite) TestParseEnvs() {
 			envs: []string{"ADDRESS=example.com:8181"},
 			want: map[string]interface{}{"serverAddr": "example.com:8181"},
 		},
+		{
+			name: "Positive case: Set env GRPC_ADDRESS",
+			envs: []string{"GRPC_ADDRESS=:3200"},
+			want: map[string]interface{}{"grpcAddr": ":3200"},
+		},
 		{
 			name: "Positive case: Set env STORE_INTERVAL",
 			envs: []string{"STORE_INTERVAL=10"},
 			want: map[string]interface{}{"storeInterval": 10 * time.Second},
 		},
+		{
+			name: "Positive case: Set env RETENTION",
+			envs: []string{"RETENTION=2h"},
+			want: map[string]interface{}{"retention": 2 * time.Hour},
+		},
+		{
+			name: "Positive case: Set envs ALERT_RULES and ALERT_INTERVAL",
+			envs: []string{"ALERT_RULES=/etc/metrics/alert-rules.json", "ALERT_INTERVAL=30s"},
+			want: map[string]interface{}{"alertRulesPath": "/etc/metrics/alert-rules.json", "alertInterval": 30 * time.Second},
+		},
+		{
+			name: "Positive case: Set alert notification envs",
+			envs: []string{"ALERT_FILE=/var/log/alerts.log", "ALERT_WEBHOOKS=http://a/hook", "ALERT_GROUP_INTERVAL=2m", "ALERT_REPEAT_INTERVAL=0s"},
+			want: map[string]interface{}{
+				"alertFile":           "/var/log/alerts.log",
+				"alertGroupInterval":  2 * time.Minute,
+				"alertRepeatInterval": time.Duration(0),
+			},
+		},
+		{
+			name: "Positive case: Set StatsD envs",
+			envs: []string{"STATSD_UDP_ADDRESS=:8125", "STATSD_TCP_ADDRESS=:8126", "STATSD_FLUSH_INTERVAL=5s"},
+			want: map[string]interface{}{
+				"statsdUDPAddr":       ":8125",
+				"statsdTCPAddr":       ":8126",
+				"statsdFlushInterval": 5 * time.Second,
+			},
+		},
+		{
+			name: "Positive case: Set Graphite envs",
+			envs: []string{"GRAPHITE_ADDRESS=:2003", "GRAPHITE_MAX_CONNECTIONS=0"},
+			want: map[string]interface{}{"graphiteAddr": ":2003", "graphiteMaxConns": 0},
+		},
 		{
 			name: "Positive case: Set env FILE_STORAGE_PATH",
 			envs: []string{"FILE_STORAGE_PATH=/temp/metrics-db.test.json"},
@@ -192,6 +385,66 @@ func (suite *FlagsTestSuite) TestParseEnvs() {
 			envs: []string{"CRYPTO_KEY=/tmp/key"},
 			want: map[string]interface{}{"privateKeyPath": "/tmp/key"},
 		},
+		{
+			name: "Positive case: Set env KEYRING_FILE",
+			envs: []string{"KEYRING_FILE=/tmp/keyring.json"},
+			want: map[string]interface{}{"keyringPath": "/tmp/keyring.json"},
+		},
+		{
+			name: "Positive case: Set env TOKENS_FILE",
+			envs: []string{"TOKENS_FILE=/tmp/tokens.json"},
+			want: map[string]interface{}{"tokensPath": "/tmp/tokens.json"},
+		},
+		{
+			name: "Positive case: Set env REPLAY_WINDOW",
+			envs: []string{"REPLAY_WINDOW=2m"},
+			want: map[string]interface{}{"replayWindow": 2 * time.Minute},
+		},
+		{
+			name: "Positive case: Set env NONCE_CACHE_SIZE",
+			envs: []string{"NONCE_CACHE_SIZE=500"},
+			want: map[string]interface{}{"nonceCacheSize": 500},
+		},
+		{
+			name: "Positive case: Set audit envs",
+			envs: []string{"AUDIT_FILE=/tmp/audit.log", "AUDIT_MAX_SIZE=0", "AUDIT_MAX_BACKUPS=10"},
+			want: map[string]interface{}{"auditPath": "/tmp/audit.log", "auditMaxSize": int64(0), "auditMaxBackups": 10},
+		},
+		{
+			name: "Positive case: Set env MAX_BODY_SIZE",
+			envs: []string{"MAX_BODY_SIZE=1024"},
+			want: map[string]interface{}{"maxBodySize": int64(1024)},
+		},
+		{
+			name: "Positive case: Set env MAX_DECOMPRESSED_SIZE",
+			envs: []string{"MAX_DECOMPRESSED_SIZE=4096"},
+			want: map[string]interface{}{"maxDecompressedSize": int64(4096)},
+		},
+		{
+			name: "Positive case: Set env MAX_BATCH_SIZE",
+			envs: []string{"MAX_BATCH_SIZE=0"},
+			want: map[string]interface{}{"maxBatchSize": 0},
+		},
+		{
+			name: "Positive case: Set envs CLIENT_RATE_LIMIT and CLIENT_RATE_BURST",
+			envs: []string{"CLIENT_RATE_LIMIT=100", "CLIENT_RATE_BURST=200"},
+			want: map[string]interface{}{"clientRateLimit": float64(100), "clientRateBurst": 200},
+		},
+		{
+			name: "Positive case: Set env TLS_CERT",
+			envs: []string{"TLS_CERT=/tmp/server.crt"},
+			want: map[string]interface{}{"tlsCertPath": "/tmp/server.crt"},
+		},
+		{
+			name: "Positive case: Set env TLS_KEY",
+			envs: []string{"TLS_KEY=/tmp/server.key"},
+			want: map[string]interface{}{"tlsKeyPath": "/tmp/server.key"},
+		},
+		{
+			name: "Positive case: Set env TLS_CLIENT_CA",
+			envs: []string{"TLS_CLIENT_CA=/tmp/ca.crt"},
+			want: map[string]interface{}{"tlsClientCAPath": "/tmp/ca.crt"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -226,6 +479,71 @@ func (suite *FlagsTestSuite) TestParseEnvs() {
 	}
 }
 
+func (suite *FlagsTestSuite) TestAlertWebhooks() {
+	suite.Run("Flag -alert-webhook", func() {
+		os.Args = append(os.Args, "-alert-webhook=http://a/hook, http://b/hook,")
+		config, err := parseFlags(newConfig())
+		suite.Require().NoError(err)
+		suite.Equal([]string{"http://a/hook", "http://b/hook"}, config.AlertWebhooks())
+	})
+
+	suite.Run("Env ALERT_WEBHOOKS", func() {
+		suite.T().Setenv("ALERT_WEBHOOKS", "http://c/hook")
+		config, err := parseEnvs(newConfig().SetAlertWebhooks([]string{"http://a/hook"}))
+		suite.Require().NoError(err)
+		suite.Equal([]string{"http://c/hook"}, config.AlertWebhooks())
+	})
+}
+
+func (suite *FlagsTestSuite) TestGraphiteTemplates() {
+	suite.Run("Flag -graphite-template", func() {
+		os.Args = append(os.Args, "-graphite-template=servers.{host}.*, jobs.{job}")
+		config, err := parseFlags(newConfig())
+		suite.Require().NoError(err)
+		suite.Equal([]string{"servers.{host}.*", "jobs.{job}"}, config.GraphiteTemplates())
+	})
+
+	suite.Run("Env GRAPHITE_TEMPLATES", func() {
+		suite.T().Setenv("GRAPHITE_TEMPLATES", "apps.{app}")
+		config, err := parseEnvs(newConfig().SetGraphiteTemplates([]string{"servers.{host}.*"}))
+		suite.Require().NoError(err)
+		suite.Equal([]string{"apps.{app}"}, config.GraphiteTemplates())
+	})
+}
+
+func (suite *FlagsTestSuite) TestTrustedSubnets() {
+	suite.Run("Flags -t and -trusted-proxies", func() {
+		os.Args = append(os.Args, "-t=192.168.0.0/24, 2001:db8::/32", "-trusted-proxies=10.0.0.1,fd00::/8")
+		config, err := parseFlags(newConfig())
+		suite.Require().NoError(err)
+		suite.Equal("192.168.0.0/24,2001:db8::/32", config.TrustedSubnets().String())
+		suite.Equal("10.0.0.1/32,fd00::/8", config.TrustedProxies().String())
+	})
+
+	suite.Run("Envs TRUSTED_SUBNET and TRUSTED_PROXIES", func() {
+		suite.T().Setenv("TRUSTED_SUBNET", "10.0.0.0/8")
+		suite.T().Setenv("TRUSTED_PROXIES", "127.0.0.1")
+		config, err := newConfig().SetTrustedSubnetsFromString("192.168.0.0/24")
+		suite.Require().NoError(err)
+		config, err = parseEnvs(config)
+		suite.Require().NoError(err)
+		suite.Equal("10.0.0.0/8", config.TrustedSubnets().String())
+		suite.Equal("127.0.0.1/32", config.TrustedProxies().String())
+	})
+
+	suite.Run("Invalid subnet", func() {
+		os.Args = append(os.Args, "-t=192.168.0.0/24,10.0.0.0/33")
+		_, err := parseFlags(newConfig())
+		suite.Error(err)
+	})
+
+	suite.Run("Invalid proxy", func() {
+		suite.T().Setenv("TRUSTED_PROXIES", "proxy.local")
+		_, err := parseEnvs(newConfig())
+		suite.Error(err)
+	})
+}
+
 func (suite *FlagsTestSuite) TestLoadConfig() {
 	testCases := []struct {
 		name string
@@ -244,6 +562,7 @@ func (suite *FlagsTestSuite) TestLoadConfig() {
 				"isReqRestore":    true,
 				"secretKey":       "",
 				"privateKeyPath":  "",
+				"replayWindow":    time.Duration(0),
 			},
 		},
 		{
@@ -270,6 +589,18 @@ func (suite *FlagsTestSuite) TestLoadConfig() {
 			envs: []string{"STORE_INTERVAL=200"},
 			want: map[string]interface{}{"storeInterval": 200 * time.Second},
 		},
+		{
+			name: "Positive case: Set flag -retention and env RETENTION",
+			args: []string{"-retention=30m"},
+			envs: []string{"RETENTION=2h"},
+			want: map[string]interface{}{"retention": 2 * time.Hour},
+		},
+		{
+			name: "Positive case: Set flag -client-rate-limit and env CLIENT_RATE_LIMIT",
+			args: []string{"-client-rate-limit=1"},
+			envs: []string{"CLIENT_RATE_LIMIT=20"},
+			want: map[string]interface{}{"clientRateLimit": float64(20)},
+		},
 		{
 			name: "Positive case: Set flag -i only",
 			args: []string{"-i=100"},
@@ -390,6 +721,30 @@ func (suite *FlagsTestSuite) TestLoadConfig() {
 			envs: []string{"CRYPTO_KEY=/tmp/key"},
 			want: map[string]interface{}{"privateKeyPath": "/tmp/key"},
 		},
+		{
+			name: "Positive case: Set flag -keyring and env KEYRING_FILE",
+			args: []string{"-keyring=/tmp/keyring1.json"},
+			envs: []string{"KEYRING_FILE=/tmp/keyring2.json"},
+			want: map[string]interface{}{"keyringPath": "/tmp/keyring2.json"},
+		},
+		{
+			name: "Positive case: Set flag -tokens and env TOKENS_FILE",
+			args: []string{"-tokens=/tmp/tokens1.json"},
+			envs: []string{"TOKENS_FILE=/tmp/tokens2.json"},
+			want: map[string]interface{}{"tokensPath": "/tmp/tokens2.json"},
+		},
+		{
+			name: "Positive case: Set flag -replay-window and env REPLAY_WINDOW",
+			args: []string{"-replay-window=30s"},
+			envs: []string{"REPLAY_WINDOW=1m"},
+			want: map[string]interface{}{"replayWindow": time.Minute},
+		},
+		{
+			name: "Positive case: Set flag -tls-cert and env TLS_CERT",
+			args: []string{"-tls-cert=/tmp/server1.crt"},
+			envs: []string{"TLS_CERT=/tmp/server2.crt"},
+			want: map[string]interface{}{"tlsCertPath": "/tmp/server2.crt"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1,13 +1,46 @@
 package server
 
 import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	"github.com/fishus/go-advanced-metrics/internal/audit"
+	"github.com/fishus/go-advanced-metrics/internal/auth"
 	"github.com/fishus/go-advanced-metrics/internal/cryptokey"
+	"github.com/fishus/go-advanced-metrics/internal/graphite"
+	"github.com/fishus/go-advanced-metrics/internal/ratelimit"
+	"github.com/fishus/go-advanced-metrics/internal/secure"
+	"github.com/fishus/go-advanced-metrics/internal/tlsconfig"
 )
 
 var Config config
 
 var PrivateKey []byte
 
+// Keys is the keyring of the secret keys that sign the data, nil if the data is not signed.
+var Keys *secure.Keyring
+
+// ReplayGuard protects the signed requests to the REST and gRPC servers from being replayed,
+// nil if the secret key is not set or the protection is disabled.
+var ReplayGuard *secure.ReplayGuard
+
+// Tokens is the registry of the API tokens of the agents, nil if the token checks are disabled.
+var Tokens *auth.Registry
+
+// Audit is the audit log of the write and admin operations, nil if the audit is disabled.
+var Audit *audit.Log
+
+// RateLimiter limits the write requests of every client to the REST and gRPC servers, nil if the limit is disabled.
+var RateLimiter *ratelimit.Limiter
+
+// TLSConfig is the TLS configuration of the REST and gRPC servers, nil if TLS is disabled.
+var TLSConfig *tls.Config
+
+var AlertRules []alerting.Rule
+
+var GraphiteTemplates []graphite.Template
+
 func Initialize() error {
 	c, err := loadConfig()
 	if err != nil {
@@ -16,6 +49,10 @@ func Initialize() error {
 
 	Config = c
 
+	if Config.serverType == ServerTypeREST && Config.grpcAddr != "" && Config.grpcAddr == Config.serverAddr {
+		return fmt.Errorf("the REST and gRPC servers cannot share the address %s", Config.serverAddr)
+	}
+
 	if Config.privateKeyPath != "" {
 		privKey, err := cryptokey.ReadKeyFile(Config.privateKeyPath)
 		if err != nil {
@@ -24,5 +61,61 @@ func Initialize() error {
 		PrivateKey = privKey
 	}
 
+	if Config.tokensPath != "" {
+		registry, err := auth.LoadRegistry(Config.tokensPath)
+		if err != nil {
+			return err
+		}
+		Tokens = registry
+	}
+
+	Keys = secure.NewKeyring([]byte(Config.secretKey))
+	if Config.keyringPath != "" {
+		keyring, err := secure.LoadKeyring(Config.keyringPath, []byte(Config.secretKey))
+		if err != nil {
+			return err
+		}
+		Keys = keyring
+	}
+
+	if !Keys.Empty() && Config.replayWindow > 0 {
+		ReplayGuard = secure.NewReplayGuard(Config.replayWindow, Config.nonceCacheSize)
+	}
+
+	if Config.auditPath != "" {
+		log, err := audit.Open(Config.auditPath, Config.auditMaxSize, Config.auditMaxBackups)
+		if err != nil {
+			return err
+		}
+		Audit = log
+	}
+
+	// Один лимитер на REST и gRPC, чтобы клиент не обходил лимит, переключая протокол
+	RateLimiter = ratelimit.New(Config.clientRateLimit, Config.clientRateBurst)
+
+	if Config.tlsCertPath != "" || Config.tlsKeyPath != "" || Config.tlsClientCAPath != "" {
+		tlsConfig, err := tlsconfig.Server(Config.tlsCertPath, Config.tlsKeyPath, Config.tlsClientCAPath)
+		if err != nil {
+			return err
+		}
+		TLSConfig = tlsConfig
+	}
+
+	if Config.alertRulesPath != "" {
+		rules, err := alerting.LoadRules(Config.alertRulesPath)
+		if err != nil {
+			return err
+		}
+		AlertRules = rules
+	}
+
+	if len(Config.graphiteTemplates) > 0 {
+		templates, err := graphite.ParseTemplates(Config.graphiteTemplates)
+		if err != nil {
+			return err
+		}
+		GraphiteTemplates = templates
+	}
+
 	return nil
 }
@@ -5,32 +5,51 @@ import (
 	"sync"
 	"time"
 
+	"github.com/fishus/go-advanced-metrics/internal/alerting"
+	"github.com/fishus/go-advanced-metrics/internal/alerting/notify"
+	"github.com/fishus/go-advanced-metrics/internal/app"
+	"github.com/fishus/go-advanced-metrics/internal/audit"
 	"github.com/fishus/go-advanced-metrics/internal/controller"
 	db "github.com/fishus/go-advanced-metrics/internal/database"
+	"github.com/fishus/go-advanced-metrics/internal/graphite"
 	grpc "github.com/fishus/go-advanced-metrics/internal/grpc/server"
 	"github.com/fishus/go-advanced-metrics/internal/handlers"
 	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+	"github.com/fishus/go-advanced-metrics/internal/statsd"
 	store "github.com/fishus/go-advanced-metrics/internal/storage"
 )
 
 var wgServer sync.WaitGroup
 
+// wgIngest waits for the metrics receivers that must finish before the metrics are saved on exit.
+var wgIngest sync.WaitGroup
+
 type IServer interface {
-	Run(ctx context.Context)
+	Run(ctx context.Context) error
 	Shutdown(ctx context.Context) error
 }
 
-var Server IServer
+// Servers are the running REST and gRPC servers.
+var Servers []IServer
 
 var Storage store.MetricsStorager
 
+var Alerts *alerting.Engine
+
+var Graphite *graphite.Listener
+
 func SetStorage() {
+	Storage = newStorage()
+	Storage.SetRetention(Config.Retention())
+}
+
+func newStorage() store.MetricsStorager {
 	dbPool, _ := db.Pool()
 	if dbPool != nil {
 		dbStorage := store.NewDBStorage(dbPool)
 		dbStorage.MigrateCreateSchema(context.Background())
-		Storage = dbStorage
-		return
+		return dbStorage
 	}
 
 	if Config.FileStoragePath() != "" {
@@ -38,11 +57,10 @@ func SetStorage() {
 		if Config.StoreInterval() == 0 {
 			s.SetIsSyncSave(true)
 		}
-		Storage = s
-		return
+		return s
 	}
 
-	Storage = store.NewMemStorage()
+	return store.NewMemStorage()
 }
 
 func LoadMetricsFromFile() {
@@ -91,11 +109,114 @@ func SaveMetricsAtIntervals(ctx context.Context) {
 	}()
 }
 
+// PruneSamplesAtIntervals periodically removes the metrics history older than the retention period.
+func PruneSamplesAtIntervals(ctx context.Context) {
+	if Config.Retention() <= 0 {
+		return
+	}
+
+	interval := Config.Retention() / 10
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+
+	wgServer.Add(1)
+	go func() {
+		defer wgServer.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := Storage.PruneSamples(ctx)
+				if err != nil {
+					logger.Log.Error(err.Error(), logger.String("event", "prune metrics history"))
+				}
+			}
+		}
+	}()
+}
+
+// EvaluateAlertsAtIntervals periodically evaluates the alerting rules.
+func EvaluateAlertsAtIntervals(ctx context.Context) {
+	if len(AlertRules) == 0 || Config.AlertInterval() <= 0 {
+		return
+	}
+
+	Alerts = alerting.NewEngine(Storage, AlertRules)
+
+	notifiers, closeNotifiers := newAlertNotifiers()
+	dispatcher := notify.NewDispatcher(notifiers, Config.AlertGroupInterval(), Config.AlertRepeatInterval())
+	dispatcher.OnResolved(Alerts.Forget)
+
+	// Уведомления доставляются отдельно, чтобы недоступный получатель не задерживал вычисление правил
+	wgServer.Add(1)
+	go func() {
+		defer wgServer.Done()
+		defer closeNotifiers()
+
+		dispatcher.Run(ctx, func(err error) {
+			logger.Log.Error(err.Error(), logger.String("event", "notify alerts"))
+		})
+	}()
+
+	wgServer.Add(1)
+	go func() {
+		defer wgServer.Done()
+
+		ticker := time.NewTicker(Config.AlertInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := time.Now()
+				changed := Alerts.Eval(ctx, now)
+				for _, alert := range changed {
+					logger.Log.Info("Alert state changed", logger.String("event", "evaluate alerts"),
+						logger.String("rule", alert.Rule), logger.String("series", alert.Key()), logger.String("state", string(alert.State)))
+				}
+
+				if err := dispatcher.Enqueue(changed, now); err != nil {
+					logger.Log.Error(err.Error(), logger.String("event", "notify alerts"))
+				}
+			}
+		}
+	}()
+}
+
+// newAlertNotifiers returns the configured notifiers and a function that releases them.
+func newAlertNotifiers() ([]notify.Notifier, func()) {
+	notifiers := make([]notify.Notifier, 0)
+	closeFn := func() {}
+
+	if Config.AlertFile() != "" {
+		f, err := notify.OpenFile(Config.AlertFile())
+		if err != nil {
+			logger.Log.Error(err.Error(), logger.String("event", "open alert notifications file"))
+		} else {
+			notifiers = append(notifiers, f)
+			closeFn = func() { _ = f.Close() }
+		}
+	}
+
+	for _, url := range Config.AlertWebhooks() {
+		notifiers = append(notifiers, notify.NewWebhook(url, Keys))
+	}
+
+	return notifiers, closeFn
+}
+
 func SaveMetricsOnExit(ctx context.Context) {
 	wgServer.Add(1)
 	go func() {
 		defer wgServer.Done()
 		<-ctx.Done()
+		wgIngest.Wait()
 
 		if s, ok := Storage.(store.Saver); ok {
 			err := s.Save()
@@ -106,31 +227,162 @@ func SaveMetricsOnExit(ctx context.Context) {
 	}()
 }
 
-func RunServer(ctx context.Context) {
+// RunStatsD starts the StatsD listener, the aggregated metrics are written through the controller.
+func RunStatsD(ctx context.Context) {
+	if (Config.StatsdUDPAddr() == "" && Config.StatsdTCPAddr() == "") || Config.StatsdFlushInterval() <= 0 {
+		return
+	}
+
+	flush := func(ctx context.Context, batch []metrics.Metrics) error {
+		_, _, err := controller.Controller{}.UpdatesMetrics(ctx, batch)
+		return err
+	}
+
+	current := func(ctx context.Context, name string, labels metrics.Labels) (float64, bool) {
+		gauges := Storage.GaugesContext(ctx, store.FilterName(name))
+		g, ok := gauges[metrics.SeriesKey(name, labels)]
+		if !ok {
+			return 0, false
+		}
+		return g.Value(), true
+	}
+
+	l := statsd.NewListener(statsd.Config{
+		UDPAddr:       Config.StatsdUDPAddr(),
+		TCPAddr:       Config.StatsdTCPAddr(),
+		FlushInterval: Config.StatsdFlushInterval(),
+	}, flush, current)
+
+	if err := l.Listen(); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "start statsd listener"))
+		return
+	}
+	logger.Log.Info("Running statsd listener", logger.String("udp", Config.StatsdUDPAddr()),
+		logger.String("tcp", Config.StatsdTCPAddr()), logger.String("event", "start statsd listener"))
+
 	controller.Storage = Storage
 
-	switch Config.ServerType() {
-	case ServerTypeREST:
-		Server = handlers.NewServer(handlers.Config{
-			ServerAddr:    Config.ServerAddr(),
-			Storage:       Storage, // TODO remove
-			SecretKey:     Config.SecretKey(),
-			PrivateKey:    PrivateKey,
-			TrustedSubnet: Config.TrustedSubnet(),
-		})
-	case ServerTypeGRPC:
-		Server = grpc.NewServer(grpc.Config{
-			ServerAddr:    Config.ServerAddr(),
-			Storage:       Storage, // TODO remove
-			SecretKey:     Config.SecretKey(),
-			PrivateKey:    PrivateKey,
-			TrustedSubnet: Config.TrustedSubnet(),
-		})
-	default:
-		logger.Log.Panic("unspecified server type")
+	wgServer.Add(1)
+	wgIngest.Add(1)
+	go func() {
+		defer wgServer.Done()
+		defer wgIngest.Done()
+		l.Serve(ctx)
+	}()
+}
+
+// RunGraphite starts the Graphite listener, it is stopped by Shutdown.
+func RunGraphite() {
+	if Config.GraphiteAddr() == "" {
+		return
 	}
 
+	l := graphite.NewListener(graphite.Config{
+		Addr:           Config.GraphiteAddr(),
+		Templates:      GraphiteTemplates,
+		MaxConnections: Config.GraphiteMaxConns(),
+	}, Storage)
+
+	if err := l.Listen(); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "start graphite listener"))
+		return
+	}
+	logger.Log.Info("Running graphite listener", logger.String("address", Config.GraphiteAddr()), logger.String("event", "start graphite listener"))
+
+	Graphite = l
+	wgIngest.Add(1)
 	go func() {
-		Server.Run(ctx)
+		if err := l.Serve(); err != nil {
+			logger.Log.Error(err.Error(), logger.String("event", "start graphite listener"))
+		}
 	}()
 }
+
+// ReloadKeysOnSignal reloads the keyring file on SIGHUP, the loaded keys are kept if it is invalid.
+func ReloadKeysOnSignal(ctx context.Context) {
+	if Config.KeyringPath() == "" {
+		return
+	}
+
+	app.RegReload(ctx, func() {
+		event := audit.Event{Protocol: audit.ProtocolSignal, Action: "SIGHUP reload keyring", Outcome: audit.OutcomeSuccess}
+		if err := Keys.Reload(); err != nil {
+			event.Outcome, event.Error = audit.OutcomeFailure, err.Error()
+			Audit.Record(event)
+			logger.Log.Error(err.Error(), logger.String("event", "reload keyring"))
+			return
+		}
+		Audit.Record(event)
+		logger.Log.Info("Keyring reloaded", logger.String("signing_key", Keys.Signing().ID), logger.String("event", "reload keyring"))
+	})
+}
+
+// RunServer starts the REST server and the gRPC server on their addresses.
+// If any of them fails, stop is called to shut down the whole process.
+func RunServer(ctx context.Context, stop context.CancelFunc) {
+	controller.Storage = Storage
+
+	Servers = newServers()
+	if len(Servers) == 0 {
+		logger.Log.Panic("unspecified server type")
+	}
+
+	wgIngest.Add(1)
+	for _, srv := range Servers {
+		go func(srv IServer) {
+			if err := srv.Run(ctx); err != nil {
+				logger.Log.Error(err.Error(), logger.String("event", "start server"))
+				stop()
+			}
+		}(srv)
+	}
+}
+
+func newServers() []IServer {
+	servers := make([]IServer, 0, 2)
+
+	if Config.ServerType() == ServerTypeREST {
+		servers = append(servers, handlers.NewServer(handlers.Config{
+			ServerAddr:     Config.ServerAddr(),
+			Storage:        Storage, // TODO remove
+			Alerts:         Alerts,
+			Keys:           Keys,
+			PrivateKey:     PrivateKey,
+			TrustedSubnets: Config.TrustedSubnets(),
+			TrustedProxies: Config.TrustedProxies(),
+			TLSConfig:      TLSConfig,
+			ReplayGuard:    ReplayGuard,
+			Tokens:         Tokens,
+			RateLimiter:    RateLimiter,
+			Audit:          Audit,
+
+			MaxBodySize:         Config.MaxBodySize(),
+			MaxDecompressedSize: Config.MaxDecompressedSize(),
+			MaxBatchSize:        Config.MaxBatchSize(),
+		}))
+	}
+
+	grpcAddr := Config.GRPCAddr()
+	if Config.ServerType() == ServerTypeGRPC && grpcAddr == "" {
+		grpcAddr = Config.ServerAddr()
+	}
+	if grpcAddr != "" {
+		servers = append(servers, grpc.NewServer(grpc.Config{
+			ServerAddr:     grpcAddr,
+			Storage:        Storage, // TODO remove
+			Keys:           Keys,
+			PrivateKey:     PrivateKey,
+			TrustedSubnets: Config.TrustedSubnets(),
+			TrustedProxies: Config.TrustedProxies(),
+			TLSConfig:      TLSConfig,
+			ReplayGuard:    ReplayGuard,
+			Tokens:         Tokens,
+			MaxMsgSize:     int(Config.MaxDecompressedSize()),
+			MaxBatchSize:   Config.MaxBatchSize(),
+			RateLimiter:    RateLimiter,
+			Audit:          Audit,
+		}))
+	}
+
+	return servers
+}
@@ -2,15 +2,53 @@ package server
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/fishus/go-advanced-metrics/internal/logger"
 )
 
+// shutdownTimeout is the time given to the servers to finish the pending requests.
+const shutdownTimeout = 10 * time.Second
+
+// Shutdown stops the servers and the listeners, waits for the background tasks and saves the metrics.
 func Shutdown() {
+	if len(Servers) > 0 {
+		shutdownServers()
+		wgIngest.Done()
+	}
+
+	if Graphite != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err := Graphite.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			logger.Log.Error(err.Error(), logger.String("event", "stop graphite listener"))
+		}
+		wgIngest.Done()
+	}
+
 	wgServer.Wait()
 
-	err := Server.Shutdown(context.Background())
-	if err != nil {
-		logger.Log.Error(err.Error(), logger.String("event", "stop server"))
+	if err := Audit.Close(); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "close audit log"))
+	}
+}
+
+// shutdownServers stops the REST and gRPC servers concurrently.
+func shutdownServers() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range Servers {
+		wg.Add(1)
+		go func(srv IServer) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				logger.Log.Error(err.Error(), logger.String("event", "stop server"))
+			}
+		}(srv)
 	}
+	wg.Wait()
 }
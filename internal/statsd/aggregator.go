@@ -0,0 +1,199 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// maxRemainders is the maximum number of the counter series whose fractional remainder is carried.
+const maxRemainders = 10_000
+
+type counterAgg struct {
+	labels metrics.Labels
+	name   string
+	sum    float64
+}
+
+type gaugeAgg struct {
+	labels   metrics.Labels
+	name     string
+	value    float64
+	relative bool // Значение является изменением текущего значения gauge
+}
+
+type setAgg struct {
+	labels metrics.Labels
+	values map[string]struct{}
+	name   string
+}
+
+type histogramAgg struct {
+	labels metrics.Labels
+	name   string
+	value  metrics.HistogramValue
+}
+
+// Aggregator accumulates the StatsD values between the flushes.
+type Aggregator struct {
+	counters   map[string]*counterAgg
+	gauges     map[string]*gaugeAgg
+	sets       map[string]*setAgg
+	histograms map[string]*histogramAgg
+	remainders map[string]float64 // Дробный остаток счётчика, не вошедший в целое приращение, по ключу серии
+	mu         sync.Mutex
+}
+
+func NewAggregator() *Aggregator {
+	a := &Aggregator{remainders: make(map[string]float64)}
+	a.reset()
+	return a
+}
+
+func (a *Aggregator) reset() {
+	a.counters = make(map[string]*counterAgg)
+	a.gauges = make(map[string]*gaugeAgg)
+	a.sets = make(map[string]*setAgg)
+	a.histograms = make(map[string]*histogramAgg)
+}
+
+// Add accumulates the value of the line.
+func (a *Aggregator) Add(line Line) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := metrics.SeriesKey(line.Name, line.Labels)
+
+	switch line.Type {
+	case TypeCounter:
+		c, ok := a.counters[key]
+		if !ok {
+			c = &counterAgg{name: line.Name, labels: line.Labels}
+			a.counters[key] = c
+		}
+		c.sum += line.Value / line.Rate
+
+	case TypeGauge:
+		g, ok := a.gauges[key]
+		if !ok {
+			g = &gaugeAgg{name: line.Name, labels: line.Labels, relative: true}
+			a.gauges[key] = g
+		}
+		if line.Relative {
+			g.value += line.Value
+		} else {
+			g.value = line.Value
+			g.relative = false
+		}
+
+	case TypeSet:
+		s, ok := a.sets[key]
+		if !ok {
+			s = &setAgg{name: line.Name, labels: line.Labels, values: make(map[string]struct{})}
+			a.sets[key] = s
+		}
+		s.values[line.Raw] = struct{}{}
+
+	case TypeTimer, TypeHistogram, TypeDistrib:
+		h, ok := a.histograms[key]
+		if !ok {
+			v, _ := metrics.NewHistogramValue(metrics.DefaultHistogramBounds)
+			h = &histogramAgg{name: line.Name, labels: line.Labels, value: v}
+			a.histograms[key] = h
+		}
+		v := line.Value
+		if line.Type == TypeTimer {
+			v /= 1000
+		}
+		h.value.Observe(v)
+	}
+}
+
+// Flush returns the accumulated values as a batch of metrics and resets the aggregator.
+// current returns the stored value of a gauge, the signed gauge values are added to it.
+// The counters are rounded to the integer increments, the fractional remainder,
+// e.g. of the sampled values, is added to the counter at the next flush.
+func (a *Aggregator) Flush(current func(name string, labels metrics.Labels) (float64, bool)) []metrics.Metrics {
+	a.mu.Lock()
+	counters, gauges, sets, histograms := a.counters, a.gauges, a.sets, a.histograms
+	a.reset()
+
+	deltas := make(map[string]int64, len(counters))
+	for key, c := range counters {
+		delta, remainder, ok := counterDelta(c.sum + a.remainders[key])
+		a.carry(key, remainder)
+		if ok {
+			deltas[key] = delta
+		}
+	}
+	a.mu.Unlock()
+
+	batch := make([]metrics.Metrics, 0, len(counters)+len(gauges)+len(sets)+len(histograms))
+
+	for key, c := range counters {
+		delta, ok := deltas[key]
+		if !ok {
+			continue
+		}
+		batch = append(batch, metrics.NewCounterMetric(c.name).WithLabels(c.labels).SetDelta(delta))
+	}
+
+	for _, g := range gauges {
+		value := g.value
+		if g.relative && current != nil {
+			if v, ok := current(g.name, g.labels); ok {
+				value += v
+			}
+		}
+		batch = append(batch, metrics.NewGaugeMetric(g.name).WithLabels(g.labels).SetValue(value))
+	}
+
+	for _, s := range sets {
+		batch = append(batch, metrics.NewGaugeMetric(s.name).WithLabels(s.labels).SetValue(float64(len(s.values))))
+	}
+
+	for _, h := range histograms {
+		batch = append(batch, metrics.NewHistogramMetric(h.name).WithLabels(h.labels).SetHistogram(h.value))
+	}
+
+	sort.SliceStable(batch, func(i, j int) bool {
+		if batch[i].MType != batch[j].MType {
+			return batch[i].MType < batch[j].MType
+		}
+		return batch[i].Key() < batch[j].Key()
+	})
+	return batch
+}
+
+// carry keeps the remainder of the counter for the next flush.
+// When the limit is reached, the remainder of an arbitrary series is dropped.
+func (a *Aggregator) carry(key string, remainder float64) {
+	if remainder == 0 || math.IsNaN(remainder) || math.IsInf(remainder, 0) {
+		delete(a.remainders, key)
+		return
+	}
+	if _, exists := a.remainders[key]; !exists && len(a.remainders) >= maxRemainders {
+		for k := range a.remainders {
+			delete(a.remainders, k)
+			break
+		}
+	}
+	a.remainders[key] = remainder
+}
+
+// counterDelta rounds the sum of the counter to the integer increment and returns the fractional remainder.
+// The sum that does not fit in int64 is clamped, the NaN sum of the infinite values of both signs is dropped.
+func counterDelta(sum float64) (delta int64, remainder float64, ok bool) {
+	switch {
+	case math.IsNaN(sum):
+		return 0, 0, false
+	case sum >= 0x1p63:
+		return math.MaxInt64, 0, true
+	case sum < -0x1p63:
+		return math.MinInt64, 0, true
+	}
+	rounded := math.Round(sum)
+	return int64(rounded), sum - rounded, true
+}
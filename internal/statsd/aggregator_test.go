@@ -0,0 +1,102 @@
+package statsd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestAggregator_Flush(t *testing.T) {
+	agg := NewAggregator()
+	for _, s := range []string{
+		"requests:1|c",
+		"requests:2|c|@0.5",
+		"temperature:20|g",
+		"temperature:+1.5|g",
+		"queue:-2|g",
+		"users:alice|s",
+		"users:bob|s",
+		"users:alice|s",
+		"latency:50|ms",
+		"latency:2000|ms",
+	} {
+		line, err := ParseLine(s)
+		require.NoError(t, err)
+		agg.Add(line)
+	}
+
+	current := func(name string, labels metrics.Labels) (float64, bool) {
+		if name == "queue" {
+			return 10, true
+		}
+		return 0, false
+	}
+
+	batch := agg.Flush(current)
+	require.Len(t, batch, 5)
+
+	got := make(map[string]metrics.Metrics)
+	for _, m := range batch {
+		got[m.MType+" "+m.ID] = m
+	}
+
+	assert.Equal(t, int64(5), *got["counter requests"].Delta)
+	assert.Equal(t, 21.5, *got["gauge temperature"].Value)
+	assert.Equal(t, float64(8), *got["gauge queue"].Value)
+	assert.Equal(t, float64(2), *got["gauge users"].Value)
+
+	h := got["histogram latency"].Histogram
+	require.NotNil(t, h)
+	assert.Equal(t, uint64(2), h.Count)
+	assert.InDelta(t, 2.05, h.Sum, 1e-9)
+
+	assert.Empty(t, agg.Flush(current))
+}
+
+func TestAggregator_FlushRemainder(t *testing.T) {
+	agg := NewAggregator()
+	line, err := ParseLine("requests:1|c|@0.4")
+	require.NoError(t, err)
+
+	// По 2.5 за сброс: остаток переносится, за четыре сброса ровно 10
+	var total int64
+	for i := 0; i < 4; i++ {
+		agg.Add(line)
+		batch := agg.Flush(nil)
+		require.Len(t, batch, 1)
+		total += *batch[0].Delta
+	}
+	assert.Equal(t, int64(10), total)
+}
+
+func TestAggregator_FlushOverflow(t *testing.T) {
+	agg := NewAggregator()
+	line, err := ParseLine("requests:1e300|c")
+	require.NoError(t, err)
+
+	// Сумма вне диапазона int64 ограничивается, остаток не переносится
+	agg.Add(line)
+	batch := agg.Flush(nil)
+	require.Len(t, batch, 1)
+	assert.Equal(t, int64(math.MaxInt64), *batch[0].Delta)
+	assert.Empty(t, agg.remainders)
+
+	line.Value = -1e300
+	agg.Add(line)
+	batch = agg.Flush(nil)
+	require.Len(t, batch, 1)
+	assert.Equal(t, int64(math.MinInt64), *batch[0].Delta)
+
+	// Сумма бесконечных значений разных знаков не отправляется
+	line.Value = math.MaxFloat64
+	line.Rate = 0.1
+	agg.Add(line)
+	line.Value = -math.MaxFloat64
+	agg.Add(line)
+	assert.Empty(t, agg.Flush(nil))
+	assert.Empty(t, agg.remainders)
+}
@@ -0,0 +1,9 @@
+// Package statsd implements a StatsD listener.
+//
+// Lines have the form name:value|type[|@rate][|#tag:value,...]. Supported types are
+// c (counter), g (gauge, a signed value changes the current one), ms, h and d (timers
+// and histograms, milliseconds are converted into seconds) and s (sets, stored as
+// the gauge of the number of unique values). The DogStatsD tags become series labels.
+//
+// Values are aggregated in memory and flushed as one batch at the flush interval.
+package statsd
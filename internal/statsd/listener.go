@@ -0,0 +1,232 @@
+package statsd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fishus/go-advanced-metrics/internal/logger"
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// maxPacketSize is the maximum size of the UDP datagram.
+const maxPacketSize = 65535
+
+type Config struct {
+	UDPAddr       string        // Адрес для приёма метрик по UDP, пустое значение отключает приём
+	TCPAddr       string        // Адрес для приёма метрик по TCP, пустое значение отключает приём
+	FlushInterval time.Duration // Периодичность записи накопленных значений
+}
+
+// FlushFunc writes the batch of the aggregated metrics.
+type FlushFunc func(ctx context.Context, batch []metrics.Metrics) error
+
+// CurrentFunc returns the stored value of the gauge.
+type CurrentFunc func(ctx context.Context, name string, labels metrics.Labels) (float64, bool)
+
+// Listener receives the StatsD lines over UDP and TCP and periodically flushes the aggregated values.
+type Listener struct {
+	udp     net.PacketConn
+	tcp     net.Listener
+	agg     *Aggregator
+	flush   FlushFunc
+	current CurrentFunc
+	conns   map[net.Conn]struct{}
+	config  Config
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	closed  bool
+}
+
+func NewListener(cfg Config, flush FlushFunc, current CurrentFunc) *Listener {
+	return &Listener{
+		config:  cfg,
+		agg:     NewAggregator(),
+		flush:   flush,
+		current: current,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen opens the configured UDP and TCP sockets.
+func (l *Listener) Listen() error {
+	if l.config.UDPAddr != "" {
+		udp, err := net.ListenPacket("udp", l.config.UDPAddr)
+		if err != nil {
+			return err
+		}
+		l.udp = udp
+	}
+
+	if l.config.TCPAddr != "" {
+		tcp, err := net.Listen("tcp", l.config.TCPAddr)
+		if err != nil {
+			if l.udp != nil {
+				_ = l.udp.Close()
+			}
+			return err
+		}
+		l.tcp = tcp
+	}
+
+	return nil
+}
+
+// UDPAddr returns the address of the UDP socket or nil.
+func (l *Listener) UDPAddr() net.Addr {
+	if l.udp == nil {
+		return nil
+	}
+	return l.udp.LocalAddr()
+}
+
+// TCPAddr returns the address of the TCP socket or nil.
+func (l *Listener) TCPAddr() net.Addr {
+	if l.tcp == nil {
+		return nil
+	}
+	return l.tcp.Addr()
+}
+
+// Serve receives the metrics until the context is done, then closes the sockets and flushes the remaining values.
+func (l *Listener) Serve(ctx context.Context) {
+	if l.udp != nil {
+		l.wg.Add(1)
+		go l.serveUDP()
+	}
+
+	if l.tcp != nil {
+		l.wg.Add(1)
+		go l.serveTCP()
+	}
+
+	ticker := time.NewTicker(l.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.close()
+			l.wg.Wait()
+			// Контекст уже отменён, записываем оставшиеся значения без него
+			l.Flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			l.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes the aggregated values.
+func (l *Listener) Flush(ctx context.Context) {
+	var current func(name string, labels metrics.Labels) (float64, bool)
+	if l.current != nil {
+		current = func(name string, labels metrics.Labels) (float64, bool) {
+			return l.current(ctx, name, labels)
+		}
+	}
+
+	batch := l.agg.Flush(current)
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := l.flush(ctx, batch); err != nil {
+		logger.Log.Error(err.Error(), logger.String("event", "flush statsd metrics"))
+	}
+}
+
+// Handle parses the lines of the packet and adds them to the aggregator.
+func (l *Listener) Handle(packet string) {
+	for _, s := range strings.Split(packet, "\n") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		line, err := ParseLine(s)
+		if err != nil {
+			logger.Log.Debug(err.Error(), logger.String("event", "parse statsd line"))
+			continue
+		}
+		l.agg.Add(line)
+	}
+}
+
+func (l *Listener) serveUDP() {
+	defer l.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := l.udp.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Error(err.Error(), logger.String("event", "read statsd packet"))
+			}
+			return
+		}
+		l.Handle(string(buf[:n]))
+	}
+}
+
+func (l *Listener) serveTCP() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.tcp.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Error(err.Error(), logger.String("event", "accept statsd connection"))
+			}
+			return
+		}
+
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		l.conns[conn] = struct{}{}
+		l.mu.Unlock()
+
+		l.wg.Add(1)
+		go l.serveConn(conn)
+	}
+}
+
+func (l *Listener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		l.Handle(scanner.Text())
+	}
+}
+
+// close closes the sockets and the open TCP connections.
+func (l *Listener) close() {
+	if l.udp != nil {
+		_ = l.udp.Close()
+	}
+	if l.tcp != nil {
+		_ = l.tcp.Close()
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.mu.Unlock()
+}
@@ -0,0 +1,66 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestListener(t *testing.T) {
+	var mu sync.Mutex
+	flushed := make(map[string]metrics.Metrics)
+	flush := func(ctx context.Context, batch []metrics.Metrics) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, m := range batch {
+			flushed[m.Key()] = m
+		}
+		return nil
+	}
+
+	l := NewListener(Config{UDPAddr: "127.0.0.1:0", TCPAddr: "127.0.0.1:0", FlushInterval: time.Hour}, flush, nil)
+	require.NoError(t, l.Listen())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Serve(ctx)
+	}()
+
+	udp, err := net.Dial("udp", l.UDPAddr().String())
+	require.NoError(t, err)
+	_, err = udp.Write([]byte("requests:3|c\ntemperature:21.5|g|#host:web1"))
+	require.NoError(t, err)
+	require.NoError(t, udp.Close())
+
+	tcp, err := net.Dial("tcp", l.TCPAddr().String())
+	require.NoError(t, err)
+	_, err = tcp.Write([]byte("requests:2|c\n"))
+	require.NoError(t, err)
+	require.NoError(t, tcp.Close())
+
+	require.Eventually(t, func() bool {
+		l.agg.mu.Lock()
+		defer l.agg.mu.Unlock()
+		c, ok := l.agg.counters["requests"]
+		return ok && c.sum == 5 && len(l.agg.gauges) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Оставшиеся значения записываются при остановке
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, flushed, 2)
+	assert.Equal(t, int64(5), *flushed["requests"].Delta)
+	assert.Equal(t, 21.5, *flushed[metrics.SeriesKey("temperature", metrics.Labels{"host": "web1"})].Value)
+}
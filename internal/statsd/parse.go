@@ -0,0 +1,103 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+// StatsD metric types.
+const (
+	TypeCounter   = "c"
+	TypeGauge     = "g"
+	TypeTimer     = "ms"
+	TypeHistogram = "h"
+	TypeDistrib   = "d"
+	TypeSet       = "s"
+)
+
+var ErrInvalidLine = errors.New("invalid statsd line")
+
+// Line is a parsed StatsD line.
+type Line struct {
+	Labels   metrics.Labels
+	Name     string
+	Type     string
+	Raw      string  // Значение в исходном виде, используется для set
+	Value    float64 //
+	Rate     float64 // Частота семплирования, 1 - все значения
+	Relative bool    // Значение gauge со знаком изменяет текущее значение
+}
+
+// ParseLine parses the line name:value|type[|@rate][|#tags].
+func ParseLine(s string) (Line, error) {
+	line := Line{Rate: 1}
+
+	name, rest, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return line, fmt.Errorf("%w %q: metric name not specified", ErrInvalidLine, s)
+	}
+	line.Name = name
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return line, fmt.Errorf("%w %q: metric type not specified", ErrInvalidLine, s)
+	}
+
+	line.Raw = parts[0]
+	line.Type = parts[1]
+	switch line.Type {
+	case TypeCounter, TypeGauge, TypeTimer, TypeHistogram, TypeDistrib:
+		v, err := strconv.ParseFloat(line.Raw, 64)
+		if err != nil {
+			return line, fmt.Errorf("%w %q: %w", ErrInvalidLine, s, err)
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return line, fmt.Errorf("%w %q: incorrect value", ErrInvalidLine, s)
+		}
+		line.Value = v
+		line.Relative = line.Type == TypeGauge && (line.Raw[0] == '+' || line.Raw[0] == '-')
+	case TypeSet:
+	default:
+		return line, fmt.Errorf("%w %q: unknown metric type %q", ErrInvalidLine, s, line.Type)
+	}
+
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			rate, err := strconv.ParseFloat(p[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return line, fmt.Errorf("%w %q: incorrect sample rate %q", ErrInvalidLine, s, p)
+			}
+			line.Rate = rate
+		case strings.HasPrefix(p, "#"):
+			labels, err := parseTags(p[1:])
+			if err != nil {
+				return line, fmt.Errorf("%w %q: %w", ErrInvalidLine, s, err)
+			}
+			line.Labels = labels
+		}
+	}
+
+	return line, nil
+}
+
+// parseTags parses the DogStatsD tags tag:value,tag2:value2. A tag without value gets an empty value.
+func parseTags(s string) (metrics.Labels, error) {
+	labels := metrics.Labels{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(tag, ":")
+		labels[name] = value
+	}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	return labels.Clone(), nil
+}
@@ -0,0 +1,102 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
+func TestParseLine(t *testing.T) {
+	testCases := []struct {
+		name    string
+		line    string
+		want    Line
+		wantErr bool
+	}{
+		{
+			name: "Counter",
+			line: "requests:3|c",
+			want: Line{Name: "requests", Type: TypeCounter, Raw: "3", Value: 3, Rate: 1},
+		},
+		{
+			name: "Counter with sample rate and tags",
+			line: "requests:1|c|@0.5|#host:web1,env:prod",
+			want: Line{Name: "requests", Type: TypeCounter, Raw: "1", Value: 1, Rate: 0.5, Labels: metrics.Labels{"host": "web1", "env": "prod"}},
+		},
+		{
+			name: "Gauge",
+			line: "temperature:21.5|g",
+			want: Line{Name: "temperature", Type: TypeGauge, Raw: "21.5", Value: 21.5, Rate: 1},
+		},
+		{
+			name: "Relative gauge",
+			line: "queue:-2|g",
+			want: Line{Name: "queue", Type: TypeGauge, Raw: "-2", Value: -2, Rate: 1, Relative: true},
+		},
+		{
+			name: "Timer",
+			line: "latency:320|ms",
+			want: Line{Name: "latency", Type: TypeTimer, Raw: "320", Value: 320, Rate: 1},
+		},
+		{
+			name: "Set",
+			line: "users:alice|s",
+			want: Line{Name: "users", Type: TypeSet, Raw: "alice", Rate: 1},
+		},
+		{
+			name:    "Without name",
+			line:    ":1|c",
+			wantErr: true,
+		},
+		{
+			name:    "Without type",
+			line:    "requests:1",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			line:    "requests:1|x",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect value",
+			line:    "requests:abc|c",
+			wantErr: true,
+		},
+		{
+			name:    "NaN value",
+			line:    "requests:NaN|c",
+			wantErr: true,
+		},
+		{
+			name:    "Infinite value",
+			line:    "temperature:+Inf|g",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect sample rate",
+			line:    "requests:1|c|@2",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect tag name",
+			line:    "requests:1|c|#1host:web1",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			line, err := ParseLine(tc.line)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLine)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, line)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	db "github.com/fishus/go-advanced-metrics/internal/database"
@@ -12,7 +14,8 @@ import (
 )
 
 type DBStorage struct {
-	pool db.Connector
+	pool      db.Connector
+	retention time.Duration // Срок хранения истории, 0 - история не ведётся
 }
 
 func NewDBStorage(pool db.Connector) *DBStorage {
@@ -53,11 +56,11 @@ func (ds *DBStorage) GetDBPool() (db.Connector, error) {
 
 // Gauge returns the gauge metric by name
 func (ds *DBStorage) Gauge(name string) (metrics.Gauge, bool) {
-	return ds.GaugeContext(context.Background(), name)
+	return ds.GaugeContext(context.Background(), name, nil)
 }
 
-// GaugeContext returns the gauge metric by name
-func (ds *DBStorage) GaugeContext(ctx context.Context, name string) (metrics.Gauge, bool) {
+// GaugeContext returns the gauge metric by name and labels
+func (ds *DBStorage) GaugeContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Gauge, bool) {
 	pool, err := ds.GetDBPool()
 	if err != nil {
 		return metrics.Gauge{}, false
@@ -66,7 +69,7 @@ func (ds *DBStorage) GaugeContext(ctx context.Context, name string) (metrics.Gau
 	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
 	defer cancel()
 
-	row := pool.QueryRow(ctxQuery, "SELECT value FROM metrics_gauge WHERE name = $1 LIMIT 1;", name)
+	row := pool.QueryRow(ctxQuery, "SELECT value FROM metrics_gauge WHERE name = $1 AND labels = $2 LIMIT 1;", name, dbLabels(labels))
 	var value float64
 	err = row.Scan(&value)
 	if errors.Is(err, db.ErrNoRows) {
@@ -77,7 +80,7 @@ func (ds *DBStorage) GaugeContext(ctx context.Context, name string) (metrics.Gau
 		return metrics.Gauge{}, false
 	}
 
-	gauge, err := metrics.NewGauge(name, value)
+	gauge, err := metrics.NewGaugeWithLabels(name, labels, value)
 	if err != nil {
 		logger.Log.Warn(err.Error())
 		return metrics.Gauge{}, false
@@ -88,12 +91,12 @@ func (ds *DBStorage) GaugeContext(ctx context.Context, name string) (metrics.Gau
 
 // GaugeValue returns the gauge metric value by name
 func (ds *DBStorage) GaugeValue(name string) (float64, bool) {
-	return ds.GaugeValueContext(context.Background(), name)
+	return ds.GaugeValueContext(context.Background(), name, nil)
 }
 
-// GaugeValueContext returns the gauge metric value by name
-func (ds *DBStorage) GaugeValueContext(ctx context.Context, name string) (float64, bool) {
-	if gauge, ok := ds.GaugeContext(ctx, name); ok {
+// GaugeValueContext returns the gauge metric value by name and labels
+func (ds *DBStorage) GaugeValueContext(ctx context.Context, name string, labels metrics.Labels) (float64, bool) {
+	if gauge, ok := ds.GaugeContext(ctx, name, labels); ok {
 		return gauge.Value(), ok
 	}
 	return 0, false
@@ -125,11 +128,8 @@ func (ds *DBStorage) GaugesContext(ctx context.Context, filters ...StorageFilter
 	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
 	defer cancel()
 
-	if len(f.names) > 0 {
-		rows, err = pool.Query(ctxQuery, "SELECT name, value FROM metrics_gauge WHERE name = ANY($1);", f.names)
-	} else {
-		rows, err = pool.Query(ctxQuery, "SELECT name, value FROM metrics_gauge;")
-	}
+	where, args := filtersToSQL(f)
+	rows, err = pool.Query(ctxQuery, "SELECT name, labels, value FROM metrics_gauge"+where+";", args...)
 	if err != nil {
 		logger.Log.Warn(err.Error())
 		return gauges
@@ -138,22 +138,23 @@ func (ds *DBStorage) GaugesContext(ctx context.Context, filters ...StorageFilter
 
 	for rows.Next() {
 		var (
-			gName  string
-			gValue float64
+			gName   string
+			gLabels metrics.Labels
+			gValue  float64
 		)
 
-		if err = rows.Scan(&gName, &gValue); err != nil {
+		if err = rows.Scan(&gName, &gLabels, &gValue); err != nil {
 			logger.Log.Warn(err.Error())
 			return map[string]metrics.Gauge{}
 		}
 
-		gauge, err2 := metrics.NewGauge(gName, gValue)
+		gauge, err2 := metrics.NewGaugeWithLabels(gName, gLabels, gValue)
 		if err2 != nil {
 			logger.Log.Warn(err2.Error())
 			return map[string]metrics.Gauge{}
 		}
 
-		gauges[gName] = *gauge
+		gauges[gauge.Key()] = *gauge
 	}
 
 	err = rows.Err()
@@ -166,24 +167,23 @@ func (ds *DBStorage) GaugesContext(ctx context.Context, filters ...StorageFilter
 }
 
 func (ds *DBStorage) SetGauge(name string, value float64) error {
-	return ds.SetGaugeContext(context.Background(), name, value)
+	return ds.SetGaugeContext(context.Background(), name, nil, value)
 }
 
-func (ds *DBStorage) SetGaugeContext(ctx context.Context, name string, value float64) error {
+func (ds *DBStorage) SetGaugeContext(ctx context.Context, name string, labels metrics.Labels, value float64) error {
 	pool, err := ds.GetDBPool()
 	if err != nil {
 		return err
 	}
 
-	if _, err = metrics.NewGauge(name, value); err != nil {
+	if _, err = metrics.NewGaugeWithLabels(name, labels, value); err != nil {
 		return err
 	}
 
 	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
 	defer cancel()
 
-	_, err = pool.Exec(ctxQuery, "INSERT INTO metrics_gauge (name, value) VALUES (@name, @value) ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value;",
-		db.NamedArgs{"name": name, "value": value})
+	_, err = pool.Exec(ctxQuery, ds.withSample(sqlUpsertGauge, "metrics_gauge_sample"), name, dbLabels(labels), value)
 	if err != nil {
 		return err
 	}
@@ -199,7 +199,7 @@ func (ds *DBStorage) ResetGauges() error {
 
 	ctx := context.Background()
 
-	_, err = pool.Exec(ctx, "TRUNCATE metrics_gauge;")
+	_, err = pool.Exec(ctx, "TRUNCATE metrics_gauge, metrics_gauge_sample;")
 	if err != nil {
 		return err
 	}
@@ -209,11 +209,11 @@ func (ds *DBStorage) ResetGauges() error {
 
 // Counter returns the counter metric by name
 func (ds *DBStorage) Counter(name string) (metrics.Counter, bool) {
-	return ds.CounterContext(context.Background(), name)
+	return ds.CounterContext(context.Background(), name, nil)
 }
 
-// CounterContext returns the counter metric by name
-func (ds *DBStorage) CounterContext(ctx context.Context, name string) (metrics.Counter, bool) {
+// CounterContext returns the counter metric by name and labels
+func (ds *DBStorage) CounterContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Counter, bool) {
 	pool, err := ds.GetDBPool()
 	if err != nil {
 		return metrics.Counter{}, false
@@ -222,7 +222,7 @@ func (ds *DBStorage) CounterContext(ctx context.Context, name string) (metrics.C
 	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
 	defer cancel()
 
-	row := pool.QueryRow(ctxQuery, "SELECT value FROM metrics_counter WHERE name = $1 LIMIT 1;", name)
+	row := pool.QueryRow(ctxQuery, "SELECT value FROM metrics_counter WHERE name = $1 AND labels = $2 LIMIT 1;", name, dbLabels(labels))
 	var value int64
 	err = row.Scan(&value)
 	if errors.Is(err, db.ErrNoRows) {
@@ -233,7 +233,7 @@ func (ds *DBStorage) CounterContext(ctx context.Context, name string) (metrics.C
 		return metrics.Counter{}, false
 	}
 
-	counter, err := metrics.NewCounter(name, value)
+	counter, err := metrics.NewCounterWithLabels(name, labels, value)
 	if err != nil {
 		logger.Log.Warn(err.Error())
 		return metrics.Counter{}, false
@@ -244,12 +244,12 @@ func (ds *DBStorage) CounterContext(ctx context.Context, name string) (metrics.C
 
 // CounterValue returns the counter metric value by name
 func (ds *DBStorage) CounterValue(name string) (int64, bool) {
-	return ds.CounterValueContext(context.Background(), name)
+	return ds.CounterValueContext(context.Background(), name, nil)
 }
 
-// CounterValueContext returns the counter metric value by name
-func (ds *DBStorage) CounterValueContext(ctx context.Context, name string) (int64, bool) {
-	if counter, ok := ds.CounterContext(ctx, name); ok {
+// CounterValueContext returns the counter metric value by name and labels
+func (ds *DBStorage) CounterValueContext(ctx context.Context, name string, labels metrics.Labels) (int64, bool) {
+	if counter, ok := ds.CounterContext(ctx, name, labels); ok {
 		return counter.Value(), ok
 	}
 	return 0, false
@@ -281,11 +281,8 @@ func (ds *DBStorage) CountersContext(ctx context.Context, filters ...StorageFilt
 	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
 	defer cancel()
 
-	if len(f.names) > 0 {
-		rows, err = pool.Query(ctxQuery, "SELECT name, value FROM metrics_counter WHERE name = ANY($1);", f.names)
-	} else {
-		rows, err = pool.Query(ctxQuery, "SELECT name, value FROM metrics_counter;")
-	}
+	where, args := filtersToSQL(f)
+	rows, err = pool.Query(ctxQuery, "SELECT name, labels, value FROM metrics_counter"+where+";", args...)
 	if err != nil {
 		logger.Log.Warn(err.Error())
 		return counters
@@ -294,23 +291,24 @@ func (ds *DBStorage) CountersContext(ctx context.Context, filters ...StorageFilt
 
 	for rows.Next() {
 		var (
-			cName  string
-			cValue int64
+			cName   string
+			cLabels metrics.Labels
+			cValue  int64
 		)
 
-		err = rows.Scan(&cName, &cValue)
+		err = rows.Scan(&cName, &cLabels, &cValue)
 		if err != nil {
 			logger.Log.Warn(err.Error())
 			return map[string]metrics.Counter{}
 		}
 
-		counter, err2 := metrics.NewCounter(cName, cValue)
+		counter, err2 := metrics.NewCounterWithLabels(cName, cLabels, cValue)
 		if err2 != nil {
 			logger.Log.Warn(err2.Error())
 			return map[string]metrics.Counter{}
 		}
 
-		counters[cName] = *counter
+		counters[counter.Key()] = *counter
 	}
 
 	err = rows.Err()
@@ -323,24 +321,23 @@ func (ds *DBStorage) CountersContext(ctx context.Context, filters ...StorageFilt
 }
 
 func (ds *DBStorage) AddCounter(name string, value int64) error {
-	return ds.AddCounterContext(context.Background(), name, value)
+	return ds.AddCounterContext(context.Background(), name, nil, value)
 }
 
-func (ds *DBStorage) AddCounterContext(ctx context.Context, name string, value int64) error {
+func (ds *DBStorage) AddCounterContext(ctx context.Context, name string, labels metrics.Labels, value int64) error {
 	pool, err := ds.GetDBPool()
 	if err != nil {
 		return err
 	}
 
-	if _, err = metrics.NewCounter(name, value); err != nil {
+	if _, err = metrics.NewCounterWithLabels(name, labels, value); err != nil {
 		return err
 	}
 
 	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
 	defer cancel()
 
-	_, err = pool.Exec(ctxQuery, "INSERT INTO metrics_counter (name, value) VALUES (@name, @value) ON CONFLICT (name) DO UPDATE SET value = metrics_counter.value + EXCLUDED.value;",
-		db.NamedArgs{"name": name, "value": value})
+	_, err = pool.Exec(ctxQuery, ds.withSample(sqlUpsertCounter, "metrics_counter_sample"), name, dbLabels(labels), value)
 	if err != nil {
 		return err
 	}
@@ -356,7 +353,7 @@ func (ds *DBStorage) ResetCounters() error {
 
 	ctx := context.Background()
 
-	_, err = pool.Exec(ctx, "TRUNCATE metrics_counter;")
+	_, err = pool.Exec(ctx, "TRUNCATE metrics_counter, metrics_counter_sample;")
 	if err != nil {
 		return err
 	}
@@ -364,6 +361,74 @@ func (ds *DBStorage) ResetCounters() error {
 	return nil
 }
 
+// GaugeSamples returns the history of the gauge series in the [from, to] time range
+func (ds *DBStorage) GaugeSamples(ctx context.Context, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error) {
+	return ds.samples(ctx, "metrics_gauge_sample", name, labels, from, to)
+}
+
+// CounterSamples returns the history of the counter series in the [from, to] time range
+func (ds *DBStorage) CounterSamples(ctx context.Context, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error) {
+	return ds.samples(ctx, "metrics_counter_sample", name, labels, from, to)
+}
+
+func (ds *DBStorage) samples(ctx context.Context, table string, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error) {
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return nil, err
+	}
+
+	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
+	defer cancel()
+
+	rows, err := pool.Query(ctxQuery, "SELECT ts, value FROM "+table+" WHERE name = $1 AND labels = $2 AND ts BETWEEN $3 AND $4 ORDER BY ts;",
+		name, dbLabels(labels), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := make([]metrics.Sample, 0)
+	for rows.Next() {
+		var sample metrics.Sample
+		if err = rows.Scan(&sample.Time, &sample.Value); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// SetRetention sets the period during which the samples are kept
+func (ds *DBStorage) SetRetention(retention time.Duration) {
+	ds.retention = retention
+}
+
+// PruneSamples removes the samples older than the retention period
+func (ds *DBStorage) PruneSamples(ctx context.Context) error {
+	if ds.retention <= 0 {
+		return nil
+	}
+
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return err
+	}
+
+	ctxQuery, cancel := context.WithTimeout(ctx, (30 * time.Second))
+	defer cancel()
+
+	cutoff := time.Now().Add(-ds.retention)
+
+	_, gErr := pool.Exec(ctxQuery, "DELETE FROM metrics_gauge_sample WHERE ts < $1;", cutoff)
+	_, cErr := pool.Exec(ctxQuery, "DELETE FROM metrics_counter_sample WHERE ts < $1;", cutoff)
+	return errors.Join(gErr, cErr)
+}
+
 // MigrateCreateSchema Создать все необходимые таблицы в базе данных.
 func (ds *DBStorage) MigrateCreateSchema(ctx context.Context) {
 	pool, err := ds.GetDBPool()
@@ -388,10 +453,261 @@ func (ds *DBStorage) MigrateCreateSchema(ctx context.Context) {
 	}
 }
 
+// Histogram returns the histogram metric by name
+func (ds *DBStorage) Histogram(name string) (metrics.Histogram, bool) {
+	return ds.HistogramContext(context.Background(), name, nil)
+}
+
+// HistogramContext returns the histogram metric by name and labels
+func (ds *DBStorage) HistogramContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Histogram, bool) {
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return metrics.Histogram{}, false
+	}
+
+	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
+	defer cancel()
+
+	row := pool.QueryRow(ctxQuery, "SELECT bounds, counts, count, sum FROM metrics_histogram WHERE name = $1 AND labels = $2 LIMIT 1;", name, dbLabels(labels))
+	var (
+		bounds []float64
+		counts []int64
+		count  int64
+		sum    float64
+	)
+	err = row.Scan(&bounds, &counts, &count, &sum)
+	if errors.Is(err, db.ErrNoRows) {
+		return metrics.Histogram{}, false
+	}
+	if err != nil {
+		logger.Log.Warn(err.Error())
+		return metrics.Histogram{}, false
+	}
+
+	histogram, err := metrics.NewHistogramWithLabels(name, labels, histogramFromDB(bounds, counts, count, sum))
+	if err != nil {
+		logger.Log.Warn(err.Error())
+		return metrics.Histogram{}, false
+	}
+
+	return *histogram, true
+}
+
+// Histograms returns all histogram metrics
+func (ds *DBStorage) Histograms(filters ...StorageFilter) map[string]metrics.Histogram {
+	return ds.HistogramsContext(context.Background(), filters...)
+}
+
+// HistogramsContext returns all histogram metrics
+func (ds *DBStorage) HistogramsContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Histogram {
+	histograms := map[string]metrics.Histogram{}
+
+	var (
+		rows db.Rows
+		err  error
+	)
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return histograms
+	}
+
+	f := &StorageFilters{}
+	for _, filter := range filters {
+		filter(f)
+	}
+
+	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
+	defer cancel()
+
+	where, args := filtersToSQL(f)
+	rows, err = pool.Query(ctxQuery, "SELECT name, labels, bounds, counts, count, sum FROM metrics_histogram"+where+";", args...)
+	if err != nil {
+		logger.Log.Warn(err.Error())
+		return histograms
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			hName   string
+			hLabels metrics.Labels
+			hBounds []float64
+			hCounts []int64
+			hCount  int64
+			hSum    float64
+		)
+
+		err = rows.Scan(&hName, &hLabels, &hBounds, &hCounts, &hCount, &hSum)
+		if err != nil {
+			logger.Log.Warn(err.Error())
+			return map[string]metrics.Histogram{}
+		}
+
+		histogram, err2 := metrics.NewHistogramWithLabels(hName, hLabels, histogramFromDB(hBounds, hCounts, hCount, hSum))
+		if err2 != nil {
+			logger.Log.Warn(err2.Error())
+			return map[string]metrics.Histogram{}
+		}
+
+		histograms[histogram.Key()] = *histogram
+	}
+
+	err = rows.Err()
+	if err != nil {
+		logger.Log.Warn(err.Error())
+		return map[string]metrics.Histogram{}
+	}
+
+	return histograms
+}
+
+func (ds *DBStorage) AddHistogram(name string, value metrics.HistogramValue) error {
+	return ds.AddHistogramContext(context.Background(), name, nil, value)
+}
+
+func (ds *DBStorage) AddHistogramContext(ctx context.Context, name string, labels metrics.Labels, value metrics.HistogramValue) error {
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return err
+	}
+
+	if _, err = metrics.NewHistogramWithLabels(name, labels, value); err != nil {
+		return err
+	}
+
+	ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
+	defer cancel()
+
+	res, err := pool.Exec(ctxQuery, sqlUpsertHistogram, histogramToDB(name, labels, value)...)
+	if err != nil {
+		return err
+	}
+
+	// Строка не изменена: серия уже существует с другими границами бакетов
+	if res.RowsAffected() == 0 {
+		return metrics.ErrHistogramBoundsMismatch
+	}
+
+	return nil
+}
+
+func (ds *DBStorage) ResetHistograms() error {
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	_, err = pool.Exec(ctx, "TRUNCATE metrics_histogram;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deleteTables are the tables of the series and of their history by the metric type.
+var deleteTables = []struct {
+	mtype   string
+	series  string
+	samples string
+}{
+	{mtype: metrics.TypeGauge, series: "metrics_gauge", samples: "metrics_gauge_sample"},
+	{mtype: metrics.TypeCounter, series: "metrics_counter", samples: "metrics_counter_sample"},
+	{mtype: metrics.TypeHistogram, series: "metrics_histogram"},
+}
+
+// sqlDelete returns the query that removes the series of the table matching the condition together with their history.
+func sqlDelete(series, samples, where string) string {
+	if samples == "" {
+		return "DELETE FROM " + series + where + ";"
+	}
+	return "WITH samples AS (DELETE FROM " + samples + where + ") DELETE FROM " + series + where + ";"
+}
+
+// DeleteSeries removes the series of the type with its history
+func (ds *DBStorage) DeleteSeries(ctx context.Context, mtype string, name string, labels metrics.Labels) (bool, error) {
+	for _, t := range deleteTables {
+		if t.mtype != mtype {
+			continue
+		}
+
+		pool, err := ds.GetDBPool()
+		if err != nil {
+			return false, err
+		}
+
+		ctxQuery, cancel := context.WithTimeout(ctx, (3 * time.Second))
+		defer cancel()
+
+		tag, err := pool.Exec(ctxQuery, sqlDelete(t.series, t.samples, " WHERE name = $1 AND labels = $2"), name, dbLabels(labels))
+		if err != nil {
+			return false, err
+		}
+		return tag.RowsAffected() > 0, nil
+	}
+	return false, ErrIncorrectType
+}
+
+// DeleteMetrics removes the series matching the filters with their history
+func (ds *DBStorage) DeleteMetrics(ctx context.Context, mtype string, filters ...StorageFilter) (int, error) {
+	f := &StorageFilters{}
+	for _, filter := range filters {
+		filter(f)
+	}
+	if f.isEmpty() {
+		return 0, ErrNoFilters
+	}
+
+	switch mtype {
+	case "", metrics.TypeGauge, metrics.TypeCounter, metrics.TypeHistogram:
+	default:
+		return 0, ErrIncorrectType
+	}
+
+	pool, err := ds.GetDBPool()
+	if err != nil {
+		return 0, err
+	}
+
+	ctxTx, cancel := context.WithTimeout(ctx, (30 * time.Second))
+	defer cancel()
+
+	tx, err := pool.Begin(ctxTx)
+	if err != nil {
+		return 0, err
+	}
+
+	where, args := filtersToSQL(f)
+
+	var n int64
+	for _, t := range deleteTables {
+		if mtype != "" && t.mtype != mtype {
+			continue
+		}
+
+		tag, err := tx.Exec(ctxTx, sqlDelete(t.series, t.samples, where), args...)
+		if err != nil {
+			if errR := tx.Rollback(ctxTx); errR != nil {
+				return 0, errors.Join(err, errR)
+			}
+			return 0, err
+		}
+		n += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctxTx); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
 func (ds *DBStorage) Reset() error {
 	gErr := ds.ResetGauges()
 	cErr := ds.ResetCounters()
-	return errors.Join(gErr, cErr)
+	hErr := ds.ResetHistograms()
+	return errors.Join(gErr, cErr, hErr)
 }
 
 func (ds *DBStorage) InsertBatch(opts ...StorageOption) error {
@@ -409,7 +725,7 @@ func (ds *DBStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 		opt(o)
 	}
 
-	if len(o.gauges) == 0 && len(o.counters) == 0 {
+	if len(o.gauges) == 0 && len(o.counters) == 0 && len(o.histograms) == 0 {
 		return nil
 	}
 
@@ -425,8 +741,7 @@ func (ds *DBStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 		ctxPrepareCounter, cancel := context.WithTimeout(ctxTx, (3 * time.Second))
 		defer cancel()
 
-		stmtCounter, err := tx.Prepare(ctxPrepareCounter, "insert-counter",
-			"INSERT INTO metrics_counter (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value = metrics_counter.value + EXCLUDED.value;")
+		stmtCounter, err := tx.Prepare(ctxPrepareCounter, "insert-counter", ds.withSample(sqlUpsertCounter, "metrics_counter_sample"))
 		if err != nil {
 			if errR := tx.Rollback(ctxTx); errR != nil {
 				return errors.Join(err, errR)
@@ -438,7 +753,7 @@ func (ds *DBStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 			ctxQuery, cancel := context.WithTimeout(ctxTx, (3 * time.Second))
 			defer cancel()
 
-			_, err := tx.Exec(ctxQuery, stmtCounter.Name, counter.Name(), counter.Value())
+			_, err := tx.Exec(ctxQuery, stmtCounter.Name, counter.Name(), dbLabels(counter.Labels()), counter.Value())
 			if err != nil {
 				if errR := tx.Rollback(ctxTx); errR != nil {
 					return errors.Join(err, errR)
@@ -452,8 +767,7 @@ func (ds *DBStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 		ctxPrepareGauge, cancel := context.WithTimeout(ctxTx, (3 * time.Second))
 		defer cancel()
 
-		stmtGauge, err := tx.Prepare(ctxPrepareGauge, "insert-gauge",
-			"INSERT INTO metrics_gauge (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value;")
+		stmtGauge, err := tx.Prepare(ctxPrepareGauge, "insert-gauge", ds.withSample(sqlUpsertGauge, "metrics_gauge_sample"))
 		if err != nil {
 			if errR := tx.Rollback(ctxTx); errR != nil {
 				return errors.Join(err, errR)
@@ -465,7 +779,36 @@ func (ds *DBStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 			ctxQuery, cancel := context.WithTimeout(ctxTx, (3 * time.Second))
 			defer cancel()
 
-			_, err := tx.Exec(ctxQuery, stmtGauge.Name, gauge.Name(), gauge.Value())
+			_, err := tx.Exec(ctxQuery, stmtGauge.Name, gauge.Name(), dbLabels(gauge.Labels()), gauge.Value())
+			if err != nil {
+				if errR := tx.Rollback(ctxTx); errR != nil {
+					return errors.Join(err, errR)
+				}
+				return err
+			}
+		}
+	}
+
+	if len(o.histograms) > 0 {
+		ctxPrepareHistogram, cancel := context.WithTimeout(ctxTx, (3 * time.Second))
+		defer cancel()
+
+		stmtHistogram, err := tx.Prepare(ctxPrepareHistogram, "insert-histogram", sqlUpsertHistogram)
+		if err != nil {
+			if errR := tx.Rollback(ctxTx); errR != nil {
+				return errors.Join(err, errR)
+			}
+			return err
+		}
+
+		for _, histogram := range o.histograms {
+			ctxQuery, cancel := context.WithTimeout(ctxTx, (3 * time.Second))
+			defer cancel()
+
+			res, err := tx.Exec(ctxQuery, stmtHistogram.Name, histogramToDB(histogram.Name(), histogram.Labels(), histogram.Value())...)
+			if err == nil && res.RowsAffected() == 0 {
+				err = metrics.ErrHistogramBoundsMismatch
+			}
 			if err != nil {
 				if errR := tx.Rollback(ctxTx); errR != nil {
 					return errors.Join(err, errR)
@@ -480,4 +823,90 @@ func (ds *DBStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 	return nil
 }
 
+const (
+	sqlUpsertGauge   = "INSERT INTO metrics_gauge (name, labels, value) VALUES ($1, $2, $3) ON CONFLICT (name, labels) DO UPDATE SET value = EXCLUDED.value"
+	sqlUpsertCounter = "INSERT INTO metrics_counter (name, labels, value) VALUES ($1, $2, $3) ON CONFLICT (name, labels) DO UPDATE SET value = metrics_counter.value + EXCLUDED.value"
+)
+
+// withSample completes the upsert query so that the resulting value of the series is also appended
+// to its history table. The history is recorded only when the retention period is set.
+func (ds *DBStorage) withSample(upsert string, sampleTable string) string {
+	if ds.retention <= 0 {
+		return upsert + ";"
+	}
+	return "WITH m AS (" + upsert + " RETURNING name, labels, value) " +
+		"INSERT INTO " + sampleTable + " (name, labels, ts, value) SELECT name, labels, now(), value FROM m;"
+}
+
+// sqlUpsertHistogram inserts a histogram series or merges the observations into the stored one.
+// Bucket counts are added element-wise; the row is left untouched if the bucket bounds differ.
+const sqlUpsertHistogram = "INSERT INTO metrics_histogram (name, labels, bounds, counts, count, sum) VALUES ($1, $2, $3, $4, $5, $6) " +
+	"ON CONFLICT (name, labels) DO UPDATE SET " +
+	"counts = (SELECT array_agg(a + b ORDER BY i) FROM unnest(metrics_histogram.counts, EXCLUDED.counts) WITH ORDINALITY AS t(a, b, i)), " +
+	"count = metrics_histogram.count + EXCLUDED.count, sum = metrics_histogram.sum + EXCLUDED.sum " +
+	"WHERE metrics_histogram.bounds = EXCLUDED.bounds;"
+
+// histogramToDB returns the arguments of sqlUpsertHistogram.
+func histogramToDB(name string, labels metrics.Labels, value metrics.HistogramValue) []any {
+	counts := make([]int64, len(value.Counts))
+	for i, c := range value.Counts {
+		counts[i] = int64(c)
+	}
+	bounds := value.Bounds
+	if bounds == nil {
+		bounds = []float64{}
+	}
+	return []any{name, dbLabels(labels), bounds, counts, int64(value.Count), value.Sum}
+}
+
+// histogramFromDB converts the stored columns into a histogram value.
+func histogramFromDB(bounds []float64, counts []int64, count int64, sum float64) metrics.HistogramValue {
+	value := metrics.HistogramValue{
+		Bounds: bounds,
+		Counts: make([]uint64, len(counts)),
+		Count:  uint64(count),
+		Sum:    sum,
+	}
+	for i, c := range counts {
+		value.Counts[i] = uint64(c)
+	}
+	return value
+}
+
+// filtersToSQL returns the WHERE clause and its arguments for the given filters.
+func filtersToSQL(f *StorageFilters) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if len(f.names) > 0 {
+		args = append(args, f.names)
+		conditions = append(conditions, fmt.Sprintf("name = ANY($%d)", len(args)))
+	}
+
+	if len(f.labels) > 0 {
+		args = append(args, dbLabels(f.labels))
+		conditions = append(conditions, fmt.Sprintf("labels @> $%d", len(args)))
+	}
+
+	if f.prefix != "" {
+		args = append(args, f.prefix)
+		conditions = append(conditions, fmt.Sprintf("starts_with(name, $%d)", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// dbLabels returns the labels as stored in the jsonb column: a series without labels has an empty object.
+func dbLabels(labels metrics.Labels) metrics.Labels {
+	if labels == nil {
+		return metrics.Labels{}
+	}
+	return labels
+}
+
 var _ MetricsStorager = (*DBStorage)(nil)
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/suite"
@@ -42,9 +45,9 @@ func (s *DBStorageSuite) TestNewDBStorage() {
 func (s *DBStorageSuite) TestGauge() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("a").WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(2.1)))
-	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("b").WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(-1.5)))
-	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("c").WillReturnRows(s.mock.NewRows([]string{"value"}))
+	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("a", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(2.1)))
+	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("b", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(-1.5)))
+	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("c", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}))
 
 	type want struct {
 		gauge metrics.Gauge
@@ -104,9 +107,9 @@ func (s *DBStorageSuite) TestGauge() {
 func (s *DBStorageSuite) TestGaugeValue() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("a").WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(2.1)))
-	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("b").WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(-1.5)))
-	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("c").WillReturnRows(s.mock.NewRows([]string{"value"}))
+	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("a", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(2.1)))
+	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("b", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(float64(-1.5)))
+	s.mock.ExpectQuery("^SELECT value FROM metrics_gauge WHERE (.+) LIMIT 1;$").WithArgs("c", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}))
 
 	type want struct {
 		value float64
@@ -160,10 +163,10 @@ func (s *DBStorageSuite) TestGaugeValue() {
 func (s *DBStorageSuite) TestGauges() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectQuery("SELECT name, value FROM metrics_gauge;").
-		WillReturnRows(s.mock.NewRows([]string{"name", "value"}).
-			AddRow("b", float64(2.1)).
-			AddRow("a", float64(1.0)))
+	s.mock.ExpectQuery("SELECT name, labels, value FROM metrics_gauge;").
+		WillReturnRows(s.mock.NewRows([]string{"name", "labels", "value"}).
+			AddRow("b", metrics.Labels{}, float64(2.1)).
+			AddRow("a", metrics.Labels{}, float64(1.0)))
 
 	want := map[string]metrics.Gauge{}
 	a, _ := metrics.NewGauge("a", 1.0)
@@ -182,11 +185,11 @@ func (s *DBStorageSuite) TestGaugesFiltered() {
 
 	filter := []string{"a", "b"}
 
-	s.mock.ExpectQuery("SELECT name, value FROM metrics_gauge WHERE (.+);").
+	s.mock.ExpectQuery("SELECT name, labels, value FROM metrics_gauge WHERE (.+);").
 		WithArgs(filter).
-		WillReturnRows(s.mock.NewRows([]string{"name", "value"}).
-			AddRow("b", float64(2.1)).
-			AddRow("a", float64(1.0)))
+		WillReturnRows(s.mock.NewRows([]string{"name", "labels", "value"}).
+			AddRow("b", metrics.Labels{}, float64(2.1)).
+			AddRow("a", metrics.Labels{}, float64(1.0)))
 
 	want := map[string]metrics.Gauge{}
 	a, _ := metrics.NewGauge("a", 1.0)
@@ -200,14 +203,34 @@ func (s *DBStorageSuite) TestGaugesFiltered() {
 	s.Require().NoError(err)
 }
 
+func (s *DBStorageSuite) TestGaugesFilteredByLabels() {
+	ds := NewDBStorage(s.mock)
+
+	labels := metrics.Labels{"host": "web1"}
+
+	s.mock.ExpectQuery(`^SELECT name, labels, value FROM metrics_gauge WHERE name = ANY\(\$1\) AND labels @> \$2;$`).
+		WithArgs([]string{"a"}, labels).
+		WillReturnRows(s.mock.NewRows([]string{"name", "labels", "value"}).
+			AddRow("a", metrics.Labels{"host": "web1", "env": "prod"}, float64(1.0)))
+
+	want := map[string]metrics.Gauge{}
+	a, _ := metrics.NewGaugeWithLabels("a", metrics.Labels{"host": "web1", "env": "prod"}, 1.0)
+	want[`a{env="prod",host="web1"}`] = *a
+
+	s.Equal(want, ds.Gauges(FilterName("a"), FilterLabels(labels)))
+
+	err := s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
 func (s *DBStorageSuite) TestSetGauge() {
 	ds := NewDBStorage(s.mock)
 
-	insertSQL := `^INSERT INTO metrics_gauge (.+) VALUES (.+) ON CONFLICT \(name\) DO UPDATE SET value = EXCLUDED.value\;$`
+	insertSQL := `^INSERT INTO metrics_gauge (.+) VALUES (.+) ON CONFLICT \(name, labels\) DO UPDATE SET value = EXCLUDED.value\;$`
 
-	s.mock.ExpectExec(insertSQL).WithArgs("a", float64(5.0)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-	s.mock.ExpectExec(insertSQL).WithArgs("a", float64(-5.0)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-	s.mock.ExpectExec(insertSQL).WithArgs("b", float64(3)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	s.mock.ExpectExec(insertSQL).WithArgs("a", metrics.Labels{}, float64(5.0)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	s.mock.ExpectExec(insertSQL).WithArgs("a", metrics.Labels{}, float64(-5.0)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	s.mock.ExpectExec(insertSQL).WithArgs("b", metrics.Labels{}, float64(3)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 	testCases := []struct {
 		name    string
@@ -258,7 +281,7 @@ func (s *DBStorageSuite) TestSetGauge() {
 func (s *DBStorageSuite) TestResetGauges() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectExec(`^TRUNCATE metrics_gauge\;$`).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	s.mock.ExpectExec(`^TRUNCATE metrics_gauge, metrics_gauge_sample\;$`).WillReturnResult(pgxmock.NewResult("DELETE", 1))
 
 	err := ds.ResetGauges()
 	s.Require().NoError(err)
@@ -270,8 +293,8 @@ func (s *DBStorageSuite) TestResetGauges() {
 func (s *DBStorageSuite) TestCounter() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("a").WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(int64(21)))
-	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("b").WillReturnRows(s.mock.NewRows([]string{"value"}))
+	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("a", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(int64(21)))
+	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("b", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}))
 
 	type want struct {
 		counter metrics.Counter
@@ -320,8 +343,8 @@ func (s *DBStorageSuite) TestCounter() {
 func (s *DBStorageSuite) TestCounterValue() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("a").WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(int64(21)))
-	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("b").WillReturnRows(s.mock.NewRows([]string{"value"}))
+	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("a", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}).AddRow(int64(21)))
+	s.mock.ExpectQuery("^SELECT (.+) FROM metrics_counter WHERE (.+)$").WithArgs("b", metrics.Labels{}).WillReturnRows(s.mock.NewRows([]string{"value"}))
 
 	type want struct {
 		value int64
@@ -367,10 +390,10 @@ func (s *DBStorageSuite) TestCounterValue() {
 func (s *DBStorageSuite) TestCounters() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectQuery("SELECT name, value FROM metrics_counter;").
-		WillReturnRows(s.mock.NewRows([]string{"name", "value"}).
-			AddRow("a", int64(1)).
-			AddRow("b", int64(100)))
+	s.mock.ExpectQuery("SELECT name, labels, value FROM metrics_counter;").
+		WillReturnRows(s.mock.NewRows([]string{"name", "labels", "value"}).
+			AddRow("a", metrics.Labels{}, int64(1)).
+			AddRow("b", metrics.Labels{}, int64(100)))
 
 	want := map[string]metrics.Counter{}
 	a, _ := metrics.NewCounter("a", 1)
@@ -389,11 +412,11 @@ func (s *DBStorageSuite) TestCountersFiltered() {
 
 	filter := []string{"a", "b"}
 
-	s.mock.ExpectQuery("SELECT name, value FROM metrics_counter WHERE (.+);").
+	s.mock.ExpectQuery("SELECT name, labels, value FROM metrics_counter WHERE (.+);").
 		WithArgs(filter).
-		WillReturnRows(s.mock.NewRows([]string{"name", "value"}).
-			AddRow("a", int64(1)).
-			AddRow("b", int64(100)))
+		WillReturnRows(s.mock.NewRows([]string{"name", "labels", "value"}).
+			AddRow("a", metrics.Labels{}, int64(1)).
+			AddRow("b", metrics.Labels{}, int64(100)))
 
 	want := map[string]metrics.Counter{}
 	a, _ := metrics.NewCounter("a", 1)
@@ -410,10 +433,10 @@ func (s *DBStorageSuite) TestCountersFiltered() {
 func (s *DBStorageSuite) TestAddCounter() {
 	ds := NewDBStorage(s.mock)
 
-	insertSQL := `^INSERT INTO metrics_counter (.+) VALUES (.+) ON CONFLICT \(name\) DO UPDATE SET value \= metrics_counter\.value \+ EXCLUDED\.value\;$`
+	insertSQL := `^INSERT INTO metrics_counter (.+) VALUES (.+) ON CONFLICT \(name, labels\) DO UPDATE SET value \= metrics_counter\.value \+ EXCLUDED\.value\;$`
 
-	s.mock.ExpectExec(insertSQL).WithArgs("a", int64(1)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-	s.mock.ExpectExec(insertSQL).WithArgs("b", int64(2)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	s.mock.ExpectExec(insertSQL).WithArgs("a", metrics.Labels{}, int64(1)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	s.mock.ExpectExec(insertSQL).WithArgs("b", metrics.Labels{}, int64(2)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
 	testCases := []struct {
 		name    string
@@ -458,7 +481,7 @@ func (s *DBStorageSuite) TestAddCounter() {
 func (s *DBStorageSuite) TestResetCounters() {
 	ds := NewDBStorage(s.mock)
 
-	s.mock.ExpectExec(`^TRUNCATE metrics_counter\;$`).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	s.mock.ExpectExec(`^TRUNCATE metrics_counter, metrics_counter_sample\;$`).WillReturnResult(pgxmock.NewResult("DELETE", 1))
 
 	err := ds.ResetCounters()
 	s.Require().NoError(err)
@@ -526,9 +549,9 @@ func (s *DBStorageSuite) TestInsertBatch() {
 
 			var countersBatch []metrics.Counter
 			if len(tc.counters) > 0 {
-				s.mock.ExpectPrepare("insert-counter", `^INSERT INTO metrics_counter \(name, value\) VALUES (.+) ON CONFLICT \(name\) DO UPDATE SET value \= metrics_counter\.value \+ EXCLUDED\.value;$`)
+				s.mock.ExpectPrepare("insert-counter", `^INSERT INTO metrics_counter \(name, labels, value\) VALUES (.+) ON CONFLICT \(name, labels\) DO UPDATE SET value \= metrics_counter\.value \+ EXCLUDED\.value;$`)
 				for _, v := range tc.counters {
-					s.mock.ExpectExec("insert-counter").WithArgs(v.name, int64(v.value)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+					s.mock.ExpectExec("insert-counter").WithArgs(v.name, metrics.Labels{}, int64(v.value)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 					c, err := metrics.NewCounter(v.name, v.value)
 					if err == nil {
 						countersBatch = append(countersBatch, *c)
@@ -538,9 +561,9 @@ func (s *DBStorageSuite) TestInsertBatch() {
 
 			var gaugesBatch []metrics.Gauge
 			if len(tc.gauges) > 0 {
-				s.mock.ExpectPrepare("insert-gauge", `^INSERT INTO metrics_gauge \(name, value\) VALUES (.+) ON CONFLICT \(name\) DO UPDATE SET value \= EXCLUDED\.value;$`)
+				s.mock.ExpectPrepare("insert-gauge", `^INSERT INTO metrics_gauge \(name, labels, value\) VALUES (.+) ON CONFLICT \(name, labels\) DO UPDATE SET value \= EXCLUDED\.value;$`)
 				for _, v := range tc.gauges {
-					s.mock.ExpectExec("insert-gauge").WithArgs(v.name, float64(v.value)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+					s.mock.ExpectExec("insert-gauge").WithArgs(v.name, metrics.Labels{}, float64(v.value)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 					g, err := metrics.NewGauge(v.name, v.value)
 					if err == nil {
 						gaugesBatch = append(gaugesBatch, *g)
@@ -568,6 +591,171 @@ func (s *DBStorageSuite) TestInsertBatch() {
 	s.Require().NoError(err)
 }
 
+func (s *DBStorageSuite) TestHistogram() {
+	ds := NewDBStorage(s.mock)
+
+	s.mock.ExpectQuery("^SELECT bounds, counts, count, sum FROM metrics_histogram WHERE (.+) LIMIT 1;$").WithArgs("h", metrics.Labels{}).
+		WillReturnRows(s.mock.NewRows([]string{"bounds", "counts", "count", "sum"}).AddRow([]float64{1, 5}, []int64{1, 2, 0}, int64(3), float64(6)))
+	s.mock.ExpectQuery("^SELECT bounds, counts, count, sum FROM metrics_histogram WHERE (.+) LIMIT 1;$").WithArgs("x", metrics.Labels{}).
+		WillReturnRows(s.mock.NewRows([]string{"bounds", "counts", "count", "sum"}))
+
+	h, ok := ds.Histogram("h")
+	s.Require().True(ok)
+	s.Equal(metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{1, 2, 0}, Count: 3, Sum: 6}, h.Value())
+
+	_, ok = ds.Histogram("x")
+	s.False(ok)
+
+	err := s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestAddHistogram() {
+	ds := NewDBStorage(s.mock)
+
+	insertSQL := `^INSERT INTO metrics_histogram \(name, labels, bounds, counts, count, sum\) VALUES (.+) ON CONFLICT \(name, labels\) DO UPDATE SET (.+) WHERE metrics_histogram\.bounds \= EXCLUDED\.bounds;$`
+
+	s.mock.ExpectExec(insertSQL).WithArgs("h", metrics.Labels{}, []float64{1, 5}, []int64{0, 2, 1}, int64(3), float64(13)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	s.mock.ExpectExec(insertSQL).WithArgs("h", metrics.Labels{}, []float64{1, 10}, []int64{0, 2, 1}, int64(3), float64(13)).WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	err := ds.AddHistogram("h", metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{0, 2, 1}, Count: 3, Sum: 13})
+	s.Require().NoError(err)
+
+	err = ds.AddHistogram("h", metrics.HistogramValue{Bounds: []float64{1, 10}, Counts: []uint64{0, 2, 1}, Count: 3, Sum: 13})
+	s.Require().ErrorIs(err, metrics.ErrHistogramBoundsMismatch)
+
+	err = ds.AddHistogram("h", metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{0, 2}, Count: 2, Sum: 13})
+	s.Require().Error(err)
+
+	err = s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestInsertBatchHistograms() {
+	ds := NewDBStorage(s.mock)
+
+	h, err := metrics.NewHistogram("h", metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 2, Sum: 3})
+	s.Require().NoError(err)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectPrepare("insert-histogram", `^INSERT INTO metrics_histogram (.+) ON CONFLICT (.+);$`)
+	s.mock.ExpectExec("insert-histogram").WithArgs("h", metrics.Labels{}, []float64{1}, []int64{1, 1}, int64(2), float64(3)).WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	s.mock.ExpectRollback()
+
+	err = ds.InsertBatch(WithHistogram(*h))
+	s.Require().ErrorIs(err, metrics.ErrHistogramBoundsMismatch)
+
+	err = s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestGaugeSamples() {
+	ds := NewDBStorage(s.mock)
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	s.mock.ExpectQuery(`^SELECT ts, value FROM metrics_gauge_sample WHERE (.+) ORDER BY ts;$`).WithArgs("a", metrics.Labels{"host": "web1"}, from, to).
+		WillReturnRows(s.mock.NewRows([]string{"ts", "value"}).
+			AddRow(from.Add(time.Minute), float64(1.5)).
+			AddRow(from.Add(2*time.Minute), float64(2.5)))
+
+	samples, err := ds.GaugeSamples(context.Background(), "a", metrics.Labels{"host": "web1"}, from, to)
+	s.Require().NoError(err)
+	s.Equal([]metrics.Sample{
+		{Time: from.Add(time.Minute), Value: 1.5},
+		{Time: from.Add(2 * time.Minute), Value: 2.5},
+	}, samples)
+
+	err = s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestSetGaugeWithHistory() {
+	ds := NewDBStorage(s.mock)
+	ds.SetRetention(time.Hour)
+
+	s.mock.ExpectExec(`^WITH m AS \(INSERT INTO metrics_gauge (.+) ON CONFLICT (.+) RETURNING name, labels, value\) INSERT INTO metrics_gauge_sample \(name, labels, ts, value\) SELECT (.+) FROM m;$`).
+		WithArgs("a", metrics.Labels{}, float64(1.5)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	s.mock.ExpectExec(`^WITH m AS \(INSERT INTO metrics_counter (.+) ON CONFLICT (.+) RETURNING name, labels, value\) INSERT INTO metrics_counter_sample \(name, labels, ts, value\) SELECT (.+) FROM m;$`).
+		WithArgs("b", metrics.Labels{}, int64(2)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	s.Require().NoError(ds.SetGauge("a", 1.5))
+	s.Require().NoError(ds.AddCounter("b", 2))
+
+	err := s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestPruneSamples() {
+	ds := NewDBStorage(s.mock)
+
+	// Без срока хранения история не удаляется
+	s.Require().NoError(ds.PruneSamples(context.Background()))
+
+	ds.SetRetention(time.Hour)
+	s.mock.ExpectExec(`^DELETE FROM metrics_gauge_sample WHERE ts < \$1;$`).WithArgs(pgxmock.AnyArg()).WillReturnResult(pgxmock.NewResult("DELETE", 3))
+	s.mock.ExpectExec(`^DELETE FROM metrics_counter_sample WHERE ts < \$1;$`).WithArgs(pgxmock.AnyArg()).WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	s.Require().NoError(ds.PruneSamples(context.Background()))
+
+	err := s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestDeleteSeries() {
+	ds := NewDBStorage(s.mock)
+
+	s.mock.ExpectExec(`^WITH samples AS \(DELETE FROM metrics_gauge_sample WHERE name = \$1 AND labels = \$2\) DELETE FROM metrics_gauge WHERE name = \$1 AND labels = \$2;$`).
+		WithArgs("a", metrics.Labels{"host": "web1"}).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	s.mock.ExpectExec(`^DELETE FROM metrics_histogram WHERE name = \$1 AND labels = \$2;$`).
+		WithArgs("h", metrics.Labels{}).WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	ok, err := ds.DeleteSeries(context.Background(), metrics.TypeGauge, "a", metrics.Labels{"host": "web1"})
+	s.Require().NoError(err)
+	s.True(ok)
+
+	ok, err = ds.DeleteSeries(context.Background(), metrics.TypeHistogram, "h", nil)
+	s.Require().NoError(err)
+	s.False(ok)
+
+	_, err = ds.DeleteSeries(context.Background(), "summary", "a", nil)
+	s.ErrorIs(err, ErrIncorrectType)
+
+	err = s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
+func (s *DBStorageSuite) TestDeleteMetrics() {
+	ds := NewDBStorage(s.mock)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(`^WITH samples AS \(DELETE FROM metrics_gauge_sample WHERE starts_with\(name, \$1\)\) DELETE FROM metrics_gauge WHERE starts_with\(name, \$1\);$`).
+		WithArgs("Heap").WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	s.mock.ExpectExec(`^WITH samples AS \(DELETE FROM metrics_counter_sample WHERE starts_with\(name, \$1\)\) DELETE FROM metrics_counter WHERE starts_with\(name, \$1\);$`).
+		WithArgs("Heap").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	s.mock.ExpectExec(`^DELETE FROM metrics_histogram WHERE starts_with\(name, \$1\);$`).
+		WithArgs("Heap").WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	s.mock.ExpectCommit()
+
+	n, err := ds.DeleteMetrics(context.Background(), "", FilterPrefix("Heap"))
+	s.Require().NoError(err)
+	s.Equal(3, n)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(`^WITH samples AS \(DELETE FROM metrics_counter_sample WHERE name = ANY\(\$1\)\) DELETE FROM metrics_counter WHERE name = ANY\(\$1\);$`).
+		WithArgs([]string{"a", "b"}).WillReturnError(errors.New("connection lost"))
+	s.mock.ExpectRollback()
+
+	_, err = ds.DeleteMetrics(context.Background(), metrics.TypeCounter, FilterNames([]string{"a", "b"}))
+	s.Require().Error(err)
+
+	_, err = ds.DeleteMetrics(context.Background(), metrics.TypeCounter)
+	s.ErrorIs(err, ErrNoFilters)
+
+	err = s.mock.ExpectationsWereMet()
+	s.Require().NoError(err)
+}
+
 func TestDBStorageSuite(t *testing.T) {
 	suite.Run(t, new(DBStorageSuite))
 }
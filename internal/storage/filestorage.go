@@ -25,6 +25,7 @@ func NewFileStorage(filename string) *FileStorage {
 	}
 	fs.gauges = make(map[string]metrics.Gauge)
 	fs.counters = make(map[string]metrics.Counter)
+	fs.histograms = make(map[string]metrics.Histogram)
 	return fs
 }
 
@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,6 +19,7 @@ func TestNewFileStorage(t *testing.T) {
 	want := &FileStorage{filename: "file.txt"}
 	want.gauges = make(map[string]metrics.Gauge)
 	want.counters = make(map[string]metrics.Counter)
+	want.histograms = make(map[string]metrics.Histogram)
 	got := NewFileStorage("file.txt")
 	assert.Equal(t, want, got)
 }
@@ -107,6 +111,44 @@ func TestFileStorage_Save(t *testing.T) {
 			want:    `{"gauges":null,"counters":null}`,
 			wantErr: false,
 		},
+		{
+			name: "Positive case #5",
+			filename: func() string {
+				tmpDir := os.TempDir()
+				file, err := os.CreateTemp(tmpDir, "test*.json")
+				if err != nil {
+					return "test4471290.json"
+				}
+				_ = file.Close()
+				return file.Name()
+			},
+			storage: func() *FileStorage {
+				fs := NewFileStorage("")
+				_ = fs.SetGaugeContext(context.Background(), "a", metrics.Labels{"host": "web1"}, 1.5)
+				return fs
+			}(),
+			want:    `{"gauges":{"a{host=\"web1\"}":{"name":"a","labels":{"host":"web1"},"value":1.5}},"counters":{}}`,
+			wantErr: false,
+		},
+		{
+			name: "Positive case #6",
+			filename: func() string {
+				tmpDir := os.TempDir()
+				file, err := os.CreateTemp(tmpDir, "test*.json")
+				if err != nil {
+					return "test5520391.json"
+				}
+				_ = file.Close()
+				return file.Name()
+			},
+			storage: func() *FileStorage {
+				fs := NewFileStorage("")
+				_ = fs.AddHistogram("h", metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{2, 1}, Count: 3, Sum: 4.5})
+				return fs
+			}(),
+			want:    `{"gauges":{},"counters":{},"histograms":{"h":{"name":"h","value":{"bounds":[1],"counts":[2,1],"count":3,"sum":4.5}}}}`,
+			wantErr: false,
+		},
 		{
 			name: "Negative case #1",
 			filename: func() string {
@@ -241,6 +283,85 @@ func TestFileStorage_Load(t *testing.T) {
 				fs := &FileStorage{}
 				fs.gauges = map[string]metrics.Gauge{}
 				fs.counters = map[string]metrics.Counter{}
+				fs.histograms = map[string]metrics.Histogram{}
+				return fs
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "Positive case #5",
+			filename: func() string {
+				tmpDir := os.TempDir()
+				file, err := os.CreateTemp(tmpDir, "test*.json")
+				if err != nil {
+					return "test8812033.json"
+				}
+				_ = file.Close()
+
+				return file.Name()
+			},
+			data: `{"gauges":{"a{host=\"web1\"}":{"name":"a","labels":{"host":"web1"},"value":1.5}},"counters":{}}`,
+			want: func() *FileStorage {
+				gauges := map[string]metrics.Gauge{}
+				ga, _ := metrics.NewGaugeWithLabels("a", metrics.Labels{"host": "web1"}, 1.5)
+				gauges[ga.Key()] = *ga
+
+				fs := &FileStorage{}
+				fs.gauges = gauges
+				fs.counters = map[string]metrics.Counter{}
+				return fs
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "Positive case #6",
+			filename: func() string {
+				tmpDir := os.TempDir()
+				file, err := os.CreateTemp(tmpDir, "test*.json")
+				if err != nil {
+					return "test2209183.json"
+				}
+				_ = file.Close()
+
+				return file.Name()
+			},
+			data: `{"gauges":{},"counters":{},"histograms":{"h":{"name":"h","value":{"bounds":[1],"counts":[2,1],"count":3,"sum":4.5}}}}`,
+			want: func() *FileStorage {
+				histograms := map[string]metrics.Histogram{}
+				h, _ := metrics.NewHistogram("h", metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{2, 1}, Count: 3, Sum: 4.5})
+				histograms["h"] = *h
+
+				fs := &FileStorage{}
+				fs.gauges = map[string]metrics.Gauge{}
+				fs.counters = map[string]metrics.Counter{}
+				fs.histograms = histograms
+				return fs
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "Positive case #7",
+			filename: func() string {
+				tmpDir := os.TempDir()
+				file, err := os.CreateTemp(tmpDir, "test*.json")
+				if err != nil {
+					return "test7730912.json"
+				}
+				_ = file.Close()
+
+				return file.Name()
+			},
+			data: `{"gauges":{"a":{"name":"a","value":2}},"counters":{},"gauge_samples":{"a":[{"time":"2024-01-01T12:00:00Z","value":1},{"time":"2024-01-01T12:00:10Z","value":2}]}}`,
+			want: func() *FileStorage {
+				ga, _ := metrics.NewGauge("a", 2)
+
+				fs := &FileStorage{}
+				fs.gauges = map[string]metrics.Gauge{"a": *ga}
+				fs.counters = map[string]metrics.Counter{}
+				fs.gaugeSamples = map[string][]metrics.Sample{"a": {
+					{Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Value: 1},
+					{Time: time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC), Value: 2},
+				}}
 				return fs
 			}(),
 			wantErr: false,
@@ -254,6 +375,7 @@ func TestFileStorage_Load(t *testing.T) {
 				fs := &FileStorage{}
 				fs.gauges = map[string]metrics.Gauge{}
 				fs.counters = map[string]metrics.Counter{}
+				fs.histograms = map[string]metrics.Histogram{}
 				return fs
 			}(),
 			wantErr: true,
@@ -267,6 +389,7 @@ func TestFileStorage_Load(t *testing.T) {
 				fs := &FileStorage{}
 				fs.gauges = map[string]metrics.Gauge{}
 				fs.counters = map[string]metrics.Counter{}
+				fs.histograms = map[string]metrics.Histogram{}
 				return fs
 			}(),
 			wantErr: true,
@@ -281,6 +404,7 @@ func TestFileStorage_Load(t *testing.T) {
 				fs := &FileStorage{}
 				fs.gauges = map[string]metrics.Gauge{}
 				fs.counters = map[string]metrics.Counter{}
+				fs.histograms = map[string]metrics.Histogram{}
 				return fs
 			}(),
 			wantErr: true,
@@ -311,3 +435,30 @@ func TestFileStorage_Load(t *testing.T) {
 		})
 	}
 }
+
+func TestFileStorage_Delete(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "metrics.json")
+
+	fs := NewFileStorage(filename)
+	require.NoError(t, fs.SetGauge("a", 1.5))
+	require.NoError(t, fs.SetGauge("b", 2.5))
+	require.NoError(t, fs.AddCounter("c", 1))
+	require.NoError(t, fs.Save())
+
+	ok, err := fs.DeleteSeries(ctx, metrics.TypeGauge, "a", nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	n, err := fs.DeleteMetrics(ctx, metrics.TypeCounter, FilterPrefix("c"))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.NoError(t, fs.Save())
+
+	// Удалённые метрики не возвращаются после перезапуска
+	loaded := NewFileStorage(filename)
+	require.NoError(t, loaded.Load())
+	assert.Len(t, loaded.Gauges(), 1)
+	_, ok = loaded.GaugeValue("b")
+	assert.True(t, ok)
+	assert.Empty(t, loaded.Counters())
+}
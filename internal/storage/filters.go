@@ -1,7 +1,15 @@
 package storage
 
+import (
+	"strings"
+
+	"github.com/fishus/go-advanced-metrics/internal/metrics"
+)
+
 type StorageFilters struct {
-	names []string
+	labels metrics.Labels
+	names  []string
+	prefix string
 }
 
 type StorageFilter func(o *StorageFilters)
@@ -17,3 +25,41 @@ func FilterName(name string) StorageFilter {
 		f.names = append(f.names, name)
 	}
 }
+
+// FilterLabels selects the series that have all of the given labels.
+func FilterLabels(labels metrics.Labels) StorageFilter {
+	return func(f *StorageFilters) {
+		f.labels = labels
+	}
+}
+
+// FilterPrefix selects the series whose name starts with the prefix.
+func FilterPrefix(prefix string) StorageFilter {
+	return func(f *StorageFilters) {
+		f.prefix = prefix
+	}
+}
+
+func (f *StorageFilters) isEmpty() bool {
+	return len(f.names) == 0 && len(f.labels) == 0 && f.prefix == ""
+}
+
+// match reports whether the series with the given name and labels passes the filters.
+func (f *StorageFilters) match(name string, labels metrics.Labels) bool {
+	if len(f.names) > 0 {
+		found := false
+		for _, n := range f.names {
+			if n == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.prefix != "" && !strings.HasPrefix(name, f.prefix) {
+		return false
+	}
+	return labels.Contains(f.labels)
+}
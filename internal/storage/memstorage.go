@@ -4,37 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"maps"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
 )
 
 // MemStorage contains a set of values for all metrics and store its in memory
 type MemStorage struct {
-	gauges   map[string]metrics.Gauge
-	counters map[string]metrics.Counter
-	mu       sync.RWMutex
-	Mi       sync.RWMutex
+	gauges         map[string]metrics.Gauge
+	counters       map[string]metrics.Counter
+	histograms     map[string]metrics.Histogram
+	gaugeSamples   map[string][]metrics.Sample // История значений gauge по ключу серии
+	counterSamples map[string][]metrics.Sample // История значений counter по ключу серии
+	retention      time.Duration               // Срок хранения истории, 0 - история не ведётся
+	mu             sync.RWMutex
+	Mi             sync.RWMutex
 }
 
 func NewMemStorage() *MemStorage {
 	return &MemStorage{
-		gauges:   make(map[string]metrics.Gauge),
-		counters: make(map[string]metrics.Counter),
+		gauges:     make(map[string]metrics.Gauge),
+		counters:   make(map[string]metrics.Counter),
+		histograms: make(map[string]metrics.Histogram),
 	}
 }
 
 // Gauge returns the gauge metric by name
 func (m *MemStorage) Gauge(name string) (metrics.Gauge, bool) {
-	return m.GaugeContext(context.Background(), name)
+	return m.GaugeContext(context.Background(), name, nil)
 }
 
-// GaugeContext returns the gauge metric by name
-func (m *MemStorage) GaugeContext(ctx context.Context, name string) (metrics.Gauge, bool) {
+// GaugeContext returns the gauge metric by name and labels
+func (m *MemStorage) GaugeContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Gauge, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if v, ok := m.gauges[name]; ok {
+	if v, ok := m.gauges[metrics.SeriesKey(name, labels)]; ok {
 		return v, ok
 	} else {
 		return metrics.Gauge{}, false
@@ -43,15 +51,15 @@ func (m *MemStorage) GaugeContext(ctx context.Context, name string) (metrics.Gau
 
 // GaugeValue returns the gauge metric value by name
 func (m *MemStorage) GaugeValue(name string) (float64, bool) {
-	return m.GaugeValueContext(context.Background(), name)
+	return m.GaugeValueContext(context.Background(), name, nil)
 }
 
-// GaugeValueContext returns the gauge metric value by name
-func (m *MemStorage) GaugeValueContext(ctx context.Context, name string) (float64, bool) {
+// GaugeValueContext returns the gauge metric value by name and labels
+func (m *MemStorage) GaugeValueContext(ctx context.Context, name string, labels metrics.Labels) (float64, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if gauge, ok := m.gauges[name]; ok {
+	if gauge, ok := m.gauges[metrics.SeriesKey(name, labels)]; ok {
 		return gauge.Value(), ok
 	}
 	return 0, false
@@ -62,7 +70,8 @@ func (m *MemStorage) Gauges(filters ...StorageFilter) map[string]metrics.Gauge {
 	return m.GaugesContext(context.Background(), filters...)
 }
 
-// GaugesContext returns all gauge metrics
+// GaugesContext returns all gauge metrics.
+// The map is a copy, it can be ranged over while the metrics are updated.
 func (m *MemStorage) GaugesContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Gauge {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -72,35 +81,36 @@ func (m *MemStorage) GaugesContext(ctx context.Context, filters ...StorageFilter
 		filter(f)
 	}
 
-	if len(f.names) > 0 {
+	if !f.isEmpty() {
 		diff := make(map[string]metrics.Gauge)
 
-		for _, name := range f.names {
-			if g, ok := m.gauges[name]; ok {
-				diff[name] = g
+		for key, g := range m.gauges {
+			if f.match(g.Name(), g.Labels()) {
+				diff[key] = g
 			}
 		}
 
 		return diff
 	}
 
-	return m.gauges
+	return maps.Clone(m.gauges)
 }
 
 func (m *MemStorage) SetGauge(name string, value float64) error {
-	return m.SetGaugeContext(context.Background(), name, value)
+	return m.SetGaugeContext(context.Background(), name, nil, value)
 }
 
-func (m *MemStorage) SetGaugeContext(ctx context.Context, name string, value float64) error {
+func (m *MemStorage) SetGaugeContext(ctx context.Context, name string, labels metrics.Labels, value float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if m.gauges == nil {
 		m.gauges = make(map[string]metrics.Gauge)
 	}
-	gauge, ok := m.gauges[name]
+	key := metrics.SeriesKey(name, labels)
+	gauge, ok := m.gauges[key]
 	if !ok {
-		g, err := metrics.NewGauge(name, value)
+		g, err := metrics.NewGaugeWithLabels(name, labels, value)
 		if err != nil {
 			return err
 		}
@@ -111,7 +121,8 @@ func (m *MemStorage) SetGaugeContext(ctx context.Context, name string, value flo
 			return err
 		}
 	}
-	m.gauges[name] = gauge
+	m.gauges[key] = gauge
+	m.gaugeSamples = m.appendSample(m.gaugeSamples, key, gauge.Value())
 	return nil
 }
 
@@ -120,20 +131,21 @@ func (m *MemStorage) ResetGauges() error {
 	defer m.mu.Unlock()
 
 	m.gauges = make(map[string]metrics.Gauge)
+	m.gaugeSamples = nil
 	return nil
 }
 
 // Counter returns the counter metric by name
 func (m *MemStorage) Counter(name string) (metrics.Counter, bool) {
-	return m.CounterContext(context.Background(), name)
+	return m.CounterContext(context.Background(), name, nil)
 }
 
-// CounterContext returns the counter metric by name
-func (m *MemStorage) CounterContext(ctx context.Context, name string) (metrics.Counter, bool) {
+// CounterContext returns the counter metric by name and labels
+func (m *MemStorage) CounterContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Counter, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if v, ok := m.counters[name]; ok {
+	if v, ok := m.counters[metrics.SeriesKey(name, labels)]; ok {
 		return v, ok
 	}
 	return metrics.Counter{}, false
@@ -141,15 +153,15 @@ func (m *MemStorage) CounterContext(ctx context.Context, name string) (metrics.C
 
 // CounterValue returns the counter metric value by name
 func (m *MemStorage) CounterValue(name string) (int64, bool) {
-	return m.CounterValueContext(context.Background(), name)
+	return m.CounterValueContext(context.Background(), name, nil)
 }
 
-// CounterValueContext returns the counter metric value by name
-func (m *MemStorage) CounterValueContext(ctx context.Context, name string) (int64, bool) {
+// CounterValueContext returns the counter metric value by name and labels
+func (m *MemStorage) CounterValueContext(ctx context.Context, name string, labels metrics.Labels) (int64, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if v, ok := m.counters[name]; ok {
+	if v, ok := m.counters[metrics.SeriesKey(name, labels)]; ok {
 		return v.Value(), ok
 	}
 	return 0, false
@@ -160,7 +172,8 @@ func (m *MemStorage) Counters(filters ...StorageFilter) map[string]metrics.Count
 	return m.CountersContext(context.Background(), filters...)
 }
 
-// CountersContext returns all counter metrics
+// CountersContext returns all counter metrics.
+// The map is a copy, it can be ranged over while the metrics are updated.
 func (m *MemStorage) CountersContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Counter {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -170,35 +183,36 @@ func (m *MemStorage) CountersContext(ctx context.Context, filters ...StorageFilt
 		filter(f)
 	}
 
-	if len(f.names) > 0 {
+	if !f.isEmpty() {
 		diff := make(map[string]metrics.Counter)
 
-		for _, name := range f.names {
-			if c, ok := m.counters[name]; ok {
-				diff[name] = c
+		for key, c := range m.counters {
+			if f.match(c.Name(), c.Labels()) {
+				diff[key] = c
 			}
 		}
 
 		return diff
 	}
 
-	return m.counters
+	return maps.Clone(m.counters)
 }
 
 func (m *MemStorage) AddCounter(name string, value int64) error {
-	return m.AddCounterContext(context.Background(), name, value)
+	return m.AddCounterContext(context.Background(), name, nil, value)
 }
 
-func (m *MemStorage) AddCounterContext(ctx context.Context, name string, value int64) error {
+func (m *MemStorage) AddCounterContext(ctx context.Context, name string, labels metrics.Labels, value int64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if m.counters == nil {
 		m.counters = make(map[string]metrics.Counter)
 	}
-	counter, ok := m.counters[name]
+	key := metrics.SeriesKey(name, labels)
+	counter, ok := m.counters[key]
 	if !ok {
-		c, err := metrics.NewCounter(name, value)
+		c, err := metrics.NewCounterWithLabels(name, labels, value)
 		if err != nil {
 			return err
 		}
@@ -209,7 +223,8 @@ func (m *MemStorage) AddCounterContext(ctx context.Context, name string, value i
 			return err
 		}
 	}
-	m.counters[name] = counter
+	m.counters[key] = counter
+	m.counterSamples = m.appendSample(m.counterSamples, key, float64(counter.Value()))
 	return nil
 }
 
@@ -218,13 +233,258 @@ func (m *MemStorage) ResetCounters() error {
 	defer m.mu.Unlock()
 
 	m.counters = make(map[string]metrics.Counter)
+	m.counterSamples = nil
 	return nil
 }
 
+// Histogram returns the histogram metric by name
+func (m *MemStorage) Histogram(name string) (metrics.Histogram, bool) {
+	return m.HistogramContext(context.Background(), name, nil)
+}
+
+// HistogramContext returns the histogram metric by name and labels
+func (m *MemStorage) HistogramContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Histogram, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if v, ok := m.histograms[metrics.SeriesKey(name, labels)]; ok {
+		return v, ok
+	}
+	return metrics.Histogram{}, false
+}
+
+// Histograms returns all histogram metrics
+func (m *MemStorage) Histograms(filters ...StorageFilter) map[string]metrics.Histogram {
+	return m.HistogramsContext(context.Background(), filters...)
+}
+
+// HistogramsContext returns all histogram metrics.
+// The map is a copy, it can be ranged over while the metrics are updated.
+func (m *MemStorage) HistogramsContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Histogram {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f := &StorageFilters{}
+	for _, filter := range filters {
+		filter(f)
+	}
+
+	if !f.isEmpty() {
+		diff := make(map[string]metrics.Histogram)
+
+		for key, h := range m.histograms {
+			if f.match(h.Name(), h.Labels()) {
+				diff[key] = h
+			}
+		}
+
+		return diff
+	}
+
+	return maps.Clone(m.histograms)
+}
+
+func (m *MemStorage) AddHistogram(name string, value metrics.HistogramValue) error {
+	return m.AddHistogramContext(context.Background(), name, nil, value)
+}
+
+func (m *MemStorage) AddHistogramContext(ctx context.Context, name string, labels metrics.Labels, value metrics.HistogramValue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.histograms == nil {
+		m.histograms = make(map[string]metrics.Histogram)
+	}
+	key := metrics.SeriesKey(name, labels)
+	histogram, ok := m.histograms[key]
+	if !ok {
+		h, err := metrics.NewHistogramWithLabels(name, labels, value)
+		if err != nil {
+			return err
+		}
+		histogram = *h
+	} else {
+		err := histogram.Merge(value)
+		if err != nil {
+			return err
+		}
+	}
+	m.histograms[key] = histogram
+	return nil
+}
+
+func (m *MemStorage) ResetHistograms() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.histograms = make(map[string]metrics.Histogram)
+	return nil
+}
+
+// GaugeSamples returns the history of the gauge series in the [from, to] time range
+func (m *MemStorage) GaugeSamples(ctx context.Context, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return samplesInRange(m.gaugeSamples[metrics.SeriesKey(name, labels)], from, to), nil
+}
+
+// CounterSamples returns the history of the counter series in the [from, to] time range
+func (m *MemStorage) CounterSamples(ctx context.Context, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return samplesInRange(m.counterSamples[metrics.SeriesKey(name, labels)], from, to), nil
+}
+
+// SetRetention sets the period during which the samples are kept
+func (m *MemStorage) SetRetention(retention time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retention = retention
+}
+
+// PruneSamples removes the samples older than the retention period
+func (m *MemStorage) PruneSamples(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.retention <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-m.retention)
+	pruneSamples(m.gaugeSamples, cutoff)
+	pruneSamples(m.counterSamples, cutoff)
+	return nil
+}
+
+// appendSample adds the current value to the history of the series and drops its expired samples.
+func (m *MemStorage) appendSample(history map[string][]metrics.Sample, key string, value float64) map[string][]metrics.Sample {
+	if m.retention <= 0 {
+		return history
+	}
+	if history == nil {
+		history = make(map[string][]metrics.Sample)
+	}
+
+	now := time.Now()
+	samples := append(history[key], metrics.Sample{Time: now, Value: value})
+	history[key] = dropSamplesBefore(samples, now.Add(-m.retention))
+	return history
+}
+
+// samplesInRange returns a copy of the samples in the [from, to] time range. Samples are sorted by time.
+func samplesInRange(samples []metrics.Sample, from, to time.Time) []metrics.Sample {
+	start := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(from) })
+	end := sort.Search(len(samples), func(i int) bool { return samples[i].Time.After(to) })
+	if start >= end {
+		return []metrics.Sample{}
+	}
+	return append([]metrics.Sample{}, samples[start:end]...)
+}
+
+// dropSamplesBefore removes the samples older than cutoff. Samples are sorted by time.
+func dropSamplesBefore(samples []metrics.Sample, cutoff time.Time) []metrics.Sample {
+	i := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(cutoff) })
+	if i == 0 {
+		return samples
+	}
+	return append([]metrics.Sample(nil), samples[i:]...)
+}
+
+func pruneSamples(history map[string][]metrics.Sample, cutoff time.Time) {
+	for key, samples := range history {
+		samples = dropSamplesBefore(samples, cutoff)
+		if len(samples) == 0 {
+			delete(history, key)
+			continue
+		}
+		history[key] = samples
+	}
+}
+
+// DeleteSeries removes the series of the type with its history
+func (m *MemStorage) DeleteSeries(ctx context.Context, mtype string, name string, labels metrics.Labels) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := metrics.SeriesKey(name, labels)
+
+	var ok bool
+	switch mtype {
+	case metrics.TypeGauge:
+		_, ok = m.gauges[key]
+		delete(m.gauges, key)
+		delete(m.gaugeSamples, key)
+	case metrics.TypeCounter:
+		_, ok = m.counters[key]
+		delete(m.counters, key)
+		delete(m.counterSamples, key)
+	case metrics.TypeHistogram:
+		_, ok = m.histograms[key]
+		delete(m.histograms, key)
+	default:
+		return false, ErrIncorrectType
+	}
+	return ok, nil
+}
+
+// DeleteMetrics removes the series matching the filters with their history
+func (m *MemStorage) DeleteMetrics(ctx context.Context, mtype string, filters ...StorageFilter) (int, error) {
+	f := &StorageFilters{}
+	for _, filter := range filters {
+		filter(f)
+	}
+	if f.isEmpty() {
+		return 0, ErrNoFilters
+	}
+
+	switch mtype {
+	case "", metrics.TypeGauge, metrics.TypeCounter, metrics.TypeHistogram:
+	default:
+		return 0, ErrIncorrectType
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int
+	if mtype == "" || mtype == metrics.TypeGauge {
+		for key, g := range m.gauges {
+			if f.match(g.Name(), g.Labels()) {
+				delete(m.gauges, key)
+				delete(m.gaugeSamples, key)
+				n++
+			}
+		}
+	}
+	if mtype == "" || mtype == metrics.TypeCounter {
+		for key, c := range m.counters {
+			if f.match(c.Name(), c.Labels()) {
+				delete(m.counters, key)
+				delete(m.counterSamples, key)
+				n++
+			}
+		}
+	}
+	if mtype == "" || mtype == metrics.TypeHistogram {
+		for key, h := range m.histograms {
+			if f.match(h.Name(), h.Labels()) {
+				delete(m.histograms, key)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
 func (m *MemStorage) Reset() error {
 	gErr := m.ResetGauges()
 	cErr := m.ResetCounters()
-	return errors.Join(gErr, cErr)
+	hErr := m.ResetHistograms()
+	return errors.Join(gErr, cErr, hErr)
 }
 
 func (m *MemStorage) InsertBatch(opts ...StorageOption) error {
@@ -237,7 +497,7 @@ func (m *MemStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 		opt(o)
 	}
 
-	if len(o.gauges) == 0 && len(o.counters) == 0 {
+	if len(o.gauges) == 0 && len(o.counters) == 0 && len(o.histograms) == 0 {
 		return nil
 	}
 
@@ -250,7 +510,7 @@ func (m *MemStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 		// Check counters for errors
 		if len(o.counters) > 0 {
 			for _, c := range o.counters {
-				err := ts.AddCounterContext(ctx, c.Name(), c.Value())
+				err := ts.AddCounterContext(ctx, c.Name(), c.Labels(), c.Value())
 				if err != nil {
 					return err
 				}
@@ -260,7 +520,22 @@ func (m *MemStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 		// Check gauges for errors
 		if len(o.gauges) > 0 {
 			for _, g := range o.gauges {
-				err := ts.SetGaugeContext(ctx, g.Name(), g.Value())
+				err := ts.SetGaugeContext(ctx, g.Name(), g.Labels(), g.Value())
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Check histograms for errors, including the bounds of already stored series
+		if len(o.histograms) > 0 {
+			for _, h := range o.histograms {
+				if stored, ok := m.HistogramContext(ctx, h.Name(), h.Labels()); ok {
+					if err := ts.AddHistogramContext(ctx, h.Name(), h.Labels(), stored.Value()); err != nil {
+						return err
+					}
+				}
+				err := ts.AddHistogramContext(ctx, h.Name(), h.Labels(), h.Value())
 				if err != nil {
 					return err
 				}
@@ -272,13 +547,19 @@ func (m *MemStorage) InsertBatchContext(ctx context.Context, opts ...StorageOpti
 	{
 		if len(o.counters) > 0 {
 			for _, c := range o.counters {
-				_ = m.AddCounterContext(ctx, c.Name(), c.Value())
+				_ = m.AddCounterContext(ctx, c.Name(), c.Labels(), c.Value())
 			}
 		}
 
 		if len(o.gauges) > 0 {
 			for _, g := range o.gauges {
-				_ = m.SetGaugeContext(ctx, g.Name(), g.Value())
+				_ = m.SetGaugeContext(ctx, g.Name(), g.Labels(), g.Value())
+			}
+		}
+
+		if len(o.histograms) > 0 {
+			for _, h := range o.histograms {
+				_ = m.AddHistogramContext(ctx, h.Name(), h.Labels(), h.Value())
 			}
 		}
 	}
@@ -291,11 +572,17 @@ func (m *MemStorage) MarshalJSON() ([]byte, error) {
 	defer m.mu.RUnlock()
 
 	return json.Marshal(&struct {
-		Gauges   map[string]metrics.Gauge   `json:"gauges"`
-		Counters map[string]metrics.Counter `json:"counters"`
+		Gauges         map[string]metrics.Gauge     `json:"gauges"`
+		Counters       map[string]metrics.Counter   `json:"counters"`
+		Histograms     map[string]metrics.Histogram `json:"histograms,omitempty"`
+		GaugeSamples   map[string][]metrics.Sample  `json:"gauge_samples,omitempty"`
+		CounterSamples map[string][]metrics.Sample  `json:"counter_samples,omitempty"`
 	}{
-		Gauges:   m.gauges,
-		Counters: m.counters,
+		Gauges:         m.gauges,
+		Counters:       m.counters,
+		Histograms:     m.histograms,
+		GaugeSamples:   m.gaugeSamples,
+		CounterSamples: m.counterSamples,
 	})
 }
 
@@ -304,8 +591,11 @@ func (m *MemStorage) UnmarshalJSON(data []byte) error {
 	defer m.mu.Unlock()
 
 	aux := &struct {
-		Gauges   map[string]metrics.Gauge   `json:"gauges"`
-		Counters map[string]metrics.Counter `json:"counters"`
+		Gauges         map[string]metrics.Gauge     `json:"gauges"`
+		Counters       map[string]metrics.Counter   `json:"counters"`
+		Histograms     map[string]metrics.Histogram `json:"histograms,omitempty"`
+		GaugeSamples   map[string][]metrics.Sample  `json:"gauge_samples,omitempty"`
+		CounterSamples map[string][]metrics.Sample  `json:"counter_samples,omitempty"`
 	}{}
 
 	if err := json.Unmarshal(data, aux); err != nil {
@@ -314,6 +604,9 @@ func (m *MemStorage) UnmarshalJSON(data []byte) error {
 
 	m.gauges = aux.Gauges
 	m.counters = aux.Counters
+	m.histograms = aux.Histograms
+	m.gaugeSamples = aux.GaugeSamples
+	m.counterSamples = aux.CounterSamples
 
 	return nil
 }
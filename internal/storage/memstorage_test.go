@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"context"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -11,8 +14,9 @@ import (
 
 func TestNewMemStorage(t *testing.T) {
 	want := &MemStorage{
-		gauges:   map[string]metrics.Gauge{},
-		counters: map[string]metrics.Counter{},
+		gauges:     map[string]metrics.Gauge{},
+		counters:   map[string]metrics.Counter{},
+		histograms: map[string]metrics.Histogram{},
 	}
 	got := NewMemStorage()
 	assert.Equal(t, want, got)
@@ -734,3 +738,237 @@ func TestMemStorage_InsertBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestMemStorage_LabeledSeries(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStorage()
+
+	require.NoError(t, m.SetGaugeContext(ctx, "Alloc", metrics.Labels{"host": "web1"}, 1.5))
+	require.NoError(t, m.SetGaugeContext(ctx, "Alloc", metrics.Labels{"host": "web2"}, 2.5))
+	require.NoError(t, m.SetGauge("Alloc", 3.5))
+	require.NoError(t, m.AddCounterContext(ctx, "PollCount", metrics.Labels{"host": "web1"}, 1))
+	require.NoError(t, m.AddCounterContext(ctx, "PollCount", metrics.Labels{"host": "web1"}, 2))
+	require.NoError(t, m.AddCounterContext(ctx, "PollCount", metrics.Labels{"host": "web2"}, 5))
+	require.Error(t, m.SetGaugeContext(ctx, "Alloc", metrics.Labels{"bad-name": "x"}, 1))
+
+	v, ok := m.GaugeValueContext(ctx, "Alloc", metrics.Labels{"host": "web1"})
+	require.True(t, ok)
+	assert.Equal(t, 1.5, v)
+
+	v, ok = m.GaugeValue("Alloc")
+	require.True(t, ok)
+	assert.Equal(t, 3.5, v)
+
+	_, ok = m.GaugeValueContext(ctx, "Alloc", metrics.Labels{"host": "web3"})
+	assert.False(t, ok)
+
+	c, ok := m.CounterValueContext(ctx, "PollCount", metrics.Labels{"host": "web1"})
+	require.True(t, ok)
+	assert.Equal(t, int64(3), c)
+
+	assert.Len(t, m.Gauges(), 3)
+	assert.Len(t, m.Gauges(FilterName("Alloc")), 3)
+
+	gauges := m.Gauges(FilterName("Alloc"), FilterLabels(metrics.Labels{"host": "web2"}))
+	require.Len(t, gauges, 1)
+	assert.Equal(t, 2.5, gauges[`Alloc{host="web2"}`].Value())
+
+	counters := m.Counters(FilterLabels(metrics.Labels{"host": "web1"}))
+	require.Len(t, counters, 1)
+	assert.Equal(t, int64(3), counters[`PollCount{host="web1"}`].Value())
+}
+
+func TestMemStorage_InsertBatchHistograms(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStorage()
+	require.NoError(t, m.AddHistogramContext(ctx, "latency", metrics.Labels{"host": "web1"},
+		metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{1, 0, 0}, Count: 1, Sum: 0.5}))
+
+	h1, err := metrics.NewHistogramWithLabels("latency", metrics.Labels{"host": "web1"},
+		metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{0, 2, 1}, Count: 3, Sum: 13})
+	require.NoError(t, err)
+	h2, err := metrics.NewHistogramWithLabels("latency", metrics.Labels{"host": "web2"},
+		metrics.HistogramValue{Bounds: []float64{1, 5}, Counts: []uint64{1, 0, 0}, Count: 1, Sum: 0.2})
+	require.NoError(t, err)
+
+	require.NoError(t, m.InsertBatch(WithHistograms([]metrics.Histogram{*h1, *h2})))
+
+	h, ok := m.HistogramContext(ctx, "latency", metrics.Labels{"host": "web1"})
+	require.True(t, ok)
+	assert.Equal(t, []uint64{1, 2, 1}, h.Value().Counts)
+	assert.Equal(t, uint64(4), h.Count())
+	assert.Equal(t, 13.5, h.Sum())
+	assert.Len(t, m.Histograms(FilterName("latency")), 2)
+
+	// Батч с несовместимыми границами бакетов не применяется целиком
+	h3, err := metrics.NewHistogramWithLabels("latency", metrics.Labels{"host": "web1"},
+		metrics.HistogramValue{Bounds: []float64{1, 10}, Counts: []uint64{1, 0, 0}, Count: 1, Sum: 0.1})
+	require.NoError(t, err)
+	err = m.InsertBatch(WithHistograms([]metrics.Histogram{*h2, *h3}))
+	require.ErrorIs(t, err, metrics.ErrHistogramBoundsMismatch)
+
+	h, _ = m.HistogramContext(ctx, "latency", metrics.Labels{"host": "web2"})
+	assert.Equal(t, uint64(1), h.Count())
+}
+
+func TestMemStorage_Samples(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStorage()
+	m.SetRetention(time.Hour)
+
+	start := time.Now()
+	require.NoError(t, m.SetGauge("HeapAlloc", 1))
+	require.NoError(t, m.SetGauge("HeapAlloc", 2))
+	require.NoError(t, m.SetGaugeContext(ctx, "HeapAlloc", metrics.Labels{"host": "web1"}, 5))
+	require.NoError(t, m.AddCounter("PollCount", 1))
+	require.NoError(t, m.AddCounter("PollCount", 2))
+	end := time.Now()
+
+	gs, err := m.GaugeSamples(ctx, "HeapAlloc", nil, start, end)
+	require.NoError(t, err)
+	require.Len(t, gs, 2)
+	assert.Equal(t, float64(1), gs[0].Value)
+	assert.Equal(t, float64(2), gs[1].Value)
+	assert.False(t, gs[1].Time.Before(gs[0].Time))
+
+	cs, err := m.CounterSamples(ctx, "PollCount", nil, start, end)
+	require.NoError(t, err)
+	require.Len(t, cs, 2)
+	assert.Equal(t, float64(3), cs[1].Value)
+
+	gs, err = m.GaugeSamples(ctx, "HeapAlloc", nil, end.Add(time.Second), end.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, gs)
+
+	// Устаревшие значения удаляются
+	m.gaugeSamples["HeapAlloc"][0].Time = start.Add(-2 * time.Hour)
+	require.NoError(t, m.PruneSamples(ctx))
+	gs, err = m.GaugeSamples(ctx, "HeapAlloc", nil, start.Add(-3*time.Hour), end)
+	require.NoError(t, err)
+	assert.Len(t, gs, 1)
+
+	require.NoError(t, m.Reset())
+	gs, err = m.GaugeSamples(ctx, "HeapAlloc", metrics.Labels{"host": "web1"}, start, end)
+	require.NoError(t, err)
+	assert.Empty(t, gs)
+}
+
+func TestMemStorage_DeleteSeries(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStorage()
+	m.SetRetention(time.Hour)
+
+	require.NoError(t, m.SetGaugeContext(ctx, "Alloc", metrics.Labels{"host": "web1"}, 1.5))
+	require.NoError(t, m.SetGaugeContext(ctx, "Alloc", metrics.Labels{"host": "web2"}, 2.5))
+	require.NoError(t, m.AddCounter("Alloc", 1))
+
+	ok, err := m.DeleteSeries(ctx, metrics.TypeGauge, "Alloc", metrics.Labels{"host": "web1"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, ok = m.GaugeValueContext(ctx, "Alloc", metrics.Labels{"host": "web1"})
+	assert.False(t, ok)
+	gs, err := m.GaugeSamples(ctx, "Alloc", metrics.Labels{"host": "web1"}, time.Time{}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, gs, "the history is removed with the series")
+
+	// Другие серии и метрики другого типа с тем же именем остаются
+	_, ok = m.GaugeValueContext(ctx, "Alloc", metrics.Labels{"host": "web2"})
+	assert.True(t, ok)
+	_, ok = m.CounterValue("Alloc")
+	assert.True(t, ok)
+
+	ok, err = m.DeleteSeries(ctx, metrics.TypeGauge, "Alloc", metrics.Labels{"host": "web1"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = m.DeleteSeries(ctx, "summary", "Alloc", nil)
+	assert.ErrorIs(t, err, ErrIncorrectType)
+}
+
+func TestMemStorage_DeleteMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	newStorage := func() *MemStorage {
+		m := NewMemStorage()
+		require.NoError(t, m.SetGauge("HeapAlloc", 1))
+		require.NoError(t, m.SetGaugeContext(ctx, "HeapAlloc", metrics.Labels{"host": "web1"}, 2))
+		require.NoError(t, m.SetGauge("HeapSys", 3))
+		require.NoError(t, m.SetGauge("Alloc", 4))
+		require.NoError(t, m.AddCounter("HeapCount", 1))
+		require.NoError(t, m.AddHistogramContext(ctx, "HeapLatency", nil,
+			metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 0}, Count: 1, Sum: 0.5}))
+		return m
+	}
+
+	testCases := []struct {
+		name    string
+		mtype   string
+		filters []StorageFilter
+		want    int
+		left    []string // Оставшиеся датчики
+		wantErr error
+	}{
+		{name: "Names", mtype: metrics.TypeGauge, filters: []StorageFilter{FilterNames([]string{"HeapAlloc", "Alloc"})}, want: 3, left: []string{"HeapSys"}},
+		{name: "Prefix of all types", filters: []StorageFilter{FilterPrefix("Heap")}, want: 5, left: []string{"Alloc"}},
+		{name: "Prefix and labels", mtype: metrics.TypeGauge, filters: []StorageFilter{FilterPrefix("Heap"), FilterLabels(metrics.Labels{"host": "web1"})}, want: 1, left: []string{"Alloc", "HeapAlloc", "HeapSys"}},
+		{name: "Nothing found", mtype: metrics.TypeCounter, filters: []StorageFilter{FilterName("Alloc")}, want: 0, left: []string{"Alloc", "HeapAlloc", "HeapAlloc", "HeapSys"}},
+		{name: "No filters", wantErr: ErrNoFilters},
+		{name: "Incorrect type", mtype: "summary", filters: []StorageFilter{FilterName("Alloc")}, wantErr: ErrIncorrectType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newStorage()
+			n, err := m.DeleteMetrics(ctx, tc.mtype, tc.filters...)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				assert.Len(t, m.Gauges(), 4, "nothing is deleted")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, n)
+
+			left := make([]string, 0)
+			for _, g := range m.Gauges() {
+				left = append(left, g.Name())
+			}
+			assert.ElementsMatch(t, tc.left, left)
+		})
+	}
+}
+
+func TestMemStorage_SeriesCopy(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemStorage()
+	require.NoError(t, m.SetGauge("a", 1))
+	require.NoError(t, m.AddCounter("b", 1))
+	require.NoError(t, m.AddHistogramContext(ctx, "c", nil, metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 0}, Count: 1, Sum: 0.5}))
+
+	gauges := m.GaugesContext(ctx)
+	counters := m.CountersContext(ctx)
+	histograms := m.HistogramsContext(ctx)
+
+	// Новые серии не попадают в уже полученные карты
+	require.NoError(t, m.SetGauge("a2", 1))
+	require.NoError(t, m.AddCounter("b2", 1))
+	require.NoError(t, m.AddHistogramContext(ctx, "c2", nil, metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 0}, Count: 1, Sum: 0.5}))
+
+	assert.Len(t, gauges, 1)
+	assert.Len(t, counters, 1)
+	assert.Len(t, histograms, 1)
+
+	// Перебор карт не мешает одновременной записи
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = m.SetGaugeContext(ctx, "a", metrics.Labels{"n": strconv.Itoa(i)}, float64(i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		for range m.GaugesContext(ctx) {
+		}
+	}
+	<-done
+}
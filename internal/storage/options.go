@@ -3,8 +3,9 @@ package storage
 import "github.com/fishus/go-advanced-metrics/internal/metrics"
 
 type StorageOptions struct {
-	gauges   []metrics.Gauge
-	counters []metrics.Counter
+	gauges     []metrics.Gauge
+	counters   []metrics.Counter
+	histograms []metrics.Histogram
 }
 
 type StorageOption func(o *StorageOptions)
@@ -32,3 +33,15 @@ func WithGauge(gauge metrics.Gauge) StorageOption {
 		o.gauges = append(o.gauges, gauge)
 	}
 }
+
+func WithHistograms(histograms []metrics.Histogram) StorageOption {
+	return func(o *StorageOptions) {
+		o.histograms = histograms
+	}
+}
+
+func WithHistogram(histogram metrics.Histogram) StorageOption {
+	return func(o *StorageOptions) {
+		o.histograms = append(o.histograms, histogram)
+	}
+}
@@ -2,38 +2,91 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/fishus/go-advanced-metrics/internal/metrics"
 )
 
+var (
+	ErrIncorrectType = errors.New("incorrect metric type")
+	ErrNoFilters     = errors.New("no filters to select the metrics")
+)
+
+// GaugeStorager is an interface for managing gauge series.
+// Series are identified by name and labels; the methods without
+// the Context suffix operate on series without labels.
+// Gauges returns the series keyed by metrics.SeriesKey.
 type GaugeStorager interface {
 	Gauge(name string) (metrics.Gauge, bool)
-	GaugeContext(ctx context.Context, name string) (metrics.Gauge, bool)
+	GaugeContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Gauge, bool)
 	GaugeValue(name string) (float64, bool)
-	GaugeValueContext(ctx context.Context, name string) (float64, bool)
+	GaugeValueContext(ctx context.Context, name string, labels metrics.Labels) (float64, bool)
 	Gauges(filters ...StorageFilter) map[string]metrics.Gauge
 	GaugesContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Gauge
 	SetGauge(name string, value float64) error
-	SetGaugeContext(ctx context.Context, name string, value float64) error
+	SetGaugeContext(ctx context.Context, name string, labels metrics.Labels, value float64) error
 	ResetGauges() error
 }
 
+// CounterStorager is an interface for managing counter series.
+// Series are identified by name and labels; the methods without
+// the Context suffix operate on series without labels.
+// Counters returns the series keyed by metrics.SeriesKey.
 type CounterStorager interface {
 	Counter(name string) (metrics.Counter, bool)
-	CounterContext(ctx context.Context, name string) (metrics.Counter, bool)
+	CounterContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Counter, bool)
 	CounterValue(name string) (int64, bool)
-	CounterValueContext(ctx context.Context, name string) (int64, bool)
+	CounterValueContext(ctx context.Context, name string, labels metrics.Labels) (int64, bool)
 	Counters(filters ...StorageFilter) map[string]metrics.Counter
 	CountersContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Counter
 	AddCounter(name string, value int64) error
-	AddCounterContext(ctx context.Context, name string, value int64) error
+	AddCounterContext(ctx context.Context, name string, labels metrics.Labels, value int64) error
 	ResetCounters() error
 }
 
+// HistogramStorager is an interface for managing histogram series.
+// AddHistogram merges the observations into the stored series;
+// the bucket bounds of the series cannot be changed.
+// Histograms returns the series keyed by metrics.SeriesKey.
+type HistogramStorager interface {
+	Histogram(name string) (metrics.Histogram, bool)
+	HistogramContext(ctx context.Context, name string, labels metrics.Labels) (metrics.Histogram, bool)
+	Histograms(filters ...StorageFilter) map[string]metrics.Histogram
+	HistogramsContext(ctx context.Context, filters ...StorageFilter) map[string]metrics.Histogram
+	AddHistogram(name string, value metrics.HistogramValue) error
+	AddHistogramContext(ctx context.Context, name string, labels metrics.Labels, value metrics.HistogramValue) error
+	ResetHistograms() error
+}
+
+// HistoryStorager is an interface for reading the history of gauge and counter series.
+// Every write appends a timestamped sample with the resulting value of the series.
+// Samples returns the samples in the [from, to] time range ordered by time.
+// PruneSamples removes the samples older than the retention period.
+// The history is recorded only when the retention period is set.
+type HistoryStorager interface {
+	GaugeSamples(ctx context.Context, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error)
+	CounterSamples(ctx context.Context, name string, labels metrics.Labels, from, to time.Time) ([]metrics.Sample, error)
+	SetRetention(retention time.Duration)
+	PruneSamples(ctx context.Context) error
+}
+
+// DeleteStorager is an interface for removing series together with their history.
+// DeleteSeries removes the series of the type with exactly the given name and labels
+// and reports whether it existed. DeleteMetrics removes the series matching the filters,
+// of all types if the type is empty, and returns their number; at least one filter is required.
+type DeleteStorager interface {
+	DeleteSeries(ctx context.Context, mtype string, name string, labels metrics.Labels) (bool, error)
+	DeleteMetrics(ctx context.Context, mtype string, filters ...StorageFilter) (int, error)
+}
+
 // MetricsStorager is an interface for managing a set of metrics
 type MetricsStorager interface {
 	GaugeStorager
 	CounterStorager
+	HistogramStorager
+	HistoryStorager
+	DeleteStorager
 	Reset() error
 	InsertBatch(opts ...StorageOption) error
 	InsertBatchContext(ctx context.Context, opts ...StorageOption) error
@@ -0,0 +1,208 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/fishus/go-advanced-metrics/internal/handlers/middleware"
+	"github.com/fishus/go-advanced-metrics/internal/identity"
+)
+
+type TLSConfigSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (s *TLSConfigSuite) SetupSuite() {
+	s.dir = s.T().TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "metrics test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	s.Require().NoError(err)
+	caCert, err := x509.ParseCertificate(caDER)
+	s.Require().NoError(err)
+	s.writePEM("ca.crt", "CERTIFICATE", caDER)
+
+	s.issue("server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, caKey)
+
+	s.issue("agent", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "agent-1"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, caKey)
+}
+
+func (s *TLSConfigSuite) issue(name string, tmpl, ca *x509.Certificate, caKey *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	s.Require().NoError(err)
+	s.writePEM(name+".crt", "CERTIFICATE", der)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	s.Require().NoError(err)
+	s.writePEM(name+".key", "EC PRIVATE KEY", keyDER)
+}
+
+func (s *TLSConfigSuite) writePEM(name, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	s.Require().NoError(os.WriteFile(s.path(name), data, 0600))
+}
+
+func (s *TLSConfigSuite) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *TLSConfigSuite) TestServer() {
+	s.Run("Positive case: Certificate without client CA", func() {
+		cfg, err := Server(s.path("server.crt"), s.path("server.key"), "")
+		s.Require().NoError(err)
+		s.Assert().Len(cfg.Certificates, 1)
+		s.Assert().Equal(tls.NoClientCert, cfg.ClientAuth)
+	})
+
+	s.Run("Positive case: Certificate with client CA", func() {
+		cfg, err := Server(s.path("server.crt"), s.path("server.key"), s.path("ca.crt"))
+		s.Require().NoError(err)
+		s.Assert().NotNil(cfg.ClientCAs)
+		s.Assert().Equal(tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+	})
+
+	s.Run("Negative case: Key is missing", func() {
+		_, err := Server(s.path("server.crt"), "", "")
+		s.Assert().Error(err)
+	})
+
+	s.Run("Negative case: Key does not match the certificate", func() {
+		_, err := Server(s.path("server.crt"), s.path("agent.key"), "")
+		s.Assert().Error(err)
+	})
+
+	s.Run("Negative case: CA bundle without certificates", func() {
+		_, err := Server(s.path("server.crt"), s.path("server.key"), s.path("server.key"))
+		s.Assert().Error(err)
+	})
+}
+
+func (s *TLSConfigSuite) TestClient() {
+	s.Run("Positive case: System roots", func() {
+		cfg, err := Client("", "", "")
+		s.Require().NoError(err)
+		s.Assert().Nil(cfg.RootCAs)
+		s.Assert().Empty(cfg.Certificates)
+	})
+
+	s.Run("Positive case: CA bundle and client certificate", func() {
+		cfg, err := Client(s.path("ca.crt"), s.path("agent.crt"), s.path("agent.key"))
+		s.Require().NoError(err)
+		s.Assert().NotNil(cfg.RootCAs)
+		s.Assert().Len(cfg.Certificates, 1)
+	})
+
+	s.Run("Negative case: Client key is missing", func() {
+		_, err := Client(s.path("ca.crt"), s.path("agent.crt"), "")
+		s.Assert().Error(err)
+	})
+
+	s.Run("Negative case: CA bundle does not exist", func() {
+		_, err := Client(s.path("missing.crt"), "", "")
+		s.Assert().Error(err)
+	})
+}
+
+func (s *TLSConfigSuite) TestMutualTLS() {
+	serverConf, err := Server(s.path("server.crt"), s.path("server.key"), s.path("ca.crt"))
+	s.Require().NoError(err)
+
+	ts := httptest.NewUnstartedServer(middleware.Identity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := identity.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, id.Name)
+	})))
+	ts.TLS = serverConf
+	ts.StartTLS()
+	defer ts.Close()
+
+	s.Run("Positive case: Client certificate", func() {
+		clientConf, err := Client(s.path("ca.crt"), s.path("agent.crt"), s.path("agent.key"))
+		s.Require().NoError(err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConf}}
+		resp, err := client.Get(ts.URL)
+		s.Require().NoError(err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		s.Require().NoError(err)
+		s.Assert().Equal(http.StatusOK, resp.StatusCode)
+		s.Assert().Equal("agent-1", string(body))
+	})
+
+	s.Run("Negative case: No client certificate", func() {
+		clientConf, err := Client(s.path("ca.crt"), "", "")
+		s.Require().NoError(err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConf}}
+		resp, err := client.Get(ts.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		s.Assert().Error(err)
+	})
+
+	s.Run("Negative case: Unknown server CA", func() {
+		clientConf, err := Client("", s.path("agent.crt"), s.path("agent.key"))
+		s.Require().NoError(err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConf}}
+		resp, err := client.Get(ts.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		s.Assert().Error(err)
+	})
+}
+
+func TestTLSConfigSuite(t *testing.T) {
+	suite.Run(t, new(TLSConfigSuite))
+}
@@ -19,8 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Metrics_Update_FullMethodName  = "/metrics.Metrics/Update"
-	Metrics_Updates_FullMethodName = "/metrics.Metrics/Updates"
+	Metrics_Update_FullMethodName        = "/metrics.Metrics/Update"
+	Metrics_Updates_FullMethodName       = "/metrics.Metrics/Updates"
+	Metrics_Value_FullMethodName         = "/metrics.Metrics/Value"
+	Metrics_List_FullMethodName          = "/metrics.Metrics/List"
+	Metrics_Watch_FullMethodName         = "/metrics.Metrics/Watch"
+	Metrics_StreamUpdates_FullMethodName = "/metrics.Metrics/StreamUpdates"
+	Metrics_Delete_FullMethodName        = "/metrics.Metrics/Delete"
+	Metrics_DeleteMetrics_FullMethodName = "/metrics.Metrics/DeleteMetrics"
 )
 
 // MetricsClient is the client API for Metrics service.
@@ -29,6 +35,12 @@ const (
 type MetricsClient interface {
 	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
 	Updates(ctx context.Context, in *UpdatesRequest, opts ...grpc.CallOption) (*UpdatesResponse, error)
+	Value(ctx context.Context, in *ValueRequest, opts ...grpc.CallOption) (*ValueResponse, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Metrics_WatchClient, error)
+	StreamUpdates(ctx context.Context, opts ...grpc.CallOption) (Metrics_StreamUpdatesClient, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	DeleteMetrics(ctx context.Context, in *DeleteMetricsRequest, opts ...grpc.CallOption) (*DeleteMetricsResponse, error)
 }
 
 type metricsClient struct {
@@ -57,12 +69,117 @@ func (c *metricsClient) Updates(ctx context.Context, in *UpdatesRequest, opts ..
 	return out, nil
 }
 
+func (c *metricsClient) Value(ctx context.Context, in *ValueRequest, opts ...grpc.CallOption) (*ValueResponse, error) {
+	out := new(ValueResponse)
+	err := c.cc.Invoke(ctx, Metrics_Value_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Metrics_List_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Metrics_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Metrics_ServiceDesc.Streams[0], Metrics_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metricsWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Metrics_WatchClient interface {
+	Recv() (*WatchResponse, error)
+	grpc.ClientStream
+}
+
+type metricsWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *metricsWatchClient) Recv() (*WatchResponse, error) {
+	m := new(WatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *metricsClient) StreamUpdates(ctx context.Context, opts ...grpc.CallOption) (Metrics_StreamUpdatesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Metrics_ServiceDesc.Streams[1], Metrics_StreamUpdates_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metricsStreamUpdatesClient{stream}
+	return x, nil
+}
+
+type Metrics_StreamUpdatesClient interface {
+	Send(*StreamUpdatesRequest) error
+	Recv() (*StreamUpdatesResponse, error)
+	grpc.ClientStream
+}
+
+type metricsStreamUpdatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *metricsStreamUpdatesClient) Send(m *StreamUpdatesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *metricsStreamUpdatesClient) Recv() (*StreamUpdatesResponse, error) {
+	m := new(StreamUpdatesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *metricsClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, Metrics_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) DeleteMetrics(ctx context.Context, in *DeleteMetricsRequest, opts ...grpc.CallOption) (*DeleteMetricsResponse, error) {
+	out := new(DeleteMetricsResponse)
+	err := c.cc.Invoke(ctx, Metrics_DeleteMetrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricsServer is the server API for Metrics service.
 // All implementations must embed UnimplementedMetricsServer
 // for forward compatibility
 type MetricsServer interface {
 	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
 	Updates(context.Context, *UpdatesRequest) (*UpdatesResponse, error)
+	Value(context.Context, *ValueRequest) (*ValueResponse, error)
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	Watch(*WatchRequest, Metrics_WatchServer) error
+	StreamUpdates(Metrics_StreamUpdatesServer) error
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	DeleteMetrics(context.Context, *DeleteMetricsRequest) (*DeleteMetricsResponse, error)
 	mustEmbedUnimplementedMetricsServer()
 }
 
@@ -76,6 +193,24 @@ func (UnimplementedMetricsServer) Update(context.Context, *UpdateRequest) (*Upda
 func (UnimplementedMetricsServer) Updates(context.Context, *UpdatesRequest) (*UpdatesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Updates not implemented")
 }
+func (UnimplementedMetricsServer) Value(context.Context, *ValueRequest) (*ValueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Value not implemented")
+}
+func (UnimplementedMetricsServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedMetricsServer) Watch(*WatchRequest, Metrics_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedMetricsServer) StreamUpdates(Metrics_StreamUpdatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUpdates not implemented")
+}
+func (UnimplementedMetricsServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedMetricsServer) DeleteMetrics(context.Context, *DeleteMetricsRequest) (*DeleteMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMetrics not implemented")
+}
 func (UnimplementedMetricsServer) mustEmbedUnimplementedMetricsServer() {}
 
 // UnsafeMetricsServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +260,125 @@ func _Metrics_Updates_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Metrics_Value_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).Value(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_Value_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).Value(ctx, req.(*ValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetricsServer).Watch(m, &metricsWatchServer{stream})
+}
+
+type Metrics_WatchServer interface {
+	Send(*WatchResponse) error
+	grpc.ServerStream
+}
+
+type metricsWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *metricsWatchServer) Send(m *WatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Metrics_StreamUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MetricsServer).StreamUpdates(&metricsStreamUpdatesServer{stream})
+}
+
+type Metrics_StreamUpdatesServer interface {
+	Send(*StreamUpdatesResponse) error
+	Recv() (*StreamUpdatesRequest, error)
+	grpc.ServerStream
+}
+
+type metricsStreamUpdatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *metricsStreamUpdatesServer) Send(m *StreamUpdatesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *metricsStreamUpdatesServer) Recv() (*StreamUpdatesRequest, error) {
+	m := new(StreamUpdatesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Metrics_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_DeleteMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).DeleteMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_DeleteMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).DeleteMetrics(ctx, req.(*DeleteMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Metrics_ServiceDesc is the grpc.ServiceDesc for Metrics service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,7 +394,35 @@ var Metrics_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Updates",
 			Handler:    _Metrics_Updates_Handler,
 		},
+		{
+			MethodName: "Value",
+			Handler:    _Metrics_Value_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Metrics_List_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Metrics_Delete_Handler,
+		},
+		{
+			MethodName: "DeleteMetrics",
+			Handler:    _Metrics_DeleteMetrics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Metrics_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamUpdates",
+			Handler:       _Metrics_StreamUpdates_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/metrics.proto",
 }